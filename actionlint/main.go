@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"dagger/actionlint/internal/dagger"
+)
+
+const (
+	// The image bundles shellcheck and pyflakes, which actionlint uses to
+	// check run: blocks.
+	actionlintImage string = "rhysd/actionlint:1.7.7"
+)
+
+type Actionlint struct {
+	// Source is the repository directory containing .github/workflows.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Ignore is the list of regular expressions matching errors to ignore.
+	//
+	// +private
+	Ignore []string
+}
+
+// New creates a new Actionlint module instance.
+func New(
+	// The repository directory containing .github/workflows. A
+	// .github/actionlint.yaml in it is used as configuration.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Regular expressions matching error messages to ignore
+	// (e.g., "SC2086", "label .+ is unknown")
+	// +optional
+	ignore []string,
+) *Actionlint {
+	return &Actionlint{
+		Source: source,
+		Ignore: ignore,
+	}
+}
+
+// Check validates the GitHub Actions workflows, including shellcheck of run:
+// blocks, and fails on any error, making this suitable for CI checks.
+//
+// +check
+func (m *Actionlint) Check(ctx context.Context) (string, error) {
+	_, err := m.actionlintContainer().
+		WithExec(m.buildArgs("-no-color")).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("workflow errors found\n\n%s", e.Stdout)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return "✅ workflows are valid", nil
+}
+
+// Report validates the GitHub Actions workflows and returns the errors as a
+// JSON array without failing.
+func (m *Actionlint) Report() *dagger.File {
+	return m.actionlintContainer().
+		WithExec(
+			m.buildArgs("-format", "{{json .}}"),
+			dagger.ContainerWithExecOpts{
+				RedirectStdout: "/actionlint.json",
+				// actionlint exits non-zero when errors are found.
+				Expect: dagger.ReturnTypeAny,
+			},
+		).
+		File("/actionlint.json")
+}
+
+// buildArgs constructs the actionlint command arguments. Any extra flags
+// passed in are appended.
+func (m *Actionlint) buildArgs(extra ...string) []string {
+	args := []string{"actionlint"}
+	for _, pattern := range m.Ignore {
+		args = append(args, "-ignore", pattern)
+	}
+	return append(args, extra...)
+}
+
+// actionlintContainer returns an actionlint container with the repository
+// mounted.
+func (m *Actionlint) actionlintContainer() *dagger.Container {
+	return dag.Container().
+		From(actionlintImage).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"dagger/baseimage/internal/dagger"
+)
+
+const (
+	craneImage string = "gcr.io/go-containerregistry/crane:v0.20.3"
+)
+
+// Baseimage finds the base images pinned in Dockerfiles and Dagger module
+// sources and checks registries for newer tags and digests.
+type Baseimage struct {
+	// Source is the repository to scan.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Policy is the largest tag update allowed: "digest", "patch", "minor",
+	// or "major".
+	//
+	// +private
+	Policy string
+
+	// Pin adds digests to references that have none.
+	//
+	// +private
+	Pin bool
+
+	// Exclude are directories and files that are not scanned.
+	//
+	// +private
+	Exclude []string
+
+	// RegistryAddresses are the registries added with WithRegistryAuth.
+	//
+	// +private
+	RegistryAddresses []string
+
+	// RegistryUsernames are the usernames for RegistryAddresses, by index.
+	//
+	// +private
+	RegistryUsernames []string
+
+	// RegistryTokens are the tokens for RegistryAddresses, by index.
+	//
+	// +private
+	RegistryTokens []*dagger.Secret
+}
+
+// UpdateResult is an updated source directory and a report of the
+// updates, ready to commit and open as a pull request.
+type UpdateResult struct {
+	// Source directory with the base image references updated
+	Source *dagger.Directory
+
+	// Markdown report, suitable as a pull request body
+	Summary string
+
+	// Every reference that was updated
+	Updates []ImageUpdate
+}
+
+// New creates a new Baseimage module instance.
+func New(
+	// The repository to scan.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Largest tag update allowed: "digest" (keep tags, only refresh
+	// digests), "patch", "minor", or "major"
+	// +optional
+	// +default="minor"
+	policy string,
+
+	// Add digests to references that have none, so that a moving tag like
+	// "latest" cannot change the image under a build
+	// +optional
+	// +default=true
+	pin bool,
+
+	// Directories and files, relative to source, that are not scanned
+	// +optional
+	exclude []string,
+) *Baseimage {
+	return &Baseimage{
+		Source:  source,
+		Policy:  policy,
+		Pin:     pin,
+		Exclude: exclude,
+	}
+}
+
+// WithRegistryAuth adds credentials for a registry, used to list tags and
+// resolve digests of private images. Chain once per registry.
+func (m *Baseimage) WithRegistryAuth(
+	// Registry address (e.g., "ghcr.io")
+	address string,
+
+	// Registry username
+	username string,
+
+	// Registry token or password
+	token *dagger.Secret,
+) *Baseimage {
+	m.RegistryAddresses = append(m.RegistryAddresses, address)
+	m.RegistryUsernames = append(m.RegistryUsernames, username)
+	m.RegistryTokens = append(m.RegistryTokens, token)
+	return m
+}
+
+// List returns every base image reference found, one "file:line image" per
+// line.
+func (m *Baseimage) List(ctx context.Context) (string, error) {
+	refs, _, err := m.scan(ctx)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, ref := range refs {
+		fmt.Fprintf(&b, "%s:%d %s\n", ref.File, ref.Line, ref.Ref)
+	}
+	return b.String(), nil
+}
+
+// Report checks every base image reference against its registry and
+// returns a markdown report of the available updates.
+func (m *Baseimage) Report(ctx context.Context) (string, error) {
+	refs, _, err := m.scan(ctx)
+	if err != nil {
+		return "", err
+	}
+	updates, err := m.updates(ctx, refs)
+	if err != nil {
+		return "", err
+	}
+	return summary(updates, len(refs)), nil
+}
+
+// Update checks every base image reference against its registry and
+// returns the source with the references updated, with a markdown report.
+func (m *Baseimage) Update(ctx context.Context) (*UpdateResult, error) {
+	refs, contents, err := m.scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	updates, err := m.updates(ctx, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	byFile := map[string][]ImageUpdate{}
+	for _, u := range updates {
+		byFile[u.File] = append(byFile[u.File], u)
+	}
+	files := make([]string, 0, len(byFile))
+	for file := range byFile {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	source := m.Source
+	for _, file := range files {
+		source = source.WithNewFile(file, rewrite(contents[file], byFile[file]))
+	}
+
+	return &UpdateResult{
+		Source:  source,
+		Summary: summary(updates, len(refs)),
+		Updates: updates,
+	}, nil
+}
+
+// scan returns the base image references in the source, in file and line
+// order, and the contents of the files they are in.
+func (m *Baseimage) scan(ctx context.Context) ([]reference, map[string]string, error) {
+	var files []string
+	for _, pattern := range []string{"**/*.go", "**/*Dockerfile*", "**/*Containerfile*"} {
+		matches, err := m.Source.Glob(ctx, pattern)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		for _, file := range matches {
+			if strings.HasSuffix(file, "/") || slices.Contains(files, file) || m.skip(file) {
+				continue
+			}
+			if path.Ext(file) == ".go" || dockerfile(file) {
+				files = append(files, file)
+			}
+		}
+	}
+	sort.Strings(files)
+
+	var refs []reference
+	contents := map[string]string{}
+	for _, file := range files {
+		data, err := m.Source.File(file).Contents(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+
+		var found []reference
+		if path.Ext(file) == ".go" {
+			found, err = scanGo(file, data)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse %s: %w", file, err)
+			}
+		} else {
+			found = scanDockerfile(file, data)
+		}
+		if len(found) > 0 {
+			refs = append(refs, found...)
+			contents[file] = data
+		}
+	}
+	return refs, contents, nil
+}
+
+// skip reports whether a file is excluded, vendored, or generated Dagger
+// client code.
+func (m *Baseimage) skip(file string) bool {
+	for _, dir := range m.Exclude {
+		dir = strings.Trim(path.Clean(dir), "/")
+		if file == dir || strings.HasPrefix(file, dir+"/") {
+			return true
+		}
+	}
+	for _, part := range strings.Split(path.Dir(file), "/") {
+		if part == "vendor" || part == "node_modules" {
+			return true
+		}
+	}
+	return strings.HasPrefix(file, "internal/dagger/") || strings.Contains(file, "/internal/dagger/")
+}
+
+// updates checks each reference against its registry and returns the
+// updates the policy allows.
+func (m *Baseimage) updates(ctx context.Context, refs []reference) ([]ImageUpdate, error) {
+	if _, ok := fixedComponents[m.Policy]; !ok && m.Policy != "digest" {
+		return nil, fmt.Errorf("invalid policy %q: must be \"digest\", \"patch\", \"minor\", or \"major\"", m.Policy)
+	}
+
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reg := &registry{ctr: ctr, tags: map[string][]string{}, digests: map[string]string{}}
+
+	var updates []ImageUpdate
+	for _, ref := range refs {
+		img := parseImage(ref.Ref)
+		tag := img.tagOrLatest()
+
+		newTag := tag
+		if _, ok := parseVersion(tag); ok && m.Policy != "digest" {
+			tags, err := reg.list(ctx, img.Name)
+			if err != nil {
+				return nil, err
+			}
+			if t := newerTag(tag, tags, m.Policy); t != "" {
+				newTag = t
+			}
+		}
+
+		to, kind := image{Name: img.Name, Tag: img.Tag}, ""
+		if newTag != tag {
+			to.Tag, kind = newTag, updateKind(tag, newTag)
+		}
+		if img.Digest != "" || m.Pin {
+			digest, err := reg.digest(ctx, img.Name+":"+newTag)
+			if err != nil {
+				return nil, err
+			}
+			to.Digest = digest
+			switch {
+			case kind != "":
+			case img.Digest == "":
+				kind = "pin"
+			case img.Digest != digest:
+				kind = "digest"
+			}
+		}
+		if kind == "" {
+			continue
+		}
+
+		updates = append(updates, ImageUpdate{
+			File: ref.File,
+			Line: ref.Line,
+			From: ref.Ref,
+			To:   to.String(),
+			Kind: kind,
+		})
+	}
+	return updates, nil
+}
+
+// registry looks up tags and digests with crane, once per repository and
+// tag.
+type registry struct {
+	ctr     *dagger.Container
+	tags    map[string][]string
+	digests map[string]string
+}
+
+// list returns the tags of a repository.
+func (r *registry) list(ctx context.Context, repo string) ([]string, error) {
+	if tags, ok := r.tags[repo]; ok {
+		return tags, nil
+	}
+	out, err := r.crane(ctx, "ls", repo)
+	if err != nil {
+		return nil, err
+	}
+	tags := strings.Fields(out)
+	r.tags[repo] = tags
+	return tags, nil
+}
+
+// digest returns the digest a tag points to.
+func (r *registry) digest(ctx context.Context, ref string) (string, error) {
+	if digest, ok := r.digests[ref]; ok {
+		return digest, nil
+	}
+	out, err := r.crane(ctx, "digest", ref)
+	if err != nil {
+		return "", err
+	}
+	digest := strings.TrimSpace(out)
+	r.digests[ref] = digest
+	return digest, nil
+}
+
+func (r *registry) crane(ctx context.Context, args ...string) (string, error) {
+	out, err := r.ctr.
+		WithExec(args, dagger.ContainerWithExecOpts{UseEntrypoint: true}).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("failed to look up %s: add registry credentials with with-registry-auth or exclude its files\n\n%s", args[len(args)-1], e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+	return out, nil
+}
+
+// container returns the crane container with a Docker config.json holding
+// the registry credentials mounted as a secret.
+func (m *Baseimage) container(ctx context.Context) (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(craneImage).
+		// Tags move between checks; always look them up.
+		WithEnvVariable("BASEIMAGE_SESSION", fmt.Sprintf("%d", time.Now().UnixNano()))
+	if len(m.RegistryAddresses) == 0 {
+		return ctr, nil
+	}
+
+	auths := map[string]map[string]string{}
+	for i, address := range m.RegistryAddresses {
+		token, err := m.RegistryTokens[i].Plaintext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token for %s: %w", address, err)
+		}
+		auths[address] = map[string]string{
+			"auth": base64.StdEncoding.EncodeToString([]byte(m.RegistryUsernames[i] + ":" + token)),
+		}
+	}
+
+	config, err := json.Marshal(map[string]any{"auths": auths})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode registry config: %w", err)
+	}
+
+	return ctr.
+		WithMountedSecret("/docker/config.json", dag.SetSecret("baseimage-docker-config", string(config))).
+		WithEnvVariable("DOCKER_CONFIG", "/docker"), nil
+}
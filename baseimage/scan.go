@@ -0,0 +1,154 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// imagePattern matches image references: a repository, with an optional
+// registry host and port, tag, and digest.
+var imagePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9._-]*[a-z0-9])?(:[0-9]+)?(/[a-z0-9]([a-z0-9._-]*[a-z0-9])?)*(:[A-Za-z0-9_][A-Za-z0-9_.-]*)?(@sha256:[a-f0-9]{64})?$`)
+
+// reference is a base image reference found in a source file.
+type reference struct {
+	// File is the path of the file, relative to the source.
+	File string
+
+	// Line is the line of the reference.
+	Line int
+
+	// Ref is the reference as written (e.g., "alpine:3.21").
+	Ref string
+}
+
+// dockerfile reports whether a file is a Dockerfile or Containerfile by its
+// name (e.g., "Dockerfile", "Dockerfile.release", "build.Dockerfile").
+func dockerfile(name string) bool {
+	base := path.Base(name)
+	for _, n := range []string{"Dockerfile", "Containerfile"} {
+		if base == n || strings.HasPrefix(base, n+".") || strings.HasSuffix(base, "."+n) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanDockerfile returns the images of a Dockerfile's FROM instructions.
+// Build stages, scratch, and references with build arguments are skipped.
+func scanDockerfile(file, contents string) []reference {
+	var refs []reference
+	stages := map[string]bool{}
+	for i, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "FROM") {
+			continue
+		}
+
+		args := fields[1:]
+		for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+			args = args[1:]
+		}
+		if len(args) == 0 {
+			continue
+		}
+		ref := args[0]
+		if ref != "scratch" && !stages[strings.ToLower(ref)] && imagePattern.MatchString(ref) {
+			refs = append(refs, reference{File: file, Line: i + 1, Ref: ref})
+		}
+		if len(args) >= 3 && strings.EqualFold(args[1], "AS") {
+			stages[strings.ToLower(args[2])] = true
+		}
+	}
+	return refs
+}
+
+// scanGo returns the images of a Dagger module source file: the string
+// arguments of From calls and the values of constants and variables named
+// "...Image".
+func scanGo(file, contents string) ([]reference, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, file, contents, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []reference
+	add := func(expr ast.Expr) {
+		lit, ok := expr.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return
+		}
+		value, err := strconv.Unquote(lit.Value)
+		if err != nil || !imagePattern.MatchString(value) {
+			return
+		}
+		refs = append(refs, reference{File: file, Line: fset.Position(lit.Pos()).Line, Ref: value})
+	}
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			if sel, ok := n.Fun.(*ast.SelectorExpr); ok && sel.Sel.Name == "From" && len(n.Args) == 1 {
+				add(n.Args[0])
+			}
+		case *ast.ValueSpec:
+			for i, name := range n.Names {
+				if i < len(n.Values) && strings.HasSuffix(strings.ToLower(name.Name), "image") {
+					add(n.Values[i])
+				}
+			}
+		}
+		return true
+	})
+	return refs, nil
+}
+
+// image is a parsed image reference.
+type image struct {
+	// Name is the repository (e.g., "alpine", "ghcr.io/acme/app").
+	Name string
+
+	// Tag is the tag, or "" when the reference has none.
+	Tag string
+
+	// Digest is the pinned digest, or "" when the reference has none.
+	Digest string
+}
+
+// parseImage splits a reference into its repository, tag, and digest.
+func parseImage(ref string) image {
+	var img image
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref, img.Digest = ref[:i], ref[i+1:]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref, img.Tag = ref[:i], ref[i+1:]
+	}
+	img.Name = ref
+	return img
+}
+
+// String returns the reference, with the tag and digest it has.
+func (img image) String() string {
+	ref := img.Name
+	if img.Tag != "" {
+		ref += ":" + img.Tag
+	}
+	if img.Digest != "" {
+		ref += "@" + img.Digest
+	}
+	return ref
+}
+
+// tagOrLatest returns the tag registries resolve the reference to.
+func (img image) tagOrLatest() string {
+	if img.Tag == "" {
+		return "latest"
+	}
+	return img.Tag
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// versionTag matches version tags: an optional "v", dotted numbers, and a
+// suffix such as "-bookworm" or "-alpine".
+var versionTag = regexp.MustCompile(`^(v?)([0-9]+(?:\.[0-9]+)*)(.*)$`)
+
+// ImageUpdate is an update to a base image reference.
+type ImageUpdate struct {
+	// File the reference is in, relative to the source
+	File string
+
+	// Line of the reference
+	Line int
+
+	// Reference as written (e.g., "alpine:3.21")
+	From string
+
+	// Updated reference (e.g., "alpine:3.22@sha256:...")
+	To string
+
+	// "major", "minor", or "patch" for a newer tag, "digest" for a pinned
+	// digest the tag no longer points to, or "pin" for an added digest
+	Kind string
+}
+
+// version is a parsed version tag.
+type version struct {
+	prefix string
+	nums   []int
+	suffix string
+}
+
+// parseVersion parses a version tag such as "1.26-bookworm" or "v3.9.4".
+func parseVersion(tag string) (version, bool) {
+	m := versionTag.FindStringSubmatch(tag)
+	if m == nil {
+		return version{}, false
+	}
+	v := version{prefix: m[1], suffix: m[3]}
+	for _, part := range strings.Split(m[2], ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return version{}, false
+		}
+		v.nums = append(v.nums, n)
+	}
+	return v, true
+}
+
+// fixedComponents is how many leading version components a policy keeps.
+var fixedComponents = map[string]int{
+	"patch": 2,
+	"minor": 1,
+	"major": 0,
+}
+
+// newerTag returns the newest of tags with the same shape as current (the
+// same prefix, number of components, and suffix, so "1.26-bookworm" only
+// moves to another "X.Y-bookworm") that the policy allows, or "" when there
+// is none.
+func newerTag(current string, tags []string, policy string) string {
+	cur, ok := parseVersion(current)
+	fixed, allowed := fixedComponents[policy]
+	if !ok || !allowed || len(cur.nums) <= fixed {
+		return ""
+	}
+
+	best, newest := "", cur
+	for _, tag := range tags {
+		v, ok := parseVersion(tag)
+		if !ok || v.prefix != cur.prefix || v.suffix != cur.suffix || len(v.nums) != len(cur.nums) {
+			continue
+		}
+		if compareNums(v.nums[:fixed], cur.nums[:fixed]) != 0 {
+			continue
+		}
+		if compareNums(v.nums, newest.nums) > 0 {
+			best, newest = tag, v
+		}
+	}
+	return best
+}
+
+// compareNums compares two version components of the same length.
+func compareNums(a, b []int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// updateKind classifies a tag change by the first component that changed.
+func updateKind(from, to string) string {
+	a, _ := parseVersion(from)
+	b, _ := parseVersion(to)
+	for i := 0; i < len(a.nums) && i < len(b.nums) && i < 2; i++ {
+		if a.nums[i] != b.nums[i] {
+			if i == 0 {
+				return "major"
+			}
+			return "minor"
+		}
+	}
+	return "patch"
+}
+
+// rewrite applies the updates of one file to its contents.
+func rewrite(contents string, updates []ImageUpdate) string {
+	lines := strings.Split(contents, "\n")
+	for _, u := range updates {
+		if u.Line < 1 || u.Line > len(lines) {
+			continue
+		}
+		lines[u.Line-1] = replaceRef(lines[u.Line-1], u.From, u.To)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// replaceRef replaces the first whole occurrence of ref in line, so that
+// "alpine" does not match inside "alpine:3.21" or "node-alpine".
+func replaceRef(line, ref, to string) string {
+	for start := 0; start < len(line); {
+		i := strings.Index(line[start:], ref)
+		if i < 0 {
+			break
+		}
+		i += start
+		end := i + len(ref)
+		if (i == 0 || !refChar(line[i-1])) && (end == len(line) || !refChar(line[end])) {
+			return line[:i] + to + line[end:]
+		}
+		start = i + 1
+	}
+	return line
+}
+
+func refChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("._-:/@", c) >= 0
+}
+
+// summary renders updates as a markdown report, one row per distinct
+// update with every reference it applies to.
+func summary(updates []ImageUpdate, checked int) string {
+	var b strings.Builder
+	b.WriteString("## Base image updates\n\n")
+
+	if len(updates) == 0 {
+		fmt.Fprintf(&b, "All %d base image references are up to date.\n", checked)
+		return b.String()
+	}
+
+	type row struct {
+		from, to, kind string
+		refs           []string
+	}
+	var rows []*row
+	byUpdate := map[string]*row{}
+	for _, u := range updates {
+		key := u.From + " " + u.To
+		r, ok := byUpdate[key]
+		if !ok {
+			r = &row{from: u.From, to: u.To, kind: u.Kind}
+			byUpdate[key] = r
+			rows = append(rows, r)
+		}
+		r.refs = append(r.refs, fmt.Sprintf("`%s:%d`", u.File, u.Line))
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].from < rows[j].from })
+
+	fmt.Fprintf(&b, "%d of %d base image references can be updated.\n\n", len(updates), checked)
+	b.WriteString("| Image | Update | Kind | References |\n")
+	b.WriteString("|-------|--------|------|------------|\n")
+	for _, r := range rows {
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s |\n", r.from, r.to, r.kind, strings.Join(r.refs, ", "))
+	}
+
+	return b.String()
+}
@@ -135,6 +135,9 @@ func (b *Bucketuploader) upload(
 			if m.ContentType != "" {
 				cmd = append(cmd, "--content-type", m.ContentType)
 			}
+			if m.CacheControl != "" {
+				cmd = append(cmd, "--cache-control", m.CacheControl)
+			}
 			if m.ChecksumSHA256 != "" {
 				cmd = append(cmd,
 					"--checksum-algorithm", "SHA256",
@@ -265,6 +268,7 @@ func (b *Bucketuploader) UploadFile(
 			Path:           name,
 			ChecksumSHA256: metadata.ChecksumSHA256,
 			ContentType:    metadata.ContentType,
+			CacheControl:   metadata.CacheControl,
 		}}
 	}
 
@@ -13,6 +13,11 @@ type FileMetadata struct {
 	// When set, the Content-Type header is sent with the upload.
 	// +optional
 	ContentType string
+
+	// Cache-Control header value for the file (e.g., "public, max-age=300").
+	// When set, the Cache-Control header is sent with the upload.
+	// +optional
+	CacheControl string
 }
 
 // FilePathMetadata pairs a relative file path with upload metadata.
@@ -31,6 +36,11 @@ type FilePathMetadata struct {
 	// When set, the Content-Type header is sent with the upload.
 	// +optional
 	ContentType string
+
+	// Cache-Control header value for the file (e.g., "public, max-age=300").
+	// When set, the Cache-Control header is sent with the upload.
+	// +optional
+	CacheControl string
 }
 
 // WithChecksumSHA256 sets the base64-encoded SHA-256 checksum that will be
@@ -73,6 +83,26 @@ func (pm *FilePathMetadata) WithContentType(
 	return pm
 }
 
+// WithCacheControl sets the Cache-Control header value that will be sent
+// during upload.
+func (m *FileMetadata) WithCacheControl(
+	// Cache-Control header value (e.g., "public, max-age=300")
+	cacheControl string,
+) *FileMetadata {
+	m.CacheControl = cacheControl
+	return m
+}
+
+// WithCacheControl sets the Cache-Control header value that will be sent
+// during upload.
+func (pm *FilePathMetadata) WithCacheControl(
+	// Cache-Control header value (e.g., "public, max-age=300")
+	cacheControl string,
+) *FilePathMetadata {
+	pm.CacheControl = cacheControl
+	return pm
+}
+
 // NewFileMetadata returns a new empty NewFileMetadata instance.
 // Use the With* methods to set individual fields:
 //
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/buf/internal/dagger"
+)
+
+const (
+	goImage  string = "golang:1.26-bookworm"
+	bufImage string = "bufbuild/buf:1.50.0"
+)
+
+type Buf struct {
+	// Source is the repository directory containing the buf workspace.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Path is the buf workspace or module directory within the source.
+	//
+	// +private
+	Path string
+
+	// Token is an optional Buf Schema Registry token.
+	//
+	// +private
+	Token *dagger.Secret
+
+	// Plugins is the list of local protoc plugins to go install.
+	//
+	// +private
+	Plugins []string
+}
+
+// New creates a new Buf module instance.
+func New(
+	// The repository directory containing the buf workspace.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Directory of the buf workspace or module within the source
+	// +optional
+	// +default="."
+	path string,
+
+	// Optional Buf Schema Registry token, used for remote plugins and
+	// dependencies to avoid rate limits and reach private modules
+	// +optional
+	token *dagger.Secret,
+
+	// Local protoc plugins to go install for generation
+	// (e.g., "google.golang.org/protobuf/cmd/protoc-gen-go@v1.36.5")
+	// +optional
+	plugins []string,
+) *Buf {
+	return &Buf{
+		Source:  source,
+		Path:    path,
+		Token:   token,
+		Plugins: plugins,
+	}
+}
+
+// Lint runs buf lint on the workspace and fails on any lint violation.
+//
+// +check
+func (m *Buf) Lint(ctx context.Context) (string, error) {
+	return m.bufContainer().
+		WithExec([]string{"buf", "lint", "--error-format", "text"}).
+		Stdout(ctx)
+}
+
+// Breaking fails when the workspace contains breaking changes compared to a
+// previous version of the schema.
+//
+// The previous version is either the against directory (a checkout of the
+// same repository) or, when it is not given, the git ref against read from
+// the source's .git directory.
+func (m *Buf) Breaking(
+	ctx context.Context,
+
+	// Git ref to compare against (e.g., "main" or "v1.2.0")
+	// +optional
+	// +default="main"
+	against string,
+
+	// Checkout of the previous version to compare against instead of a git
+	// ref
+	// +optional
+	againstSource *dagger.Directory,
+) (string, error) {
+	ctr := m.bufContainer()
+
+	input := fmt.Sprintf("/src/.git#ref=%s", against)
+	if m.Path != "." && m.Path != "" {
+		input += ",subdir=" + m.Path
+	}
+	if againstSource != nil {
+		ctr = ctr.WithDirectory("/against", againstSource)
+		input = "/against/" + m.Path
+	}
+
+	out, err := ctr.
+		WithExec([]string{"buf", "breaking", "--against", input, "--error-format", "text"}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("breaking changes found: %w", err)
+	}
+
+	return fmt.Sprintf("✅ no breaking changes\n%s", out), nil
+}
+
+// Generate runs buf generate on the workspace and returns the source
+// directory with the generated code written.
+func (m *Buf) Generate(
+	// Alternative buf.gen.yaml template to generate with
+	// +optional
+	template *dagger.File,
+) *dagger.Directory {
+	ctr := m.bufContainer()
+
+	args := []string{"buf", "generate"}
+	if template != nil {
+		ctr = ctr.WithFile("/buf.gen.yaml", template)
+		args = append(args, "--template", "/buf.gen.yaml")
+	}
+
+	return ctr.
+		WithExec(args).
+		Directory("/src")
+}
+
+// bufContainer returns a Go container with buf and the local plugins
+// installed, the source mounted, and the buf cache and the Go module and
+// build caches shared with the other Go modules in this daggerverse.
+func (m *Buf) bufContainer() *dagger.Container {
+	ctr := dag.Container().
+		From(goImage).
+		WithFile("/usr/local/bin/buf", dag.Container().From(bufImage).File("/usr/local/bin/buf")).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithMountedCache("/root/.cache/buf", dag.CacheVolume("buf")).
+		WithEnvVariable("BUF_CACHE_DIR", "/root/.cache/buf")
+
+	for _, plugin := range m.Plugins {
+		ctr = ctr.WithExec([]string{"go", "install", plugin})
+	}
+
+	if m.Token != nil {
+		ctr = ctr.WithSecretVariable("BUF_TOKEN", m.Token)
+	}
+
+	return ctr.
+		WithDirectory("/src", m.Source).
+		WithWorkdir("/src/" + strings.TrimPrefix(m.Path, "/"))
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dagger/cargopublish/internal/dagger"
+)
+
+const (
+	rustImage string = "rust:1.86-bookworm"
+)
+
+type Cargopublish struct {
+	// Source is the repository containing the crate or workspace.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Path is the crate or workspace directory, relative to Source.
+	//
+	// +private
+	Path string
+
+	// Member selects a workspace member (cargo -p).
+	//
+	// +private
+	Member string
+
+	// Features are crate features to enable.
+	//
+	// +private
+	Features []string
+
+	// AllFeatures enables every feature.
+	//
+	// +private
+	AllFeatures bool
+}
+
+// New creates a new Cargopublish module instance.
+func New(
+	// The repository containing the crate or workspace.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Crate or workspace directory, relative to source (e.g., "rust")
+	// +optional
+	// +default="."
+	path string,
+
+	// Workspace member to test, package, and publish (cargo -p)
+	// +optional
+	pkg string,
+
+	// Features to enable
+	// +optional
+	features []string,
+
+	// Enable every feature
+	// +optional
+	allFeatures bool,
+) *Cargopublish {
+	return &Cargopublish{
+		Source:      source,
+		Path:        path,
+		Member:      pkg,
+		Features:    features,
+		AllFeatures: allFeatures,
+	}
+}
+
+// Test runs "cargo test" and returns its output.
+func (m *Cargopublish) Test(ctx context.Context) (string, error) {
+	out, err := m.container().
+		// Test builds are cached between runs; package and publish always
+		// build from a clean target directory.
+		WithMountedCache("/target", dag.CacheVolume("cargo-target")).
+		WithEnvVariable("CARGO_TARGET_DIR", "/target").
+		WithExec(m.args("test")).
+		CombinedOutput(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("cargo test failed\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return out, nil
+}
+
+// Check runs the tests, making this suitable for CI checks.
+//
+// +check
+func (m *Cargopublish) Check(ctx context.Context) (string, error) {
+	return m.Test(ctx)
+}
+
+// Package runs "cargo package", which verifies the crate builds from its
+// packaged sources, and returns the .crate files.
+func (m *Cargopublish) Package(ctx context.Context) (*dagger.Directory, error) {
+	out := m.container().
+		WithEnvVariable("CARGO_TARGET_DIR", "/package").
+		WithExec(m.args("package")).
+		Directory("/package/package")
+
+	crates, err := out.Glob(ctx, "*.crate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packaged crates: %w", err)
+	}
+	if len(crates) == 0 {
+		return nil, fmt.Errorf("no crates were packaged")
+	}
+
+	return dag.Directory().WithDirectory(".", out, dagger.DirectoryWithDirectoryOpts{
+		Include: crates,
+	}), nil
+}
+
+// Publish runs the tests and publishes the crate to crates.io, or to an
+// alternate registry configured in .cargo/config.toml. With dryRun, the
+// crate is packaged and verified but not uploaded, and no token is needed.
+func (m *Cargopublish) Publish(
+	ctx context.Context,
+
+	// Registry token
+	// +optional
+	token *dagger.Secret,
+
+	// Alternate registry name from .cargo/config.toml (default: crates.io)
+	// +optional
+	registry string,
+
+	// Run the tests before publishing
+	// +optional
+	// +default=true
+	test bool,
+
+	// Verify the crate without uploading it
+	// +optional
+	dryRun bool,
+) (string, error) {
+	if token == nil && !dryRun {
+		return "", fmt.Errorf("token is required unless dry-run is set")
+	}
+
+	if test {
+		if _, err := m.Test(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	args := m.args("publish")
+	if registry != "" {
+		args = append(args, "--registry", registry)
+	}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+
+	ctr := m.container()
+	if token != nil {
+		// Cargo reads the token for crates.io from CARGO_REGISTRY_TOKEN and
+		// for a named registry from CARGO_REGISTRIES_<NAME>_TOKEN.
+		tokenVar := "CARGO_REGISTRY_TOKEN"
+		if registry != "" {
+			tokenVar = "CARGO_REGISTRIES_" + strings.ToUpper(strings.ReplaceAll(registry, "-", "_")) + "_TOKEN"
+		}
+		ctr = ctr.WithSecretVariable(tokenVar, token)
+	}
+
+	out, err := ctr.WithExec(args).CombinedOutput(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("cargo publish failed\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return out, nil
+}
+
+// args builds a cargo command line with the package and feature selection.
+func (m *Cargopublish) args(command string) []string {
+	args := []string{"cargo", command}
+	if m.Member != "" {
+		args = append(args, "--package", m.Member)
+	}
+	if len(m.Features) > 0 {
+		args = append(args, "--features", strings.Join(m.Features, ","))
+	}
+	if m.AllFeatures {
+		args = append(args, "--all-features")
+	}
+	return args
+}
+
+// container returns a Rust container with the source mounted and the cargo
+// registry and git caches.
+func (m *Cargopublish) container() *dagger.Container {
+	return dag.Container().
+		From(rustImage).
+		WithMountedCache("/usr/local/cargo/registry", dag.CacheVolume("cargo-registry")).
+		WithMountedCache("/usr/local/cargo/git", dag.CacheVolume("cargo-git")).
+		WithDirectory("/src", m.Source, dagger.ContainerWithDirectoryOpts{
+			Exclude: []string{"**/target"},
+		}).
+		WithWorkdir("/src/" + m.Path)
+}
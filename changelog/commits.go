@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// conventionalPattern matches a conventional commit subject, optionally
+// prefixed with an emoji or gitmoji shortcode as used by ghrelease (e.g.,
+// "✨ feat(api)!: add streaming").
+var conventionalPattern = regexp.MustCompile(`^(?:(?::\w+:|[^\w\s]+)\s*)?(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+
+// group is a release notes section.
+type group struct {
+	key   string
+	title string
+}
+
+// groups are the release notes sections in display order, matching the
+// headings ghrelease generates.
+var groups = []group{
+	{"breaking", "⚠️ Breaking Changes"},
+	{"features", "✨ Features"},
+	{"fixes", "🔧 Fixes"},
+	{"chores", "🧹 Chores"},
+	{"other", "📦 Other Changes"},
+}
+
+// typeGroups maps conventional commit types to group keys. Unknown types go
+// to "other".
+var typeGroups = map[string]string{
+	"feat":     "features",
+	"fix":      "fixes",
+	"perf":     "fixes",
+	"chore":    "chores",
+	"build":    "chores",
+	"ci":       "chores",
+	"docs":     "chores",
+	"refactor": "chores",
+	"style":    "chores",
+	"test":     "chores",
+}
+
+// Entry is a single commit in the changelog.
+type Entry struct {
+	// Full commit hash
+	Hash string `json:"hash"`
+
+	// Conventional commit type (e.g., "feat"), empty for non-conventional
+	// commits
+	Type string `json:"type"`
+
+	// Conventional commit scope, if any
+	Scope string `json:"scope,omitempty"`
+
+	// Commit subject without the type and scope prefix
+	Subject string `json:"subject"`
+
+	// Whether the commit is a breaking change ("!" or a BREAKING CHANGE
+	// footer)
+	Breaking bool `json:"breaking"`
+
+	// Commit author name
+	Author string `json:"author"`
+
+	// Release notes group: "breaking", "features", "fixes", "chores", or
+	// "other"
+	Group string `json:"group"`
+}
+
+// parseLog parses "git log" output where each commit is
+// "<hash>\x1f<author>\x1f<subject>\x1f<body>" terminated by "\x1e".
+func parseLog(out string) ([]Entry, error) {
+	entries := []Entry{}
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+
+		fields := strings.SplitN(record, "\x1f", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected git log record %q", record)
+		}
+
+		entries = append(entries, parseCommit(fields[0], fields[1], fields[2], fields[3]))
+	}
+	return entries, nil
+}
+
+// parseCommit classifies a single commit.
+func parseCommit(hash, author, subject, body string) Entry {
+	e := Entry{Hash: hash, Author: author, Subject: subject, Group: "other"}
+
+	if m := conventionalPattern.FindStringSubmatch(subject); m != nil {
+		e.Type = strings.ToLower(m[1])
+		e.Scope = m[2]
+		e.Breaking = m[3] == "!" || e.Type == "breaking"
+		e.Subject = m[4]
+		if g, ok := typeGroups[e.Type]; ok {
+			e.Group = g
+		}
+	}
+
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "BREAKING CHANGE:") || strings.HasPrefix(line, "BREAKING-CHANGE:") {
+			e.Breaking = true
+		}
+	}
+	if e.Breaking {
+		e.Group = "breaking"
+	}
+
+	return e
+}
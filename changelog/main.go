@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"dagger/changelog/internal/dagger"
+)
+
+const (
+	gitImage string = "alpine/git:v2.47.1"
+)
+
+type Changelog struct {
+	// Source is the git repository to read history from. It must include
+	// .git.
+	//
+	// +private
+	Source *dagger.Directory
+}
+
+// Release is the changelog for a range of commits.
+type Release struct {
+	// Start of the range (exclusive), empty when covering all history
+	From string `json:"from"`
+
+	// End of the range (inclusive)
+	To string `json:"to"`
+
+	// Commits in the range, newest first
+	Entries []Entry `json:"entries"`
+}
+
+// New creates a new Changelog module instance.
+func New(
+	// A directory containing a git repository (must include .git).
+	// +defaultPath="/"
+	source *dagger.Directory,
+) *Changelog {
+	return &Changelog{
+		Source: source,
+	}
+}
+
+// Generate parses conventional commits between two refs into a changelog.
+// Commit subjects like "feat(api): ..." or ghrelease's "✨ feat: ..." are
+// grouped into breaking changes, features, fixes, chores, and other changes.
+// Render the result with Markdown or JSON.
+func (m *Changelog) Generate(
+	ctx context.Context,
+
+	// Start of the range (exclusive). Defaults to the latest tag reachable
+	// from "to", or all history when there are no tags.
+	// +optional
+	from string,
+
+	// End of the range (inclusive)
+	// +optional
+	// +default="HEAD"
+	to string,
+
+	// Omit merge commits
+	// +optional
+	// +default=true
+	noMerges bool,
+) (*Release, error) {
+	ctr := dag.Container().
+		From(gitImage).
+		WithDirectory("/src", m.Source).
+		WithWorkdir("/src").
+		// The mounted source may be owned by another user.
+		WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "/src"})
+
+	if from == "" {
+		out, err := ctr.
+			WithExec([]string{"sh", "-c", `git describe --tags --abbrev=0 "$0" 2>/dev/null || true`, to}).
+			Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find latest tag: %w", err)
+		}
+		from = strings.TrimSpace(out)
+	}
+
+	rangeArg := to
+	if from != "" {
+		rangeArg = from + ".." + to
+	}
+
+	args := []string{"git", "log", "--format=%H%x1f%an%x1f%s%x1f%b%x1e"}
+	if noMerges {
+		args = append(args, "--no-merges")
+	}
+	out, err := ctr.WithExec(append(args, rangeArg, "--")).Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git log for %s: %w", rangeArg, err)
+	}
+
+	entries, err := parseLog(out)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Release{
+		From:    from,
+		To:      to,
+		Entries: entries,
+	}, nil
+}
+
+// Markdown renders grouped release notes, suitable as the notes of a GitHub
+// release (e.g., with ghrelease's WithNotes).
+func (r *Release) Markdown() string {
+	var b strings.Builder
+	for _, g := range groups {
+		var lines []string
+		for _, e := range r.Entries {
+			if e.Group != g.key {
+				continue
+			}
+			line := e.Subject
+			if e.Scope != "" {
+				line = fmt.Sprintf("**%s:** %s", e.Scope, line)
+			}
+			lines = append(lines, fmt.Sprintf("- %s (%s)", line, shortHash(e.Hash)))
+		}
+
+		if len(lines) > 0 {
+			fmt.Fprintf(&b, "## %s\n\n%s\n\n", g.title, strings.Join(lines, "\n"))
+		}
+	}
+
+	if b.Len() == 0 {
+		return "No changes.\n"
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// JSON renders the changelog as indented JSON.
+func (r *Release) JSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode changelog: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// MarkdownFile returns the markdown release notes as a file.
+func (r *Release) MarkdownFile(
+	// File name
+	// +optional
+	// +default="CHANGELOG.md"
+	name string,
+) *dagger.File {
+	return dag.Directory().
+		WithNewFile(name, r.Markdown()).
+		File(name)
+}
+
+// shortHash abbreviates a commit hash.
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
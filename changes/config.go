@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// config is the change detection config file.
+type config struct {
+	// Paths whose changes affect every target (e.g., CI workflows)
+	Global []string `json:"global"`
+
+	// Paths whose changes affect no target (e.g., documentation)
+	Ignore []string `json:"ignore"`
+
+	Targets []target `json:"targets"`
+}
+
+// target is a module or service the pipeline builds and tests.
+type target struct {
+	Name string `json:"name"`
+
+	// Paths whose changes affect the target
+	Paths []string `json:"paths"`
+
+	// Targets whose changes also affect this one
+	Needs []string `json:"needs"`
+}
+
+// matcher is a config compiled for matching paths.
+type matcher struct {
+	global  []*regexp.Regexp
+	ignore  []*regexp.Regexp
+	targets []compiledTarget
+}
+
+type compiledTarget struct {
+	name  string
+	paths []*regexp.Regexp
+	needs []string
+}
+
+// parseConfig decodes the config file, converted to JSON.
+func parseConfig(data string) (*config, error) {
+	var c config
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return &c, nil
+}
+
+// compile validates a config and compiles its patterns, returning every
+// problem found.
+func compile(c *config) (*matcher, []string) {
+	var problems []string
+	patterns := func(where string, ps []string) []*regexp.Regexp {
+		var res []*regexp.Regexp
+		for _, p := range ps {
+			re, err := compilePattern(p)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: invalid pattern %q: %v", where, p, err))
+				continue
+			}
+			res = append(res, re)
+		}
+		return res
+	}
+
+	m := &matcher{
+		global: patterns("global", c.Global),
+		ignore: patterns("ignore", c.Ignore),
+	}
+
+	names := map[string]bool{}
+	for i, t := range c.Targets {
+		switch {
+		case t.Name == "":
+			problems = append(problems, fmt.Sprintf("target #%d has no name", i+1))
+			continue
+		case names[t.Name]:
+			problems = append(problems, fmt.Sprintf("target %q is defined twice", t.Name))
+			continue
+		case len(t.Paths) == 0 && len(t.Needs) == 0:
+			problems = append(problems, fmt.Sprintf("target %q has no paths or needs", t.Name))
+		}
+		names[t.Name] = true
+		m.targets = append(m.targets, compiledTarget{
+			name:  t.Name,
+			paths: patterns("target "+t.Name, t.Paths),
+			needs: t.Needs,
+		})
+	}
+
+	for _, t := range c.Targets {
+		for _, n := range t.Needs {
+			if !names[n] {
+				problems = append(problems, fmt.Sprintf("target %q needs unknown target %q", t.Name, n))
+			}
+		}
+	}
+
+	return m, problems
+}
+
+// affected returns why each affected target is affected, by name: the
+// first changed file that matches it, a global file, or a target it needs.
+func (m *matcher) affected(files []string) map[string]string {
+	reasons := map[string]string{}
+	for _, f := range files {
+		if matchAny(m.ignore, f) {
+			continue
+		}
+		if matchAny(m.global, f) {
+			for _, t := range m.targets {
+				if _, ok := reasons[t.name]; !ok {
+					reasons[t.name] = fmt.Sprintf("global: `%s`", f)
+				}
+			}
+			continue
+		}
+		for _, t := range m.targets {
+			if _, ok := reasons[t.name]; !ok && matchAny(t.paths, f) {
+				reasons[t.name] = fmt.Sprintf("`%s`", f)
+			}
+		}
+	}
+
+	// Needs are transitive: repeat until no more targets are affected.
+	for changed := true; changed; {
+		changed = false
+		for _, t := range m.targets {
+			if _, ok := reasons[t.name]; ok {
+				continue
+			}
+			for _, n := range t.needs {
+				if _, ok := reasons[n]; ok {
+					reasons[t.name] = fmt.Sprintf("needs `%s`", n)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return reasons
+}
+
+// names returns the target names, sorted.
+func (m *matcher) names() []string {
+	names := make([]string, len(m.targets))
+	for i, t := range m.targets {
+		names[i] = t.name
+	}
+	sort.Strings(names)
+	return names
+}
+
+func matchAny(res []*regexp.Regexp, path string) bool {
+	for _, re := range res {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// compilePattern converts a path pattern, which follows .gitignore rules,
+// to a regular expression matching file paths relative to the repository
+// root. A pattern matching a directory matches everything beneath it.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	switch {
+	case strings.HasPrefix(pattern, "!"):
+		return nil, fmt.Errorf("negation is not supported: use ignore")
+	case strings.ContainsAny(pattern, "[]"):
+		return nil, fmt.Errorf("character ranges are not supported")
+	}
+
+	// A leading slash or a slash in the middle anchors the pattern to the
+	// root; otherwise it matches at any depth.
+	dirOnly := strings.HasSuffix(pattern, "/")
+	p := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		case p[i] == '\\' && i+1 < len(p):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(p[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(p[i])))
+		}
+	}
+
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"dagger/changes/internal/dagger"
+)
+
+const (
+	gitImage string = "alpine/git:v2.47.1"
+	yqImage  string = "mikefarah/yq:4.45.1"
+)
+
+type Changes struct {
+	// Source is the repository to check.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Config is the config file, relative to Source.
+	//
+	// +private
+	Config string
+
+	// Base is the ref changes are detected against.
+	//
+	// +private
+	Base string
+
+	// Head is the ref with the changes.
+	//
+	// +private
+	Head string
+}
+
+// New creates a new Changes module instance.
+func New(
+	// The repository to check. Must include .git.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Ref to detect changes against, from its merge base with head (e.g.,
+	// the pull request's base branch)
+	// +optional
+	// +default="origin/main"
+	base string,
+
+	// Ref with the changes
+	// +optional
+	// +default="HEAD"
+	head string,
+
+	// Config file (YAML or JSON) mapping paths to targets, relative to
+	// source. Without it, every top-level directory is a target.
+	// +optional
+	// +default=".changes.yaml"
+	config string,
+) *Changes {
+	return &Changes{
+		Source: source,
+		Config: config,
+		Base:   base,
+		Head:   head,
+	}
+}
+
+// Files returns the files changed between the merge base of base and head,
+// and head, including deleted files and both sides of renames.
+func (m *Changes) Files(ctx context.Context) ([]string, error) {
+	out, err := dag.Container().
+		From(gitImage).
+		WithDirectory("/src", m.Source).
+		WithWorkdir("/src").
+		// The mounted source may be owned by another user.
+		WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "/src"}).
+		WithExec([]string{"git", "diff", "--name-only", "--no-renames", m.Base + "..." + m.Head, "--"}).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return nil, fmt.Errorf("failed to diff %s...%s: the checkout must include both refs (e.g. fetch-depth: 0)\n\n%s", m.Base, m.Head, e.Stderr)
+	} else if err != nil {
+		return nil, fmt.Errorf("unexpected error: %w", err)
+	}
+
+	var files []string
+	for _, line := range strings.Split(out, "\n") {
+		if line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// Targets returns the names of the targets affected by the changes, sorted,
+// for a pipeline to build and test only those.
+func (m *Changes) Targets(ctx context.Context) ([]string, error) {
+	_, reasons, _, err := m.detect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return affectedNames(reasons), nil
+}
+
+// Matrix returns the affected targets as a JSON array, for a GitHub
+// Actions job matrix (e.g., `matrix: { target: ${{ fromJSON(...) }} }`).
+func (m *Changes) Matrix(ctx context.Context) (string, error) {
+	targets, err := m.Targets(ctx)
+	if err != nil {
+		return "", err
+	}
+	if targets == nil {
+		targets = []string{}
+	}
+	data, err := json.Marshal(targets)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode targets: %w", err)
+	}
+	return string(data), nil
+}
+
+// Affected returns whether the changes affect a target, for gating a
+// single job.
+func (m *Changes) Affected(
+	ctx context.Context,
+
+	// Target name
+	target string,
+) (bool, error) {
+	match, reasons, _, err := m.detect(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !slices.Contains(match.names(), target) {
+		return false, fmt.Errorf("unknown target %q: must be one of %s", target, strings.Join(match.names(), ", "))
+	}
+	_, ok := reasons[target]
+	return ok, nil
+}
+
+// Report returns a markdown report of the affected targets and why each
+// is affected, for a pull request comment or job summary.
+func (m *Changes) Report(ctx context.Context) (string, error) {
+	match, reasons, files, err := m.detect(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("## Affected targets\n\n")
+	names := affectedNames(reasons)
+	if len(names) == 0 {
+		fmt.Fprintf(&b, "No targets are affected by the %d files changed since %s.\n", len(files), m.Base)
+		return b.String(), nil
+	}
+
+	fmt.Fprintf(&b, "%d of %d targets are affected by the %d files changed since %s.\n\n",
+		len(names), len(match.targets), len(files), m.Base)
+	b.WriteString("| Target | Reason |\n")
+	b.WriteString("|--------|--------|\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "| `%s` | %s |\n", name, reasons[name])
+	}
+	return b.String(), nil
+}
+
+// detect returns the targets, the reasons the affected ones are affected,
+// and the changed files.
+func (m *Changes) detect(ctx context.Context) (*matcher, map[string]string, []string, error) {
+	match, err := m.matcher(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	files, err := m.Files(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return match, match.affected(files), files, nil
+}
+
+// matcher reads and compiles the config file or, when there is none,
+// makes every top-level directory a target, and the files and hidden
+// directories beside them global.
+func (m *Changes) matcher(ctx context.Context) (*matcher, error) {
+	c := &config{}
+
+	ok, err := m.Source.Exists(ctx, m.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect source directory: %w", err)
+	}
+
+	if ok {
+		data, err := dag.Container().
+			From(yqImage).
+			WithFile("/config", m.Source.File(m.Config)).
+			WithExec([]string{"yq", "--output-format", "json", ".", "/config"}).
+			Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config %s: %w", m.Config, err)
+		}
+		if c, err = parseConfig(data); err != nil {
+			return nil, err
+		}
+	} else {
+		entries, err := m.Source.Glob(ctx, "*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list source directory: %w", err)
+		}
+		// Glob returns directory entries with a trailing slash.
+		for _, entry := range entries {
+			if !strings.HasSuffix(entry, "/") || strings.HasPrefix(entry, ".") {
+				c.Global = append(c.Global, "/"+entry)
+				continue
+			}
+			dir := strings.TrimSuffix(entry, "/")
+			c.Targets = append(c.Targets, target{Name: dir, Paths: []string{"/" + entry}})
+		}
+	}
+
+	match, problems := compile(c)
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%s has %d errors\n\n  %s", m.Config, len(problems), strings.Join(problems, "\n  "))
+	}
+	return match, nil
+}
+
+// affectedNames returns the names of the affected targets, sorted.
+func affectedNames(reasons map[string]string) []string {
+	var names []string
+	for name := range reasons {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,113 @@
+#!/usr/bin/env sh
+set -e
+set -f
+
+# Generates a sibling checksum file for every file under the current
+# directory, one per algorithm listed in $ALGORITHMS (e.g. "sha256 md5").
+# Each file is read exactly once: its contents are fanned out with tee to a
+# named pipe per algorithm, so adding algorithms does not re-read artifacts.
+#
+# When $SKIP_EXISTING is "true", algorithms whose checksum file is strictly
+# newer than the file it describes, and holds a single well-formed checksum
+# line for it, are skipped. Files or checksums with an epoch mtime (e.g.
+# normalized by SOURCE_DATE_EPOCH or WithTimestamps) are always re-hashed,
+# since their mtimes say nothing about freshness.
+#
+# When $AGGREGATE is "true", a sorted <ALGO>SUMS file (e.g. SHA256SUMS) listing
+# every file is also written at the root of the directory.
+
+# is_fresh FILE ALGO succeeds when FILE.ALGO can be trusted as up to date.
+is_fresh() {
+  SUMS_FILE="${1}.${2}"
+  [ -f "$SUMS_FILE" ] || return 1
+  [ "$SUMS_FILE" -nt "$1" ] || return 1
+  [ "$(stat -c %Y "$1")" -gt 0 ] || return 1
+  [ "$(stat -c %Y "$SUMS_FILE")" -gt 0 ] || return 1
+  [ "$(wc -l < "$SUMS_FILE")" -eq 1 ] || return 1
+
+  LINE=$(cat "$SUMS_FILE")
+  case "$LINE" in
+    *"  ${1}") ;;
+    *) return 1 ;;
+  esac
+
+  HASH="${LINE%  "${1}"}"
+  case "$HASH" in
+    ""|*[!0-9a-f]*) return 1 ;;
+  esac
+
+  case "$2" in
+    md5) LEN=32 ;;
+    sha1) LEN=40 ;;
+    sha256) LEN=64 ;;
+    sha512) LEN=128 ;;
+    *) return 1 ;;
+  esac
+  [ "${#HASH}" -eq "$LEN" ]
+}
+
+WORK=$(mktemp -d)
+trap 'rm -rf "$WORK"' EXIT
+
+# --- Never checksum existing checksum files, whichever algorithm made them ---
+FIND_ARGS=""
+for ALGO in $SUPPORTED_ALGORITHMS; do
+  SUMS_NAME="$(echo "$ALGO" | tr 'a-z' 'A-Z')SUMS"
+  FIND_ARGS="${FIND_ARGS} ! -name *.${ALGO} ! -path ./${SUMS_NAME}"
+done
+
+find . -type f $FIND_ARGS | sed 's|^\./||' | LC_ALL=C sort > "${WORK}/files"
+
+while IFS= read -r FILE; do
+  # --- Work out which checksums are missing or stale ---
+  STALE=""
+  for ALGO in $ALGORITHMS; do
+    if [ "$SKIP_EXISTING" = "true" ] && is_fresh "$FILE" "$ALGO"; then
+      continue
+    fi
+    STALE="${STALE} ${ALGO}"
+  done
+
+  if [ -z "$STALE" ]; then
+    echo "Skipping ${FILE}: checksums are up to date"
+    continue
+  fi
+
+  PIPES=""
+  PIDS=""
+  for ALGO in $STALE; do
+    mkfifo "${WORK}/${ALGO}"
+    "${ALGO}sum" < "${WORK}/${ALGO}" > "${WORK}/${ALGO}.out" &
+    PIDS="${PIDS} $!"
+    PIPES="${PIPES} ${WORK}/${ALGO}"
+  done
+
+  tee $PIPES < "$FILE" > /dev/null
+
+  for PID in $PIDS; do
+    if ! wait "$PID"; then
+      echo "Failed to hash ${FILE}" >&2
+      exit 1
+    fi
+  done
+
+  for ALGO in $STALE; do
+    SUM=$(cut -d ' ' -f 1 < "${WORK}/${ALGO}.out")
+    if [ -z "$SUM" ]; then
+      echo "Failed to compute ${ALGO} checksum for ${FILE}" >&2
+      exit 1
+    fi
+    printf '%s  %s\n' "$SUM" "$FILE" > "${FILE}.${ALGO}"
+    rm -f "${WORK}/${ALGO}" "${WORK}/${ALGO}.out"
+  done
+done < "${WORK}/files"
+
+# --- Write aggregated sums files ---
+if [ "$AGGREGATE" = "true" ]; then
+  for ALGO in $ALGORITHMS; do
+    SUMS_NAME="$(echo "$ALGO" | tr 'a-z' 'A-Z')SUMS"
+    while IFS= read -r FILE; do
+      cat "${FILE}.${ALGO}"
+    done < "${WORK}/files" > "$SUMS_NAME"
+  done
+fi
@@ -1,22 +1,160 @@
 package main
 
 import (
+	_ "embed"
+	"fmt"
+	"strings"
+
 	"dagger/checksum/internal/dagger"
 )
 
+//go:embed checksum.sh
+var checksumScript string
+
+// supportedAlgorithms lists the checksum algorithms Checksum can compute.
+// Each name doubles as the extension of the generated checksum file.
+var supportedAlgorithms = []string{"md5", "sha1", "sha256", "sha512"}
+
 type Checksumer struct{}
 
 // Checksum recursively generates SHA256 checksums for all files in the given directory.
 // These files land as `/some/path/filename.sha256`, `/some/other/path/filename.sha256`.
-func (m *Checksumer) Checksum(dir *dagger.Directory) *dagger.Directory {
-	return dag.Container().
+//
+// Additional algorithms can be requested together (e.g. sha256, sha512, and md5).
+// Each file is read once and all requested checksums are computed from that
+// single pass, producing one sibling file per algorithm (`filename.sha512`,
+// `filename.md5`, ...).
+//
+// By default the returned directory contains the original artifacts alongside
+// their checksums. When detached is set, only the generated checksum files are
+// returned (mirroring the input tree structure) so callers can publish
+// checksums to a different location from the artifacts.
+//
+// When aggregate is set, a single sorted `<ALGO>SUMS` file per algorithm
+// (e.g. `SHA256SUMS`) listing every file is also written at the root of the
+// directory, in the format consumed by `sha256sum -c` and VerifyRemote.
+//
+// When skipExisting is set, checksum files that are strictly newer than the
+// file they describe and hold a well-formed checksum line for it are kept
+// as-is instead of being recomputed, so iterative local runs only hash new or
+// modified files. Files or checksums with normalized (epoch) timestamps are
+// always re-hashed, so skipExisting gives no speedup on reproducible inputs.
+//
+// Files are processed in sorted order and each checksum line references the
+// file by its clean relative path (no leading "./"), so aggregated sums files
+// are stable across runs. When reproducible is set, the timestamps of the
+// generated checksum files are also reset so identical inputs always yield
+// byte-identical checksum outputs. The artifacts themselves are never
+// modified.
+func (m *Checksumer) Checksum(
+	// Directory of files to checksum
+	dir *dagger.Directory,
+
+	// Checksum algorithms to compute: any of "md5", "sha1", "sha256",
+	// "sha512". Defaults to sha256 only.
+	// +optional
+	algorithms []string,
+
+	// Return only the generated checksum files instead of the artifacts and
+	// checksums combined.
+	// +optional
+	detached bool,
+
+	// Also write a sorted <ALGO>SUMS file (e.g. SHA256SUMS) listing every
+	// file at the root of the directory.
+	// +optional
+	aggregate bool,
+
+	// Skip files whose checksum files are strictly newer than the file itself
+	// and well-formed. Epoch timestamps are never trusted.
+	// +optional
+	skipExisting bool,
+
+	// Reset the generated checksum files' timestamps to the Unix epoch for
+	// byte-identical, cache-friendly outputs.
+	// +optional
+	reproducible bool,
+) (*dagger.Directory, error) {
+	algos, err := normalizeAlgorithms(algorithms)
+	if err != nil {
+		return nil, err
+	}
+
+	generated := dag.Container().
 		From("alpine:latest").
 		WithDirectory("/artifacts", dir).
 		WithWorkdir("/artifacts").
-		WithExec([]string{"sh", "-c", `
-			find . -type f ! -name "*.sha256" | while read file; do
-				sha256sum "$file" | sed 's|./||' > "${file}.sha256"
-			done
-		`}).
+		WithEnvVariable("ALGORITHMS", strings.Join(algos, " ")).
+		WithEnvVariable("SUPPORTED_ALGORITHMS", strings.Join(supportedAlgorithms, " ")).
+		WithEnvVariable("AGGREGATE", fmt.Sprintf("%t", aggregate)).
+		WithEnvVariable("SKIP_EXISTING", fmt.Sprintf("%t", skipExisting)).
+		WithNewFile("/usr/local/bin/checksum.sh", checksumScript, dagger.ContainerWithNewFileOpts{Permissions: 0o755}).
+		WithExec([]string{"/usr/local/bin/checksum.sh"}).
 		Directory("/artifacts")
+
+	// Only carry the checksum outputs forward so the artifacts are never
+	// touched by the container round trip or the timestamp reset.
+	include := make([]string, 0, len(algos)*2)
+	for _, algo := range algos {
+		include = append(include, "**/*."+algo)
+		if aggregate {
+			include = append(include, sumsFileName(algo))
+		}
+	}
+	sums := dag.Directory().WithDirectory(".", generated, dagger.DirectoryWithDirectoryOpts{
+		Include: include,
+	})
+
+	if reproducible {
+		sums = sums.WithTimestamps(0)
+	}
+
+	if detached {
+		return sums, nil
+	}
+
+	return dir.WithDirectory(".", sums), nil
+}
+
+// sumsFileName returns the aggregated sums file name for an algorithm,
+// e.g. "SHA256SUMS" for "sha256".
+func sumsFileName(algo string) string {
+	return strings.ToUpper(algo) + "SUMS"
+}
+
+// normalizeAlgorithms lowercases, de-duplicates, and validates the requested
+// checksum algorithms, defaulting to sha256 when none are given.
+func normalizeAlgorithms(algorithms []string) ([]string, error) {
+	if len(algorithms) == 0 {
+		return []string{"sha256"}, nil
+	}
+
+	seen := map[string]bool{}
+	algos := make([]string, 0, len(algorithms))
+	for _, a := range algorithms {
+		algo := strings.ToLower(strings.TrimSpace(a))
+		if !isSupportedAlgorithm(algo) {
+			return nil, fmt.Errorf(
+				"unsupported checksum algorithm %q: must be one of %s",
+				a,
+				strings.Join(supportedAlgorithms, ", "),
+			)
+		}
+		if seen[algo] {
+			continue
+		}
+		seen[algo] = true
+		algos = append(algos, algo)
+	}
+
+	return algos, nil
+}
+
+func isSupportedAlgorithm(algo string) bool {
+	for _, s := range supportedAlgorithms {
+		if algo == s {
+			return true
+		}
+	}
+	return false
 }
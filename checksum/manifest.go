@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dagger/checksum/internal/dagger"
+)
+
+// defaultContentType is used when no content type can be guessed for a file.
+const defaultContentType = "application/octet-stream"
+
+// contentTypes covers common release artifact extensions that are missing
+// from Go's builtin MIME table.
+var contentTypes = map[string]string{
+	".gz":     "application/gzip",
+	".tgz":    "application/gzip",
+	".zip":    "application/zip",
+	".tar":    "application/x-tar",
+	".xz":     "application/x-xz",
+	".zst":    "application/zstd",
+	".deb":    "application/vnd.debian.binary-package",
+	".rpm":    "application/x-rpm",
+	".txt":    "text/plain; charset=utf-8",
+	".md":     "text/markdown; charset=utf-8",
+	".sh":     "text/x-shellscript",
+	".sha1":   "text/plain; charset=utf-8",
+	".sha256": "text/plain; charset=utf-8",
+	".sha512": "text/plain; charset=utf-8",
+	".md5":    "text/plain; charset=utf-8",
+	".sig":    "application/pgp-signature",
+	".asc":    "application/pgp-signature",
+	".pem":    "application/x-pem-file",
+	".yaml":   "application/yaml",
+	".yml":    "application/yaml",
+}
+
+// ManifestEntry describes a single file in a checksum manifest.
+type ManifestEntry struct {
+	// Relative path of the file inside the directory (e.g., "bin/myapp")
+	Path string `json:"path"`
+
+	// Size of the file in bytes
+	Size int64 `json:"size"`
+
+	// Hex-encoded SHA256 checksum of the file contents
+	SHA256 string `json:"sha256"`
+
+	// Base64-encoded SHA256 checksum, as expected by bucketupload metadata
+	// and the x-amz-checksum-sha256 header
+	ChecksumSHA256 string `json:"checksumSHA256"`
+
+	// MIME content type guessed from the file extension
+	ContentType string `json:"contentType"`
+}
+
+// Manifest generates a JSON manifest of every file in the given directory
+// with its relative path, size, SHA256 checksum, and a content type guessed
+// from the file extension. The result is a single manifest.json file that
+// can feed bucketupload metadata or release notes generation.
+//
+// Entries are always sorted by path and paths never carry a leading "./".
+// When reproducible is set, the manifest's timestamp is also reset so
+// identical inputs always yield a byte-identical file.
+func (m *Checksumer) Manifest(
+	ctx context.Context,
+
+	// Directory of files to describe
+	dir *dagger.Directory,
+
+	// Reset the manifest file timestamp to the Unix epoch for a
+	// byte-identical, cache-friendly output.
+	// +optional
+	reproducible bool,
+) (*dagger.File, error) {
+	entries, err := hashFiles(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	manifest := dag.Directory().
+		WithNewFile("manifest.json", string(data)+"\n").
+		File("manifest.json")
+
+	if reproducible {
+		manifest = manifest.WithTimestamps(0)
+	}
+
+	return manifest, nil
+}
+
+// hashFiles computes the size and SHA256 checksum of every file in dir and
+// returns them as manifest entries sorted by path.
+func hashFiles(ctx context.Context, dir *dagger.Directory) ([]ManifestEntry, error) {
+	out, err := dag.Container().
+		From("alpine:latest").
+		WithDirectory("/artifacts", dir).
+		WithWorkdir("/artifacts").
+		WithExec([]string{"sh", "-c", `
+			find . -type f | sed 's|^\./||' | LC_ALL=C sort | while IFS= read -r file; do
+				printf '%s %s %s\n' "$(sha256sum "$file" | cut -d ' ' -f 1)" "$(stat -c %s "$file")" "$file"
+			done
+		`}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash files: %w", err)
+	}
+
+	return parseManifestLines(out)
+}
+
+// parseManifestLines parses "<sha256> <size> <path>" lines into manifest
+// entries sorted by path.
+func parseManifestLines(out string) ([]ManifestEntry, error) {
+	entries := []ManifestEntry{}
+
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, " ", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("unexpected hash output line %q", line)
+		}
+
+		sum, err := hex.DecodeString(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid checksum for %s: %w", parts[2], err)
+		}
+
+		size, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size for %s: %w", parts[2], err)
+		}
+
+		entries = append(entries, ManifestEntry{
+			Path:           parts[2],
+			Size:           size,
+			SHA256:         parts[0],
+			ChecksumSHA256: base64.StdEncoding.EncodeToString(sum),
+			ContentType:    guessContentType(parts[2]),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+
+	return entries, nil
+}
+
+// guessContentType returns a MIME type for the given file name based on its
+// extension, falling back to application/octet-stream.
+func guessContentType(name string) string {
+	ext := strings.ToLower(path.Ext(name))
+	if ext == "" {
+		return defaultContentType
+	}
+	if t, ok := contentTypes[ext]; ok {
+		return t
+	}
+	if t := mime.TypeByExtension(ext); t != "" {
+		return t
+	}
+	return defaultContentType
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"dagger/checksum/internal/dagger"
+)
+
+// bsdSumPattern matches BSD-style checksum lines, e.g. "SHA256 (bin/myapp) = <hex>".
+var bsdSumPattern = regexp.MustCompile(`^SHA256 \((.+)\) = ([0-9a-fA-F]{64})$`)
+
+// VerifyRemote downloads a published SHA256SUMS file and verifies the files
+// in the given directory against it. Every file listed in the sums file must
+// be present in the directory with a matching checksum; files in the directory
+// that are not listed are ignored. Verification fails if no listed file was
+// verified at all, so an empty or wrong mirror never passes. This is useful
+// for mirror integrity and release promotion checks.
+func (m *Checksumer) VerifyRemote(
+	ctx context.Context,
+
+	// Directory of files to verify
+	dir *dagger.Directory,
+
+	// URL of the SHA256SUMS file (e.g., "https://example.com/v1.2.3/SHA256SUMS")
+	sumsURL string,
+
+	// Skip entries in the sums file that are not present in the directory
+	// instead of failing.
+	// +optional
+	ignoreMissing bool,
+) (string, error) {
+	contents, err := dag.HTTP(sumsURL).Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to download sums file %s: %w", sumsURL, err)
+	}
+
+	expected, err := parseSums(contents)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse sums file %s: %w", sumsURL, err)
+	}
+	if len(expected) == 0 {
+		return "", fmt.Errorf("sums file %s contains no checksums", sumsURL)
+	}
+
+	entries, err := hashFiles(ctx, dir)
+	if err != nil {
+		return "", err
+	}
+
+	actual := make(map[string]string, len(entries))
+	for _, e := range entries {
+		actual[e.Path] = e.SHA256
+	}
+
+	paths := make([]string, 0, len(expected))
+	for p := range expected {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var failures []string
+	verified := 0
+	for _, p := range paths {
+		got, ok := actual[p]
+		if !ok {
+			if !ignoreMissing {
+				failures = append(failures, fmt.Sprintf("  - %s: missing", p))
+			}
+			continue
+		}
+		if got != expected[p] {
+			failures = append(failures, fmt.Sprintf("  - %s: expected %s, got %s", p, expected[p], got))
+			continue
+		}
+		verified++
+	}
+
+	if len(failures) > 0 {
+		return "", fmt.Errorf(
+			"%d file(s) failed verification against %s:\n%s",
+			len(failures),
+			sumsURL,
+			strings.Join(failures, "\n"),
+		)
+	}
+
+	if verified == 0 {
+		return "", fmt.Errorf("none of the %d file(s) listed in %s were found in the directory", len(expected), sumsURL)
+	}
+
+	return fmt.Sprintf("✅ verified %d file(s) against %s", verified, sumsURL), nil
+}
+
+// parseSums parses the contents of a sums file into a map of clean relative
+// paths to lowercase hex checksums. Both GNU ("<hex>  <path>" or
+// "<hex> *<path>") and BSD ("SHA256 (<path>) = <hex>") formats are accepted.
+// A path listed more than once with different checksums is an error.
+func parseSums(contents string) (map[string]string, error) {
+	sums := map[string]string{}
+
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var sum, name string
+		if match := bsdSumPattern.FindStringSubmatch(line); match != nil {
+			name, sum = match[1], match[2]
+		} else {
+			parts := strings.SplitN(line, " ", 2)
+			if len(parts) != 2 || len(parts[0]) != 64 {
+				return nil, fmt.Errorf("unrecognized line %q", line)
+			}
+			sum = parts[0]
+			name = strings.TrimPrefix(strings.TrimPrefix(parts[1], " "), "*")
+		}
+
+		name = strings.TrimPrefix(name, "./")
+		sum = strings.ToLower(sum)
+		if prev, ok := sums[name]; ok && prev != sum {
+			return nil, fmt.Errorf("conflicting checksums for %s: %s and %s", name, prev, sum)
+		}
+		sums[name] = sum
+	}
+
+	return sums, nil
+}
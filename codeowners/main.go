@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/codeowners/internal/dagger"
+)
+
+const (
+	gitImage string = "alpine/git:v2.47.1"
+)
+
+// codeownersPaths are where GitHub looks for CODEOWNERS, in order.
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type Codeowners struct {
+	// Source is the repository to check.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// File is an optional CODEOWNERS file overriding the one in Source.
+	//
+	// +private
+	File *dagger.File
+}
+
+// New creates a new Codeowners module instance.
+func New(
+	// The repository to check. Must include .git to check a change set
+	// against a base ref.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// CODEOWNERS file to use instead of the one in source, which is looked
+	// up in .github/, the root, and docs/ like GitHub does
+	// +optional
+	file *dagger.File,
+) *Codeowners {
+	return &Codeowners{
+		Source: source,
+		File:   file,
+	}
+}
+
+// Validate checks the CODEOWNERS syntax: every owner must be a @user,
+// @org/team, or email, and patterns must not use negation or character
+// ranges, which GitHub does not support.
+func (m *Codeowners) Validate(ctx context.Context) (string, error) {
+	name, rules, err := m.rules(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("✅ %s is valid (%d rules)", name, len(rules)), nil
+}
+
+// Unowned returns the files without an owner. The files checked are the
+// given paths, the files changed since base when it is set, or otherwise
+// every file in the source.
+func (m *Codeowners) Unowned(
+	ctx context.Context,
+
+	// Paths to check, relative to the repository root
+	// +optional
+	paths []string,
+
+	// Check the files changed between this ref and HEAD (e.g., "origin/main")
+	// +optional
+	base string,
+) ([]string, error) {
+	_, rules, err := m.rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := m.files(ctx, paths, base)
+	if err != nil {
+		return nil, err
+	}
+
+	var unowned []string
+	for _, f := range files {
+		if owners, _ := ownersOf(rules, f); len(owners) == 0 {
+			unowned = append(unowned, f)
+		}
+	}
+	return unowned, nil
+}
+
+// Verify validates CODEOWNERS and fails listing every unowned file among
+// the given paths, the files changed since base, or every file in the
+// source, e.g. to enforce that each pull request has a required reviewer.
+func (m *Codeowners) Verify(
+	ctx context.Context,
+
+	// Paths to check, relative to the repository root
+	// +optional
+	paths []string,
+
+	// Check the files changed between this ref and HEAD (e.g., "origin/main")
+	// +optional
+	base string,
+) (string, error) {
+	unowned, err := m.Unowned(ctx, paths, base)
+	if err != nil {
+		return "", err
+	}
+
+	if len(unowned) > 0 {
+		return "", fmt.Errorf("%d files have no code owner\n\n  %s", len(unowned), strings.Join(unowned, "\n  "))
+	}
+
+	return "✅ every file has a code owner", nil
+}
+
+// Check validates CODEOWNERS and verifies every file in the source has an
+// owner, making this suitable for CI checks.
+//
+// +check
+func (m *Codeowners) Check(ctx context.Context) (string, error) {
+	return m.Verify(ctx, nil, "")
+}
+
+// Owners returns the owners of a path and the CODEOWNERS line that assigns
+// them, which helps debug why a review was or was not requested.
+func (m *Codeowners) Owners(
+	ctx context.Context,
+
+	// Path relative to the repository root
+	path string,
+) (string, error) {
+	name, rules, err := m.rules(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	owners, r := ownersOf(rules, strings.TrimPrefix(path, "/"))
+	switch {
+	case r == nil:
+		return fmt.Sprintf("%s: no matching rule in %s", path, name), nil
+	case len(owners) == 0:
+		return fmt.Sprintf("%s: unowned by %s:%d (%s)", path, name, r.line, r.pattern), nil
+	default:
+		return fmt.Sprintf("%s: %s from %s:%d (%s)", path, strings.Join(owners, " "), name, r.line, r.pattern), nil
+	}
+}
+
+// rules reads and parses the CODEOWNERS file, returning its name and rules
+// or an error listing every syntax problem.
+func (m *Codeowners) rules(ctx context.Context) (string, []rule, error) {
+	file, name := m.File, "CODEOWNERS"
+	if file == nil {
+		for _, p := range codeownersPaths {
+			ok, err := m.Source.Exists(ctx, p)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to inspect source directory: %w", err)
+			}
+			if ok {
+				file, name = m.Source.File(p), p
+				break
+			}
+		}
+		if file == nil {
+			return "", nil, fmt.Errorf("no CODEOWNERS file found in %s", strings.Join(codeownersPaths, ", "))
+		}
+	}
+
+	contents, err := file.Contents(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	rules, problems := parseCodeowners(contents)
+	if len(problems) > 0 {
+		return "", nil, fmt.Errorf("%s has %d errors\n\n  %s", name, len(problems), strings.Join(problems, "\n  "))
+	}
+
+	return name, rules, nil
+}
+
+// files returns the paths to check: the given paths, the files changed
+// since base, or every file in the source outside .git.
+func (m *Codeowners) files(ctx context.Context, paths []string, base string) ([]string, error) {
+	if len(paths) > 0 {
+		files := make([]string, len(paths))
+		for i, p := range paths {
+			files[i] = strings.TrimPrefix(p, "/")
+		}
+		return files, nil
+	}
+
+	if base != "" {
+		// Deleted files need no owner.
+		out, err := dag.Container().
+			From(gitImage).
+			WithDirectory("/src", m.Source).
+			WithWorkdir("/src").
+			// The mounted source may be owned by another user.
+			WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "/src"}).
+			WithExec([]string{"git", "diff", "--name-only", "--diff-filter=d", base + "...HEAD", "--"}).
+			Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files changed since %s: %w", base, err)
+		}
+		var files []string
+		for _, line := range strings.Split(out, "\n") {
+			if line != "" {
+				files = append(files, line)
+			}
+		}
+		return files, nil
+	}
+
+	entries, err := m.Source.Glob(ctx, "**/*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list source files: %w", err)
+	}
+
+	var files []string
+	// Glob returns directory entries with a trailing slash — skip them.
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") || entry == ".git" || strings.HasPrefix(entry, ".git/") {
+			continue
+		}
+		files = append(files, entry)
+	}
+	return files, nil
+}
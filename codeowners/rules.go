@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ownerPattern matches a GitHub user ("@octocat"), a team
+// ("@acme/platform"), or an email address.
+var ownerPattern = regexp.MustCompile(`^(?:@[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?(?:/[A-Za-z0-9._-]+)?|[^@\s]+@[^@\s]+\.[^@\s]+)$`)
+
+// rule is one CODEOWNERS line: a path pattern and its owners. A rule
+// without owners explicitly leaves matching paths unowned.
+type rule struct {
+	line    int
+	pattern string
+	owners  []string
+	re      *regexp.Regexp
+}
+
+// parseCodeowners parses a CODEOWNERS file, returning its rules and the
+// syntax errors found, each prefixed with its line number.
+func parseCodeowners(contents string) ([]rule, []string) {
+	var rules []rule
+	var problems []string
+
+	for i, line := range strings.Split(contents, "\n") {
+		n := i + 1
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		pattern := fields[0]
+		var owners []string
+		for _, f := range fields[1:] {
+			if strings.HasPrefix(f, "#") {
+				break
+			}
+			if !ownerPattern.MatchString(f) {
+				problems = append(problems, fmt.Sprintf("line %d: invalid owner %q: must be @user, @org/team, or an email", n, f))
+				continue
+			}
+			owners = append(owners, f)
+		}
+
+		re, err := compilePattern(pattern)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: invalid pattern %q: %v", n, pattern, err))
+			continue
+		}
+
+		rules = append(rules, rule{line: n, pattern: pattern, owners: owners, re: re})
+	}
+
+	return rules, problems
+}
+
+// compilePattern converts a CODEOWNERS path pattern, which follows
+// .gitignore rules, to a regular expression matching file paths relative to
+// the repository root. A pattern matching a directory matches everything
+// beneath it.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	switch {
+	case strings.HasPrefix(pattern, "!"):
+		return nil, fmt.Errorf("negation is not supported")
+	case strings.ContainsAny(pattern, "[]"):
+		return nil, fmt.Errorf("character ranges are not supported")
+	}
+
+	// A leading slash or a slash in the middle anchors the pattern to the
+	// root; otherwise it matches at any depth.
+	dirOnly := strings.HasSuffix(pattern, "/")
+	p := strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+	if p == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		case p[i] == '\\' && i+1 < len(p):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(p[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(p[i])))
+		}
+	}
+
+	// Unlike .gitignore, GitHub does not let a trailing wildcard segment
+	// ("docs/*") match files nested more deeply.
+	last := p[strings.LastIndex(p, "/")+1:]
+	switch {
+	case dirOnly:
+		b.WriteString("/.*$")
+	case strings.Contains(last, "*") && last != "**":
+		b.WriteString("$")
+	default:
+		b.WriteString("(?:/.*)?$")
+	}
+
+	return regexp.Compile(b.String())
+}
+
+// ownersOf returns the owners of a path: those of the last matching rule,
+// as GitHub applies them. The rule is nil when no rule matches.
+func ownersOf(rules []rule, path string) ([]string, *rule) {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].re.MatchString(path) {
+			return rules[i].owners, &rules[i]
+		}
+	}
+	return nil, nil
+}
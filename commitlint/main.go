@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/commitlint/internal/dagger"
+)
+
+const (
+	gitImage string = "alpine/git:v2.47.1"
+)
+
+type Commitlint struct {
+	// Source is the git repository to read history from. It must include
+	// .git.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Types is the list of accepted commit types.
+	//
+	// +private
+	Types []string
+
+	// Scopes is the list of accepted scopes. Any scope is accepted when empty.
+	//
+	// +private
+	Scopes []string
+
+	// RequireScope rejects headers without a scope.
+	//
+	// +private
+	RequireScope bool
+
+	// MaxLength is the maximum header length in characters.
+	//
+	// +private
+	MaxLength int
+}
+
+// New creates a new Commitlint module instance with a Conventional Commits
+// ruleset.
+func New(
+	// A directory containing a git repository (must include .git).
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Accepted commit types (defaults to feat, fix, perf, chore, build, ci,
+	// docs, refactor, style, test, revert, and breaking)
+	// +optional
+	types []string,
+
+	// Accepted scopes. Any scope is accepted when empty.
+	// +optional
+	scopes []string,
+
+	// Reject headers without a scope
+	// +optional
+	requireScope bool,
+
+	// Maximum header length in characters. 0 disables the limit.
+	// +optional
+	// +default=72
+	maxLength int,
+) *Commitlint {
+	if len(types) == 0 {
+		types = defaultTypes
+	}
+	return &Commitlint{
+		Source:       source,
+		Types:        types,
+		Scopes:       scopes,
+		RequireScope: requireScope,
+		MaxLength:    maxLength,
+	}
+}
+
+// Lint checks every commit header in from..to against the ruleset and fails
+// listing each commit that breaks it. Headers prefixed with an emoji or
+// gitmoji, as used by ghrelease, and "git revert" subjects are accepted.
+func (m *Commitlint) Lint(
+	ctx context.Context,
+
+	// Start of the range (exclusive), e.g. the pull request base branch.
+	// Defaults to the latest tag reachable from "to", or all history when
+	// there are no tags.
+	// +optional
+	from string,
+
+	// End of the range (inclusive)
+	// +optional
+	// +default="HEAD"
+	to string,
+
+	// Skip merge commits
+	// +optional
+	// +default=true
+	noMerges bool,
+) (string, error) {
+	ctr := dag.Container().
+		From(gitImage).
+		WithDirectory("/src", m.Source).
+		WithWorkdir("/src").
+		// The mounted source may be owned by another user.
+		WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "/src"})
+
+	if from == "" {
+		out, err := ctr.
+			WithExec([]string{"sh", "-c", `git describe --tags --abbrev=0 "$0" 2>/dev/null || true`, to}).
+			Stdout(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to find latest tag: %w", err)
+		}
+		from = strings.TrimSpace(out)
+	}
+
+	rangeArg := to
+	if from != "" {
+		rangeArg = from + ".." + to
+	}
+
+	args := []string{"git", "log", "--format=%H%x1f%s%x1e"}
+	if noMerges {
+		args = append(args, "--no-merges")
+	}
+	out, err := ctr.WithExec(append(args, rangeArg, "--")).Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read git log for %s: %w", rangeArg, err)
+	}
+
+	var failures []string
+	checked := 0
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		hash, header, ok := strings.Cut(record, "\x1f")
+		if !ok {
+			return "", fmt.Errorf("unexpected git log record %q", record)
+		}
+
+		checked++
+		if problems := m.rules().lintHeader(header); len(problems) > 0 {
+			failures = append(failures, fmt.Sprintf("%s %s\n    - %s", shortHash(hash), header, strings.Join(problems, "\n    - ")))
+		}
+	}
+
+	if len(failures) > 0 {
+		return "", fmt.Errorf("%d of %d commits in %s break the commit message rules\n\n  %s",
+			len(failures), checked, rangeArg, strings.Join(failures, "\n  "))
+	}
+
+	return fmt.Sprintf("✅ %d commits in %s follow the commit message rules", checked, rangeArg), nil
+}
+
+// Check lints the commits since the latest tag, making this suitable for CI
+// checks.
+//
+// +check
+func (m *Commitlint) Check(ctx context.Context) (string, error) {
+	return m.Lint(ctx, "", "HEAD", true)
+}
+
+// Message checks a single commit header against the ruleset, e.g. a pull
+// request title that becomes the squash commit subject.
+func (m *Commitlint) Message(
+	// Commit header to check
+	header string,
+) (string, error) {
+	header = strings.TrimSpace(strings.SplitN(header, "\n", 2)[0])
+	if problems := m.rules().lintHeader(header); len(problems) > 0 {
+		return "", fmt.Errorf("%q breaks the commit message rules\n  - %s", header, strings.Join(problems, "\n  - "))
+	}
+	return fmt.Sprintf("✅ %q follows the commit message rules", header), nil
+}
+
+func (m *Commitlint) rules() rules {
+	return rules{
+		types:        m.Types,
+		scopes:       m.Scopes,
+		requireScope: m.RequireScope,
+		maxLength:    m.MaxLength,
+	}
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
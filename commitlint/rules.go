@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// headerPattern matches a conventional commit header, optionally prefixed
+// with an emoji or gitmoji shortcode as used by ghrelease (e.g.,
+// "✨ feat(api)!: add streaming"). It mirrors the changelog and semver
+// modules so every commit they can classify passes the lint.
+var headerPattern = regexp.MustCompile(`^(?:(?::\w+:|[^\w\s]+)\s*)?(\w+)(?:\(([^)]*)\))?(!)?:\s*(.*)$`)
+
+// revertPattern matches the subject "git revert" writes, which is accepted
+// as-is.
+var revertPattern = regexp.MustCompile(`^Revert ".+"$`)
+
+// defaultTypes are the commit types accepted when none are configured: the
+// types the changelog module groups, plus "revert" and "breaking".
+var defaultTypes = []string{
+	"feat", "fix", "perf", "chore", "build", "ci", "docs", "refactor", "style", "test", "revert", "breaking",
+}
+
+// rules is a commit message ruleset.
+type rules struct {
+	types        []string
+	scopes       []string
+	requireScope bool
+	maxLength    int
+}
+
+// lintHeader returns the problems with a commit header, or nil when it
+// follows the ruleset.
+func (r rules) lintHeader(header string) []string {
+	if revertPattern.MatchString(header) {
+		return nil
+	}
+
+	var problems []string
+	if n := utf8.RuneCountInString(header); r.maxLength > 0 && n > r.maxLength {
+		problems = append(problems, fmt.Sprintf("header is %d characters, longer than %d", n, r.maxLength))
+	}
+
+	m := headerPattern.FindStringSubmatch(header)
+	if m == nil {
+		return append(problems, `header must be "type(scope): subject"`)
+	}
+	typ, scope, subject := m[1], m[2], m[4]
+
+	if !contains(r.types, strings.ToLower(typ)) {
+		problems = append(problems, fmt.Sprintf("type %q must be one of %s", typ, strings.Join(r.types, ", ")))
+	}
+
+	switch {
+	case scope == "" && r.requireScope:
+		problems = append(problems, "scope is required")
+	case scope != "" && len(r.scopes) > 0 && !contains(r.scopes, scope):
+		problems = append(problems, fmt.Sprintf("scope %q must be one of %s", scope, strings.Join(r.scopes, ", ")))
+	}
+
+	if strings.TrimSpace(subject) == "" {
+		problems = append(problems, "subject must not be empty")
+	}
+
+	return problems
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
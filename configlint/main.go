@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/configlint/internal/dagger"
+)
+
+const (
+	nodeImage    string = "node:22-alpine"
+	markdownlint string = "markdownlint-cli2@0.17.2"
+)
+
+// Configlint lints YAML, JSON, and Markdown files.
+type Configlint struct {
+	// Source is the directory containing the files to lint.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// YamlConfig is an optional yamllint configuration file.
+	//
+	// +private
+	YamlConfig *dagger.File
+
+	// MarkdownConfig is an optional markdownlint configuration file.
+	//
+	// +private
+	MarkdownConfig *dagger.File
+
+	// Exclude is the list of globs removed from every tool's selection.
+	//
+	// +private
+	Exclude []string
+}
+
+// Finding is the outcome of one lint tool.
+type Finding struct {
+	// Tool name: "yamllint", "jsonlint", or "markdownlint"
+	Tool string
+
+	// Number of files linted
+	Files int
+
+	// Whether the tool found no problems
+	Passed bool
+
+	// Tool output
+	Output string
+}
+
+// New creates a new Configlint module instance.
+func New(
+	// The directory containing the files to lint.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Optional yamllint configuration file. When not given, a .yamllint at
+	// the source root is used if present.
+	// +optional
+	yamlConfig *dagger.File,
+
+	// Optional markdownlint configuration file (.markdownlint.yaml or
+	// .markdownlint-cli2.yaml). When not given, one at the source root is
+	// used if present.
+	// +optional
+	markdownConfig *dagger.File,
+
+	// Globs removed from every tool's selection (defaults to
+	// "**/node_modules/**" and "**/vendor/**")
+	// +optional
+	exclude []string,
+) *Configlint {
+	if len(exclude) == 0 {
+		exclude = []string{"**/node_modules/**", "**/vendor/**"}
+	}
+	return &Configlint{
+		Source:         source,
+		YamlConfig:     yamlConfig,
+		MarkdownConfig: markdownConfig,
+		Exclude:        exclude,
+	}
+}
+
+// YAML runs yamllint on the selected YAML files.
+func (m *Configlint) YAML(
+	ctx context.Context,
+
+	// Globs selecting the files to lint (defaults to "**/*.yml" and
+	// "**/*.yaml")
+	// +optional
+	include []string,
+) (*Finding, error) {
+	if len(include) == 0 {
+		include = []string{"**/*.yml", "**/*.yaml"}
+	}
+
+	args := []string{"yamllint", "--strict", "--format", "parsable"}
+	if m.YamlConfig != nil {
+		args = append(args, "--config-file", "/config/yamllint.yaml")
+	}
+
+	return m.lint(ctx, "yamllint", include, args)
+}
+
+// JSON checks that the selected JSON files parse.
+func (m *Configlint) JSON(
+	ctx context.Context,
+
+	// Globs selecting the files to lint (defaults to "**/*.json")
+	// +optional
+	include []string,
+) (*Finding, error) {
+	if len(include) == 0 {
+		include = []string{"**/*.json"}
+	}
+
+	// jq reports the line and column of the first syntax error in each file.
+	args := []string{"sh", "-c", `status=0; for f; do jq empty "$f" || status=1; done; exit $status`, "jsonlint"}
+
+	return m.lint(ctx, "jsonlint", include, args)
+}
+
+// Markdown runs markdownlint on the selected Markdown files.
+func (m *Configlint) Markdown(
+	ctx context.Context,
+
+	// Globs selecting the files to lint (defaults to "**/*.md")
+	// +optional
+	include []string,
+) (*Finding, error) {
+	if len(include) == 0 {
+		include = []string{"**/*.md"}
+	}
+
+	args := []string{"markdownlint-cli2"}
+	if m.MarkdownConfig != nil {
+		// markdownlint-cli2 infers the config format from the file name.
+		name, err := m.MarkdownConfig.Name(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read markdownlint config name: %w", err)
+		}
+		args = append(args, "--config", "/config/"+name)
+	}
+
+	return m.lint(ctx, "markdownlint", include, args)
+}
+
+// Check runs yamllint, jsonlint, and markdownlint with their default file
+// selections and fails with the aggregated findings when any tool reports
+// problems, making this suitable for CI checks.
+//
+// +check
+func (m *Configlint) Check(ctx context.Context) (string, error) {
+	var findings []*Finding
+	for _, run := range []func(context.Context, []string) (*Finding, error){m.YAML, m.JSON, m.Markdown} {
+		f, err := run(ctx, nil)
+		if err != nil {
+			return "", err
+		}
+		findings = append(findings, f)
+	}
+
+	var b strings.Builder
+	failed := 0
+	for _, f := range findings {
+		switch {
+		case f.Files == 0:
+			fmt.Fprintf(&b, "➖ %s: no files\n", f.Tool)
+		case f.Passed:
+			fmt.Fprintf(&b, "✅ %s: %d files\n", f.Tool, f.Files)
+		default:
+			failed++
+			fmt.Fprintf(&b, "❌ %s: %d files\n\n%s\n\n", f.Tool, f.Files, strings.TrimSpace(f.Output))
+		}
+	}
+
+	if failed > 0 {
+		return "", fmt.Errorf("%d of %d linters found problems\n\n%s", failed, len(findings), b.String())
+	}
+
+	return b.String(), nil
+}
+
+// lint runs a linter over the files matching include and returns its
+// finding. The linter's stdout and stderr are combined into the output.
+func (m *Configlint) lint(ctx context.Context, tool string, include []string, args []string) (*Finding, error) {
+	files, err := m.files(ctx, include)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files for %s: %w", tool, err)
+	}
+	if len(files) == 0 {
+		return &Finding{Tool: tool, Passed: true}, nil
+	}
+
+	cmd := append([]string{"sh", "-c", `exec "$@" 2>&1`, tool}, args...)
+	ctr, err := m.lintContainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctr = ctr.
+		WithExec(append(cmd, files...), dagger.ContainerWithExecOpts{
+			Expect: dagger.ReturnTypeAny,
+		})
+
+	code, err := ctr.ExitCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w", tool, err)
+	}
+	out, err := ctr.Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s output: %w", tool, err)
+	}
+
+	return &Finding{
+		Tool:   tool,
+		Files:  len(files),
+		Passed: code == 0,
+		Output: out,
+	}, nil
+}
+
+// files returns the relative paths of the source files matching include.
+func (m *Configlint) files(ctx context.Context, include []string) ([]string, error) {
+	entries, err := m.Source.
+		Filter(dagger.DirectoryFilterOpts{Include: include, Exclude: m.Exclude}).
+		Glob(ctx, "**/*")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	// Glob returns directory entries with a trailing slash — skip them.
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+		files = append(files, entry)
+	}
+	return files, nil
+}
+
+// lintContainer returns a container with yamllint, jq, and markdownlint
+// installed, the source mounted, and any tool configs mounted under
+// /config.
+func (m *Configlint) lintContainer(ctx context.Context) (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(nodeImage).
+		WithExec([]string{"apk", "add", "--no-cache", "yamllint", "jq"}).
+		WithMountedCache("/root/.npm", dag.CacheVolume("npm")).
+		WithExec([]string{"npm", "install", "--global", markdownlint})
+
+	if m.YamlConfig != nil {
+		ctr = ctr.WithFile("/config/yamllint.yaml", m.YamlConfig)
+	}
+	if m.MarkdownConfig != nil {
+		name, err := m.MarkdownConfig.Name(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read markdownlint config name: %w", err)
+		}
+		ctr = ctr.WithFile("/config/"+name, m.MarkdownConfig)
+	}
+
+	return ctr.
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source), nil
+}
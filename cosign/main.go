@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/cosign/internal/dagger"
+)
+
+const (
+	cosignImage string = "gcr.io/projectsigstore/cosign:v2.4.1"
+)
+
+type Cosign struct {
+	// RegistryAddresses are the registries added with WithRegistryAuth.
+	//
+	// +private
+	RegistryAddresses []string
+
+	// RegistryUsernames are the usernames for RegistryAddresses, by index.
+	//
+	// +private
+	RegistryUsernames []string
+
+	// RegistryTokens are the tokens for RegistryAddresses, by index.
+	//
+	// +private
+	RegistryTokens []*dagger.Secret
+}
+
+// New creates a new Cosign module instance.
+func New() *Cosign {
+	return &Cosign{}
+}
+
+// WithRegistryAuth adds credentials for a registry, used to read images and
+// push signatures and attestations. Chain once per registry.
+func (m *Cosign) WithRegistryAuth(
+	// Registry address (e.g., "ghcr.io")
+	address string,
+
+	// Registry username
+	username string,
+
+	// Registry token or password
+	token *dagger.Secret,
+) *Cosign {
+	m.RegistryAddresses = append(m.RegistryAddresses, address)
+	m.RegistryUsernames = append(m.RegistryUsernames, username)
+	m.RegistryTokens = append(m.RegistryTokens, token)
+	return m
+}
+
+// Sign signs an image and pushes the signature to the registry. Signs with
+// a private key when key is given, otherwise keylessly through Fulcio using
+// identityToken (an OIDC token, e.g. from GitHub Actions). Always sign
+// digest references ("repo@sha256:...") so the signature covers exactly
+// the pushed image.
+func (m *Cosign) Sign(
+	ctx context.Context,
+
+	// Image reference to sign, preferably by digest
+	imageRef string,
+
+	// Private key (cosign.key) for key-based signing
+	// +optional
+	key *dagger.Secret,
+
+	// Password protecting the private key
+	// +optional
+	password *dagger.Secret,
+
+	// OIDC identity token for keyless signing
+	// +optional
+	identityToken *dagger.Secret,
+
+	// Annotations to add to the signature in "KEY=VALUE" format
+	// +optional
+	annotations []string,
+) (string, error) {
+	args := []string{"sign", "--yes"}
+	for _, a := range annotations {
+		if k, _, ok := strings.Cut(a, "="); !ok || k == "" {
+			return "", fmt.Errorf("invalid annotation %q: must be in KEY=VALUE format", a)
+		}
+		args = append(args, "-a", a)
+	}
+
+	ctr, args, err := m.signer(ctx, args, key, password, identityToken)
+	if err != nil {
+		return "", err
+	}
+
+	return m.run(ctx, ctr, append(args, imageRef), "sign "+imageRef)
+}
+
+// Attest attaches a signed in-toto attestation with the given predicate to
+// an image, using the same key-based or keyless signing as Sign.
+func (m *Cosign) Attest(
+	ctx context.Context,
+
+	// Image reference to attest, preferably by digest
+	imageRef string,
+
+	// Predicate file (e.g., SLSA provenance or an SBOM)
+	predicate *dagger.File,
+
+	// Predicate type: a cosign shorthand ("slsaprovenance", "spdxjson",
+	// "cyclonedx", "vuln", "custom") or a URI
+	// +optional
+	// +default="slsaprovenance"
+	predicateType string,
+
+	// Private key (cosign.key) for key-based signing
+	// +optional
+	key *dagger.Secret,
+
+	// Password protecting the private key
+	// +optional
+	password *dagger.Secret,
+
+	// OIDC identity token for keyless signing
+	// +optional
+	identityToken *dagger.Secret,
+) (string, error) {
+	ctr, args, err := m.signer(
+		ctx,
+		[]string{"attest", "--yes", "--predicate", "/predicate", "--type", predicateType},
+		key, password, identityToken,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	ctr = ctr.WithFile("/predicate", predicate)
+	return m.run(ctx, ctr, append(args, imageRef), "attest "+imageRef)
+}
+
+// Verify verifies an image's signature, with a public key when key is given
+// or otherwise against the keyless certificate identity and OIDC issuer.
+// When policy is given, the image's attestations are also verified against
+// the CUE or Rego policy.
+func (m *Cosign) Verify(
+	ctx context.Context,
+
+	// Image reference to verify
+	imageRef string,
+
+	// CUE (.cue) or Rego (.rego) policy that attestations must satisfy
+	// +optional
+	policy *dagger.File,
+
+	// Public key (cosign.pub) for key-based verification
+	// +optional
+	key *dagger.File,
+
+	// Expected signer identity for keyless verification as a regular
+	// expression (e.g., "^https://github.com/acme/myapp/")
+	// +optional
+	certificateIdentityRegexp string,
+
+	// Expected OIDC issuer for keyless verification
+	// +optional
+	// +default="https://token.actions.githubusercontent.com"
+	certificateOidcIssuer string,
+
+	// Attestation predicate type checked against the policy
+	// +optional
+	// +default="slsaprovenance"
+	attestationType string,
+) (string, error) {
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var identity []string
+	switch {
+	case key != nil:
+		ctr = ctr.WithFile("/cosign.pub", key)
+		identity = []string{"--key", "/cosign.pub"}
+	case certificateIdentityRegexp != "":
+		identity = []string{
+			"--certificate-identity-regexp", certificateIdentityRegexp,
+			"--certificate-oidc-issuer", certificateOidcIssuer,
+		}
+	default:
+		return "", fmt.Errorf("either key or certificate-identity-regexp is required to verify")
+	}
+
+	out, err := m.run(ctx, ctr, append(append([]string{"verify"}, identity...), imageRef), "verify "+imageRef)
+	if err != nil {
+		return "", err
+	}
+
+	if policy != nil {
+		name, err := policy.Name(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to read policy name: %w", err)
+		}
+		if ext := path.Ext(name); ext != ".cue" && ext != ".rego" {
+			return "", fmt.Errorf("unsupported policy %s: must be a .cue or .rego file", name)
+		}
+
+		policyPath := "/policy/" + name
+		args := append([]string{"verify-attestation", "--type", attestationType, "--policy", policyPath}, identity...)
+		attOut, err := m.run(ctx, ctr.WithFile(policyPath, policy), append(args, imageRef), "verify attestations of "+imageRef)
+		if err != nil {
+			return "", err
+		}
+		out += attOut
+	}
+
+	return out, nil
+}
+
+// signer adds the signing identity to a cosign command: a mounted private
+// key and password, or a keyless identity token.
+func (m *Cosign) signer(
+	ctx context.Context,
+	args []string,
+	key, password, identityToken *dagger.Secret,
+) (*dagger.Container, []string, error) {
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case key != nil:
+		ctr = ctr.WithMountedSecret("/cosign.key", key)
+		if password != nil {
+			ctr = ctr.WithSecretVariable("COSIGN_PASSWORD", password)
+		} else {
+			ctr = ctr.WithEnvVariable("COSIGN_PASSWORD", "")
+		}
+		args = append(args, "--key", "/cosign.key")
+	case identityToken != nil:
+		// --identity-token accepts a path to a file containing the token.
+		ctr = ctr.WithMountedSecret("/identity-token", identityToken)
+		args = append(args, "--identity-token", "/identity-token")
+	default:
+		return nil, nil, fmt.Errorf("either key or identity-token is required to sign")
+	}
+
+	return ctr, args, nil
+}
+
+// run executes a cosign command, returning its combined output.
+func (m *Cosign) run(ctx context.Context, ctr *dagger.Container, args []string, what string) (string, error) {
+	out, err := ctr.
+		WithExec(args, dagger.ContainerWithExecOpts{UseEntrypoint: true}).
+		CombinedOutput(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to %s: %w", what, err)
+	}
+	return out, nil
+}
+
+// container returns the cosign container with a Docker config.json holding
+// the registry credentials mounted as a secret.
+func (m *Cosign) container(ctx context.Context) (*dagger.Container, error) {
+	ctr := dag.Container().From(cosignImage)
+	if len(m.RegistryAddresses) == 0 {
+		return ctr, nil
+	}
+
+	auths := map[string]map[string]string{}
+	for i, address := range m.RegistryAddresses {
+		token, err := m.RegistryTokens[i].Plaintext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token for %s: %w", address, err)
+		}
+		auths[address] = map[string]string{
+			"auth": base64.StdEncoding.EncodeToString([]byte(m.RegistryUsernames[i] + ":" + token)),
+		}
+	}
+
+	config, err := json.Marshal(map[string]any{"auths": auths})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode registry config: %w", err)
+	}
+
+	return ctr.
+		WithMountedSecret("/docker/config.json", dag.SetSecret("cosign-docker-config", string(config))).
+		WithEnvVariable("DOCKER_CONFIG", "/docker"), nil
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"dagger/dbmigrate/internal/dagger"
+)
+
+const (
+	goImage      string = "golang:1.26-bookworm"
+	gooseVersion string = "v3.24.2"
+	migrateImage string = "migrate/migrate:v4.18.2"
+	atlasImage   string = "arigaio/atlas:0.31.0"
+
+	databaseURL string = "postgres://app:app@db:5432/app?sslmode=disable"
+	devURL      string = "postgres://app:app@db:5432/dev?sslmode=disable"
+)
+
+// Migration tools.
+const (
+	toolGoose   string = "goose"
+	toolMigrate string = "migrate"
+	toolAtlas   string = "atlas"
+)
+
+type Dbmigrate struct {
+	// Source is the repository containing the migrations.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Migrations is the migrations directory, relative to Source.
+	//
+	// +private
+	Migrations string
+
+	// Tool is the migration tool: "goose", "migrate", or "atlas".
+	//
+	// +private
+	Tool string
+
+	// Snapshot is the committed schema snapshot, relative to Source.
+	//
+	// +private
+	Snapshot string
+
+	// PostgresImage is the PostgreSQL image to migrate.
+	//
+	// +private
+	PostgresImage string
+}
+
+// New creates a new Dbmigrate module instance.
+func New(
+	// The repository containing the migrations.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Migrations directory, relative to source
+	// +optional
+	// +default="migrations"
+	migrations string,
+
+	// Migration tool: "goose", "migrate" (golang-migrate), or "atlas"
+	// +optional
+	// +default="goose"
+	tool string,
+
+	// Committed schema snapshot, relative to source
+	// +optional
+	// +default="schema.sql"
+	snapshot string,
+
+	// PostgreSQL image, matching the production major version
+	// +optional
+	// +default="postgres:17-alpine"
+	postgresImage string,
+) *Dbmigrate {
+	return &Dbmigrate{
+		Source:        source,
+		Migrations:    migrations,
+		Tool:          tool,
+		Snapshot:      snapshot,
+		PostgresImage: postgresImage,
+	}
+}
+
+// Apply applies every migration to an empty PostgreSQL database and returns
+// the tool output.
+func (m *Dbmigrate) Apply(ctx context.Context) (string, error) {
+	ctr, err := m.runner("apply")
+	if err != nil {
+		return "", err
+	}
+
+	up, _, err := m.commands(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := ctr.WithExec(up).CombinedOutput(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("migrations failed to apply\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return out, nil
+}
+
+// Reversible applies every migration, rolls them all back, and applies them
+// again, failing unless the rollback restores the empty schema and the
+// second apply reproduces the first.
+func (m *Dbmigrate) Reversible(ctx context.Context) (string, error) {
+	ctr, err := m.runner("reversible")
+	if err != nil {
+		return "", err
+	}
+
+	up, down, err := m.commands(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	empty, err := dump(ctx, ctr)
+	if err != nil {
+		return "", err
+	}
+
+	ctr = ctr.WithExec(up)
+	applied, err := dump(ctx, ctr)
+	if err != nil {
+		return "", fmt.Errorf("migrations failed to apply: %w", err)
+	}
+
+	ctr = ctr.WithExec(down)
+	rolledBack, err := dump(ctx, ctr)
+	if err != nil {
+		return "", fmt.Errorf("migrations failed to roll back: %w", err)
+	}
+	if rolledBack != empty {
+		diff, err := m.diff(ctx, empty, rolledBack)
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("rolling back every migration leaves schema behind\n\n%s", diff)
+	}
+
+	ctr = ctr.WithExec(up)
+	reapplied, err := dump(ctx, ctr)
+	if err != nil {
+		return "", fmt.Errorf("migrations failed to re-apply after rollback: %w", err)
+	}
+	if reapplied != applied {
+		diff, err := m.diff(ctx, applied, reapplied)
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("re-applying migrations after rollback produces a different schema\n\n%s", diff)
+	}
+
+	return "✅ migrations are reversible", nil
+}
+
+// Schema applies every migration and returns the normalized schema dump,
+// e.g. to update the committed snapshot.
+func (m *Dbmigrate) Schema(ctx context.Context) (*dagger.File, error) {
+	schema, err := m.schema(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return dag.Directory().WithNewFile("schema.sql", schema).File("schema.sql"), nil
+}
+
+// Drift applies every migration and fails with a diff when the resulting
+// schema differs from the committed snapshot, catching migrations added
+// without updating the snapshot and snapshot edits without a migration.
+func (m *Dbmigrate) Drift(ctx context.Context) (string, error) {
+	committed, err := m.Source.File(m.Snapshot).Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read schema snapshot %s: %w", m.Snapshot, err)
+	}
+
+	schema, err := m.schema(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if normalizeDump(committed) != schema {
+		diff, err := m.diff(ctx, normalizeDump(committed), schema)
+		if err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("schema has drifted from %s; regenerate it with the schema function\n\n%s", m.Snapshot, diff)
+	}
+
+	return fmt.Sprintf("✅ schema matches %s", m.Snapshot), nil
+}
+
+// Check verifies the migrations are reversible and, when a schema snapshot
+// is committed, that the schema has not drifted from it, making this
+// suitable for CI checks.
+//
+// +check
+func (m *Dbmigrate) Check(ctx context.Context) (string, error) {
+	out, err := m.Reversible(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ok, err := m.Source.Exists(ctx, m.Snapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect source directory: %w", err)
+	}
+	if !ok {
+		return out, nil
+	}
+
+	driftOut, err := m.Drift(ctx)
+	if err != nil {
+		return "", err
+	}
+	return out + "\n" + driftOut, nil
+}
+
+// schema applies every migration and returns the normalized schema dump.
+func (m *Dbmigrate) schema(ctx context.Context) (string, error) {
+	ctr, err := m.runner("schema")
+	if err != nil {
+		return "", err
+	}
+
+	up, _, err := m.commands(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	schema, err := dump(ctx, ctr.WithExec(up))
+	if err != nil {
+		return "", fmt.Errorf("migrations failed to apply: %w", err)
+	}
+	return schema, nil
+}
+
+// commands returns the tool's commands to apply and to roll back every
+// migration.
+func (m *Dbmigrate) commands(ctx context.Context) ([]string, []string, error) {
+	dir := "/src/" + m.Migrations
+	switch m.Tool {
+	case toolGoose:
+		return []string{"goose", "-dir", dir, "postgres", databaseURL, "up"},
+			[]string{"goose", "-dir", dir, "postgres", databaseURL, "reset"}, nil
+
+	case toolMigrate:
+		return []string{"migrate", "-path", dir, "-database", databaseURL, "up"},
+			[]string{"migrate", "-path", dir, "-database", databaseURL, "down", "-all"}, nil
+
+	case toolAtlas:
+		// atlas plans rollbacks against a scratch dev database and takes
+		// the number of migrations to revert.
+		files, err := m.Source.Directory(m.Migrations).Glob(ctx, "*.sql")
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list migrations: %w", err)
+		}
+		return []string{"atlas", "migrate", "apply", "--dir", "file://" + dir, "--url", databaseURL},
+			[]string{
+				"atlas", "migrate", "down", "--dir", "file://" + dir, "--url", databaseURL,
+				"--dev-url", devURL, "--amount", strconv.Itoa(len(files)),
+			}, nil
+
+	default:
+		return nil, nil, fmt.Errorf("invalid tool %q: must be %q, %q, or %q", m.Tool, toolGoose, toolMigrate, toolAtlas)
+	}
+}
+
+// diff returns a unified diff between two schema dumps.
+func (m *Dbmigrate) diff(ctx context.Context, expected, actual string) (string, error) {
+	return dag.Container().
+		From(m.PostgresImage).
+		WithNewFile("/diff/expected.sql", expected).
+		WithNewFile("/diff/actual.sql", actual).
+		WithWorkdir("/diff").
+		WithExec([]string{"diff", "-u", "expected.sql", "actual.sql"}, dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny}).
+		Stdout(ctx)
+}
+
+// dump returns the normalized schema of the database, without the
+// migration tools' bookkeeping tables.
+func dump(ctx context.Context, ctr *dagger.Container) (string, error) {
+	out, err := ctr.
+		WithExec([]string{
+			"pg_dump", "--schema-only", "--no-owner", "--no-privileges",
+			"--exclude-table=goose_db_version",
+			"--exclude-table=schema_migrations",
+			"--exclude-schema=atlas_schema_revisions",
+			databaseURL,
+		}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to dump schema: %w", err)
+	}
+	return normalizeDump(out), nil
+}
+
+// runner returns a container with the migration tool, pg_dump, and the
+// migrations, bound to a fresh PostgreSQL service at "db". Each run gets
+// its own database so runs cannot see each other's migrations.
+func (m *Dbmigrate) runner(run string) (*dagger.Container, error) {
+	db := dag.Container().
+		From(m.PostgresImage).
+		WithEnvVariable("POSTGRES_DB", "app").
+		WithEnvVariable("POSTGRES_USER", "app").
+		WithEnvVariable("POSTGRES_PASSWORD", "app").
+		WithEnvVariable("DBMIGRATE_RUN", run).
+		WithExposedPort(5432).
+		AsService(dagger.ContainerAsServiceOpts{UseEntrypoint: true})
+
+	// The PostgreSQL image provides a pg_dump matching the server.
+	ctr := dag.Container().
+		From(m.PostgresImage).
+		WithServiceBinding("db", db).
+		WithDirectory("/src", m.Source).
+		WithWorkdir("/src")
+
+	switch m.Tool {
+	case toolGoose:
+		goose := dag.Container().
+			From(goImage).
+			WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+			WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+			WithEnvVariable("CGO_ENABLED", "0").
+			WithExec([]string{"go", "install", "github.com/pressly/goose/v3/cmd/goose@" + gooseVersion}).
+			File("/go/bin/goose")
+		ctr = ctr.WithFile("/usr/local/bin/goose", goose)
+
+	case toolMigrate:
+		ctr = ctr.WithFile("/usr/local/bin/migrate", dag.Container().From(migrateImage).File("/usr/local/bin/migrate"))
+
+	case toolAtlas:
+		ctr = ctr.
+			WithFile("/usr/local/bin/atlas", dag.Container().From(atlasImage).File("/atlas")).
+			WithExec([]string{"psql", databaseURL, "-c", "CREATE DATABASE dev"})
+
+	default:
+		return nil, fmt.Errorf("invalid tool %q: must be %q, %q, or %q", m.Tool, toolGoose, toolMigrate, toolAtlas)
+	}
+
+	return ctr, nil
+}
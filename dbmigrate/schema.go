@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+)
+
+// normalizeDump strips the parts of a pg_dump schema dump that change
+// between runs or pg_dump versions (comments, session settings, and the
+// \restrict guards of newer clients), so dumps can be compared and
+// committed.
+func normalizeDump(dump string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(dump, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		switch {
+		case strings.HasPrefix(line, "--"),
+			strings.HasPrefix(line, "SET "),
+			strings.HasPrefix(line, `\restrict`),
+			strings.HasPrefix(line, `\unrestrict`),
+			strings.HasPrefix(line, "SELECT pg_catalog.set_config('search_path'"):
+			continue
+		case line == "":
+			// Collapse runs of blank lines left by the removed lines.
+			if blank || len(lines) == 0 {
+				continue
+			}
+			blank = true
+		default:
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")) + "\n"
+}
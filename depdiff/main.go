@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"dagger/depdiff/internal/dagger"
+)
+
+const (
+	gitImage  string = "alpine/git:v2.47.1"
+	syftImage string = "anchore/syft:v1.18.1"
+)
+
+type Depdiff struct {
+	// Source is the repository whose refs are compared.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// RemoteLicenses looks up licenses of Go and JavaScript dependencies
+	// from their registries.
+	//
+	// +private
+	RemoteLicenses bool
+}
+
+// New creates a new Depdiff module instance.
+func New(
+	// The repository whose refs are compared. Must include .git.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Look up licenses of Go and JavaScript dependencies from the module
+	// proxy and npm registry, which lockfiles alone do not record
+	// +optional
+	// +default=true
+	remoteLicenses bool,
+) *Depdiff {
+	return &Depdiff{
+		Source:         source,
+		RemoteLicenses: remoteLicenses,
+	}
+}
+
+// Sbom returns a CycloneDX SBOM of the dependencies declared by the
+// manifests and lockfiles (go.mod, package-lock.json, Cargo.lock, ...) at a
+// git ref.
+func (m *Depdiff) Sbom(
+	// Git ref to catalog (e.g., "v1.2.0" or "HEAD")
+	// +optional
+	// +default="HEAD"
+	ref string,
+) *dagger.File {
+	tree := dag.Container().
+		From(gitImage).
+		WithDirectory("/src", m.Source).
+		WithWorkdir("/src").
+		// The mounted source may be owned by another user.
+		WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "/src"}).
+		WithExec([]string{"mkdir", "/tree"}).
+		WithExec([]string{"sh", "-c", `git archive "$1" | tar -x -C /tree`, "sh", ref}).
+		Directory("/tree")
+
+	ctr := dag.Container().
+		From(syftImage).
+		WithDirectory("/src", tree)
+	if m.RemoteLicenses {
+		ctr = ctr.
+			WithEnvVariable("SYFT_GOLANG_SEARCH_REMOTE_LICENSES", "true").
+			WithEnvVariable("SYFT_JAVASCRIPT_SEARCH_REMOTE_LICENSES", "true")
+	}
+
+	return ctr.
+		WithExec(
+			[]string{"scan", "dir:/src", "--output", "cyclonedx-json=/out/sbom.cdx.json"},
+			dagger.ContainerWithExecOpts{UseEntrypoint: true},
+		).
+		File("/out/sbom.cdx.json")
+}
+
+// Report compares the dependencies of two refs and returns the added,
+// removed, and updated dependencies and license changes as markdown for
+// release notes.
+//
+// Either side may be given as a CycloneDX JSON SBOM instead of a ref, e.g.
+// one attached to a previous release.
+func (m *Depdiff) Report(
+	ctx context.Context,
+
+	// Git ref of the previous release (e.g., "v1.2.0")
+	// +optional
+	base string,
+
+	// Git ref of the new release
+	// +optional
+	// +default="HEAD"
+	head string,
+
+	// CycloneDX JSON SBOM of the previous release, instead of base
+	// +optional
+	baseSbom *dagger.File,
+
+	// CycloneDX JSON SBOM of the new release, instead of head
+	// +optional
+	headSbom *dagger.File,
+) (string, error) {
+	if baseSbom == nil {
+		if base == "" {
+			return "", fmt.Errorf("either base or base-sbom is required")
+		}
+		baseSbom = m.Sbom(base)
+	} else if base == "" {
+		base = "base"
+	}
+	if headSbom == nil {
+		headSbom = m.Sbom(head)
+	}
+
+	baseDeps, err := m.parse(ctx, baseSbom)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s dependencies: %w", base, err)
+	}
+	headDeps, err := m.parse(ctx, headSbom)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s dependencies: %w", head, err)
+	}
+
+	return diffSBOMs(baseDeps, headDeps).markdown(base, head), nil
+}
+
+// parse reads the dependencies of a CycloneDX JSON SBOM.
+func (m *Depdiff) parse(ctx context.Context, sbom *dagger.File) (map[string]*dependency, error) {
+	data, err := sbom.Contents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseSBOM(data)
+}
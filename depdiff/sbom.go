@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dependency is a package in an SBOM, keyed by ecosystem and name.
+type dependency struct {
+	Ecosystem string
+	Name      string
+	Versions  []string
+	Licenses  []string
+}
+
+// cyclonedx is the subset of a CycloneDX JSON SBOM the diff reads.
+type cyclonedx struct {
+	Components []struct {
+		Name     string `json:"name"`
+		Version  string `json:"version"`
+		Purl     string `json:"purl"`
+		Licenses []struct {
+			License struct {
+				ID   string `json:"id"`
+				Name string `json:"name"`
+			} `json:"license"`
+			Expression string `json:"expression"`
+		} `json:"licenses"`
+	} `json:"components"`
+}
+
+// parseSBOM returns the dependencies of a CycloneDX JSON SBOM by
+// "<ecosystem>/<name>". Components without a package URL, such as the
+// files syft catalogs, are not dependencies and are skipped.
+func parseSBOM(data string) (map[string]*dependency, error) {
+	var sbom cyclonedx
+	if err := json.Unmarshal([]byte(data), &sbom); err != nil {
+		return nil, fmt.Errorf("failed to parse CycloneDX SBOM: %w", err)
+	}
+
+	deps := map[string]*dependency{}
+	for _, c := range sbom.Components {
+		if !strings.HasPrefix(c.Purl, "pkg:") {
+			continue
+		}
+		ecosystem, _, _ := strings.Cut(strings.TrimPrefix(c.Purl, "pkg:"), "/")
+
+		key := ecosystem + "/" + c.Name
+		dep, ok := deps[key]
+		if !ok {
+			dep = &dependency{Ecosystem: ecosystem, Name: c.Name}
+			deps[key] = dep
+		}
+		if c.Version != "" {
+			dep.Versions = appendUnique(dep.Versions, c.Version)
+		}
+		for _, l := range c.Licenses {
+			switch {
+			case l.Expression != "":
+				dep.Licenses = appendUnique(dep.Licenses, l.Expression)
+			case l.License.ID != "":
+				dep.Licenses = appendUnique(dep.Licenses, l.License.ID)
+			case l.License.Name != "":
+				dep.Licenses = appendUnique(dep.Licenses, l.License.Name)
+			}
+		}
+	}
+
+	for _, dep := range deps {
+		sort.Strings(dep.Versions)
+		sort.Strings(dep.Licenses)
+	}
+	return deps, nil
+}
+
+// change is a dependency present in both SBOMs whose versions or licenses
+// differ.
+type change struct {
+	From *dependency
+	To   *dependency
+}
+
+// report is the difference between two SBOMs.
+type report struct {
+	Added   []*dependency
+	Removed []*dependency
+	Updated []change
+	// Relicensed lists dependencies whose licenses changed, whether or not
+	// their versions did.
+	Relicensed []change
+}
+
+// diffSBOMs compares the dependencies of two SBOMs. Every list is sorted by
+// ecosystem and name.
+func diffSBOMs(base, head map[string]*dependency) report {
+	var r report
+	for _, key := range sortedKeys(head) {
+		to := head[key]
+		from, ok := base[key]
+		if !ok {
+			r.Added = append(r.Added, to)
+			continue
+		}
+		if !equal(from.Versions, to.Versions) {
+			r.Updated = append(r.Updated, change{From: from, To: to})
+		}
+		if !equal(from.Licenses, to.Licenses) {
+			r.Relicensed = append(r.Relicensed, change{From: from, To: to})
+		}
+	}
+	for _, key := range sortedKeys(base) {
+		if _, ok := head[key]; !ok {
+			r.Removed = append(r.Removed, base[key])
+		}
+	}
+	return r
+}
+
+// markdown renders the report for release notes.
+func (r report) markdown(base, head string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Dependency changes (%s → %s)\n\n", base, head)
+
+	if len(r.Added)+len(r.Removed)+len(r.Updated)+len(r.Relicensed) == 0 {
+		b.WriteString("No dependency changes.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "%d added, %d removed, %d updated, %d license changes.\n",
+		len(r.Added), len(r.Removed), len(r.Updated), len(r.Relicensed))
+
+	if len(r.Relicensed) > 0 {
+		b.WriteString("\n### ⚠️ License changes\n\n")
+		b.WriteString("| Dependency | Version | From | To |\n")
+		b.WriteString("|------------|---------|------|----|\n")
+		for _, c := range r.Relicensed {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s |\n",
+				name(c.To), list(c.To.Versions), list(c.From.Licenses), list(c.To.Licenses))
+		}
+	}
+
+	if len(r.Added) > 0 {
+		b.WriteString("\n### Added\n\n")
+		b.WriteString("| Dependency | Version | License |\n")
+		b.WriteString("|------------|---------|---------|\n")
+		for _, d := range r.Added {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", name(d), list(d.Versions), list(d.Licenses))
+		}
+	}
+
+	if len(r.Updated) > 0 {
+		b.WriteString("\n### Updated\n\n")
+		b.WriteString("| Dependency | From | To |\n")
+		b.WriteString("|------------|------|----|\n")
+		for _, c := range r.Updated {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", name(c.To), list(c.From.Versions), list(c.To.Versions))
+		}
+	}
+
+	if len(r.Removed) > 0 {
+		b.WriteString("\n### Removed\n\n")
+		b.WriteString("| Dependency | Version |\n")
+		b.WriteString("|------------|---------|\n")
+		for _, d := range r.Removed {
+			fmt.Fprintf(&b, "| %s | %s |\n", name(d), list(d.Versions))
+		}
+	}
+
+	return b.String()
+}
+
+// name renders a dependency name with its ecosystem.
+func name(d *dependency) string {
+	return fmt.Sprintf("`%s` (%s)", d.Name, d.Ecosystem)
+}
+
+// list renders a list of versions or licenses, or "unknown" when empty.
+func list(items []string) string {
+	if len(items) == 0 {
+		return "unknown"
+	}
+	return strings.Join(items, ", ")
+}
+
+func sortedKeys(deps map[string]*dependency) []string {
+	keys := make([]string, 0, len(deps))
+	for k := range deps {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func appendUnique(items []string, item string) []string {
+	for _, i := range items {
+		if i == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"dagger/desktoppack/internal/dagger"
+)
+
+const (
+	debianImage         string = "debian:bookworm-slim"
+	appimagetoolVersion string = "1.9.0"
+)
+
+// guidPattern matches a GUID such as an MSI upgrade code.
+var guidPattern = regexp.MustCompile(`^[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}$`)
+
+type Desktoppack struct {
+	// Name is the application name, used for the executable and installers.
+	//
+	// +private
+	Name string
+
+	// Version is the application version.
+	//
+	// +private
+	Version string
+
+	// Identifier is the reverse-DNS bundle identifier.
+	//
+	// +private
+	Identifier string
+
+	// Publisher is the company or author shown by installers.
+	//
+	// +private
+	Publisher string
+
+	// Icon is an optional PNG application icon.
+	//
+	// +private
+	Icon *dagger.File
+}
+
+// New creates a new Desktoppack module instance.
+func New(
+	// Application name (e.g., "myapp")
+	name string,
+
+	// Application version (e.g., "v1.2.3")
+	version string,
+
+	// Reverse-DNS bundle identifier, required for macOS (e.g., "dev.acme.myapp")
+	// +optional
+	identifier string,
+
+	// Company or author shown by installers
+	// +optional
+	publisher string,
+
+	// Application icon as a 512x512 PNG, required for AppImage
+	// +optional
+	icon *dagger.File,
+) *Desktoppack {
+	if publisher == "" {
+		publisher = name
+	}
+	return &Desktoppack{
+		Name:       name,
+		Version:    strings.TrimPrefix(version, "v"),
+		Identifier: identifier,
+		Publisher:  publisher,
+		Icon:       icon,
+	}
+}
+
+// Dmg wraps a darwin binary into a .app bundle inside a .dmg disk image.
+// With a Developer ID certificate, the app is signed with the hardened
+// runtime, and with an App Store Connect API key it is also notarized and
+// the ticket stapled to it, so Gatekeeper opens it without warnings.
+// Signing and notarization use the macsign module.
+func (m *Desktoppack) Dmg(
+	ctx context.Context,
+
+	// darwin binary (universal, arm64, or amd64)
+	binary *dagger.File,
+
+	// Developer ID Application certificate and private key as a .p12 file
+	// +optional
+	certificate *dagger.Secret,
+
+	// Password of the .p12 file
+	// +optional
+	certificatePassword *dagger.Secret,
+
+	// App Store Connect API key JSON, as written by
+	// "rcodesign encode-app-store-connect-api-key"
+	// +optional
+	apiKey *dagger.Secret,
+) (*dagger.File, error) {
+	if m.Identifier == "" {
+		return nil, fmt.Errorf("identifier is required to build a .app bundle")
+	}
+	if apiKey != nil && certificate == nil {
+		return nil, fmt.Errorf("notarization requires a signing certificate")
+	}
+
+	plist, err := render(infoPlist, m.data())
+	if err != nil {
+		return nil, err
+	}
+
+	app := "/build/" + m.Name + ".app"
+	ctr := m.tools("genisoimage", "icnsutils").
+		WithFile(app+"/Contents/MacOS/"+m.Name, binary, dagger.ContainerWithFileOpts{Permissions: 0o755}).
+		WithNewFile(app+"/Contents/Info.plist", plist)
+
+	if m.Icon != nil {
+		ctr = ctr.
+			WithFile("/icon.png", m.Icon).
+			WithExec([]string{"mkdir", "-p", app + "/Contents/Resources"}).
+			WithExec([]string{"png2icns", app + "/Contents/Resources/icon.icns", "/icon.png"})
+	}
+
+	bundle := ctr.Directory("/build")
+	if certificate != nil {
+		signer := dag.Macsign(certificate, dagger.MacsignOpts{
+			CertificatePassword: certificatePassword,
+			APIKey:              apiKey,
+		})
+		bundle = signer.Sign(bundle)
+		if apiKey != nil {
+			// Notarize and staple the app rather than the image: Gatekeeper
+			// checks the app, and the stapled ticket travels with it when it
+			// is copied out of the image.
+			bundle = signer.Notarize(bundle)
+		}
+	}
+
+	out := fmt.Sprintf("/out/%s-%s.dmg", m.Name, m.Version)
+	return ctr.
+		WithDirectory("/build", bundle).
+		WithExec([]string{"mkdir", "-p", "/out"}).
+		WithExec([]string{"genisoimage", "-V", m.Name, "-D", "-R", "-apple", "-no-pad", "-o", out, "/build"}).
+		File(out), nil
+}
+
+// Msi wraps a Windows binary into an .msi installer built with wixl, which
+// installs it under Program Files with a Start menu shortcut. The upgrade
+// code must stay the same across releases so new versions replace old ones.
+func (m *Desktoppack) Msi(
+	// Windows amd64 executable
+	binary *dagger.File,
+
+	// Upgrade code GUID, fixed for the lifetime of the product
+	// (e.g., "0D6B4A3C-9A1E-4C4B-8E4F-2B9F1C7D5A10")
+	upgradeCode string,
+) (*dagger.File, error) {
+	if !guidPattern.MatchString(upgradeCode) {
+		return nil, fmt.Errorf("invalid upgrade code %q: must be a GUID", upgradeCode)
+	}
+
+	data := m.data()
+	data.UpgradeCode = strings.ToUpper(upgradeCode)
+	wxs, err := render(wixSource, data)
+	if err != nil {
+		return nil, err
+	}
+
+	out := fmt.Sprintf("/out/%s-%s.msi", m.Name, m.Version)
+	return m.tools("wixl").
+		WithWorkdir("/build").
+		WithFile(m.Name+".exe", binary).
+		WithNewFile("product.wxs", wxs).
+		WithExec([]string{"mkdir", "-p", "/out"}).
+		WithExec([]string{"wixl", "--arch", "x64", "-o", out, "product.wxs"}).
+		File(out), nil
+}
+
+// Nsis wraps a Windows binary into a setup.exe installer built with NSIS,
+// with a Start menu shortcut and an uninstaller registered in Add/Remove
+// Programs.
+func (m *Desktoppack) Nsis(
+	// Windows amd64 executable
+	binary *dagger.File,
+) (*dagger.File, error) {
+	out := fmt.Sprintf("/out/%s-%s-setup.exe", m.Name, m.Version)
+	data := m.data()
+	data.OutFile = out
+	script, err := render(nsisScript, data)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr := m.tools("nsis", "icoutils").
+		WithWorkdir("/build").
+		WithFile(m.Name+".exe", binary).
+		WithNewFile("installer.nsi", script)
+
+	if m.Icon != nil {
+		ctr = ctr.
+			WithFile("/icon.png", m.Icon).
+			WithExec([]string{"icotool", "-c", "-o", "icon.ico", "/icon.png"})
+	}
+
+	return ctr.
+		WithExec([]string{"mkdir", "-p", "/out"}).
+		WithExec([]string{"makensis", "-V2", "installer.nsi"}).
+		File(out), nil
+}
+
+// AppImage wraps a Linux binary into a portable AppImage with a desktop
+// entry and icon. appimagetool itself runs on amd64 hosts.
+func (m *Desktoppack) AppImage(
+	// Linux executable
+	binary *dagger.File,
+
+	// Target architecture: "x86_64" or "aarch64"
+	// +optional
+	// +default="x86_64"
+	arch string,
+
+	// Desktop entry categories, separated by ";" (e.g., "Development;Utility")
+	// +optional
+	// +default="Utility"
+	categories string,
+
+	// Run the application in a terminal
+	// +optional
+	terminal bool,
+) (*dagger.File, error) {
+	if m.Icon == nil {
+		return nil, fmt.Errorf("icon is required to build an AppImage")
+	}
+	if arch != "x86_64" && arch != "aarch64" {
+		return nil, fmt.Errorf("invalid arch %q: must be \"x86_64\" or \"aarch64\"", arch)
+	}
+
+	data := m.data()
+	data.Categories = strings.TrimSuffix(categories, ";")
+	data.Terminal = terminal
+	entry, err := render(desktopEntry, data)
+	if err != nil {
+		return nil, err
+	}
+
+	tool := fmt.Sprintf(
+		"https://github.com/AppImage/appimagetool/releases/download/%s/appimagetool-x86_64.AppImage",
+		appimagetoolVersion,
+	)
+	out := fmt.Sprintf("/out/%s-%s-%s.AppImage", m.Name, m.Version, arch)
+	return m.tools("file").
+		WithFile("/usr/local/bin/appimagetool", dag.HTTP(tool), dagger.ContainerWithFileOpts{Permissions: 0o755}).
+		// Run the tool without FUSE, which containers do not have.
+		WithEnvVariable("APPIMAGE_EXTRACT_AND_RUN", "1").
+		WithEnvVariable("ARCH", arch).
+		WithFile("/AppDir/usr/bin/"+m.Name, binary, dagger.ContainerWithFileOpts{Permissions: 0o755}).
+		WithNewFile("/AppDir/AppRun", fmt.Sprintf(appRun, m.Name), dagger.ContainerWithNewFileOpts{Permissions: 0o755}).
+		WithNewFile("/AppDir/"+m.Name+".desktop", entry).
+		WithFile("/AppDir/"+m.Name+".png", m.Icon).
+		WithExec([]string{"mkdir", "-p", "/out"}).
+		WithExec([]string{"appimagetool", "/AppDir", out}).
+		File(out), nil
+}
+
+// templateData is the data passed to the installer templates.
+type templateData struct {
+	Name        string
+	Version     string
+	FileVersion string
+	Identifier  string
+	Publisher   string
+	Icon        bool
+	UpgradeCode string
+	Categories  string
+	Terminal    bool
+	OutFile     string
+}
+
+// data returns the template data for the application. Installers need a
+// numeric version, so any pre-release or build suffix is dropped.
+func (m *Desktoppack) data() templateData {
+	version := m.Version
+	if i := strings.IndexAny(version, "-+"); i >= 0 {
+		version = version[:i]
+	}
+	return templateData{
+		Name:        m.Name,
+		Version:     version,
+		FileVersion: version + ".0",
+		Identifier:  m.Identifier,
+		Publisher:   m.Publisher,
+		Icon:        m.Icon != nil,
+	}
+}
+
+// tools returns a Debian container with the given packages installed.
+func (m *Desktoppack) tools(packages ...string) *dagger.Container {
+	return dag.Container().
+		From(debianImage).
+		WithMountedCache("/var/cache/apt", dag.CacheVolume("desktoppack-apt")).
+		WithExec([]string{"apt-get", "update"}).
+		WithExec(append([]string{"apt-get", "install", "-y", "--no-install-recommends", "ca-certificates"}, packages...))
+}
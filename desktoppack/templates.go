@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// funcs are the functions available to the XML templates.
+var funcs = template.FuncMap{
+	"xml": func(s string) string {
+		var b strings.Builder
+		_ = xml.EscapeText(&b, []byte(s))
+		return b.String()
+	},
+}
+
+// infoPlist is the Info.plist of a macOS .app bundle.
+var infoPlist = template.Must(template.New("Info.plist").Funcs(funcs).Parse(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>CFBundleName</key>
+	<string>{{xml .Name}}</string>
+	<key>CFBundleDisplayName</key>
+	<string>{{xml .Name}}</string>
+	<key>CFBundleIdentifier</key>
+	<string>{{xml .Identifier}}</string>
+	<key>CFBundleVersion</key>
+	<string>{{xml .Version}}</string>
+	<key>CFBundleShortVersionString</key>
+	<string>{{xml .Version}}</string>
+	<key>CFBundleExecutable</key>
+	<string>{{xml .Name}}</string>
+	<key>CFBundlePackageType</key>
+	<string>APPL</string>
+{{- if .Icon}}
+	<key>CFBundleIconFile</key>
+	<string>icon.icns</string>
+{{- end}}
+	<key>NSHighResolutionCapable</key>
+	<true/>
+</dict>
+</plist>
+`))
+
+// wixSource is a WiX source installing the binary under Program Files with
+// a Start menu shortcut, built with wixl.
+var wixSource = template.Must(template.New("product.wxs").Funcs(funcs).Parse(`<?xml version="1.0" encoding="utf-8"?>
+<Wix xmlns="http://schemas.microsoft.com/wix/2006/wi">
+  <Product Id="*" Name="{{xml .Name}}" Version="{{xml .Version}}" Manufacturer="{{xml .Publisher}}" Language="1033" UpgradeCode="{{xml .UpgradeCode}}">
+    <Package InstallerVersion="200" Compressed="yes" InstallScope="perMachine" Platform="x64" Manufacturer="{{xml .Publisher}}"/>
+    <MajorUpgrade DowngradeErrorMessage="A newer version of {{xml .Name}} is already installed."/>
+    <Media Id="1" Cabinet="product.cab" EmbedCab="yes"/>
+    <Directory Id="TARGETDIR" Name="SourceDir">
+      <Directory Id="ProgramFiles64Folder">
+        <Directory Id="INSTALLDIR" Name="{{xml .Name}}">
+          <Component Id="MainExecutable" Guid="*" Win64="yes">
+            <File Id="MainExe" Name="{{xml .Name}}.exe" Source="{{xml .Name}}.exe" KeyPath="yes"/>
+          </Component>
+        </Directory>
+      </Directory>
+      <Directory Id="ProgramMenuFolder">
+        <Component Id="StartMenuShortcut" Guid="*">
+          <Shortcut Id="StartMenuShortcut" Name="{{xml .Name}}" Target="[INSTALLDIR]{{xml .Name}}.exe" WorkingDirectory="INSTALLDIR"/>
+          <RegistryValue Root="HKCU" Key="Software\{{xml .Publisher}}\{{xml .Name}}" Name="installed" Type="integer" Value="1" KeyPath="yes"/>
+        </Component>
+      </Directory>
+    </Directory>
+    <Feature Id="Main" Level="1">
+      <ComponentRef Id="MainExecutable"/>
+      <ComponentRef Id="StartMenuShortcut"/>
+    </Feature>
+  </Product>
+</Wix>
+`))
+
+// nsisScript is an NSIS script for a per-machine setup.exe with a Start
+// menu shortcut and an uninstaller registered in Add/Remove Programs.
+var nsisScript = template.Must(template.New("installer.nsi").Parse(`Unicode true
+!include "MUI2.nsh"
+!include "x64.nsh"
+
+Name "{{.Name}}"
+OutFile "{{.OutFile}}"
+InstallDir "$PROGRAMFILES64\{{.Name}}"
+RequestExecutionLevel admin
+VIProductVersion "{{.FileVersion}}"
+VIAddVersionKey "ProductName" "{{.Name}}"
+VIAddVersionKey "CompanyName" "{{.Publisher}}"
+VIAddVersionKey "FileVersion" "{{.Version}}"
+VIAddVersionKey "ProductVersion" "{{.Version}}"
+VIAddVersionKey "FileDescription" "{{.Name}} installer"
+{{- if .Icon}}
+!define MUI_ICON "icon.ico"
+{{- end}}
+
+!insertmacro MUI_PAGE_DIRECTORY
+!insertmacro MUI_PAGE_INSTFILES
+!insertmacro MUI_UNPAGE_CONFIRM
+!insertmacro MUI_UNPAGE_INSTFILES
+!insertmacro MUI_LANGUAGE "English"
+
+!define UNINSTALL_KEY "Software\Microsoft\Windows\CurrentVersion\Uninstall\{{.Name}}"
+
+Section "Install"
+  SetOutPath "$INSTDIR"
+  File "{{.Name}}.exe"
+  WriteUninstaller "$INSTDIR\uninstall.exe"
+  CreateShortCut "$SMPROGRAMS\{{.Name}}.lnk" "$INSTDIR\{{.Name}}.exe"
+  WriteRegStr HKLM "${UNINSTALL_KEY}" "DisplayName" "{{.Name}}"
+  WriteRegStr HKLM "${UNINSTALL_KEY}" "DisplayVersion" "{{.Version}}"
+  WriteRegStr HKLM "${UNINSTALL_KEY}" "Publisher" "{{.Publisher}}"
+  WriteRegStr HKLM "${UNINSTALL_KEY}" "UninstallString" "$\"$INSTDIR\uninstall.exe$\""
+SectionEnd
+
+Section "Uninstall"
+  Delete "$SMPROGRAMS\{{.Name}}.lnk"
+  Delete "$INSTDIR\{{.Name}}.exe"
+  Delete "$INSTDIR\uninstall.exe"
+  RMDir "$INSTDIR"
+  DeleteRegKey HKLM "${UNINSTALL_KEY}"
+SectionEnd
+`))
+
+// desktopEntry is the freedesktop.org .desktop file of an AppImage.
+var desktopEntry = template.Must(template.New("app.desktop").Parse(`[Desktop Entry]
+Type=Application
+Name={{.Name}}
+Exec={{.Name}}
+Icon={{.Name}}
+Categories={{.Categories}};
+Terminal={{.Terminal}}
+`))
+
+// appRun starts the binary from inside the mounted AppImage.
+const appRun string = `#!/bin/sh
+HERE="$(dirname "$(readlink -f "$0")")"
+exec "$HERE/usr/bin/%s" "$@"
+`
+
+// render executes a template with data.
+func render(t *template.Template, data any) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", t.Name(), err)
+	}
+	return buf.String(), nil
+}
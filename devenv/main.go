@@ -0,0 +1,343 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"dagger/devenv/internal/dagger"
+)
+
+const (
+	goImage     string = "golang:1.26-bookworm"
+	alpineImage string = "alpine:3.21"
+
+	// appBin is where the reloaders build the app.
+	appBin string = "/tmp/devenv/app"
+)
+
+// reloaders maps each supported reloader to the package "go install"
+// installs, or "" when none is needed.
+var reloaders = map[string]string{
+	"air":  "github.com/air-verse/air@v1.61.7",
+	"gow":  "github.com/mitranim/gow@latest",
+	"none": "",
+}
+
+// Devenv runs a project's app and its service dependencies as Dagger
+// services, exposed together on the host.
+type Devenv struct {
+	// Source is the Go source directory of the app.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Pkg is the app's main package.
+	//
+	// +private
+	Pkg string
+
+	// Port is the port the app listens on.
+	//
+	// +private
+	Port int
+
+	// Reloader rebuilds and restarts the app: "air", "gow", or "none".
+	//
+	// +private
+	Reloader string
+
+	// Args are the app's command-line arguments.
+	//
+	// +private
+	Args []string
+
+	// Ctr is the container the app runs in.
+	//
+	// +private
+	Ctr *dagger.Container
+
+	// Dependencies are the services the app runs against.
+	//
+	// +private
+	Dependencies []Dependency
+
+	// EnvVars are the app's environment variables, including the
+	// connection settings of the dependencies, in "KEY=VALUE" format.
+	//
+	// +private
+	EnvVars []string
+}
+
+// Dependency is a service container the app runs against.
+type Dependency struct {
+	// Hostname the service is reachable at from the app
+	Name string
+
+	// Service container
+	Ctr *dagger.Container
+
+	// Ports exposed on the host
+	Ports []int
+}
+
+// New creates a new Devenv module instance.
+func New(
+	// The Go source directory of the app.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Main package to run, relative to the source directory
+	// +optional
+	// +default="."
+	pkg string,
+
+	// Port the app listens on, also set as PORT
+	// +optional
+	// +default=8080
+	port int,
+
+	// Tool that rebuilds and restarts the app on changes: "air", "gow", or
+	// "none" for a plain "go run". Air uses the source's .air.toml when
+	// present.
+	// +optional
+	// +default="air"
+	reloader string,
+
+	// Command-line arguments for the app
+	// +optional
+	args []string,
+
+	// Optional container to run the app in. Defaults to a Go container
+	// with the module and build caches shared with the other Go modules in
+	// this daggerverse.
+	// +optional
+	baseCtr *dagger.Container,
+
+	// Environment variables for the app in "KEY=VALUE" format
+	// +optional
+	envVars []string,
+) (*Devenv, error) {
+	if _, ok := reloaders[reloader]; !ok {
+		return nil, fmt.Errorf("invalid reloader %q: must be \"air\", \"gow\", or \"none\"", reloader)
+	}
+
+	ctr := baseCtr
+	if ctr == nil {
+		ctr = dag.Container().
+			From(goImage).
+			WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+			WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build"))
+	}
+
+	return &Devenv{
+		Source:   source,
+		Pkg:      pkg,
+		Port:     port,
+		Reloader: reloader,
+		Args:     args,
+		Ctr:      ctr,
+		EnvVars:  envVars,
+	}, nil
+}
+
+// WithPostgres adds a PostgreSQL service reachable at "postgres:5432" and
+// sets DATABASE_URL and the PGHOST, PGPORT, PGUSER, PGPASSWORD, and
+// PGDATABASE variables.
+func (m *Devenv) WithPostgres(
+	// PostgreSQL image
+	// +optional
+	// +default="postgres:17-alpine"
+	image string,
+
+	// Database name
+	// +optional
+	// +default="app"
+	database string,
+
+	// Database user
+	// +optional
+	// +default="app"
+	user string,
+
+	// Database password
+	// +optional
+	// +default="app"
+	password string,
+) *Devenv {
+	ctr := dag.Container().
+		From(image).
+		WithEnvVariable("POSTGRES_DB", database).
+		WithEnvVariable("POSTGRES_USER", user).
+		WithEnvVariable("POSTGRES_PASSWORD", password).
+		WithExposedPort(5432)
+
+	return m.withDependency("postgres", ctr, []int{5432},
+		fmt.Sprintf("DATABASE_URL=postgres://%s:%s@postgres:5432/%s?sslmode=disable", user, password, database),
+		"PGHOST=postgres",
+		"PGPORT=5432",
+		"PGUSER="+user,
+		"PGPASSWORD="+password,
+		"PGDATABASE="+database,
+	)
+}
+
+// WithRedis adds a Redis service reachable at "redis:6379" and sets
+// REDIS_URL.
+func (m *Devenv) WithRedis(
+	// Redis image
+	// +optional
+	// +default="redis:7-alpine"
+	image string,
+) *Devenv {
+	ctr := dag.Container().
+		From(image).
+		WithExposedPort(6379)
+
+	return m.withDependency("redis", ctr, []int{6379},
+		"REDIS_URL=redis://redis:6379/0",
+	)
+}
+
+// WithService adds any service container, reachable at its name and run
+// with its entrypoint. The container must expose ports.
+func (m *Devenv) WithService(
+	// Hostname of the service
+	name string,
+
+	// Service container
+	ctr *dagger.Container,
+
+	// Ports of the service to expose on the host
+	// +optional
+	ports []int,
+
+	// Environment variables to set for the app, in "KEY=VALUE" format
+	// +optional
+	env []string,
+) *Devenv {
+	return m.withDependency(name, ctr, ports, env...)
+}
+
+// App returns the app as a service, running under the reloader with the
+// dependencies bound.
+//
+// The source is snapshotted when the call starts: the reloader picks up
+// changes made inside the container, while changes on the host need the
+// stack to be restarted. Restarts reuse the module and build caches.
+func (m *Devenv) App() (*dagger.Service, error) {
+	ctr, err := m.Shell()
+	if err != nil {
+		return nil, err
+	}
+
+	ctr = ctr.
+		WithEnvVariable("PORT", fmt.Sprintf("%d", m.Port)).
+		WithExposedPort(m.Port)
+
+	var args []string
+	switch m.Reloader {
+	case "air":
+		// Air runs the binary through a shell; the wrapper passes the app's
+		// arguments through intact.
+		wrapper := "#!/bin/sh\nexec " + appBin
+		for _, arg := range m.Args {
+			wrapper += " " + shellQuote(arg)
+		}
+		ctr = ctr.
+			WithExec([]string{"go", "install", reloaders["air"]}).
+			WithNewFile("/usr/local/bin/devenv-app", wrapper+"\n", dagger.ContainerWithNewFileOpts{Permissions: 0o755})
+		args = []string{"sh", "-c", `if [ -f .air.toml ]; then exec air -c .air.toml; fi; exec air --build.cmd "go build -o $0 $1" --build.bin /usr/local/bin/devenv-app`, appBin, m.Pkg}
+	case "gow":
+		ctr = ctr.WithExec([]string{"go", "install", reloaders["gow"]})
+		args = append([]string{"gow", "run", m.Pkg}, m.Args...)
+	default:
+		args = append([]string{"go", "run", m.Pkg}, m.Args...)
+	}
+
+	return ctr.AsService(dagger.ContainerAsServiceOpts{Args: args}), nil
+}
+
+// Stack returns a service exposing the app at its port and every
+// dependency at its ports, for "up":
+//
+//	dagger call stack up
+//
+// Each port is forwarded to its service by a socat proxy, so the whole
+// stack comes up and goes down together.
+func (m *Devenv) Stack() (*dagger.Service, error) {
+	app, err := m.App()
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := dag.Container().
+		From(alpineImage).
+		WithExec([]string{"apk", "add", "--no-cache", "socat"}).
+		WithServiceBinding("app", app).
+		WithExposedPort(m.Port)
+	targets := []string{fmt.Sprintf("app:%d", m.Port)}
+	exposed := []int{m.Port}
+
+	for _, d := range m.Dependencies {
+		proxy = proxy.WithServiceBinding(d.Name, d.service())
+		for _, port := range d.Ports {
+			if slices.Contains(exposed, port) {
+				return nil, fmt.Errorf("port %d of %s is already exposed by another service", port, d.Name)
+			}
+			exposed = append(exposed, port)
+			proxy = proxy.WithExposedPort(port)
+			targets = append(targets, fmt.Sprintf("%s:%d", d.Name, port))
+		}
+	}
+
+	return proxy.AsService(dagger.ContainerAsServiceOpts{
+		Args: append([]string{"sh", "-c",
+			`for t in "$@"; do socat "TCP-LISTEN:${t##*:},fork,reuseaddr" "TCP:$t" & done; wait`,
+			"proxy"}, targets...),
+	}), nil
+}
+
+// Shell returns the app's container with the source mounted, the
+// dependencies bound, and their connection settings set, e.g. to run
+// migrations or a debugger from "terminal".
+func (m *Devenv) Shell() (*dagger.Container, error) {
+	ctr := m.Ctr.
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+
+	for _, d := range m.Dependencies {
+		ctr = ctr.WithServiceBinding(d.Name, d.service())
+	}
+
+	for _, env := range m.EnvVars {
+		k, v, ok := strings.Cut(env, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(k, v)
+	}
+
+	return ctr, nil
+}
+
+func (m *Devenv) withDependency(name string, ctr *dagger.Container, ports []int, env ...string) *Devenv {
+	m.Dependencies = append(m.Dependencies, Dependency{
+		Name:  name,
+		Ctr:   ctr,
+		Ports: ports,
+	})
+	m.EnvVars = append(m.EnvVars, env...)
+	return m
+}
+
+// service returns the dependency as a service. The app and the proxy bind
+// the same service, so both reach one instance.
+func (d Dependency) service() *dagger.Service {
+	return d.Ctr.AsService(dagger.ContainerAsServiceOpts{UseEntrypoint: true})
+}
+
+// shellQuote quotes s for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
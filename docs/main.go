@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+
+	"dagger/docs/internal/dagger"
+)
+
+//go:embed pages.sh
+var pagesScript string
+
+const (
+	hugo       = "hugo"
+	mkdocs     = "mkdocs"
+	docusaurus = "docusaurus"
+)
+
+// generatorConfigs maps the config files that identify each supported site
+// generator, checked in order.
+var generatorConfigs = []struct {
+	generator string
+	files     []string
+}{
+	{hugo, []string{"hugo.toml", "hugo.yaml", "hugo.json", "config.toml"}},
+	{mkdocs, []string{"mkdocs.yml", "mkdocs.yaml"}},
+	{docusaurus, []string{"docusaurus.config.js", "docusaurus.config.ts", "docusaurus.config.mjs"}},
+}
+
+// extraContentTypes covers web asset extensions that the Go mime table may
+// not know about in a minimal container.
+var extraContentTypes = map[string]string{
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ico":         "image/x-icon",
+	".map":         "application/json",
+	".webmanifest": "application/manifest+json",
+	".txt":         "text/plain; charset=utf-8",
+	".md":          "text/markdown; charset=utf-8",
+}
+
+// revalidatedExtensions are served with the short page cache header because
+// their URLs do not change when their contents do.
+var revalidatedExtensions = map[string]bool{
+	".html": true,
+	".xml":  true,
+	".json": true,
+	".txt":  true,
+}
+
+// Docs builds static documentation sites with Hugo, MkDocs, or Docusaurus
+// and publishes them to GitHub Pages or an S3-compatible bucket.
+type Docs struct {
+	// Documentation source directory
+	//
+	// +private
+	Source *dagger.Directory
+}
+
+// New creates a new Docs module instance.
+func New(
+	// Documentation source directory containing the site generator config
+	// +defaultPath="/"
+	source *dagger.Directory,
+) *Docs {
+	return &Docs{Source: source}
+}
+
+// Build builds the site and returns the generated output directory.
+//
+// The generator is detected from the config file in the source directory
+// when not given. When baseURL is set, Hugo receives it via --baseURL; MkDocs
+// and Docusaurus configs can read it from the DOCS_BASE_URL environment
+// variable (e.g. `site_url: !ENV DOCS_BASE_URL`).
+func (m *Docs) Build(
+	ctx context.Context,
+
+	// Site generator: "hugo", "mkdocs", or "docusaurus". Detected when empty.
+	// +optional
+	generator string,
+
+	// Absolute URL the site is served from, including any version subpath
+	// (e.g. "https://docs.example.com/v1.2/")
+	// +optional
+	baseURL string,
+) (*dagger.Directory, error) {
+	if generator == "" {
+		detected, err := m.detect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		generator = detected
+	}
+
+	switch generator {
+	case hugo:
+		args := []string{"hugo", "--minify", "--destination", "/out"}
+		if baseURL != "" {
+			args = append(args, "--baseURL", baseURL)
+		}
+		return dag.Container().
+			From("ghcr.io/gohugoio/hugo:latest").
+			WithUser("root").
+			WithDirectory("/src", m.Source).
+			WithWorkdir("/src").
+			WithEnvVariable("DOCS_BASE_URL", baseURL).
+			WithExec(args).
+			Directory("/out"), nil
+
+	case mkdocs:
+		ctr := dag.Container().
+			From("squidfunk/mkdocs-material:latest").
+			WithMountedCache("/root/.cache/pip", dag.CacheVolume("pip")).
+			WithDirectory("/src", m.Source).
+			WithWorkdir("/src").
+			WithEnvVariable("DOCS_BASE_URL", baseURL)
+
+		hasRequirements, err := m.Source.Exists(ctx, "requirements.txt")
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect source directory: %w", err)
+		}
+		if hasRequirements {
+			ctr = ctr.WithExec([]string{"pip", "install", "-r", "requirements.txt"})
+		}
+
+		return ctr.
+			WithExec([]string{"mkdocs", "build", "--strict", "--site-dir", "/out"}).
+			Directory("/out"), nil
+
+	case docusaurus:
+		install, build, err := m.nodeCommands(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return dag.Container().
+			From("node:22-bookworm").
+			WithExec([]string{"corepack", "enable"}).
+			WithMountedCache("/root/.npm", dag.CacheVolume("npm")).
+			WithMountedCache("/root/.cache/yarn", dag.CacheVolume("yarn")).
+			WithMountedCache("/root/.local/share/pnpm/store", dag.CacheVolume("pnpm")).
+			WithDirectory("/src", m.Source, dagger.ContainerWithDirectoryOpts{
+				Exclude: []string{"node_modules", "build", ".docusaurus"},
+			}).
+			WithWorkdir("/src").
+			WithEnvVariable("DOCS_BASE_URL", baseURL).
+			WithExec(install).
+			WithExec(build).
+			Directory("/out"), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported generator %q: must be one of %q, %q, %q", generator, hugo, mkdocs, docusaurus)
+	}
+}
+
+// PublishPages commits a built site to a GitHub Pages branch.
+//
+// When version is set, the site is published under that subpath (and also
+// under "latest" when latest is set), leaving other versions on the branch
+// untouched. Without a version the whole branch is replaced.
+func (m *Docs) PublishPages(
+	ctx context.Context,
+
+	// Built site, e.g. the output of build
+	site *dagger.Directory,
+
+	// GitHub repository in owner/repo format
+	repo string,
+
+	// GitHub token with contents:write permission
+	token *dagger.Secret,
+
+	// Pages branch
+	// +default="gh-pages"
+	branch string,
+
+	// Version subpath to publish under (e.g. "v1.2")
+	// +optional
+	version string,
+
+	// Also publish the site under the "latest" subpath
+	// +optional
+	latest bool,
+
+	// Custom domain written to the CNAME file at the branch root
+	// +optional
+	cname string,
+) (string, error) {
+	subpaths, err := versionPaths(".", version, latest)
+	if err != nil {
+		return "", err
+	}
+
+	message := "docs: publish site"
+	if version != "" {
+		message = "docs: publish " + version
+	}
+
+	_, err = dag.Container().
+		From("alpine/git:latest").
+		WithEnvVariable("REPO", repo).
+		WithSecretVariable("GITHUB_TOKEN", token).
+		WithEnvVariable("BRANCH", branch).
+		WithEnvVariable("SUBPATHS", strings.Join(subpaths, " ")).
+		WithEnvVariable("CNAME", cname).
+		WithEnvVariable("MESSAGE", message).
+		WithEnvVariable("GIT_AUTHOR_NAME", "github-actions[bot]").
+		WithEnvVariable("GIT_AUTHOR_EMAIL", "41898282+github-actions[bot]@users.noreply.github.com").
+		WithEnvVariable("GIT_COMMITTER_NAME", "github-actions[bot]").
+		WithEnvVariable("GIT_COMMITTER_EMAIL", "41898282+github-actions[bot]@users.noreply.github.com").
+		WithDirectory("/site", site).
+		WithNewFile("/usr/local/bin/pages.sh", pagesScript, dagger.ContainerWithNewFileOpts{Permissions: 0o755}).
+		WithExec([]string{"/usr/local/bin/pages.sh"}).
+		Sync(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to publish to GitHub Pages: %w", err)
+	}
+
+	return fmt.Sprintf("✅ Published to %s@%s:%s", repo, branch, strings.Join(subpaths, ", ")), nil
+}
+
+// PublishBucket uploads a built site to an S3-compatible bucket via the
+// bucketupload module. Every file is uploaded with a Content-Type derived
+// from its extension and a Cache-Control header: pages and other files
+// whose URLs stay the same across deploys (html, xml, json, txt) use
+// pageCacheControl, everything else uses assetCacheControl.
+//
+// When version is set, the site is uploaded under "<prefix>/<version>" (and
+// also "<prefix>/latest" when latest is set).
+func (m *Docs) PublishBucket(
+	ctx context.Context,
+
+	// Built site, e.g. the output of build
+	site *dagger.Directory,
+
+	// Bucket endpoint URL
+	endpoint *dagger.Secret,
+
+	// Bucket name
+	bucket *dagger.Secret,
+
+	// Bucket access key ID
+	accessKeyID *dagger.Secret,
+
+	// Bucket secret access key
+	secretAccessKey *dagger.Secret,
+
+	// Bucket key prefix. Use "" to upload at the bucket root.
+	// +optional
+	prefix string,
+
+	// Version subpath to publish under (e.g. "v1.2")
+	// +optional
+	version string,
+
+	// Also publish the site under the "latest" subpath
+	// +optional
+	latest bool,
+
+	// Cache-Control for pages and other files served at stable URLs
+	// +default="public, max-age=300, must-revalidate"
+	pageCacheControl string,
+
+	// Cache-Control for all other assets
+	// +default="public, max-age=86400"
+	assetCacheControl string,
+) (string, error) {
+	prefixes, err := versionPaths(prefix, version, latest)
+	if err != nil {
+		return "", err
+	}
+
+	uploader := dag.Bucketuploader(endpoint, bucket, accessKeyID, secretAccessKey)
+
+	entries, err := site.Glob(ctx, "**/*")
+	if err != nil {
+		return "", fmt.Errorf("failed to list site files: %w", err)
+	}
+
+	var metadata []*dagger.BucketuploaderFilePathMetadata
+	// Glob returns directory entries with a trailing slash — skip them.
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+
+		ext := strings.ToLower(path.Ext(entry))
+		cacheControl := assetCacheControl
+		if revalidatedExtensions[ext] {
+			cacheControl = pageCacheControl
+		}
+
+		meta := uploader.NewFilePathMetadata(entry).WithCacheControl(cacheControl)
+		if contentType := contentTypeFor(ext); contentType != "" {
+			meta = meta.WithContentType(contentType)
+		}
+		metadata = append(metadata, meta)
+	}
+
+	for _, p := range prefixes {
+		err := uploader.UploadTree(ctx, site, dagger.BucketuploaderUploadTreeOpts{
+			Prefix:   p,
+			Metadata: metadata,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to publish site under %q: %w", p, err)
+		}
+	}
+
+	return fmt.Sprintf("✅ Published %d files under %s", len(metadata), strings.Join(prefixes, ", ")), nil
+}
+
+// detect returns the site generator whose config file is present in the
+// source directory.
+func (m *Docs) detect(ctx context.Context) (string, error) {
+	for _, g := range generatorConfigs {
+		for _, file := range g.files {
+			ok, err := m.Source.Exists(ctx, file)
+			if err != nil {
+				return "", fmt.Errorf("failed to inspect source directory: %w", err)
+			}
+			if ok {
+				return g.generator, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("could not detect site generator: no Hugo, MkDocs, or Docusaurus config found")
+}
+
+// nodeCommands returns the install and build commands for the package
+// manager whose lockfile is present in the source directory.
+func (m *Docs) nodeCommands(ctx context.Context) ([]string, []string, error) {
+	lockfiles := []struct {
+		file    string
+		install []string
+		build   []string
+	}{
+		{"pnpm-lock.yaml", []string{"pnpm", "install", "--frozen-lockfile"}, []string{"pnpm", "run", "build", "--out-dir", "/out"}},
+		{"yarn.lock", []string{"yarn", "install", "--frozen-lockfile"}, []string{"yarn", "run", "build", "--out-dir", "/out"}},
+		{"package-lock.json", []string{"npm", "ci"}, []string{"npm", "run", "build", "--", "--out-dir", "/out"}},
+	}
+	for _, l := range lockfiles {
+		ok, err := m.Source.Exists(ctx, l.file)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to inspect source directory: %w", err)
+		}
+		if ok {
+			return l.install, l.build, nil
+		}
+	}
+	return []string{"npm", "install"}, []string{"npm", "run", "build", "--", "--out-dir", "/out"}, nil
+}
+
+// versionPaths returns the paths a site is published under: base itself, or
+// base/version and optionally base/latest.
+func versionPaths(base, version string, latest bool) ([]string, error) {
+	if version == "" {
+		if latest {
+			return nil, fmt.Errorf("latest requires a version")
+		}
+		return []string{base}, nil
+	}
+	if strings.Contains(version, "/") || version == "." || version == ".." {
+		return nil, fmt.Errorf("invalid version %q: must be a single path segment", version)
+	}
+
+	paths := []string{path.Join(base, version)}
+	if latest {
+		paths = append(paths, path.Join(base, "latest"))
+	}
+	return paths, nil
+}
+
+// contentTypeFor returns the Content-Type for a file extension, or "" to
+// let the uploader guess.
+func contentTypeFor(ext string) string {
+	if t, ok := extraContentTypes[ext]; ok {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
@@ -0,0 +1,59 @@
+#!/bin/sh
+# Publishes a built site to a GitHub Pages branch.
+#
+# Environment:
+#   REPO           - GitHub repository in owner/repo format
+#   GITHUB_TOKEN   - token with contents:write permission
+#   BRANCH         - Pages branch (e.g. gh-pages)
+#   SUBPATHS       - space-separated subpaths to replace; "." is the site root
+#   CNAME          - optional custom domain written to the branch root
+#   MESSAGE        - commit message
+#
+# The site to publish is mounted at /site. Only the target subpaths are
+# replaced, so other versions already on the branch are kept.
+
+set -eu
+
+AUTH=$(printf 'x-access-token:%s' "$GITHUB_TOKEN" | base64 | tr -d '\n')
+git_auth() {
+	git -c "http.extraHeader=Authorization: Basic ${AUTH}" "$@"
+}
+
+URL="https://github.com/${REPO}.git"
+
+if git_auth ls-remote --exit-code --heads "$URL" "$BRANCH" >/dev/null 2>&1; then
+	git_auth clone --quiet --depth 1 --branch "$BRANCH" "$URL" /pages
+else
+	echo "Branch ${BRANCH} does not exist, creating it"
+	git init --quiet /pages
+	git -C /pages checkout --quiet --orphan "$BRANCH"
+	git -C /pages remote add origin "$URL"
+fi
+
+cd /pages
+
+for subpath in $SUBPATHS; do
+	if [ "$subpath" = "." ]; then
+		find . -mindepth 1 -maxdepth 1 ! -name .git -exec rm -rf {} +
+	else
+		rm -rf "$subpath"
+	fi
+	mkdir -p "$subpath"
+	cp -R /site/. "$subpath/"
+done
+
+# Serve files and directories starting with "_" as-is.
+touch .nojekyll
+
+if [ -n "${CNAME:-}" ]; then
+	printf '%s\n' "$CNAME" > CNAME
+fi
+
+git add -A
+if git diff --cached --quiet; then
+	echo "No changes to publish"
+	exit 0
+fi
+
+git commit --quiet -m "$MESSAGE"
+git_auth push --quiet origin "HEAD:${BRANCH}"
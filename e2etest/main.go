@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"dagger/e2etest/internal/dagger"
+)
+
+const (
+	goImage string = "golang:1.26-bookworm"
+
+	// logsDir is where services write their logs, on a cache volume shared
+	// with the test container.
+	logsDir string = "/var/log/e2etest"
+
+	// logLines is the number of trailing log lines collected per service.
+	logLines int = 200
+)
+
+// E2Etest runs integration tests against service dependencies.
+type E2Etest struct {
+	// Source is the source directory to test.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Ctr is the container the test command runs in.
+	//
+	// +private
+	Ctr *dagger.Container
+
+	// Dependencies are the services bound to the test container.
+	//
+	// +private
+	Dependencies []Dependency
+
+	// EnvVars is the list of connection environment variables injected into
+	// the test container, in "KEY=VALUE" format.
+	//
+	// +private
+	EnvVars []string
+}
+
+// Dependency is a service container the tests run against.
+type Dependency struct {
+	// Hostname the service is reachable at from the test container
+	Name string
+
+	// Service container
+	Ctr *dagger.Container
+
+	// Command that starts the service. Its output is captured as the service
+	// logs when set.
+	Command []string
+}
+
+// TestResult is the outcome of a test run.
+type TestResult struct {
+	// Whether the test command succeeded
+	Passed bool
+
+	// Exit code of the test command
+	ExitCode int
+
+	// Combined output of the test command
+	Output string
+
+	// Trailing log lines of every service, collected when the tests fail
+	ServiceLogs string
+}
+
+// New creates a new E2Etest module instance.
+func New(
+	// The source directory to test.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Optional container to run the test command in. Defaults to a Go
+	// container with the module and build caches shared with the other Go
+	// modules in this daggerverse.
+	// +optional
+	baseCtr *dagger.Container,
+
+	// Optional extra environment variables for the test container.
+	// Each entry must be in "KEY=VALUE" format.
+	// +optional
+	envVars []string,
+) *E2Etest {
+	ctr := baseCtr
+	if ctr == nil {
+		ctr = dag.Container().
+			From(goImage).
+			WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+			WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build"))
+	}
+
+	return &E2Etest{
+		Source:  source,
+		Ctr:     ctr,
+		EnvVars: envVars,
+	}
+}
+
+// WithPostgres adds a PostgreSQL service reachable at "postgres:5432" and
+// sets DATABASE_URL and the PGHOST, PGPORT, PGUSER, PGPASSWORD, and
+// PGDATABASE variables.
+func (m *E2Etest) WithPostgres(
+	// PostgreSQL image
+	// +optional
+	// +default="postgres:17-alpine"
+	image string,
+
+	// Database name
+	// +optional
+	// +default="app"
+	database string,
+
+	// Database user
+	// +optional
+	// +default="app"
+	user string,
+
+	// Database password
+	// +optional
+	// +default="app"
+	password string,
+) *E2Etest {
+	ctr := dag.Container().
+		From(image).
+		WithEnvVariable("POSTGRES_DB", database).
+		WithEnvVariable("POSTGRES_USER", user).
+		WithEnvVariable("POSTGRES_PASSWORD", password).
+		WithExposedPort(5432)
+
+	return m.withDependency("postgres", ctr, []string{"docker-entrypoint.sh", "postgres"},
+		fmt.Sprintf("DATABASE_URL=postgres://%s:%s@postgres:5432/%s?sslmode=disable", user, password, database),
+		"PGHOST=postgres",
+		"PGPORT=5432",
+		"PGUSER="+user,
+		"PGPASSWORD="+password,
+		"PGDATABASE="+database,
+	)
+}
+
+// WithRedis adds a Redis service reachable at "redis:6379" and sets
+// REDIS_URL.
+func (m *E2Etest) WithRedis(
+	// Redis image
+	// +optional
+	// +default="redis:7-alpine"
+	image string,
+) *E2Etest {
+	ctr := dag.Container().
+		From(image).
+		WithExposedPort(6379)
+
+	return m.withDependency("redis", ctr, []string{"redis-server"},
+		"REDIS_URL=redis://redis:6379/0",
+	)
+}
+
+// WithMinio adds a MinIO S3-compatible service reachable at "minio:9000"
+// and sets S3_ENDPOINT, AWS_ENDPOINT_URL, AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, and AWS_REGION.
+func (m *E2Etest) WithMinio(
+	// MinIO image
+	// +optional
+	// +default="minio/minio:latest"
+	image string,
+
+	// Root access key
+	// +optional
+	// +default="minioadmin"
+	accessKey string,
+
+	// Root secret key
+	// +optional
+	// +default="minioadmin"
+	secretKey string,
+) *E2Etest {
+	ctr := dag.Container().
+		From(image).
+		WithEnvVariable("MINIO_ROOT_USER", accessKey).
+		WithEnvVariable("MINIO_ROOT_PASSWORD", secretKey).
+		WithExposedPort(9000)
+
+	return m.withDependency("minio", ctr, []string{"minio", "server", "/data"},
+		"S3_ENDPOINT=http://minio:9000",
+		"AWS_ENDPOINT_URL=http://minio:9000",
+		"AWS_ACCESS_KEY_ID="+accessKey,
+		"AWS_SECRET_ACCESS_KEY="+secretKey,
+		"AWS_REGION=us-east-1",
+	)
+}
+
+// WithNats adds a NATS service with JetStream enabled reachable at
+// "nats:4222" and sets NATS_URL.
+func (m *E2Etest) WithNats(
+	// NATS image. Must include a shell for log capture, like the alpine
+	// variants.
+	// +optional
+	// +default="nats:2.10-alpine"
+	image string,
+) *E2Etest {
+	ctr := dag.Container().
+		From(image).
+		WithExposedPort(4222)
+
+	return m.withDependency("nats", ctr, []string{"nats-server", "--jetstream"},
+		"NATS_URL=nats://nats:4222",
+	)
+}
+
+// WithService adds any service container, reachable at its name. The
+// container must expose the ports the tests connect to.
+func (m *E2Etest) WithService(
+	// Hostname of the service
+	name string,
+
+	// Service container
+	ctr *dagger.Container,
+
+	// Command that starts the service. Its output is captured as the service
+	// logs, which requires a shell in the image. When empty, the image's
+	// entrypoint runs and no logs are collected.
+	// +optional
+	command []string,
+
+	// Environment variables to inject into the test container, in
+	// "KEY=VALUE" format
+	// +optional
+	env []string,
+) *E2Etest {
+	return m.withDependency(name, ctr, command, env...)
+}
+
+// Run starts the services, runs the test command against them, and returns
+// the result without failing the pipeline on test failures. When the tests
+// fail, the trailing logs of every service are collected.
+func (m *E2Etest) Run(
+	ctx context.Context,
+
+	// Test command (defaults to "go test -count=1 ./...")
+	// +optional
+	command []string,
+) (*TestResult, error) {
+	if len(command) == 0 {
+		command = []string{"go", "test", "-count=1", "./..."}
+	}
+
+	// Key the logs volume by the source so concurrent runs of different
+	// revisions do not mix their logs.
+	digest, err := m.Source.Digest(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to digest source: %w", err)
+	}
+	logs := dag.CacheVolume("e2etest-logs-" + digest)
+
+	ctr := m.Ctr.
+		WithMountedCache(logsDir, logs).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+
+	for _, d := range m.Dependencies {
+		svc := d.Ctr.WithMountedCache(logsDir, logs)
+		var service *dagger.Service
+		if len(d.Command) > 0 {
+			service = svc.AsService(dagger.ContainerAsServiceOpts{
+				Args: append([]string{"sh", "-c", `exec "$@" > "$0" 2>&1`, logsDir + "/" + d.Name + ".log"}, d.Command...),
+			})
+		} else {
+			service = svc.AsService(dagger.ContainerAsServiceOpts{UseEntrypoint: true})
+		}
+		ctr = ctr.WithServiceBinding(d.Name, service)
+	}
+
+	for _, env := range m.EnvVars {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(parts[0], parts[1])
+	}
+
+	ctr = ctr.WithExec(
+		append([]string{"sh", "-c", `exec "$@" 2>&1`, "test"}, command...),
+		dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny},
+	)
+
+	exitCode, err := ctr.ExitCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run tests: %w", err)
+	}
+
+	output, err := ctr.Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test output: %w", err)
+	}
+
+	result := &TestResult{
+		Passed:   exitCode == 0,
+		ExitCode: exitCode,
+		Output:   output,
+	}
+	if result.Passed {
+		return result, nil
+	}
+
+	// Read the logs from a plain container: reusing the test container would
+	// start the services again just to read what they wrote.
+	serviceLogs, err := dag.Container().
+		From("alpine:latest").
+		WithMountedCache(logsDir, logs).
+		// The volume outlives this run; always read its current contents.
+		WithEnvVariable("E2ETEST_SESSION", fmt.Sprintf("%d", time.Now().UnixNano())).
+		WithExec([]string{"sh", "-c", fmt.Sprintf(
+			`for f in %s/*.log; do [ -e "$f" ] || continue; echo "==> $(basename "$f" .log) <=="; tail -n %d "$f"; echo; done`,
+			logsDir, logLines,
+		)}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect service logs: %w", err)
+	}
+	result.ServiceLogs = serviceLogs
+
+	return result, nil
+}
+
+// Check runs the test command against the services and fails the pipeline
+// when it fails, including the service logs in the error.
+func (m *E2Etest) Check(
+	ctx context.Context,
+
+	// Test command (defaults to "go test -count=1 ./...")
+	// +optional
+	command []string,
+) (string, error) {
+	res, err := m.Run(ctx, command)
+	if err != nil {
+		return "", err
+	}
+	if !res.Passed {
+		return "", fmt.Errorf("tests failed with exit code %d\n\n%s\n\nService logs:\n\n%s", res.ExitCode, res.Output, res.ServiceLogs)
+	}
+	return res.Output, nil
+}
+
+// withDependency records a service and its connection environment
+// variables.
+func (m *E2Etest) withDependency(name string, ctr *dagger.Container, command []string, env ...string) *E2Etest {
+	m.Dependencies = append(m.Dependencies, Dependency{
+		Name:    name,
+		Ctr:     ctr,
+		Command: command,
+	})
+	m.EnvVars = append(m.EnvVars, env...)
+	return m
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TestStats is the outcome of one test across every run.
+type TestStats struct {
+	// Test name, "<package>.<test>" for go test and "<classname>.<name>"
+	// for JUnit
+	Name string
+
+	// Runs the test passed or failed in
+	Runs int
+
+	// Runs the test failed in
+	Failures int
+
+	// Failures divided by runs
+	FlakeRate float64
+}
+
+// flaky reports whether the test both passed and failed.
+func (s TestStats) flaky() bool {
+	return s.Failures > 0 && s.Failures < s.Runs
+}
+
+// summaryTest is a test's entry in the summary.json of the testreport
+// module, which parses and merges the results of every run.
+type summaryTest struct {
+	Name   string `json:"name"`
+	Passed int    `json:"passed"`
+	Failed int    `json:"failed"`
+}
+
+// flakeStats turns a testreport summary.json into per-test statistics,
+// sorted by flake rate and then name. Tests that never passed or failed,
+// because they were skipped in every run, are left out.
+func flakeStats(summaryJSON string) ([]TestStats, error) {
+	var summary struct {
+		Results []summaryTest `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(summaryJSON), &summary); err != nil {
+		return nil, fmt.Errorf("failed to parse test summary: %w", err)
+	}
+
+	var stats []TestStats
+	for _, t := range summary.Results {
+		runs := t.Passed + t.Failed
+		if runs == 0 {
+			continue
+		}
+		stats = append(stats, TestStats{
+			Name:      t.Name,
+			Runs:      runs,
+			Failures:  t.Failed,
+			FlakeRate: float64(t.Failed) / float64(runs),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].FlakeRate != stats[j].FlakeRate {
+			return stats[i].FlakeRate > stats[j].FlakeRate
+		}
+		return stats[i].Name < stats[j].Name
+	})
+	return stats, nil
+}
+
+// topLevelTest matches the top-level test of a "<package>.<test>" name.
+var topLevelTest = regexp.MustCompile(`\.((?:Test|Example|Fuzz)[^/]*)`)
+
+// quarantinePattern returns a "go test -skip" pattern matching the
+// top-level Go tests of the given "<package>.<test>" names.
+func quarantinePattern(names []string) string {
+	seen := map[string]bool{}
+	var tests []string
+	for _, name := range names {
+		m := topLevelTest.FindStringSubmatch(name)
+		if m == nil || seen[m[1]] {
+			continue
+		}
+		seen[m[1]] = true
+		tests = append(tests, regexp.QuoteMeta(m[1]))
+	}
+	sort.Strings(tests)
+	return "^(" + strings.Join(tests, "|") + ")$"
+}
+
+// markdown renders the flake report.
+func markdown(runs, failedRuns int, stats []TestStats, goTest bool) string {
+	var b strings.Builder
+	b.WriteString("## Flake report\n\n")
+	fmt.Fprintf(&b, "%d of %d runs failed.\n", failedRuns, runs)
+
+	var flaky, broken []TestStats
+	for _, s := range stats {
+		switch {
+		case s.flaky():
+			flaky = append(flaky, s)
+		case s.Failures > 0:
+			broken = append(broken, s)
+		}
+	}
+
+	if len(stats) == 0 {
+		b.WriteString("\nNo test results were found in the output.\n")
+		return b.String()
+	}
+	if len(flaky)+len(broken) == 0 {
+		fmt.Fprintf(&b, "\n✅ All %d tests passed in every run.\n", len(stats))
+		return b.String()
+	}
+
+	if len(flaky) > 0 {
+		b.WriteString("\n### Flaky tests\n\n")
+		b.WriteString("| Test | Failures | Flake rate |\n")
+		b.WriteString("|------|----------|------------|\n")
+		var names []string
+		for _, s := range flaky {
+			fmt.Fprintf(&b, "| `%s` | %d / %d | %.0f%% |\n", s.Name, s.Failures, s.Runs, s.FlakeRate*100)
+			names = append(names, s.Name)
+		}
+		if goTest {
+			fmt.Fprintf(&b, "\nTo quarantine them: `go test -skip '%s'`\n", quarantinePattern(names))
+		}
+	}
+
+	if len(broken) > 0 {
+		b.WriteString("\n### Failing in every run\n\n")
+		for _, s := range broken {
+			fmt.Fprintf(&b, "- `%s`\n", s.Name)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"dagger/flaketest/internal/dagger"
+)
+
+const (
+	goImage        string = "golang:1.26-bookworm"
+	alpineImage    string = "alpine:3.21"
+	toxiproxyImage string = "ghcr.io/shopify/toxiproxy:2.12.0"
+
+	// resultsDir is where each run's exit code, output, and JUnit report
+	// are collected.
+	resultsDir string = "/flaketest"
+)
+
+// runScript runs the test command FLAKETEST_RUNS times, keeping going
+// after failures, and collects every run's results in resultsDir.
+const runScript string = `set -u
+i=1
+while [ "$i" -le "$FLAKETEST_RUNS" ]; do
+	if [ -n "$FLAKETEST_JUNIT" ]; then rm -f "$FLAKETEST_JUNIT"; fi
+	"$@" > "/flaketest/run-$i.out" 2>&1
+	echo "$?" > "/flaketest/run-$i.exit"
+	if [ -n "$FLAKETEST_JUNIT" ] && [ -f "$FLAKETEST_JUNIT" ]; then
+		cp "$FLAKETEST_JUNIT" "/flaketest/run-$i.xml"
+	fi
+	i=$((i+1))
+done
+`
+
+// proxyScript starts toxiproxy with its proxies disabled, adds latency to
+// each, and only then enables them, so no connection bypasses the toxics.
+const proxyScript string = `set -eu
+toxiproxy-server -host 0.0.0.0 -config /toxiproxy.json &
+until toxiproxy-cli list > /dev/null 2>&1; do sleep 0.1; done
+for proxy in $PROXIES; do
+	toxiproxy-cli toxic add --type latency --attribute "latency=$LATENCY" --attribute "jitter=$JITTER" "$proxy"
+	toxiproxy-cli toggle "$proxy"
+done
+wait
+`
+
+// Flaketest reruns tests to measure how flaky they are.
+type Flaketest struct {
+	// Source is the source directory to test.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Ctr is the container the test command runs in.
+	//
+	// +private
+	Ctr *dagger.Container
+
+	// Services are the services bound to the test container.
+	//
+	// +private
+	Services []Service
+
+	// EnvVars is the list of environment variables set in the test
+	// container, in "KEY=VALUE" format.
+	//
+	// +private
+	EnvVars []string
+}
+
+// Service is a service container the tests run against, optionally behind
+// a toxiproxy that delays its traffic.
+type Service struct {
+	// Hostname the service is reachable at from the test container
+	Name string
+
+	// Service container
+	Ctr *dagger.Container
+
+	// Ports proxied through toxiproxy
+	Ports []int
+
+	// Latency added to the service's responses, in milliseconds
+	Latency int
+
+	// Random variation of the latency, in milliseconds
+	Jitter int
+}
+
+// FlakeReport is the outcome of rerunning a test command.
+type FlakeReport struct {
+	// Number of runs
+	Runs int
+
+	// Runs in which the test command failed
+	FailedRuns int
+
+	// Outcome of every test across the runs, flakiest first
+	Tests []TestStats
+
+	// Names of tests that both passed and failed
+	Flaky []string
+
+	// Report as markdown
+	Markdown string
+}
+
+// New creates a new Flaketest module instance.
+func New(
+	// The source directory to test.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Optional container to run the test command in. Defaults to a Go
+	// container with the module and build caches shared with the other Go
+	// modules in this daggerverse.
+	// +optional
+	baseCtr *dagger.Container,
+
+	// Optional extra environment variables for the test container.
+	// Each entry must be in "KEY=VALUE" format.
+	// +optional
+	envVars []string,
+) *Flaketest {
+	ctr := baseCtr
+	if ctr == nil {
+		ctr = dag.Container().
+			From(goImage).
+			WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+			WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build"))
+	}
+
+	return &Flaketest{
+		Source:  source,
+		Ctr:     ctr,
+		EnvVars: envVars,
+	}
+}
+
+// WithService adds a service container, reachable at its name. With a
+// latency or jitter, its ports are served through toxiproxy, which delays
+// the service's responses to shake out timing-dependent tests.
+func (m *Flaketest) WithService(
+	// Hostname of the service
+	name string,
+
+	// Service container, started with its entrypoint. Must expose the
+	// ports the tests connect to.
+	ctr *dagger.Container,
+
+	// Ports to proxy; required with a latency or jitter
+	// +optional
+	ports []int,
+
+	// Latency added to the service's responses, in milliseconds
+	// +optional
+	latency int,
+
+	// Random variation of the latency, in milliseconds
+	// +optional
+	jitter int,
+
+	// Environment variables to set in the test container, in "KEY=VALUE"
+	// format
+	// +optional
+	env []string,
+) *Flaketest {
+	m.Services = append(m.Services, Service{
+		Name:    name,
+		Ctr:     ctr,
+		Ports:   ports,
+		Latency: latency,
+		Jitter:  jitter,
+	})
+	m.EnvVars = append(m.EnvVars, env...)
+	return m
+}
+
+// Run runs the test command repeatedly and returns per-test flake rates
+// without failing the pipeline.
+//
+// Test results are read from "go test -json" output, or from the JUnit
+// report the command writes when junit is set. Every call reruns the
+// tests, even with unchanged inputs.
+func (m *Flaketest) Run(
+	ctx context.Context,
+
+	// Test command (defaults to "go test -json -count=1 ./...")
+	// +optional
+	command []string,
+
+	// Number of runs
+	// +optional
+	// +default=10
+	runs int,
+
+	// JUnit XML report the command writes, relative to the source
+	// directory, to read results from instead of "go test -json" output
+	// +optional
+	junit string,
+) (*FlakeReport, error) {
+	if runs < 1 {
+		return nil, fmt.Errorf("runs must be at least 1")
+	}
+	if len(command) == 0 {
+		command = []string{"go", "test", "-json", "-count=1", "./..."}
+	}
+
+	ctr := m.Ctr.
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+
+	for _, s := range m.Services {
+		svc, err := s.service()
+		if err != nil {
+			return nil, err
+		}
+		ctr = ctr.WithServiceBinding(s.Name, svc)
+	}
+
+	for _, env := range m.EnvVars {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(parts[0], parts[1])
+	}
+
+	results := ctr.
+		WithEnvVariable("FLAKETEST_RUNS", strconv.Itoa(runs)).
+		WithEnvVariable("FLAKETEST_JUNIT", junit).
+		// Measuring flakiness means running again; never reuse a cached
+		// result.
+		WithEnvVariable("FLAKETEST_SESSION", strconv.FormatInt(time.Now().UnixNano(), 10)).
+		WithExec([]string{"mkdir", "-p", resultsDir}).
+		WithExec(append([]string{"sh", "-c", runScript, "flaketest"}, command...)).
+		Directory(resultsDir)
+
+	// Every run is one more report of the same job to the testreport
+	// module, so its pass and fail counts are the flake counts.
+	report := &FlakeReport{Runs: runs}
+	merged := dag.Testreport()
+	reports := 0
+	for i := 1; i <= runs; i++ {
+		exit, err := results.File(fmt.Sprintf("run-%d.exit", i)).Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read exit code of run %d: %w", i, err)
+		}
+		if strings.TrimSpace(exit) != "0" {
+			report.FailedRuns++
+		}
+
+		if junit == "" {
+			merged = merged.WithGoTest(results.File(fmt.Sprintf("run-%d.out", i)), dagger.TestreportWithGoTestOpts{Job: "flaketest"})
+			reports++
+			continue
+		}
+
+		ok, err := results.Exists(ctx, fmt.Sprintf("run-%d.xml", i))
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect results: %w", err)
+		}
+		if !ok {
+			// The command failed before writing a report.
+			continue
+		}
+		merged = merged.WithJunit(results.File(fmt.Sprintf("run-%d.xml", i)), dagger.TestreportWithJunitOpts{Job: "flaketest"})
+		reports++
+	}
+
+	if reports > 0 {
+		summary, err := merged.Report().File("summary.json").Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge test results: %w", err)
+		}
+		if report.Tests, err = flakeStats(summary); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range report.Tests {
+		if s.flaky() {
+			report.Flaky = append(report.Flaky, s.Name)
+		}
+	}
+	report.Markdown = markdown(runs, report.FailedRuns, report.Tests, junit == "")
+
+	return report, nil
+}
+
+// Check runs the test command repeatedly and fails with the flake report
+// when any test fails more often than the allowed flake rate.
+func (m *Flaketest) Check(
+	ctx context.Context,
+
+	// Test command (defaults to "go test -json -count=1 ./...")
+	// +optional
+	command []string,
+
+	// Number of runs
+	// +optional
+	// +default=10
+	runs int,
+
+	// JUnit XML report the command writes, relative to the source
+	// directory, to read results from instead of "go test -json" output
+	// +optional
+	junit string,
+
+	// Highest flake rate allowed per test, between 0 and 1
+	// +optional
+	maxFlakeRate float64,
+) (string, error) {
+	report, err := m.Run(ctx, command, runs, junit)
+	if err != nil {
+		return "", err
+	}
+
+	var failing int
+	for _, s := range report.Tests {
+		if s.FlakeRate > maxFlakeRate {
+			failing++
+		}
+	}
+	if failing > 0 {
+		return "", fmt.Errorf("%d test(s) fail more often than %.0f%% of runs\n\n%s", failing, maxFlakeRate*100, report.Markdown)
+	}
+	if len(report.Tests) == 0 && report.FailedRuns > 0 {
+		return "", fmt.Errorf("%d of %d runs failed without test results; is the output in the expected format?\n\n%s", report.FailedRuns, runs, report.Markdown)
+	}
+
+	return report.Markdown, nil
+}
+
+// toxiproxyProxy is a proxy in a toxiproxy config file.
+type toxiproxyProxy struct {
+	Name     string `json:"name"`
+	Listen   string `json:"listen"`
+	Upstream string `json:"upstream"`
+	Enabled  bool   `json:"enabled"`
+}
+
+// service returns the service to bind at the service's name: the
+// container itself, or a toxiproxy in front of it.
+func (s Service) service() (*dagger.Service, error) {
+	upstream := s.Ctr.AsService(dagger.ContainerAsServiceOpts{UseEntrypoint: true})
+	if s.Latency == 0 && s.Jitter == 0 {
+		return upstream, nil
+	}
+	if len(s.Ports) == 0 {
+		return nil, fmt.Errorf("service %q needs ports to add latency to", s.Name)
+	}
+
+	proxy := dag.Container().
+		From(alpineImage).
+		WithFile("/usr/local/bin/toxiproxy-server", dag.Container().From(toxiproxyImage).File("/toxiproxy")).
+		WithFile("/usr/local/bin/toxiproxy-cli", dag.Container().From(toxiproxyImage).File("/toxiproxy-cli")).
+		WithServiceBinding("upstream", upstream)
+
+	var proxies []toxiproxyProxy
+	var names []string
+	for _, port := range s.Ports {
+		name := fmt.Sprintf("%s-%d", s.Name, port)
+		proxies = append(proxies, toxiproxyProxy{
+			Name:     name,
+			Listen:   fmt.Sprintf("0.0.0.0:%d", port),
+			Upstream: fmt.Sprintf("upstream:%d", port),
+		})
+		names = append(names, name)
+		proxy = proxy.WithExposedPort(port)
+	}
+	config, err := json.MarshalIndent(proxies, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to write toxiproxy config: %w", err)
+	}
+
+	return proxy.
+		WithNewFile("/toxiproxy.json", string(config)).
+		WithEnvVariable("PROXIES", strings.Join(names, " ")).
+		WithEnvVariable("LATENCY", strconv.Itoa(s.Latency)).
+		WithEnvVariable("JITTER", strconv.Itoa(s.Jitter)).
+		AsService(dagger.ContainerAsServiceOpts{Args: []string{"sh", "-c", proxyScript}}), nil
+}
@@ -0,0 +1,164 @@
+// Sticky GitHub pull request comments.
+//
+// Upsert keeps a single comment per marker on a pull request, creating it
+// on the first run and editing it in place afterwards, so repeated CI runs
+// update one comment instead of piling up new ones.
+
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"dagger/ghcomment/internal/dagger"
+)
+
+//go:embed sticky-comment.sh
+var stickyCommentScript string
+
+// maxBodyLength is GitHub's limit on comment bodies, in characters.
+const maxBodyLength = 65536
+
+// validMarker restricts markers to characters that are safe in the jq
+// filter and HTML comment they are embedded in.
+var validMarker = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Ghcomment manages sticky pull request comments.
+type Ghcomment struct {
+	// GitHub token
+	//
+	// +private
+	Token *dagger.Secret
+
+	// Github repo ("owner/repo")
+	//
+	// +private
+	Repo string
+}
+
+// New creates a new Ghcomment instance.
+func New(
+	// GitHub token with permissions to write pull request comments
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	repo string,
+) *Ghcomment {
+	return &Ghcomment{
+		Token: token,
+		Repo:  repo,
+	}
+}
+
+// Upsert creates or updates the comment identified by marker on a pull
+// request and returns its URL. The body is given inline or as a file; a
+// hidden marker line is prepended so later runs find the comment again.
+// Use a different marker per report (e.g. "coverage", "lint") to keep
+// several sticky comments on the same pull request.
+func (m *Ghcomment) Upsert(
+	ctx context.Context,
+
+	// The pull request number
+	number int,
+
+	// Markdown body
+	// +optional
+	body string,
+
+	// Markdown body file, instead of body
+	// +optional
+	bodyFile *dagger.File,
+
+	// Marker identifying the comment
+	// +optional
+	// +default="ghcomment"
+	marker string,
+) (string, error) {
+	if bodyFile != nil {
+		contents, err := bodyFile.Contents(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to read body file: %w", err)
+		}
+		body = contents
+	}
+	if body == "" {
+		return "", fmt.Errorf("either body or body-file is required")
+	}
+
+	tag, err := markerTag(marker)
+	if err != nil {
+		return "", err
+	}
+
+	comment := tag + "\n" + body
+	if len(comment) > maxBodyLength {
+		const notice = "\n\n_Comment truncated: it exceeded GitHub's length limit._"
+		// Cutting at a byte offset may split a character; drop the remains.
+		comment = strings.ToValidUTF8(comment[:maxBodyLength-len(notice)], "") + notice
+	}
+
+	out, err := m.run(ctx, "upsert", number, tag, comment)
+	if err != nil {
+		return "", fmt.Errorf("failed to upsert comment on PR #%d in %s: %w", number, m.Repo, err)
+	}
+
+	return out, nil
+}
+
+// Delete removes the comment identified by marker from a pull request, if
+// there is one, e.g. once the report it carried no longer applies.
+func (m *Ghcomment) Delete(
+	ctx context.Context,
+
+	// The pull request number
+	number int,
+
+	// Marker identifying the comment
+	// +optional
+	// +default="ghcomment"
+	marker string,
+) (string, error) {
+	tag, err := markerTag(marker)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := m.run(ctx, "delete", number, tag, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to delete comment on PR #%d in %s: %w", number, m.Repo, err)
+	}
+
+	return out, nil
+}
+
+// run runs the sticky comment script.
+func (m *Ghcomment) run(ctx context.Context, action string, number int, tag, comment string) (string, error) {
+	return dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "github-cli"}).
+		WithSecretVariable("GH_TOKEN", m.Token).
+		WithEnvVariable("GH_REPO", m.Repo).
+		WithEnvVariable("NUMBER", strconv.Itoa(number)).
+		WithEnvVariable("MARKER", tag).
+		WithEnvVariable("ACTION", action).
+		// The comment may have been edited or deleted since the last run;
+		// always look it up again.
+		WithEnvVariable("GHCOMMENT_SESSION", strconv.FormatInt(time.Now().UnixNano(), 10)).
+		WithNewFile("/comment.md", comment).
+		WithNewFile("/usr/local/bin/sticky-comment.sh", stickyCommentScript, dagger.ContainerWithNewFileOpts{Permissions: 0o755}).
+		WithExec([]string{"/usr/local/bin/sticky-comment.sh"}).
+		Stdout(ctx)
+}
+
+// markerTag returns the hidden HTML comment identifying a sticky comment.
+func markerTag(marker string) (string, error) {
+	if !validMarker.MatchString(marker) {
+		return "", fmt.Errorf("invalid marker %q: must contain only letters, digits, '.', '_', and '-'", marker)
+	}
+	return fmt.Sprintf("<!-- ghcomment:%s -->", marker), nil
+}
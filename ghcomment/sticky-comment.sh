@@ -0,0 +1,26 @@
+#!/usr/bin/env sh
+set -eu
+
+# --- Find the sticky comment by its marker ---
+# The marker is validated by the module, so it is safe to inline in the
+# jq filter.
+ID=$(gh api --paginate "repos/${GH_REPO}/issues/${NUMBER}/comments" \
+  --jq ".[] | select(.body | contains(\"${MARKER}\")) | .id" | head -n1)
+
+case "$ACTION" in
+upsert)
+  if [ -n "$ID" ]; then
+    gh api -X PATCH "repos/${GH_REPO}/issues/comments/${ID}" -F body=@/comment.md --jq .html_url
+  else
+    gh api -X POST "repos/${GH_REPO}/issues/${NUMBER}/comments" -F body=@/comment.md --jq .html_url
+  fi
+  ;;
+delete)
+  if [ -n "$ID" ]; then
+    gh api -X DELETE "repos/${GH_REPO}/issues/comments/${ID}"
+    echo "Deleted comment ${ID}"
+  else
+    echo "No comment to delete"
+  fi
+  ;;
+esac
@@ -20,6 +20,7 @@ type Ghcontrib struct {
 	Repo string
 }
 
+// New creates a new Ghcontrib module instance.
 func New(
 	// GitHub token.
 	token *dagger.Secret,
@@ -126,6 +126,11 @@ ${OTHER}
 "
 fi
 
+# --- Use caller-provided notes when given ---
+if [ -n "${NOTES_FILE:-}" ]; then
+  NOTES=$(cat "$NOTES_FILE")
+fi
+
 echo "---"
 echo "$NOTES"
 echo "---"
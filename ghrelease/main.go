@@ -53,6 +53,11 @@ type Ghrelease struct {
 	//
 	// +private
 	ReleaseDryRun bool
+
+	// Release notes that replace the generated ones in Create
+	//
+	// +private
+	Notes *dagger.File
 }
 
 // New creates a new Ghrelease instance.
@@ -80,6 +85,7 @@ func (m *Ghrelease) WithSource(
 
 // WithAssets sets the assets directory for release upload
 func (m *Ghrelease) WithAssets(
+	// Directory of assets to upload
 	assets *dagger.Directory,
 ) *Ghrelease {
 	m.Assets = assets
@@ -88,6 +94,7 @@ func (m *Ghrelease) WithAssets(
 
 // WithRepo sets the "org/repo" repo string for release target
 func (m *Ghrelease) WithRepo(
+	// GitHub repository (e.g. "owner/repo")
 	repo string,
 ) *Ghrelease {
 	m.Repo = repo
@@ -120,6 +127,17 @@ func (m *Ghrelease) WithDryRun() *Ghrelease {
 	return m
 }
 
+// WithNotes sets the release notes used by Create instead of the generated
+// ones (e.g., the markdown output of the changelog module). The version bump
+// is still determined from the git history.
+func (m *Ghrelease) WithNotes(
+	// Markdown release notes
+	notes *dagger.File,
+) *Ghrelease {
+	m.Notes = notes
+	return m
+}
+
 // Create inspects the git history in Source to determine the next semantic
 // version, generates categorized release notes, and creates a new GitHub
 // release. Commits since the last tag are classified as follows:
@@ -128,7 +146,8 @@ func (m *Ghrelease) WithDryRun() *Ghrelease {
 //   - "✨ feat:" → minor bump
 //   - "🔧 fix:", "🧹 chore:", and everything else → patch bump
 //
-// The highest-priority bump wins. WithSource must be called before Create.
+// The highest-priority bump wins. Chain WithNotes to replace the generated
+// notes. WithSource must be called before Create.
 // Chain WithDryRun before Create to skip the actual release creation.
 func (m *Ghrelease) Create(ctx context.Context) (string, error) {
 	if m.Source == nil {
@@ -140,14 +159,22 @@ func (m *Ghrelease) Create(ctx context.Context) (string, error) {
 		dryRun = "true"
 	}
 
-	out, err := dag.Container().
+	ctr := dag.Container().
 		From("alpine:latest").
 		WithExec([]string{"apk", "add", "--no-cache", "github-cli", "git"}).
 		WithSecretVariable("GH_TOKEN", m.Token).
 		WithEnvVariable("GH_REPO", m.Repo).
 		WithEnvVariable("DRY_RUN", dryRun).
 		WithDirectory("/src", m.Source).
-		WithWorkdir("/src").
+		WithWorkdir("/src")
+
+	if m.Notes != nil {
+		ctr = ctr.
+			WithFile("/release-notes.md", m.Notes).
+			WithEnvVariable("NOTES_FILE", "/release-notes.md")
+	}
+
+	out, err := ctr.
 		WithNewFile("/usr/local/bin/create-release.sh", createReleaseScript, dagger.ContainerWithNewFileOpts{Permissions: 0o755}).
 		WithExec([]string{"/usr/local/bin/create-release.sh"}).
 		Stdout(ctx)
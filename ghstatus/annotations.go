@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// maxAnnotations is the number of annotations GitHub accepts per check run
+// request; more are sent in follow-up updates.
+const maxAnnotations = 50
+
+// maxOutputLength is GitHub's limit on a check run's summary and text, in
+// characters.
+const maxOutputLength = 65535
+
+// diagnosticLine matches the "path:line[:column]: message" lines most
+// compilers and linters print.
+var diagnosticLine = regexp.MustCompile(`^([^\s:][^:]*):(\d+)(?::(\d+))?:\s*(.+)$`)
+
+// annotation is a check run annotation.
+type annotation struct {
+	Path            string `json:"path"`
+	StartLine       int    `json:"start_line"`
+	EndLine         int    `json:"end_line"`
+	StartColumn     int    `json:"start_column,omitempty"`
+	EndColumn       int    `json:"end_column,omitempty"`
+	AnnotationLevel string `json:"annotation_level"`
+	Message         string `json:"message"`
+}
+
+// parseAnnotations returns an annotation for every diagnostic line of a
+// tool's output. Paths are made relative to the repository root by
+// dropping a leading "./" or "/src/", where the modules in this
+// daggerverse mount sources.
+func parseAnnotations(output, level string) []annotation {
+	var annotations []annotation
+	for _, line := range strings.Split(output, "\n") {
+		m := diagnosticLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		a := annotation{
+			Path:            strings.TrimPrefix(strings.TrimPrefix(m[1], "/src/"), "./"),
+			AnnotationLevel: level,
+			Message:         m[4],
+		}
+		a.StartLine, _ = strconv.Atoi(m[2])
+		a.EndLine = a.StartLine
+		if m[3] != "" {
+			a.StartColumn, _ = strconv.Atoi(m[3])
+			a.EndColumn = a.StartColumn
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations
+}
+
+// checkRunOutput is the output of a check run.
+type checkRunOutput struct {
+	Title       string       `json:"title"`
+	Summary     string       `json:"summary"`
+	Text        string       `json:"text,omitempty"`
+	Annotations []annotation `json:"annotations,omitempty"`
+}
+
+// checkRunRequests returns the JSON bodies creating a check run and then
+// updating it with the annotations that did not fit the first request.
+func checkRunRequests(create map[string]any, output checkRunOutput) ([]string, error) {
+	annotations := output.Annotations
+	output.Summary = truncate(output.Summary)
+	output.Text = truncate(output.Text)
+
+	var requests []string
+	for first := true; first || len(annotations) > 0; first = false {
+		batch := annotations
+		if len(batch) > maxAnnotations {
+			batch = batch[:maxAnnotations]
+		}
+		annotations = annotations[len(batch):]
+
+		body := map[string]any{}
+		if first {
+			body = create
+			body["output"] = checkRunOutput{Title: output.Title, Summary: output.Summary, Text: output.Text, Annotations: batch}
+		} else {
+			// Updates append annotations; title and summary are required.
+			body["output"] = checkRunOutput{Title: output.Title, Summary: output.Summary, Annotations: batch}
+		}
+
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode check run: %w", err)
+		}
+		requests = append(requests, string(data))
+	}
+	return requests, nil
+}
+
+// truncate shortens text to GitHub's output limit.
+func truncate(s string) string {
+	if len(s) <= maxOutputLength {
+		return s
+	}
+	const notice = "\n\n_Output truncated: it exceeded GitHub's length limit._"
+	// Cutting at a byte offset may split a character; drop the remains.
+	return strings.ToValidUTF8(s[:maxOutputLength-len(notice)], "") + notice
+}
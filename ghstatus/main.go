@@ -0,0 +1,231 @@
+// GitHub commit statuses and check runs.
+//
+// Status and CheckRun report Dagger pipeline results to GitHub from any
+// runner — local, Jenkins, or self-hosted — so branch protection can
+// require them like GitHub Actions jobs.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"dagger/ghstatus/internal/dagger"
+)
+
+// maxDescriptionLength is GitHub's limit on commit status descriptions.
+const maxDescriptionLength = 140
+
+var (
+	validStates      = []string{"pending", "success", "failure", "error"}
+	validConclusions = []string{"success", "failure", "neutral", "cancelled", "skipped", "timed_out", "action_required"}
+	validLevels      = []string{"notice", "warning", "failure"}
+)
+
+// Ghstatus reports pipeline results to GitHub.
+type Ghstatus struct {
+	// GitHub token
+	//
+	// +private
+	Token *dagger.Secret
+
+	// Github repo ("owner/repo")
+	//
+	// +private
+	Repo string
+}
+
+// New creates a new Ghstatus instance.
+func New(
+	// GitHub token. Commit statuses need "repo:status" or "statuses: write";
+	// check runs need a GitHub App installation token with "checks: write".
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo").
+	repo string,
+) *Ghstatus {
+	return &Ghstatus{
+		Token: token,
+		Repo:  repo,
+	}
+}
+
+// Status sets a commit status, shown as a check on pull requests, and
+// returns the state set.
+func (m *Ghstatus) Status(
+	ctx context.Context,
+
+	// Commit SHA
+	sha string,
+
+	// Status name, shown as the check name (e.g. "dagger/lint")
+	name string,
+
+	// State: "pending", "success", "failure", or "error"
+	state string,
+
+	// Short description, truncated to 140 characters
+	// +optional
+	description string,
+
+	// Link to the full results (e.g. a Dagger Cloud trace or Jenkins build)
+	// +optional
+	targetURL string,
+) (string, error) {
+	if !slices.Contains(validStates, state) {
+		return "", fmt.Errorf("invalid state %q: must be one of %s", state, strings.Join(validStates, ", "))
+	}
+	if len([]rune(description)) > maxDescriptionLength {
+		description = string([]rune(description)[:maxDescriptionLength-1]) + "…"
+	}
+
+	args := []string{
+		"gh", "api", "-X", "POST", fmt.Sprintf("repos/%s/statuses/%s", m.Repo, sha),
+		"-f", "state=" + state,
+		"-f", "context=" + name,
+		"--jq", ".state",
+	}
+	if description != "" {
+		args = append(args, "-f", "description="+description)
+	}
+	if targetURL != "" {
+		args = append(args, "-f", "target_url="+targetURL)
+	}
+
+	out, err := m.ghContainer().WithExec(args).Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to set status %q on %s: %w", name, sha, err)
+	}
+
+	return fmt.Sprintf("✅ %s: %s", name, strings.TrimSpace(out)), nil
+}
+
+// CheckRun creates a check run with a markdown summary and returns its
+// URL. Every "path:line[:column]: message" line of output, as printed by
+// most compilers and linters, becomes an annotation on the pull request's
+// diff. Without a conclusion the check run stays in progress.
+func (m *Ghstatus) CheckRun(
+	ctx context.Context,
+
+	// Commit SHA
+	sha string,
+
+	// Check run name (e.g. "dagger/lint")
+	name string,
+
+	// Conclusion: "success", "failure", "neutral", "cancelled", "skipped",
+	// "timed_out", or "action_required". Leave empty for an in-progress
+	// check run.
+	// +optional
+	conclusion string,
+
+	// Title of the output
+	// +optional
+	title string,
+
+	// Markdown summary of the output
+	// +optional
+	summary string,
+
+	// Tool output to annotate from and to include as the detailed text
+	// +optional
+	output string,
+
+	// Level of the annotations: "notice", "warning", or "failure"
+	// +optional
+	// +default="failure"
+	annotationLevel string,
+
+	// Link to the full results (e.g. a Dagger Cloud trace or Jenkins build)
+	// +optional
+	detailsURL string,
+) (string, error) {
+	if conclusion != "" && !slices.Contains(validConclusions, conclusion) {
+		return "", fmt.Errorf("invalid conclusion %q: must be one of %s", conclusion, strings.Join(validConclusions, ", "))
+	}
+	if !slices.Contains(validLevels, annotationLevel) {
+		return "", fmt.Errorf("invalid annotation level %q: must be one of %s", annotationLevel, strings.Join(validLevels, ", "))
+	}
+
+	create := map[string]any{
+		"name":     name,
+		"head_sha": sha,
+		"status":   "in_progress",
+	}
+	if conclusion != "" {
+		create["status"] = "completed"
+		create["conclusion"] = conclusion
+	}
+	if detailsURL != "" {
+		create["details_url"] = detailsURL
+	}
+
+	if title == "" {
+		title = name
+	}
+	if summary == "" {
+		summary = name + ": in progress"
+		if conclusion != "" {
+			summary = name + ": " + conclusion
+		}
+	}
+	text := ""
+	if output != "" {
+		text = "```\n" + output + "\n```"
+	}
+
+	requests, err := checkRunRequests(create, checkRunOutput{
+		Title:       title,
+		Summary:     summary,
+		Text:        text,
+		Annotations: parseAnnotations(output, annotationLevel),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ctr := m.ghContainer()
+	created, err := ctr.
+		WithNewFile("/request.json", requests[0]).
+		WithExec([]string{
+			"gh", "api", "-X", "POST", fmt.Sprintf("repos/%s/check-runs", m.Repo),
+			"--input", "/request.json",
+			"--jq", `"\(.id) \(.html_url)"`,
+		}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to create check run %q on %s: %w", name, sha, err)
+	}
+	id, url, _ := strings.Cut(strings.TrimSpace(created), " ")
+
+	for i, request := range requests[1:] {
+		_, err := ctr.
+			WithNewFile("/request.json", request).
+			WithExec([]string{
+				"gh", "api", "-X", "PATCH", fmt.Sprintf("repos/%s/check-runs/%s", m.Repo, id),
+				"--input", "/request.json",
+			}).
+			Sync(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to add annotations batch %d to check run %q: %w", i+2, name, err)
+		}
+	}
+
+	return url, nil
+}
+
+// ghContainer returns a container with the GitHub CLI installed and
+// authenticated.
+func (m *Ghstatus) ghContainer() *dagger.Container {
+	return dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "github-cli"}).
+		WithSecretVariable("GH_TOKEN", m.Token).
+		// A status may have changed since the last identical call; always
+		// post again.
+		WithEnvVariable("GHSTATUS_SESSION", strconv.FormatInt(time.Now().UnixNano(), 10))
+}
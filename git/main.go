@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/git/internal/dagger"
+)
+
+const (
+	alpineImage string = "alpine:3.21"
+)
+
+// pushScript pushes refs to a remote, authenticating HTTPS remotes with
+// GIT_TOKEN when set. SSH remotes use GIT_SSH_COMMAND.
+const pushScript = `
+	set -e
+	if [ -n "${GIT_TOKEN:-}" ]; then
+		AUTH=$(printf '%s:%s' "$GIT_USERNAME" "$GIT_TOKEN" | base64 | tr -d '\n')
+		set -- -c "http.extraHeader=Authorization: Basic ${AUTH}" "$@"
+	fi
+	git "$@"
+`
+
+// Git performs git operations such as tagging, committing, and pushing on a
+// repository from within a Dagger pipeline.
+type Git struct {
+	// Repository directory, including .git
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Committer and tagger name
+	//
+	// +private
+	AuthorName string
+
+	// Committer and tagger email
+	//
+	// +private
+	AuthorEmail string
+
+	// Token for HTTPS remotes
+	//
+	// +private
+	Token *dagger.Secret
+
+	// Username sent with Token
+	//
+	// +private
+	TokenUsername string
+
+	// Private key for SSH remotes
+	//
+	// +private
+	SshKey *dagger.Secret
+
+	// known_hosts file for SSH remotes
+	//
+	// +private
+	KnownHosts *dagger.File
+
+	// Armored GPG private key for signing tags and commits
+	//
+	// +private
+	SigningKey *dagger.Secret
+
+	// Passphrase protecting SigningKey
+	//
+	// +private
+	SigningPassphrase *dagger.Secret
+}
+
+// New creates a new Git instance for a repository.
+func New(
+	// A directory containing a git repository (must include .git)
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Committer and tagger name
+	// +optional
+	// +default="github-actions[bot]"
+	authorName string,
+
+	// Committer and tagger email
+	// +optional
+	// +default="41898282+github-actions[bot]@users.noreply.github.com"
+	authorEmail string,
+) *Git {
+	return &Git{
+		Source:      source,
+		AuthorName:  authorName,
+		AuthorEmail: authorEmail,
+	}
+}
+
+// WithTokenAuth authenticates pushes to HTTPS remotes with a token, such as
+// a GitHub token.
+func (m *Git) WithTokenAuth(
+	// Token with push permissions
+	token *dagger.Secret,
+
+	// Username sent with the token
+	// +optional
+	// +default="x-access-token"
+	username string,
+) *Git {
+	m.Token = token
+	m.TokenUsername = username
+	return m
+}
+
+// WithSshAuth authenticates pushes to SSH remotes with a private key.
+// Without known hosts, host keys are accepted on first use.
+func (m *Git) WithSshAuth(
+	// SSH private key
+	key *dagger.Secret,
+
+	// known_hosts file pinning the remote's host keys
+	// +optional
+	knownHosts *dagger.File,
+) *Git {
+	m.SshKey = key
+	m.KnownHosts = knownHosts
+	return m
+}
+
+// WithSigningKey signs tags and commits with a GPG key.
+func (m *Git) WithSigningKey(
+	// Armored GPG private key (e.g., from "gpg --armor --export-secret-keys")
+	key *dagger.Secret,
+
+	// Passphrase protecting the key
+	// +optional
+	passphrase *dagger.Secret,
+) *Git {
+	m.SigningKey = key
+	m.SigningPassphrase = passphrase
+	return m
+}
+
+// Tag creates an annotated tag, signed when a signing key is set. Chain
+// Push to publish it.
+func (m *Git) Tag(
+	// Tag name (e.g., "v1.2.3")
+	name string,
+
+	// Tag message. Defaults to the tag name.
+	// +optional
+	message string,
+
+	// Commit to tag
+	// +optional
+	// +default="HEAD"
+	ref string,
+) *Git {
+	if message == "" {
+		message = name
+	}
+
+	args := []string{"git", "tag", "--annotate", "--message", message}
+	if m.SigningKey != nil {
+		args = append(args, "--sign")
+	}
+
+	m.Source = m.container().
+		WithExec(append(args, name, ref)).
+		Directory("/src")
+	return m
+}
+
+// Commit copies changes (e.g., a regenerated changelog, a version bump, or a
+// formula update) into the repository and commits them, signed when a
+// signing key is set. Nothing is committed when the changes match the
+// repository. Chain Push to publish the commit.
+func (m *Git) Commit(
+	// Files to write into the repository, at their relative paths
+	changes *dagger.Directory,
+
+	// Commit message
+	message string,
+
+	// Branch to commit to, created from the current HEAD when missing
+	// +optional
+	branch string,
+) *Git {
+	ctr := m.container()
+	if branch != "" {
+		ctr = ctr.WithExec([]string{"git", "checkout", "-B", branch})
+	}
+
+	args := []string{"git", "commit", "--message", message}
+	if m.SigningKey != nil {
+		args = append(args, "--gpg-sign")
+	}
+
+	m.Source = ctr.
+		WithDirectory("/src", changes).
+		WithExec([]string{"git", "add", "--all"}).
+		WithExec(append([]string{"sh", "-c", `git diff --cached --quiet || exec "$@"`, "sh"}, args...)).
+		Directory("/src")
+	return m
+}
+
+// Push pushes refs (e.g., "HEAD:main" or "v1.2.3") to a remote and returns
+// the push output.
+func (m *Git) Push(
+	ctx context.Context,
+
+	// Refs to push
+	refs []string,
+
+	// Remote name or URL
+	// +optional
+	// +default="origin"
+	remote string,
+
+	// Force-push with lease
+	// +optional
+	force bool,
+) (string, error) {
+	if len(refs) == 0 {
+		return "", fmt.Errorf("no refs to push")
+	}
+
+	args := []string{"push"}
+	if force {
+		args = append(args, "--force-with-lease")
+	}
+	args = append(args, remote)
+	args = append(args, refs...)
+
+	ctr := m.container()
+	if m.Token != nil {
+		ctr = ctr.
+			WithEnvVariable("GIT_USERNAME", m.TokenUsername).
+			WithSecretVariable("GIT_TOKEN", m.Token)
+	}
+	if m.SshKey != nil {
+		hostKeyCheck := "accept-new"
+		if m.KnownHosts != nil {
+			ctr = ctr.WithFile("/ssh/known_hosts", m.KnownHosts)
+			hostKeyCheck = "yes"
+		}
+		ctr = ctr.
+			WithMountedSecret("/ssh/id_key", m.SshKey, dagger.ContainerWithMountedSecretOpts{Mode: 0o600}).
+			WithEnvVariable("GIT_SSH_COMMAND", "ssh -i /ssh/id_key -o IdentitiesOnly=yes -o UserKnownHostsFile=/ssh/known_hosts -o StrictHostKeyChecking="+hostKeyCheck)
+	}
+
+	out, err := ctr.
+		WithExec(append([]string{"sh", "-c", pushScript, "sh"}, args...)).
+		CombinedOutput(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to push %s to %s: %w", strings.Join(refs, ", "), remote, err)
+	}
+
+	return out, nil
+}
+
+// Directory returns the repository, including .git, with any tags and
+// commits made so far.
+func (m *Git) Directory() *dagger.Directory {
+	return m.Source
+}
+
+// Head returns the commit hash of HEAD.
+func (m *Git) Head(ctx context.Context) (string, error) {
+	out, err := m.container().
+		WithExec([]string{"git", "rev-parse", "HEAD"}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// container returns a git container with the repository mounted, the
+// author configured, and the signing key imported when set.
+func (m *Git) container() *dagger.Container {
+	ctr := dag.Container().
+		From(alpineImage).
+		WithExec([]string{"apk", "add", "--no-cache", "git", "openssh-client", "gnupg"}).
+		WithDirectory("/src", m.Source).
+		WithWorkdir("/src").
+		// The mounted source may be owned by another user.
+		WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "/src"}).
+		WithExec([]string{"git", "config", "--global", "user.name", m.AuthorName}).
+		WithExec([]string{"git", "config", "--global", "user.email", m.AuthorEmail})
+
+	if m.SigningKey == nil {
+		return ctr
+	}
+
+	ctr = ctr.WithMountedSecret("/keys/signing.asc", m.SigningKey)
+	gpg := "gpg --batch"
+	if m.SigningPassphrase != nil {
+		ctr = ctr.WithMountedSecret("/keys/passphrase", m.SigningPassphrase)
+		gpg = "gpg --batch --pinentry-mode loopback --passphrase-file /keys/passphrase"
+	}
+
+	return ctr.
+		WithNewFile("/usr/local/bin/gpg-sign", "#!/bin/sh\nexec "+gpg+` "$@"`+"\n", dagger.ContainerWithNewFileOpts{Permissions: 0o755}).
+		WithExec([]string{"sh", "-c", `
+			gpg --batch --import /keys/signing.asc
+			KEY_ID=$(gpg --batch --list-secret-keys --with-colons | awk -F: '/^sec/ { print $5; exit }')
+			git config --global user.signingkey "$KEY_ID"
+			git config --global gpg.program /usr/local/bin/gpg-sign
+		`})
+}
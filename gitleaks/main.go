@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"dagger/gitleaks/internal/dagger"
+)
+
+const (
+	gitleaksImage string = "zricethezav/gitleaks:v8.24.0"
+)
+
+type Gitleaks struct {
+	// Source is the directory to scan.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Config is an optional gitleaks configuration file.
+	//
+	// +private
+	Config *dagger.File
+
+	// Baseline is an optional gitleaks report of accepted findings.
+	//
+	// +private
+	Baseline *dagger.File
+
+	// History scans the full git history instead of the working tree.
+	//
+	// +private
+	History bool
+}
+
+// New creates a new Gitleaks module instance.
+func New(
+	// The directory to scan. Must include .git when history is set.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Optional gitleaks configuration file with custom rules and allowlists.
+	// When not given, a .gitleaks.toml at the source root is used if present.
+	// +optional
+	config *dagger.File,
+
+	// Optional baseline: a previous gitleaks JSON report whose findings are
+	// accepted and not reported again
+	// +optional
+	baseline *dagger.File,
+
+	// Scan every commit in the git history instead of only the working tree
+	// +optional
+	history bool,
+) *Gitleaks {
+	return &Gitleaks{
+		Source:   source,
+		Config:   config,
+		Baseline: baseline,
+		History:  history,
+	}
+}
+
+// Scan scans the source for leaked credentials. Secrets are redacted in the
+// output. When failOnFindings is set (the default), the scan fails when any
+// finding that is not in the baseline or a .gitleaksignore file is found.
+func (m *Gitleaks) Scan(
+	ctx context.Context,
+
+	// Fail when leaks are found
+	// +optional
+	// +default=true
+	failOnFindings bool,
+) (string, error) {
+	exitCode := "0"
+	if failOnFindings {
+		exitCode = "1"
+	}
+
+	out, err := m.gitleaksContainer().
+		WithExec(m.buildArgs("--exit-code", exitCode, "--verbose")).
+		CombinedOutput(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("leaked credentials found\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return out, nil
+}
+
+// Check scans the source for leaked credentials and fails on any finding,
+// making this suitable for CI checks.
+//
+// +check
+func (m *Gitleaks) Check(ctx context.Context) (string, error) {
+	return m.Scan(ctx, true)
+}
+
+// Report scans the source and returns the findings in the given format
+// ("json", "sarif", "csv", or "junit") without failing, e.g. for uploading
+// SARIF to GitHub code scanning or recording a new baseline.
+func (m *Gitleaks) Report(
+	// Report format: "json", "sarif", "csv", or "junit"
+	// +optional
+	// +default="sarif"
+	format string,
+) (*dagger.File, error) {
+	name := "gitleaks." + format
+	switch format {
+	case "json", "sarif", "csv":
+	case "junit":
+		name = "gitleaks.xml"
+	default:
+		return nil, fmt.Errorf("invalid format %q: must be \"json\", \"sarif\", \"csv\", or \"junit\"", format)
+	}
+
+	return m.gitleaksContainer().
+		WithExec(m.buildArgs(
+			"--exit-code", "0",
+			"--report-format", format,
+			"--report-path", "/"+name,
+		)).
+		File("/" + name), nil
+}
+
+// buildArgs constructs the gitleaks command arguments for a working tree or
+// history scan. Any extra flags passed in are appended.
+func (m *Gitleaks) buildArgs(extra ...string) []string {
+	args := []string{"gitleaks", "dir", "/src"}
+	if m.History {
+		args = []string{"gitleaks", "git", "/src"}
+	}
+
+	args = append(args, "--no-banner", "--redact")
+	if m.Config != nil {
+		args = append(args, "--config", "/gitleaks.toml")
+	}
+	if m.Baseline != nil {
+		args = append(args, "--baseline-path", "/baseline.json")
+	}
+	return append(args, extra...)
+}
+
+// gitleaksContainer returns a gitleaks container with the source, config,
+// and baseline mounted.
+func (m *Gitleaks) gitleaksContainer() *dagger.Container {
+	ctr := dag.Container().
+		From(gitleaksImage).
+		// Let git read the mounted repository regardless of its ownership.
+		WithEnvVariable("GIT_CONFIG_COUNT", "1").
+		WithEnvVariable("GIT_CONFIG_KEY_0", "safe.directory").
+		WithEnvVariable("GIT_CONFIG_VALUE_0", "/src").
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+
+	if m.Config != nil {
+		ctr = ctr.WithFile("/gitleaks.toml", m.Config)
+	}
+	if m.Baseline != nil {
+		ctr = ctr.WithFile("/baseline.json", m.Baseline)
+	}
+
+	return ctr
+}
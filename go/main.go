@@ -12,7 +12,9 @@ type Go struct {
 	Source *dagger.Directory
 }
 
+// New creates a new Go module instance.
 func New(
+	// The Go source directory to check.
 	// +defaultPath="/"
 	source *dagger.Directory,
 ) *Go {
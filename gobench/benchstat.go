@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Delta is the change of one benchmark metric between the baseline and the
+// current run.
+type Delta struct {
+	// Benchmark name, including the GOMAXPROCS suffix
+	Name string
+
+	// Metric unit (e.g., "sec/op", "B/op", "allocs/op")
+	Unit string
+
+	// Baseline value as reported by benchstat
+	Baseline string
+
+	// Current value as reported by benchstat
+	Current string
+
+	// Change in percent, positive when the value grew. Zero when benchstat
+	// found no statistically significant difference.
+	Percent float64
+
+	// Whether benchstat found a statistically significant difference
+	Significant bool
+
+	// Whether the change is a regression beyond the threshold
+	Regression bool
+}
+
+// higherIsBetter reports whether a larger value of the unit is an
+// improvement, as for throughput metrics like "B/s".
+func higherIsBetter(unit string) bool {
+	return strings.HasSuffix(unit, "/s")
+}
+
+// parseBenchstatCSV parses "benchstat -format csv" output comparing two
+// files and marks regressions worse than threshold percent.
+//
+// Each metric table starts with a header row whose first cell is empty and
+// that contains a "vs base" column, followed by one row per benchmark and a
+// "geomean" summary row.
+func parseBenchstatCSV(out string, threshold float64) ([]Delta, error) {
+	r := csv.NewReader(strings.NewReader(out))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse benchstat output: %w", err)
+	}
+
+	var deltas []Delta
+	unit, baseIdx, currentIdx, deltaIdx := "", -1, -1, -1
+	for _, row := range rows {
+		if len(row) < 2 {
+			continue
+		}
+
+		if row[0] == "" {
+			if idx := indexOf(row, "vs base"); idx >= 0 {
+				unit, baseIdx, currentIdx, deltaIdx = row[1], 1, -1, idx
+				for i := 2; i < idx; i++ {
+					if row[i] == unit {
+						currentIdx = i
+						break
+					}
+				}
+			}
+			continue
+		}
+
+		if deltaIdx < 0 || currentIdx < 0 || row[0] == "geomean" || len(row) <= deltaIdx {
+			continue
+		}
+
+		d := Delta{
+			Name:     row[0],
+			Unit:     unit,
+			Baseline: row[baseIdx],
+			Current:  row[currentIdx],
+		}
+		if change := strings.TrimSpace(row[deltaIdx]); change != "~" && change != "" {
+			pct, err := strconv.ParseFloat(strings.TrimSuffix(change, "%"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid change %q for %s (%s): %w", change, d.Name, unit, err)
+			}
+			d.Percent = pct
+			d.Significant = true
+
+			worse := pct
+			if higherIsBetter(unit) {
+				worse = -pct
+			}
+			d.Regression = worse > threshold
+		}
+		deltas = append(deltas, d)
+	}
+
+	return deltas, nil
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
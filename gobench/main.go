@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dagger/gobench/internal/dagger"
+)
+
+const (
+	goImage string = "golang:1.26-bookworm"
+	// golang.org/x/perf is not tagged, so benchstat tracks its latest
+	// pseudo-version.
+	benchstat string = "golang.org/x/perf/cmd/benchstat@latest"
+)
+
+type Gobench struct {
+	// Source is the Go source directory to benchmark.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// EnvVars is an optional list of environment variables to set in the
+	// benchmark container. Each entry must be in "KEY=VALUE" format.
+	//
+	// +private
+	EnvVars []string
+}
+
+// Comparison is a benchstat comparison of a baseline and a current run.
+type Comparison struct {
+	// Per-benchmark, per-metric changes
+	Deltas []Delta
+
+	// Regression threshold in percent
+	Threshold int
+
+	// benchstat's text report
+	Report string
+}
+
+// New creates a new Gobench module instance.
+func New(
+	// The Go source directory to benchmark.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Optional environment variables to set in the benchmark container.
+	// Each entry must be in "KEY=VALUE" format (e.g. "GOEXPERIMENT=rangefunc").
+	// +optional
+	envVars []string,
+) *Gobench {
+	return &Gobench{
+		Source:  source,
+		EnvVars: envVars,
+	}
+}
+
+// Run runs the benchmarks with -benchmem and returns the raw results, which
+// can be stored and later passed to Compare as a baseline.
+func (m *Gobench) Run(
+	// Benchmarks to run, as a -bench regular expression
+	// +optional
+	// +default="."
+	bench string,
+
+	// Packages to benchmark (defaults to "./...")
+	// +optional
+	packages []string,
+
+	// Number of times to run each benchmark. benchstat needs at least 6 runs
+	// to report significant differences.
+	// +optional
+	// +default=10
+	count int,
+
+	// Optional -benchtime (e.g., "2s" or "1000x")
+	// +optional
+	benchtime string,
+
+	// Optional -cpu list (e.g., "1,4")
+	// +optional
+	cpu string,
+) (*dagger.File, error) {
+	ctr, err := m.benchContainer()
+	if err != nil {
+		return nil, fmt.Errorf("could not create benchmark container: %w", err)
+	}
+
+	args := []string{"go", "test", "-run", "^$", "-bench", bench, "-benchmem", "-count", strconv.Itoa(count)}
+	if benchtime != "" {
+		args = append(args, "-benchtime", benchtime)
+	}
+	if cpu != "" {
+		args = append(args, "-cpu", cpu)
+	}
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+	args = append(args, packages...)
+
+	return ctr.
+		WithExec(args, dagger.ContainerWithExecOpts{RedirectStdout: "/bench.txt"}).
+		File("/bench.txt"), nil
+}
+
+// Compare compares benchmark results against a baseline with benchstat.
+// When current is not given, the benchmarks are run with the given options
+// first. Regressions are statistically significant changes for the worse
+// (slower, more memory, or lower throughput) larger than threshold percent.
+func (m *Gobench) Compare(
+	ctx context.Context,
+
+	// Baseline results, e.g. a stored output of run on the main branch
+	baseline *dagger.File,
+
+	// Current results. Run with the options below when not given.
+	// +optional
+	current *dagger.File,
+
+	// Regression threshold in percent
+	// +optional
+	// +default=5
+	threshold int,
+
+	// Benchmarks to run, as a -bench regular expression
+	// +optional
+	// +default="."
+	bench string,
+
+	// Packages to benchmark (defaults to "./...")
+	// +optional
+	packages []string,
+
+	// Number of times to run each benchmark
+	// +optional
+	// +default=10
+	count int,
+) (*Comparison, error) {
+	if current == nil {
+		run, err := m.Run(bench, packages, count, "", "")
+		if err != nil {
+			return nil, err
+		}
+		current = run
+	}
+
+	ctr := dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithExec([]string{"go", "install", benchstat}).
+		// benchstat labels the columns with the file names.
+		WithFile("/bench/base.txt", baseline).
+		WithFile("/bench/current.txt", current).
+		WithWorkdir("/bench")
+
+	report, err := ctr.WithExec([]string{"benchstat", "base.txt", "current.txt"}).Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare benchmarks: %w", err)
+	}
+
+	out, err := ctr.WithExec([]string{"benchstat", "-format", "csv", "base.txt", "current.txt"}).Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare benchmarks: %w", err)
+	}
+
+	deltas, err := parseBenchstatCSV(out, float64(threshold))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Comparison{
+		Deltas:    deltas,
+		Threshold: threshold,
+		Report:    report,
+	}, nil
+}
+
+// Markdown renders the comparison as a markdown table, flagging regressions,
+// for pull request comments or job summaries.
+func (c *Comparison) Markdown() string {
+	var b strings.Builder
+	b.WriteString("| Benchmark | Unit | Baseline | Current | Change |\n")
+	b.WriteString("|-----------|------|----------|---------|--------|\n")
+	for _, d := range c.Deltas {
+		change := "~"
+		if d.Significant {
+			change = fmt.Sprintf("%+.2f%%", d.Percent)
+		}
+		if d.Regression {
+			change = "❌ " + change
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n", d.Name, d.Unit, d.Baseline, d.Current, change)
+	}
+	fmt.Fprintf(&b, "\n`~` means no statistically significant change. ❌ marks regressions over %d%%.\n", c.Threshold)
+	return b.String()
+}
+
+// Check fails when any benchmark regressed beyond the threshold.
+func (c *Comparison) Check() (string, error) {
+	var regressions []string
+	for _, d := range c.Deltas {
+		if d.Regression {
+			regressions = append(regressions, fmt.Sprintf("%s (%s): %+.2f%%", d.Name, d.Unit, d.Percent))
+		}
+	}
+
+	if len(regressions) > 0 {
+		return "", fmt.Errorf("%d benchmark regressions over %d%%:\n  %s\n\n%s",
+			len(regressions), c.Threshold, strings.Join(regressions, "\n  "), c.Report)
+	}
+
+	return fmt.Sprintf("✅ no benchmark regressions over %d%%\n\n%s", c.Threshold, c.Report), nil
+}
+
+// benchContainer returns a container with the source mounted and the Go
+// module and build caches shared with the other Go modules in this
+// daggerverse.
+func (m *Gobench) benchContainer() (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+
+	// Apply caller-provided environment variables.
+	for _, env := range m.EnvVars {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(parts[0], parts[1])
+	}
+
+	return ctr, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/gobuild/internal/dagger"
+)
+
+const (
+	goImage string = "golang:1.26-bookworm"
+)
+
+// defaultTargets are the GOOS/GOARCH pairs built when no targets are given.
+var defaultTargets = []string{
+	"linux/amd64",
+	"linux/arm64",
+	"darwin/amd64",
+	"darwin/arm64",
+	"windows/amd64",
+}
+
+type Gobuild struct {
+	// Source is the Go source directory to build.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// BaseCtr is an optional base container with the Go toolchain installed.
+	//
+	// +private
+	BaseCtr *dagger.Container
+}
+
+// New creates a new Gobuild module instance.
+func New(
+	// The Go source directory to build.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Optional base container with the Go toolchain already installed.
+	// When provided it replaces the default golang image, allowing callers to
+	// pin a Go version or supply C toolchains for CGO builds.
+	// The container must have go on PATH.
+	// +optional
+	baseCtr *dagger.Container,
+) *Gobuild {
+	return &Gobuild{
+		Source:  source,
+		BaseCtr: baseCtr,
+	}
+}
+
+// Build cross-compiles a Go main package for every GOOS/GOARCH target and
+// returns a directory in the <os>/<arch>/<binary> layout expected by the
+// checksum, ghrelease, and bucketupload modules. Windows binaries get an
+// ".exe" suffix.
+//
+// When version is set it is stamped into the binary with
+// "-X <version-var>=<version>", alongside any extra ldflags.
+func (m *Gobuild) Build(
+	ctx context.Context,
+
+	// Name of the output binary. Defaults to the last element of the
+	// package's import path.
+	// +optional
+	name string,
+
+	// Package to build, relative to the source directory
+	// +optional
+	// +default="."
+	pkg string,
+
+	// GOOS/GOARCH targets (e.g., "linux/amd64"). Defaults to linux, darwin,
+	// and windows on amd64 plus linux and darwin on arm64.
+	// +optional
+	targets []string,
+
+	// Version to stamp into the binary (e.g., "v1.2.3")
+	// +optional
+	version string,
+
+	// Fully qualified variable that receives the version
+	// +optional
+	// +default="main.version"
+	versionVar string,
+
+	// Extra linker flags (e.g., "-X main.commit=abc123")
+	// +optional
+	ldflags []string,
+
+	// Build tags
+	// +optional
+	tags []string,
+
+	// Enable CGO. Cross-compiling with CGO requires a base container with a
+	// suitable C toolchain for every target.
+	// +optional
+	cgo bool,
+) (*dagger.Directory, error) {
+	if len(targets) == 0 {
+		targets = defaultTargets
+	}
+
+	ctr := m.goContainer()
+
+	if name == "" {
+		importPath, err := ctr.
+			WithExec([]string{"go", "list", "-f", "{{.ImportPath}}", pkg}).
+			Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve package %s: %w", pkg, err)
+		}
+		name = path.Base(strings.TrimSpace(importPath))
+	}
+
+	args := []string{"go", "build", "-trimpath", "-ldflags", m.ldflags(version, versionVar, ldflags)}
+	if len(tags) > 0 {
+		args = append(args, "-tags", strings.Join(tags, ","))
+	}
+
+	cgoEnabled := "0"
+	if cgo {
+		cgoEnabled = "1"
+	}
+
+	out := dag.Directory()
+	for _, target := range targets {
+		goos, goarch, ok := strings.Cut(target, "/")
+		if !ok || goos == "" || goarch == "" || strings.Contains(goarch, "/") {
+			return nil, fmt.Errorf("invalid target %q: must be in GOOS/GOARCH format", target)
+		}
+
+		binary := name
+		if goos == "windows" {
+			binary += ".exe"
+		}
+		dest := path.Join("/out", goos, goarch, binary)
+
+		out = out.WithFile(
+			path.Join(goos, goarch, binary),
+			ctr.
+				WithEnvVariable("CGO_ENABLED", cgoEnabled).
+				WithEnvVariable("GOOS", goos).
+				WithEnvVariable("GOARCH", goarch).
+				WithExec(append(append([]string{}, args...), "-o", dest, pkg)).
+				File(dest),
+		)
+	}
+
+	return out, nil
+}
+
+// ldflags assembles the -ldflags value: symbol table and DWARF stripping,
+// the version stamp, and any caller-provided flags.
+func (m *Gobuild) ldflags(version, versionVar string, extra []string) string {
+	flags := []string{"-s", "-w"}
+	if version != "" {
+		flags = append(flags, fmt.Sprintf("-X %s=%s", versionVar, version))
+	}
+	return strings.Join(append(flags, extra...), " ")
+}
+
+// goContainer returns a container with the source mounted and the Go module
+// and build caches shared with the other Go modules in this daggerverse.
+func (m *Gobuild) goContainer() *dagger.Container {
+	ctr := m.BaseCtr
+	if ctr == nil {
+		ctr = dag.Container().From(goImage)
+	}
+
+	return ctr.
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/gocover/internal/dagger"
+)
+
+const (
+	goImage     string = "golang:1.26-bookworm"
+	codecovCli  string = "codecov-cli==10.4.0"
+	pythonImage string = "python:3.13-slim"
+	goveralls   string = "github.com/mattn/goveralls@v0.0.12"
+)
+
+type Gocover struct {
+	// Source is the Go source directory the profiles were generated from.
+	// It is needed to render HTML and per-function reports.
+	//
+	// +private
+	Source *dagger.Directory
+}
+
+// New creates a new Gocover module instance.
+func New(
+	// The Go source directory the coverage profiles were generated from.
+	// +defaultPath="/"
+	source *dagger.Directory,
+) *Gocover {
+	return &Gocover{
+		Source: source,
+	}
+}
+
+// Merge merges one or more coverage profiles (e.g., from unit and
+// integration test runs) into a single coverage.out. Execution counts are
+// summed, or OR'ed for "set" mode profiles; all profiles must share a mode.
+func (m *Gocover) Merge(
+	ctx context.Context,
+
+	// Coverage profiles written by "go test -coverprofile"
+	profiles []*dagger.File,
+) (*dagger.File, error) {
+	p, err := m.merge(ctx, profiles)
+	if err != nil {
+		return nil, err
+	}
+
+	return dag.Directory().
+		WithNewFile("coverage.out", p.String()).
+		File("coverage.out"), nil
+}
+
+// Report merges the profiles and renders a report directory with the merged
+// coverage.out, a coverage.html page, a per-function coverage.txt summary
+// from "go tool cover -func", and a per-package packages.txt summary.
+func (m *Gocover) Report(
+	ctx context.Context,
+
+	// Coverage profiles written by "go test -coverprofile"
+	profiles []*dagger.File,
+) (*dagger.Directory, error) {
+	p, err := m.merge(ctx, profiles)
+	if err != nil {
+		return nil, err
+	}
+
+	total, pkgs := p.coverage()
+
+	return m.goContainer().
+		WithNewFile("/reports/coverage.out", p.String()).
+		WithNewFile("/reports/packages.txt", formatCoverage(total, pkgs)).
+		WithExec([]string{"go", "tool", "cover", "-html=/reports/coverage.out", "-o", "/reports/coverage.html"}).
+		WithExec([]string{"sh", "-c", "go tool cover -func=/reports/coverage.out > /reports/coverage.txt"}).
+		Directory("/reports"), nil
+}
+
+// Threshold merges the profiles and fails if overall statement coverage is
+// below minimum, or if any package is below packageMinimum. It returns the
+// per-package coverage summary.
+func (m *Gocover) Threshold(
+	ctx context.Context,
+
+	// Coverage profiles written by "go test -coverprofile"
+	profiles []*dagger.File,
+
+	// Minimum overall coverage percentage (e.g., 80)
+	// +optional
+	minimum float64,
+
+	// Minimum coverage percentage for every package. 0 disables the
+	// per-package check.
+	// +optional
+	packageMinimum float64,
+
+	// Import paths of packages exempt from the per-package check (e.g.,
+	// generated code)
+	// +optional
+	ignorePackages []string,
+) (string, error) {
+	p, err := m.merge(ctx, profiles)
+	if err != nil {
+		return "", err
+	}
+
+	total, pkgs := p.coverage()
+	summary := formatCoverage(total, pkgs)
+
+	ignored := map[string]bool{}
+	for _, pkg := range ignorePackages {
+		ignored[pkg] = true
+	}
+
+	var failures []string
+	if total.percent() < minimum {
+		failures = append(failures, fmt.Sprintf("total coverage %.1f%% is below %.1f%%", total.percent(), minimum))
+	}
+	if packageMinimum > 0 {
+		for _, c := range pkgs {
+			if !ignored[c.pkg] && c.percent() < packageMinimum {
+				failures = append(failures, fmt.Sprintf("%s coverage %.1f%% is below %.1f%%", c.pkg, c.percent(), packageMinimum))
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return "", fmt.Errorf("coverage threshold not met:\n  %s\n\n%s", strings.Join(failures, "\n  "), summary)
+	}
+
+	return fmt.Sprintf("✅ coverage %.1f%% meets thresholds\n\n%s", total.percent(), summary), nil
+}
+
+// Upload merges the profiles and uploads the result to Codecov or Coveralls.
+// The source directory must include .git so the uploader can detect the
+// commit, unless commit and branch are given explicitly.
+func (m *Gocover) Upload(
+	ctx context.Context,
+
+	// Coverage profiles written by "go test -coverprofile"
+	profiles []*dagger.File,
+
+	// Upload token (CODECOV_TOKEN or COVERALLS_REPO_TOKEN)
+	token *dagger.Secret,
+
+	// Coverage service: "codecov" or "coveralls"
+	// +optional
+	// +default="codecov"
+	service string,
+
+	// Commit SHA the coverage belongs to
+	// +optional
+	commit string,
+
+	// Branch the coverage belongs to
+	// +optional
+	branch string,
+
+	// Repository slug in "org/repo" format
+	// +optional
+	repo string,
+) (string, error) {
+	p, err := m.merge(ctx, profiles)
+	if err != nil {
+		return "", err
+	}
+
+	var ctr *dagger.Container
+	switch service {
+	case "codecov":
+		args := []string{"codecovcli", "upload-process", "--disable-search", "--file", "coverage.out", "--git-service", "github"}
+		if commit != "" {
+			args = append(args, "--sha", commit)
+		}
+		if branch != "" {
+			args = append(args, "--branch", branch)
+		}
+		if repo != "" {
+			args = append(args, "--slug", repo)
+		}
+		ctr = dag.Container().
+			From(pythonImage).
+			WithExec([]string{"apt-get", "update"}).
+			WithExec([]string{"apt-get", "install", "-y", "--no-install-recommends", "git"}).
+			WithExec([]string{"pip", "install", "--no-cache-dir", codecovCli}).
+			WithWorkdir("/src").
+			WithDirectory("/src", m.Source).
+			WithNewFile("/src/coverage.out", p.String()).
+			WithSecretVariable("CODECOV_TOKEN", token).
+			WithExec(args)
+	case "coveralls":
+		ctr = m.goContainer().
+			WithNewFile("/src/coverage.out", p.String()).
+			WithSecretVariable("COVERALLS_TOKEN", token)
+		if commit != "" {
+			ctr = ctr.WithEnvVariable("GIT_COMMIT", commit)
+		}
+		if branch != "" {
+			ctr = ctr.WithEnvVariable("GIT_BRANCH", branch)
+		}
+		ctr = ctr.WithExec([]string{"go", "run", goveralls, "-coverprofile=coverage.out", "-service=dagger"})
+	default:
+		return "", fmt.Errorf("unsupported coverage service %q: must be \"codecov\" or \"coveralls\"", service)
+	}
+
+	out, err := ctr.Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload coverage to %s: %w", service, err)
+	}
+
+	return out, nil
+}
+
+// merge reads and merges the given profiles.
+func (m *Gocover) merge(ctx context.Context, profiles []*dagger.File) (*profile, error) {
+	contents := make([]string, 0, len(profiles))
+	for i, f := range profiles {
+		content, err := f.Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read profile %d: %w", i+1, err)
+		}
+		contents = append(contents, content)
+	}
+
+	p, err := mergeProfiles(contents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge profiles: %w", err)
+	}
+
+	return p, nil
+}
+
+// formatCoverage renders per-package coverage followed by the total.
+func formatCoverage(total packageCoverage, pkgs []packageCoverage) string {
+	var b strings.Builder
+	for _, c := range append(pkgs, total) {
+		fmt.Fprintf(&b, "%-60s %6.1f%% (%d/%d statements)\n", c.pkg, c.percent(), c.covered, c.total)
+	}
+	return b.String()
+}
+
+// goContainer returns a container with the source mounted and the Go module
+// and build caches shared with the other Go modules in this daggerverse.
+func (m *Gocover) goContainer() *dagger.Container {
+	return dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+}
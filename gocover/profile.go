@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// profile is a parsed Go coverage profile.
+type profile struct {
+	// Coverage mode: "set", "count", or "atomic"
+	mode string
+
+	// Execution counts keyed by block ("file:start,end")
+	counts map[string]int
+
+	// Number of statements per block
+	stmts map[string]int
+}
+
+// packageCoverage is the statement coverage of a single package.
+type packageCoverage struct {
+	pkg     string
+	covered int
+	total   int
+}
+
+// percent returns the covered statement percentage, 100 for packages without
+// statements.
+func (c packageCoverage) percent() float64 {
+	if c.total == 0 {
+		return 100
+	}
+	return float64(c.covered) * 100 / float64(c.total)
+}
+
+// mergeProfiles merges coverage profiles: counts are summed in "count" and
+// "atomic" mode and OR'ed in "set" mode. All profiles must share a mode.
+func mergeProfiles(contents []string) (*profile, error) {
+	merged := &profile{counts: map[string]int{}, stmts: map[string]int{}}
+
+	for i, content := range contents {
+		lines := strings.Split(strings.TrimSpace(content), "\n")
+		mode, ok := strings.CutPrefix(lines[0], "mode: ")
+		if !ok {
+			return nil, fmt.Errorf("profile %d: missing \"mode:\" header", i+1)
+		}
+		if merged.mode == "" {
+			merged.mode = mode
+		} else if merged.mode != mode {
+			return nil, fmt.Errorf("profile %d: mode %q does not match %q", i+1, mode, merged.mode)
+		}
+
+		for _, line := range lines[1:] {
+			if line == "" {
+				continue
+			}
+
+			// Format: "file:startLine.startCol,endLine.endCol numStmts count"
+			fields := strings.Fields(line)
+			if len(fields) != 3 {
+				return nil, fmt.Errorf("profile %d: malformed line %q", i+1, line)
+			}
+			stmts, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("profile %d: invalid statement count in %q: %w", i+1, line, err)
+			}
+			count, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("profile %d: invalid execution count in %q: %w", i+1, line, err)
+			}
+
+			block := fields[0]
+			merged.stmts[block] = stmts
+			if mode == "set" {
+				if count > 0 {
+					merged.counts[block] = 1
+				} else if _, ok := merged.counts[block]; !ok {
+					merged.counts[block] = 0
+				}
+			} else {
+				merged.counts[block] += count
+			}
+		}
+	}
+
+	if merged.mode == "" {
+		return nil, fmt.Errorf("no coverage profiles given")
+	}
+
+	return merged, nil
+}
+
+// String renders the profile in the format "go tool cover" reads, with
+// blocks sorted for stable output.
+func (p *profile) String() string {
+	blocks := make([]string, 0, len(p.counts))
+	for block := range p.counts {
+		blocks = append(blocks, block)
+	}
+	sort.Strings(blocks)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "mode: %s\n", p.mode)
+	for _, block := range blocks {
+		fmt.Fprintf(&b, "%s %d %d\n", block, p.stmts[block], p.counts[block])
+	}
+	return b.String()
+}
+
+// coverage returns the overall statement coverage and per-package coverage
+// sorted by package import path.
+func (p *profile) coverage() (packageCoverage, []packageCoverage) {
+	total := packageCoverage{pkg: "total"}
+	byPkg := map[string]*packageCoverage{}
+
+	for block, count := range p.counts {
+		file, _, _ := strings.Cut(block, ":")
+		pkg := path.Dir(file)
+		c, ok := byPkg[pkg]
+		if !ok {
+			c = &packageCoverage{pkg: pkg}
+			byPkg[pkg] = c
+		}
+
+		stmts := p.stmts[block]
+		c.total += stmts
+		total.total += stmts
+		if count > 0 {
+			c.covered += stmts
+			total.covered += stmts
+		}
+	}
+
+	pkgs := make([]packageCoverage, 0, len(byPkg))
+	for _, c := range byPkg {
+		pkgs = append(pkgs, *c)
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		return pkgs[i].pkg < pkgs[j].pkg
+	})
+
+	return total, pkgs
+}
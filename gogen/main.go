@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dagger/gogen/internal/dagger"
+)
+
+const (
+	goImage string = "golang:1.26-bookworm"
+)
+
+type Gogen struct {
+	// Source is the Go source directory to regenerate.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Tools is the list of generator tools to go install.
+	//
+	// +private
+	Tools []string
+
+	// EnvVars is an optional list of environment variables to set in the
+	// generate container. Each entry must be in "KEY=VALUE" format.
+	//
+	// +private
+	EnvVars []string
+}
+
+// New creates a new Gogen module instance.
+func New(
+	// The Go source directory to regenerate.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Generator tools to go install before running go generate
+	// (e.g., "go.uber.org/mock/mockgen@v0.5.0",
+	// "golang.org/x/tools/cmd/stringer@v0.30.0"). Tools declared with
+	// go.mod "tool" directives need no install.
+	// +optional
+	tools []string,
+
+	// Optional environment variables to set in the generate container.
+	// Each entry must be in "KEY=VALUE" format (e.g. "GOFLAGS=-mod=mod").
+	// +optional
+	envVars []string,
+) *Gogen {
+	return &Gogen{
+		Source:  source,
+		Tools:   tools,
+		EnvVars: envVars,
+	}
+}
+
+// Generate runs "go generate" and returns the source directory with the
+// generated files written.
+func (m *Gogen) Generate(
+	// Packages to generate (defaults to "./...")
+	// +optional
+	packages []string,
+) (*dagger.Directory, error) {
+	ctr, err := m.generateContainer(packages)
+	if err != nil {
+		return nil, fmt.Errorf("could not create generate container: %w", err)
+	}
+
+	return ctr.Directory("/src"), nil
+}
+
+// Check runs "go generate" and fails with the resulting diff if it changes
+// any file, indicating that the caller forgot to regenerate before
+// committing.
+//
+// +check
+func (m *Gogen) Check(
+	ctx context.Context,
+
+	// Packages to generate (defaults to "./...")
+	// +optional
+	packages []string,
+) (string, error) {
+	ctr, err := m.generateContainer(packages)
+	if err != nil {
+		return "", fmt.Errorf("could not create generate container: %w", err)
+	}
+
+	_, err = ctr.
+		WithDirectory("/committed", m.Source).
+		WithExec([]string{"diff", "-ruN", "--exclude=.git", "/committed", "/src"}).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf(
+			"generated files are out of date: run 'go generate %s' and commit the changes\n\n%s",
+			strings.Join(defaultPackages(packages), " "),
+			e.Stdout,
+		)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return "✅ generated files are up to date", nil
+}
+
+// generateContainer returns a container that has run "go generate" over the
+// source, with the generator tools installed and the Go module and build
+// caches shared with the other Go modules in this daggerverse.
+func (m *Gogen) generateContainer(packages []string) (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build"))
+
+	for _, tool := range m.Tools {
+		ctr = ctr.WithExec([]string{"go", "install", tool})
+	}
+
+	// Apply caller-provided environment variables.
+	for _, env := range m.EnvVars {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(parts[0], parts[1])
+	}
+
+	args := append([]string{"go", "generate"}, defaultPackages(packages)...)
+
+	return ctr.
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source).
+		WithExec(args), nil
+}
+
+// defaultPackages returns packages, or "./..." when none are given.
+func defaultPackages(packages []string) []string {
+	if len(packages) == 0 {
+		return []string{"./..."}
+	}
+	return packages
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"dagger/golicenses/internal/dagger"
+)
+
+const (
+	goImage    string = "golang:1.26-bookworm"
+	goLicenses string = "github.com/google/go-licenses@v1.6.0"
+)
+
+// Golicenses inventories the licenses of a Go module's dependencies.
+type Golicenses struct {
+	// Source is the Go source directory to inspect.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Ignore is the list of package path prefixes left out of the inventory.
+	//
+	// +private
+	Ignore []string
+}
+
+// New creates a new Golicenses module instance.
+func New(
+	// The Go source directory to inspect.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Package path prefixes to leave out of the inventory, typically the
+	// module's own path (e.g., "github.com/papercomputeco/myproject")
+	// +optional
+	ignore []string,
+) *Golicenses {
+	return &Golicenses{
+		Source: source,
+		Ignore: ignore,
+	}
+}
+
+// Report inventories the licenses of every package the given packages
+// depend on.
+func (m *Golicenses) Report(
+	ctx context.Context,
+
+	// Packages to inspect (defaults to "./...")
+	// +optional
+	packages []string,
+) (*Report, error) {
+	out, err := m.goLicensesContainer().
+		WithNewFile("/tmp/report.tpl", reportTemplate).
+		WithExec(m.args("report", packages, "--template", "/tmp/report.tpl")).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inventory licenses: %w", err)
+	}
+
+	deps, err := parseReport(out)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Package < deps[j].Package })
+
+	return &Report{Dependencies: deps}, nil
+}
+
+// Check fails when a dependency of the source uses a denied license (AGPL,
+// GPL, and SSPL by default) or a license that could not be identified,
+// making this suitable for CI checks.
+//
+// +check
+func (m *Golicenses) Check(
+	ctx context.Context,
+
+	// License identifiers to reject (defaults to AGPL-3.0, GPL-2.0, GPL-3.0,
+	// and SSPL-1.0). Entries match by prefix, so "GPL-3.0" also matches
+	// "GPL-3.0-or-later".
+	// +optional
+	deny []string,
+
+	// Accept dependencies whose license could not be identified
+	// +optional
+	allowUnknown bool,
+) (string, error) {
+	report, err := m.Report(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	return report.Check(deny, allowUnknown)
+}
+
+// Notices returns a THIRD_PARTY_NOTICES file with the name, version, and
+// full license text of every dependency, for inclusion in release
+// artifacts.
+func (m *Golicenses) Notices(
+	// Packages to inspect (defaults to "./...")
+	// +optional
+	packages []string,
+) *dagger.File {
+	return m.goLicensesContainer().
+		WithNewFile("/tmp/notices.tpl", noticesTemplate).
+		WithExec(
+			m.args("report", packages, "--template", "/tmp/notices.tpl"),
+			dagger.ContainerWithExecOpts{RedirectStdout: "/THIRD_PARTY_NOTICES"},
+		).
+		File("/THIRD_PARTY_NOTICES")
+}
+
+// args builds a go-licenses command line for the given subcommand.
+func (m *Golicenses) args(command string, packages []string, extra ...string) []string {
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+
+	args := append([]string{"go-licenses", command}, packages...)
+	for _, prefix := range m.Ignore {
+		args = append(args, "--ignore", prefix)
+	}
+	return append(args, extra...)
+}
+
+// goLicensesContainer returns a container with go-licenses installed, the
+// source mounted with its dependencies downloaded, and the Go module and
+// build caches shared with the other Go modules in this daggerverse.
+func (m *Golicenses) goLicensesContainer() *dagger.Container {
+	return dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithExec([]string{"go", "install", goLicenses}).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source).
+		WithExec([]string{"go", "mod", "download"})
+}
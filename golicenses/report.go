@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// defaultDenylist lists the licenses Check rejects when no denylist is
+// given. Entries match license names by prefix, so "GPL-3.0" also matches
+// "GPL-3.0-only" and "GPL-3.0-or-later".
+var defaultDenylist = []string{"AGPL-3.0", "GPL-2.0", "GPL-3.0", "SSPL-1.0"}
+
+// unknownLicense is the license name go-licenses reports when it cannot
+// classify a dependency's license.
+const unknownLicense = "Unknown"
+
+// reportTemplate renders one tab-separated line per package for parseReport.
+const reportTemplate = "{{ range . }}{{ .Name }}\t{{ .Version }}\t{{ .LicenseName }}\t{{ .LicenseURL }}\n{{ end }}"
+
+// noticesTemplate renders the THIRD_PARTY_NOTICES file.
+const noticesTemplate = `THIRD-PARTY SOFTWARE NOTICES
+
+This software includes the following third-party packages.
+{{ range . }}
+================================================================================
+{{ .Name }}{{ if .Version }} {{ .Version }}{{ end }}
+License: {{ .LicenseName }}{{ if .LicenseURL }} ({{ .LicenseURL }}){{ end }}
+--------------------------------------------------------------------------------
+
+{{ .LicenseText }}
+{{ end }}`
+
+// Report is a dependency license inventory.
+type Report struct {
+	// Dependencies in the inventory, sorted by package
+	Dependencies []Dependency
+}
+
+// Dependency is a Go package and the license it is distributed under.
+type Dependency struct {
+	// Package import path
+	Package string
+
+	// Module version, when known
+	Version string
+
+	// SPDX license identifier, or "Unknown"
+	License string
+
+	// URL of the license file
+	URL string
+}
+
+// Markdown renders the inventory as a markdown table.
+func (r *Report) Markdown() string {
+	var b strings.Builder
+	b.WriteString("| Package | Version | License |\n")
+	b.WriteString("|---------|---------|---------|\n")
+	for _, d := range r.Dependencies {
+		license := d.License
+		if d.URL != "" {
+			license = fmt.Sprintf("[%s](%s)", d.License, d.URL)
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", d.Package, d.Version, license)
+	}
+	return b.String()
+}
+
+// JSON renders the inventory as JSON.
+func (r *Report) JSON() (string, error) {
+	out, err := json.MarshalIndent(r.Dependencies, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode report: %w", err)
+	}
+	return string(out), nil
+}
+
+// Check fails when a dependency uses a license on the denylist, or one that
+// could not be identified unless allowUnknown is set. Denylist entries match
+// license identifiers by prefix.
+func (r *Report) Check(
+	// License identifiers to reject (defaults to AGPL-3.0, GPL-2.0, GPL-3.0,
+	// and SSPL-1.0)
+	// +optional
+	deny []string,
+
+	// Accept dependencies whose license could not be identified
+	// +optional
+	allowUnknown bool,
+) (string, error) {
+	if len(deny) == 0 {
+		deny = defaultDenylist
+	}
+
+	var denied, unknown []string
+	for _, d := range r.Dependencies {
+		switch {
+		case d.License == unknownLicense || d.License == "":
+			unknown = append(unknown, d.Package)
+		case isDenied(d.License, deny):
+			denied = append(denied, fmt.Sprintf("%s (%s)", d.Package, d.License))
+		}
+	}
+
+	var problems []string
+	if len(denied) > 0 {
+		problems = append(problems, fmt.Sprintf("denied licenses:\n  %s", strings.Join(denied, "\n  ")))
+	}
+	if len(unknown) > 0 && !allowUnknown {
+		problems = append(problems, fmt.Sprintf("unknown licenses:\n  %s", strings.Join(unknown, "\n  ")))
+	}
+	if len(problems) > 0 {
+		return "", fmt.Errorf("license check failed\n\n%s", strings.Join(problems, "\n\n"))
+	}
+
+	return fmt.Sprintf("✅ %d dependencies use allowed licenses", len(r.Dependencies)), nil
+}
+
+// parseReport parses the tab-separated output of reportTemplate.
+func parseReport(out string) ([]Dependency, error) {
+	var deps []Dependency
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("invalid report line %q", line)
+		}
+		deps = append(deps, Dependency{
+			Package: fields[0],
+			Version: fields[1],
+			License: fields[2],
+			URL:     fields[3],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read report: %w", err)
+	}
+	return deps, nil
+}
+
+func isDenied(license string, deny []string) bool {
+	for _, d := range deny {
+		if strings.HasPrefix(strings.ToLower(license), strings.ToLower(d)) {
+			return true
+		}
+	}
+	return false
+}
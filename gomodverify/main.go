@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/gomodverify/internal/dagger"
+)
+
+const (
+	goImage string = "golang:1.26-bookworm"
+
+	// outputLines is how many lines of a failed check's output are shown.
+	outputLines int = 40
+)
+
+// check is a verification run in each Go module directory.
+type check struct {
+	name   string
+	script string
+}
+
+// checks are the verifications, in order. Downloading first lets go mod
+// verify check every dependency, not only those already in the cache.
+var checks = []check{
+	{"go mod verify", "go mod download && go mod verify"},
+	{"go mod tidy", "go mod tidy -diff"},
+	{"go.sum complete", "go list -mod=readonly -deps -test ./... > /dev/null"},
+}
+
+type Gomodverify struct {
+	// Source is the repository containing the Go modules.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Paths are the Go module directories, relative to Source.
+	//
+	// +private
+	Paths []string
+
+	// EnvVars is an optional list of environment variables in "KEY=VALUE"
+	// format.
+	//
+	// +private
+	EnvVars []string
+
+	// BaseCtr is an optional base container with the Go toolchain installed.
+	//
+	// +private
+	BaseCtr *dagger.Container
+}
+
+// CheckResult is the outcome of one check in one Go module.
+type CheckResult struct {
+	// Go module directory, relative to the source
+	Module string
+
+	// Check name (e.g., "go mod tidy")
+	Name string
+
+	// Whether the check passed
+	Passed bool
+
+	// Combined output of the check
+	Output string
+}
+
+// VerifyResult is the outcome of every check in every Go module.
+type VerifyResult struct {
+	// Whether every check passed
+	Passed bool
+
+	// Outcome of each check
+	Checks []CheckResult
+
+	// Outcome of each check as markdown
+	Report string
+}
+
+// New creates a new Gomodverify module instance.
+func New(
+	// The repository containing the Go modules.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Go module directories, relative to source (defaults to ".")
+	// +optional
+	paths []string,
+
+	// Optional environment variables in "KEY=VALUE" format
+	// (e.g. "GOPRIVATE=github.com/acme/*")
+	// +optional
+	envVars []string,
+
+	// Optional base container with the Go toolchain already installed.
+	// When provided it replaces the default golang image. The container
+	// must have go on PATH.
+	// +optional
+	baseCtr *dagger.Container,
+) *Gomodverify {
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	return &Gomodverify{
+		Source:  source,
+		Paths:   paths,
+		EnvVars: envVars,
+		BaseCtr: baseCtr,
+	}
+}
+
+// Verify checks each Go module's dependencies with go mod verify, that
+// go.mod and go.sum are tidy, and that go.sum has an entry for every
+// package the module builds and tests, and returns the outcome without
+// failing.
+func (m *Gomodverify) Verify(ctx context.Context) (*VerifyResult, error) {
+	ctr, err := m.container()
+	if err != nil {
+		return nil, fmt.Errorf("could not create go container: %w", err)
+	}
+
+	res := &VerifyResult{Passed: true}
+	for _, dir := range m.Paths {
+		dir = path.Clean(strings.TrimPrefix(dir, "/"))
+		for _, c := range checks {
+			ran := ctr.
+				WithWorkdir(path.Join("/src", dir)).
+				WithExec([]string{"sh", "-c", c.script}, dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny})
+
+			code, err := ran.ExitCode(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected error: %w", err)
+			}
+			output, err := ran.CombinedOutput(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s output: %w", c.name, err)
+			}
+
+			res.Checks = append(res.Checks, CheckResult{
+				Module: dir,
+				Name:   c.name,
+				Passed: code == 0,
+				Output: output,
+			})
+			if code != 0 {
+				res.Passed = false
+			}
+		}
+	}
+	res.Report = report(res.Checks)
+
+	return res, nil
+}
+
+// Check runs every verification and fails with the report when any fails,
+// making this suitable for CI checks.
+//
+// +check
+func (m *Gomodverify) Check(ctx context.Context) (string, error) {
+	res, err := m.Verify(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !res.Passed {
+		return "", fmt.Errorf("go module drift\n\n%s", res.Report)
+	}
+	return "✅ go modules verified", nil
+}
+
+// Tidy runs go mod tidy in each Go module and returns the source with
+// go.mod and go.sum updated, which fixes what Check reports as drift.
+func (m *Gomodverify) Tidy() (*dagger.Directory, error) {
+	ctr, err := m.container()
+	if err != nil {
+		return nil, fmt.Errorf("could not create go container: %w", err)
+	}
+
+	for _, dir := range m.Paths {
+		ctr = ctr.
+			WithWorkdir(path.Join("/src", strings.TrimPrefix(dir, "/"))).
+			WithExec([]string{"go", "mod", "tidy"})
+	}
+	return ctr.Directory("/src"), nil
+}
+
+// Warm downloads the dependencies of each Go module into the go-mod cache
+// volume shared with the other Go modules in this daggerverse (gotest,
+// golangcilint, and others), so their first runs do not download them.
+func (m *Gomodverify) Warm(
+	ctx context.Context,
+
+	// Also build every package to warm the go-build cache volume
+	// +optional
+	build bool,
+) (string, error) {
+	ctr, err := m.container()
+	if err != nil {
+		return "", fmt.Errorf("could not create go container: %w", err)
+	}
+
+	for _, dir := range m.Paths {
+		ctr = ctr.
+			WithWorkdir(path.Join("/src", strings.TrimPrefix(dir, "/"))).
+			WithExec([]string{"go", "mod", "download"})
+		if build {
+			ctr = ctr.WithExec([]string{"go", "build", "./..."})
+		}
+	}
+
+	if _, err := ctr.Sync(ctx); err != nil {
+		return "", fmt.Errorf("failed to warm caches: %w", err)
+	}
+	return fmt.Sprintf("✅ warmed caches for %d go modules", len(m.Paths)), nil
+}
+
+// report renders the check results as markdown, with the output of each
+// failed check.
+func report(results []CheckResult) string {
+	var b strings.Builder
+	b.WriteString("## Go module verification\n\n")
+	b.WriteString("| Module | Check | Result |\n")
+	b.WriteString("|--------|-------|--------|\n")
+	for _, r := range results {
+		status := "✅"
+		if !r.Passed {
+			status = "❌"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", r.Module, r.Name, status)
+	}
+
+	for _, r := range results {
+		if r.Passed {
+			continue
+		}
+		fmt.Fprintf(&b, "\n<details><summary><code>%s</code>: %s</summary>\n\n```\n%s\n```\n\n</details>\n",
+			r.Module, r.Name, tail(strings.TrimSpace(r.Output), outputLines))
+	}
+
+	return b.String()
+}
+
+// container returns a Go container with the source mounted and the Go
+// module and build caches shared with the other Go modules in this
+// daggerverse.
+func (m *Gomodverify) container() (*dagger.Container, error) {
+	ctr := m.BaseCtr
+	if ctr == nil {
+		ctr = dag.Container().From(goImage)
+	}
+
+	ctr = ctr.
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		// Check each module on its own, not as part of a workspace.
+		WithEnvVariable("GOWORK", "off").
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+
+	// Apply caller-provided environment variables.
+	for _, env := range m.EnvVars {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(parts[0], parts[1])
+	}
+
+	return ctr, nil
+}
+
+// tail returns the last n lines of s.
+func tail(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
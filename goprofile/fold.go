@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// sampleLine matches a sample of "go tool pprof -raw" output: its
+	// values, then its location IDs from leaf to root.
+	sampleLine = regexp.MustCompile(`^((?:\s+-?\d+)+): ((?:\d+ )*)\s*$`)
+
+	// locationLine matches the first line of a location: its ID, address,
+	// optional mapping and folded markers, then the innermost function.
+	locationLine = regexp.MustCompile(`^\s*(\d+): 0x[0-9a-f]+ (?:M=\d+ )?(?:\[F\] )?(.*)$`)
+)
+
+// foldRaw converts "go tool pprof -raw" output into the folded stacks
+// flamegraph.pl reads, one "root;...;leaf value" line per distinct stack,
+// using the values of the given sample type (e.g. "cpu" or "inuse_space").
+func foldRaw(raw, sampleType string) (string, error) {
+	var (
+		section string
+		index   = -1
+		types   []string
+		samples [][2]string // values, location IDs
+		frames  = map[string][]string{}
+		current string
+	)
+
+	for _, line := range strings.Split(raw, "\n") {
+		switch strings.TrimSpace(line) {
+		case "Samples:", "Locations", "Mappings":
+			section = strings.TrimSpace(line)
+			continue
+		}
+
+		switch section {
+		case "Samples:":
+			if types == nil {
+				// The first line of the section names the sample types,
+				// e.g. "samples/count cpu/nanoseconds[dflt]".
+				for _, t := range strings.Fields(line) {
+					name, _, _ := strings.Cut(t, "/")
+					types = append(types, name)
+				}
+				for i, t := range types {
+					if t == sampleType {
+						index = i
+					}
+				}
+				if index < 0 {
+					return "", fmt.Errorf("profile has no %q samples, only %s", sampleType, strings.Join(types, ", "))
+				}
+				continue
+			}
+			if m := sampleLine.FindStringSubmatch(line); m != nil {
+				samples = append(samples, [2]string{m[1], m[2]})
+			}
+
+		case "Locations":
+			if m := locationLine.FindStringSubmatch(line); m != nil {
+				current = m[1]
+				frames[current] = append(frames[current], function(m[2]))
+			} else if current != "" && strings.TrimSpace(line) != "" {
+				// Further lines of a location are the functions it was
+				// inlined into.
+				frames[current] = append(frames[current], function(line))
+			}
+		}
+	}
+	if index < 0 {
+		return "", fmt.Errorf("no samples found in profile")
+	}
+
+	totals := map[string]int64{}
+	for _, s := range samples {
+		values := strings.Fields(s[0])
+		v, err := strconv.ParseInt(values[index], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid sample value %q: %w", values[index], err)
+		}
+		if v == 0 {
+			continue
+		}
+
+		// Locations and their inlined functions run from leaf to root.
+		var stack []string
+		for _, id := range strings.Fields(s[1]) {
+			stack = append(stack, frames[id]...)
+		}
+		for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+			stack[i], stack[j] = stack[j], stack[i]
+		}
+		totals[strings.Join(stack, ";")] += v
+	}
+
+	stacks := make([]string, 0, len(totals))
+	for stack := range totals {
+		stacks = append(stacks, stack)
+	}
+	sort.Strings(stacks)
+
+	var b strings.Builder
+	for _, stack := range stacks {
+		fmt.Fprintf(&b, "%s %d\n", stack, totals[stack])
+	}
+	return b.String(), nil
+}
+
+// function returns the function name of a location line, or "??" when the
+// location was not symbolized.
+func function(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] == "??" {
+		return "??"
+	}
+	return strings.ReplaceAll(fields[0], ";", ":")
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"dagger/goprofile/internal/dagger"
+)
+
+const (
+	goImage     string = "golang:1.26-bookworm"
+	alpineImage string = "alpine:3.21"
+
+	// FlameGraph is not tagged, so flamegraph.pl tracks its default
+	// branch.
+	flameGraphRepo string = "https://github.com/brendangregg/FlameGraph"
+)
+
+// collectScript fetches CPU, trace, and heap profiles from a net/http/pprof
+// endpoint at $PPROF_URL while the load runs. The CPU profile and trace
+// cover the same window; the heap profile is taken at its end.
+const collectScript string = `set -eu
+mkdir -p /profiles
+curl -fsS -o /profiles/cpu.pprof "$PPROF_URL/profile?seconds=$PROFILE_SECONDS" &
+cpu=$!
+curl -fsS -o /profiles/trace.out "$PPROF_URL/trace?seconds=$PROFILE_SECONDS" &
+trace=$!
+wait "$cpu"
+wait "$trace"
+curl -fsS -o /profiles/heap.pprof "$PPROF_URL/heap"
+`
+
+// flamegraph is a flamegraph rendered from one sample type of a profile.
+type flamegraph struct {
+	Profile    string
+	SampleType string
+	Name       string
+	Title      string
+	CountName  string
+}
+
+// flamegraphs lists the flamegraphs rendered for the collected profiles.
+var flamegraphs = []flamegraph{
+	{Profile: "cpu.pprof", SampleType: "cpu", Name: "cpu", Title: "CPU", CountName: "nanoseconds"},
+	{Profile: "heap.pprof", SampleType: "inuse_space", Name: "heap-inuse", Title: "Heap in use", CountName: "bytes"},
+	{Profile: "heap.pprof", SampleType: "alloc_space", Name: "heap-alloc", Title: "Heap allocated", CountName: "bytes"},
+}
+
+type Goprofile struct {
+	// Source is the Go source directory to profile.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// EnvVars is an optional list of environment variables to set in the
+	// benchmark container. Each entry must be in "KEY=VALUE" format.
+	//
+	// +private
+	EnvVars []string
+}
+
+// New creates a new Goprofile module instance.
+func New(
+	// The Go source directory to profile.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Optional environment variables to set in the benchmark container.
+	// Each entry must be in "KEY=VALUE" format (e.g. "GOEXPERIMENT=greenteagc").
+	// +optional
+	envVars []string,
+) *Goprofile {
+	return &Goprofile{
+		Source:  source,
+		EnvVars: envVars,
+	}
+}
+
+// Benchmark runs benchmarks of one package with CPU and memory profiling
+// and an execution trace, and returns a directory with the profiles
+// (cpu.pprof, heap.pprof, trace.out), the test binary (bench.test), the
+// benchmark output (bench.txt), flamegraph SVGs, and top-function
+// summaries.
+func (m *Goprofile) Benchmark(
+	ctx context.Context,
+
+	// Benchmarks to run, as a -bench regular expression
+	// +optional
+	// +default="."
+	bench string,
+
+	// Package to benchmark; go test profiles a single package at a time
+	// +optional
+	// +default="."
+	pkg string,
+
+	// Optional -benchtime (e.g., "5s" or "1000x")
+	// +optional
+	benchtime string,
+
+	// Number of times to run each benchmark
+	// +optional
+	// +default=1
+	count int,
+) (*dagger.Directory, error) {
+	ctr := dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build"))
+
+	// Apply caller-provided environment variables.
+	for _, env := range m.EnvVars {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(parts[0], parts[1])
+	}
+
+	args := []string{
+		"go", "test", "-run", "^$", "-bench", bench, "-benchmem", "-count", strconv.Itoa(count),
+		"-cpuprofile", "/profiles/cpu.pprof",
+		"-memprofile", "/profiles/heap.pprof",
+		"-trace", "/profiles/trace.out",
+		"-o", "/profiles/bench.test",
+	}
+	if benchtime != "" {
+		args = append(args, "-benchtime", benchtime)
+	}
+	args = append(args, pkg)
+
+	profiles := ctr.
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source).
+		WithExec([]string{"mkdir", "-p", "/profiles"}).
+		WithExec(args, dagger.ContainerWithExecOpts{RedirectStdout: "/profiles/bench.txt"}).
+		Directory("/profiles")
+
+	return m.render(ctx, profiles)
+}
+
+// Load profiles a running service while a load generator drives it, and
+// returns a directory with the profiles (cpu.pprof, heap.pprof,
+// trace.out), flamegraph SVGs, and top-function summaries.
+//
+// The service must serve net/http/pprof. It is reachable at "app" from
+// the load generator. Every call profiles anew, even with unchanged
+// inputs.
+func (m *Goprofile) Load(
+	ctx context.Context,
+
+	// Service to profile, serving net/http/pprof
+	app *dagger.Service,
+
+	// Container to generate load from (e.g., with hey, k6, or vegeta)
+	load *dagger.Container,
+
+	// Load command, which should run at least as long as the profile
+	// (e.g., "hey -z 40s http://app:8080/")
+	loadCommand []string,
+
+	// Port the service serves /debug/pprof on
+	// +optional
+	// +default=6060
+	port int,
+
+	// Length of the CPU profile and trace, in seconds
+	// +optional
+	// +default=30
+	seconds int,
+) (*dagger.Directory, error) {
+	if len(loadCommand) == 0 {
+		return nil, fmt.Errorf("load-command is required")
+	}
+
+	// Run the load as a service so it drives the app while the profiles
+	// are collected.
+	generator := load.
+		WithServiceBinding("app", app).
+		AsService(dagger.ContainerAsServiceOpts{Args: loadCommand})
+
+	profiles := dag.Container().
+		From(alpineImage).
+		WithExec([]string{"apk", "add", "--no-cache", "curl"}).
+		WithServiceBinding("app", app).
+		WithServiceBinding("load", generator).
+		WithEnvVariable("PPROF_URL", fmt.Sprintf("http://app:%d/debug/pprof", port)).
+		WithEnvVariable("PROFILE_SECONDS", strconv.Itoa(seconds)).
+		// A profile of a live service is only valid for the run that took
+		// it; never reuse a cached one.
+		WithEnvVariable("GOPROFILE_SESSION", strconv.FormatInt(time.Now().UnixNano(), 10)).
+		WithExec([]string{"sh", "-c", collectScript}).
+		Directory("/profiles")
+
+	return m.render(ctx, profiles)
+}
+
+// render adds flamegraph SVGs and top-function summaries to a directory
+// of profiles, for every profile it contains.
+func (m *Goprofile) render(ctx context.Context, profiles *dagger.Directory) (*dagger.Directory, error) {
+	ctr := dag.Container().
+		From(goImage).
+		WithMountedCache("/var/cache/apt", dag.CacheVolume("goprofile-apt")).
+		WithExec([]string{"apt-get", "update"}).
+		WithExec([]string{"apt-get", "install", "-y", "--no-install-recommends", "perl"}).
+		WithFile("/usr/local/bin/flamegraph.pl", dag.Git(flameGraphRepo).Branch("master").Tree().File("flamegraph.pl")).
+		WithDirectory("/profiles", profiles).
+		WithWorkdir("/profiles")
+
+	for _, fg := range flamegraphs {
+		ok, err := profiles.Exists(ctx, fg.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect profiles: %w", err)
+		}
+		if !ok {
+			continue
+		}
+
+		raw, err := ctr.WithExec([]string{"go", "tool", "pprof", "-raw", fg.Profile}).Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", fg.Profile, err)
+		}
+		folded, err := foldRaw(raw, fg.SampleType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fold %s: %w", fg.Profile, err)
+		}
+
+		ctr = ctr.
+			WithNewFile("/folded/"+fg.Name+".folded", folded).
+			WithExec(
+				[]string{
+					"perl", "/usr/local/bin/flamegraph.pl",
+					"--title", fg.Title, "--countname", fg.CountName,
+					"/folded/" + fg.Name + ".folded",
+				},
+				dagger.ContainerWithExecOpts{RedirectStdout: fg.Name + ".svg"},
+			).
+			WithExec(
+				[]string{"go", "tool", "pprof", "-top", "-nodecount=40", "-sample_index=" + fg.SampleType, fg.Profile},
+				dagger.ContainerWithExecOpts{RedirectStdout: fg.Name + "-top.txt"},
+			)
+	}
+
+	return ctr.Directory("/profiles"), nil
+}
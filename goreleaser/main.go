@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/goreleaser/internal/dagger"
+)
+
+const (
+	goreleaserImage string = "goreleaser/goreleaser:v2.5.1"
+)
+
+type Goreleaser struct {
+	// Source is the git repository to release. It must include .git so
+	// goreleaser can read tags and commit history.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Config is an optional goreleaser configuration file that replaces the
+	// repository's .goreleaser.yaml.
+	//
+	// +private
+	Config *dagger.File
+
+	// EnvVars is an optional list of environment variables to set in the
+	// goreleaser container. Each entry must be in "KEY=VALUE" format.
+	//
+	// +private
+	EnvVars []string
+}
+
+// New creates a new Goreleaser module instance.
+func New(
+	// A directory containing a git repository (must include .git).
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// An optional goreleaser configuration file that replaces the
+	// repository's .goreleaser.yaml.
+	// +optional
+	config *dagger.File,
+
+	// Optional environment variables to set in the goreleaser container,
+	// e.g. values referenced from the config. Each entry must be in
+	// "KEY=VALUE" format.
+	// +optional
+	envVars []string,
+) *Goreleaser {
+	return &Goreleaser{
+		Source:  source,
+		Config:  config,
+		EnvVars: envVars,
+	}
+}
+
+// Check validates the goreleaser configuration.
+//
+// +check
+func (m *Goreleaser) Check(ctx context.Context) (string, error) {
+	ctr, err := m.container()
+	if err != nil {
+		return "", fmt.Errorf("could not create goreleaser container: %w", err)
+	}
+
+	return ctr.
+		WithExec(m.args("check")).
+		CombinedOutput(ctx)
+}
+
+// Build runs "goreleaser build" and returns the dist directory. Builds are
+// snapshots by default; disable snapshot to build a tagged commit with its
+// release version.
+func (m *Goreleaser) Build(
+	// Build a snapshot, not requiring a tag
+	// +optional
+	// +default=true
+	snapshot bool,
+
+	// Only build for the engine's GOOS/GOARCH
+	// +optional
+	singleTarget bool,
+
+	// Only build the build with these IDs
+	// +optional
+	ids []string,
+) (*dagger.Directory, error) {
+	ctr, err := m.container()
+	if err != nil {
+		return nil, fmt.Errorf("could not create goreleaser container: %w", err)
+	}
+
+	args := m.args("build", "--clean")
+	if snapshot {
+		args = append(args, "--snapshot")
+	}
+	if singleTarget {
+		args = append(args, "--single-target")
+	}
+	for _, id := range ids {
+		args = append(args, "--id", id)
+	}
+
+	return ctr.
+		WithExec(args).
+		Directory("/src/dist"), nil
+}
+
+// Snapshot runs a full "goreleaser release --snapshot" without publishing
+// anything and returns the dist directory, including archives, checksums,
+// and packages, for inspecting a release before tagging it.
+func (m *Goreleaser) Snapshot() (*dagger.Directory, error) {
+	ctr, err := m.container()
+	if err != nil {
+		return nil, fmt.Errorf("could not create goreleaser container: %w", err)
+	}
+
+	return ctr.
+		WithExec(m.args("release", "--snapshot", "--clean")).
+		Directory("/src/dist"), nil
+}
+
+// Release runs "goreleaser release" for the tagged commit, publishing to
+// GitHub with the given token, and returns the goreleaser output.
+func (m *Goreleaser) Release(
+	ctx context.Context,
+
+	// GitHub token with permissions to create releases
+	token *dagger.Secret,
+
+	// Path of a release notes file to use instead of the generated changelog,
+	// relative to the source directory
+	// +optional
+	releaseNotes string,
+
+	// Skip these pipes (e.g., "publish", "announce", "validate")
+	// +optional
+	skip []string,
+) (string, error) {
+	ctr, err := m.container()
+	if err != nil {
+		return "", fmt.Errorf("could not create goreleaser container: %w", err)
+	}
+
+	args := m.args("release", "--clean")
+	if releaseNotes != "" {
+		args = append(args, "--release-notes", releaseNotes)
+	}
+	if len(skip) > 0 {
+		args = append(args, "--skip", strings.Join(skip, ","))
+	}
+
+	out, err := ctr.
+		WithSecretVariable("GITHUB_TOKEN", token).
+		WithExec(args).
+		CombinedOutput(ctx)
+	if err != nil {
+		return "", fmt.Errorf("goreleaser release failed: %w", err)
+	}
+
+	return out, nil
+}
+
+// args returns the goreleaser command for a subcommand, pointing at the
+// override config when one is set.
+func (m *Goreleaser) args(subcommand string, extra ...string) []string {
+	args := []string{"goreleaser", subcommand}
+	if m.Config != nil {
+		args = append(args, "--config", "/goreleaser.yaml")
+	}
+	return append(args, extra...)
+}
+
+// container returns a goreleaser container with the source mounted and the
+// Go module and build caches shared with the other Go modules in this
+// daggerverse.
+func (m *Goreleaser) container() (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(goreleaserImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source).
+		// The mounted source may be owned by another user.
+		WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "/src"})
+
+	if m.Config != nil {
+		ctr = ctr.WithFile("/goreleaser.yaml", m.Config)
+	}
+
+	// Apply caller-provided environment variables.
+	for _, env := range m.EnvVars {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(parts[0], parts[1])
+	}
+
+	return ctr, nil
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/gosec/internal/dagger"
+)
+
+const (
+	goImage string = "golang:1.26-bookworm"
+	gosec   string = "github.com/securego/gosec/v2/cmd/gosec@v2.22.4"
+)
+
+// levels lists the severity and confidence levels gosec accepts.
+var levels = []string{"low", "medium", "high"}
+
+type Gosec struct {
+	// Source is the Go source directory to scan.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Config is an optional gosec JSON configuration file.
+	//
+	// +private
+	Config *dagger.File
+
+	// Exclude is the list of rule IDs to skip.
+	//
+	// +private
+	Exclude []string
+
+	// ExcludeDirs is the list of directories to skip.
+	//
+	// +private
+	ExcludeDirs []string
+
+	// EnvVars is an optional list of environment variables to set in the
+	// scan container. Each entry must be in "KEY=VALUE" format.
+	//
+	// +private
+	EnvVars []string
+}
+
+// New creates a new Gosec module instance.
+func New(
+	// The Go source directory to scan.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// An optional gosec JSON configuration file with global settings and
+	// per-rule options (passed via -conf).
+	// +optional
+	config *dagger.File,
+
+	// Rule IDs to skip (e.g., "G104", "G304")
+	// +optional
+	exclude []string,
+
+	// Directories to skip, relative to the source (e.g., "testdata", "gen")
+	// +optional
+	excludeDirs []string,
+
+	// Optional environment variables to set in the scan container.
+	// Each entry must be in "KEY=VALUE" format (e.g. "GOFLAGS=-tags=integration").
+	// +optional
+	envVars []string,
+) *Gosec {
+	return &Gosec{
+		Source:      source,
+		Config:      config,
+		Exclude:     exclude,
+		ExcludeDirs: excludeDirs,
+		EnvVars:     envVars,
+	}
+}
+
+// Scan runs gosec over the source directory and fails when an issue at or
+// above both the severity and confidence thresholds is found. Returns the
+// gosec text output.
+func (m *Gosec) Scan(
+	ctx context.Context,
+
+	// Minimum issue severity to report: "low", "medium", or "high"
+	// +optional
+	// +default="medium"
+	severity string,
+
+	// Minimum issue confidence to report: "low", "medium", or "high"
+	// +optional
+	// +default="medium"
+	confidence string,
+) (string, error) {
+	ctr, err := m.gosecContainer()
+	if err != nil {
+		return "", fmt.Errorf("could not create gosec container: %w", err)
+	}
+
+	args, err := m.buildArgs(severity, confidence, "-fmt", "text")
+	if err != nil {
+		return "", err
+	}
+
+	return ctr.WithExec(args).Stdout(ctx)
+}
+
+// Check runs gosec over the source directory and fails on medium or higher
+// severity issues reported with medium or higher confidence, making this
+// suitable for CI checks.
+//
+// +check
+func (m *Gosec) Check(ctx context.Context) (string, error) {
+	return m.Scan(ctx, "medium", "medium")
+}
+
+// Report runs gosec over the source directory and returns its output in the
+// given format ("sarif", "json", or "text") without failing on issues, e.g.
+// for uploading SARIF to GitHub code scanning.
+func (m *Gosec) Report(
+	// Output format: "sarif", "json", or "text"
+	// +optional
+	// +default="sarif"
+	format string,
+
+	// Minimum issue severity to report: "low", "medium", or "high"
+	// +optional
+	// +default="low"
+	severity string,
+
+	// Minimum issue confidence to report: "low", "medium", or "high"
+	// +optional
+	// +default="low"
+	confidence string,
+) (*dagger.File, error) {
+	name := "gosec." + format
+	switch format {
+	case "sarif", "json":
+	case "text":
+		name = "gosec.txt"
+	default:
+		return nil, fmt.Errorf("invalid format %q: must be \"sarif\", \"json\", or \"text\"", format)
+	}
+
+	ctr, err := m.gosecContainer()
+	if err != nil {
+		return nil, fmt.Errorf("could not create gosec container: %w", err)
+	}
+
+	args, err := m.buildArgs(severity, confidence, "-fmt", format, "-out", "/"+name, "-no-fail")
+	if err != nil {
+		return nil, err
+	}
+
+	return ctr.WithExec(args).File("/" + name), nil
+}
+
+// buildArgs constructs the gosec command arguments. Any extra flags passed
+// in are appended before the package pattern.
+func (m *Gosec) buildArgs(severity, confidence string, extra ...string) ([]string, error) {
+	if err := validateLevel("severity", severity); err != nil {
+		return nil, err
+	}
+	if err := validateLevel("confidence", confidence); err != nil {
+		return nil, err
+	}
+
+	args := []string{"gosec", "-severity", severity, "-confidence", confidence}
+	if m.Config != nil {
+		args = append(args, "-conf", "/gosec.json")
+	}
+	if len(m.Exclude) > 0 {
+		args = append(args, "-exclude", strings.Join(m.Exclude, ","))
+	}
+	for _, dir := range m.ExcludeDirs {
+		args = append(args, "-exclude-dir", dir)
+	}
+	args = append(args, extra...)
+	args = append(args, "./...")
+	return args, nil
+}
+
+// gosecContainer returns a container with gosec installed, the source and
+// config mounted, and the Go module and build caches shared with the other
+// Go modules in this daggerverse.
+func (m *Gosec) gosecContainer() (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithExec([]string{"go", "install", gosec}).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+
+	if m.Config != nil {
+		ctr = ctr.WithFile("/gosec.json", m.Config)
+	}
+
+	// Apply caller-provided environment variables.
+	for _, env := range m.EnvVars {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(parts[0], parts[1])
+	}
+
+	return ctr, nil
+}
+
+func validateLevel(name, level string) error {
+	for _, l := range levels {
+		if level == l {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %s %q: must be \"low\", \"medium\", or \"high\"", name, level)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/gotest/internal/dagger"
+)
+
+const (
+	goImage   string = "golang:1.26-bookworm"
+	gotestsum string = "gotest.tools/gotestsum@v1.12.3"
+)
+
+type Gotest struct {
+	// Source is the Go source directory to test.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// EnvVars is an optional list of environment variables to set in the
+	// test container. Each entry must be in "KEY=VALUE" format.
+	//
+	// +private
+	EnvVars []string
+
+	// BaseCtr is an optional base container with the Go toolchain installed.
+	//
+	// +private
+	BaseCtr *dagger.Container
+}
+
+// TestResult is the outcome of a test run.
+type TestResult struct {
+	// Whether every test passed
+	Passed bool
+
+	// Exit code of the test run
+	ExitCode int
+
+	// Human-readable test output
+	Output string
+
+	// Report directory containing junit.xml, test.json, coverage.out, and
+	// coverage.txt (per-function coverage summary)
+	Reports *dagger.Directory
+}
+
+// New creates a new Gotest module instance.
+func New(
+	// The Go source directory to test.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Optional environment variables to set in the test container.
+	// Each entry must be in "KEY=VALUE" format (e.g. "GOEXPERIMENT=rangefunc").
+	// +optional
+	envVars []string,
+
+	// Optional base container with the Go toolchain already installed.
+	// When provided it replaces the default golang image, allowing callers to
+	// supply extra system libraries or tooling. The container must have go
+	// and a C compiler (for the race detector) on PATH.
+	// +optional
+	baseCtr *dagger.Container,
+) *Gotest {
+	return &Gotest{
+		Source:  source,
+		EnvVars: envVars,
+		BaseCtr: baseCtr,
+	}
+}
+
+// Test runs "go test" with coverage and JUnit XML output and returns the
+// result without failing the pipeline on test failures, so reports can be
+// collected either way. Use Check to fail on test failures instead.
+func (m *Gotest) Test(
+	ctx context.Context,
+
+	// Packages to test. Defaults to "./...".
+	// +optional
+	packages []string,
+
+	// Disable the race detector
+	// +optional
+	noRace bool,
+
+	// Build tags
+	// +optional
+	tags []string,
+
+	// Overall test timeout (go test -timeout)
+	// +optional
+	// +default="10m"
+	timeout string,
+
+	// Only run tests matching this regular expression (go test -run)
+	// +optional
+	run string,
+
+	// Extra arguments passed to go test (e.g., "-count=1", "-short")
+	// +optional
+	extraArgs []string,
+) (*TestResult, error) {
+	ctr, err := m.testContainer()
+	if err != nil {
+		return nil, fmt.Errorf("could not create test container: %w", err)
+	}
+
+	args := []string{
+		"gotestsum",
+		"--format", "testname",
+		"--junitfile", "/reports/junit.xml",
+		"--jsonfile", "/reports/test.json",
+		"--",
+		"-coverprofile=/reports/coverage.out",
+		"-covermode=atomic",
+		"-timeout", timeout,
+	}
+	if !noRace {
+		args = append(args, "-race")
+	}
+	if len(tags) > 0 {
+		args = append(args, "-tags", strings.Join(tags, ","))
+	}
+	if run != "" {
+		args = append(args, "-run", run)
+	}
+	args = append(args, extraArgs...)
+
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+	args = append(args, packages...)
+
+	ctr = ctr.WithExec(args, dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny})
+
+	exitCode, err := ctr.ExitCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run tests: %w", err)
+	}
+
+	output, err := ctr.Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test output: %w", err)
+	}
+
+	// Summarize coverage when a profile was written; compilation failures
+	// leave none behind.
+	reports := ctr.
+		WithExec([]string{
+			"sh", "-c",
+			"if [ -s /reports/coverage.out ]; then go tool cover -func=/reports/coverage.out > /reports/coverage.txt; fi",
+		}).
+		Directory("/reports")
+
+	return &TestResult{
+		Passed:   exitCode == 0,
+		ExitCode: exitCode,
+		Output:   output,
+		Reports:  reports,
+	}, nil
+}
+
+// Check runs "go test ./..." with the race detector and fails the pipeline
+// when any test fails, making this suitable for CI checks.
+//
+// +check
+func (m *Gotest) Check(ctx context.Context) (string, error) {
+	res, err := m.Test(ctx, nil, false, nil, "10m", "", nil)
+	if err != nil {
+		return "", err
+	}
+	if !res.Passed {
+		return "", fmt.Errorf("tests failed with exit code %d\n\n%s", res.ExitCode, res.Output)
+	}
+	return res.Output, nil
+}
+
+// testContainer returns a container with gotestsum installed, the source
+// mounted, and the Go module and build caches shared with the other Go
+// modules in this daggerverse.
+func (m *Gotest) testContainer() (*dagger.Container, error) {
+	ctr := m.BaseCtr
+	if ctr == nil {
+		ctr = dag.Container().From(goImage)
+	}
+
+	ctr = ctr.
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithExec([]string{"go", "install", gotestsum}).
+		WithEnvVariable("CGO_ENABLED", "1").
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source).
+		WithExec([]string{"mkdir", "-p", "/reports"})
+
+	// Apply caller-provided environment variables.
+	for _, env := range m.EnvVars {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(parts[0], parts[1])
+	}
+
+	return ctr, nil
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"dagger/goupdate/internal/dagger"
+)
+
+const (
+	goImage string = "golang:1.26-bookworm"
+
+	// maxMajorBumps bounds how many major versions ahead are probed.
+	maxMajorBumps int = 5
+)
+
+type Goupdate struct {
+	// Source is the repository containing the Go module.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Path is the Go module directory, relative to Source.
+	//
+	// +private
+	Path string
+
+	// Policy is the largest update allowed: "patch", "minor", or "major".
+	//
+	// +private
+	Policy string
+
+	// Allow are module path prefixes that may be updated.
+	//
+	// +private
+	Allow []string
+
+	// Deny are module path prefixes that are never updated.
+	//
+	// +private
+	Deny []string
+
+	// EnvVars is an optional list of environment variables in "KEY=VALUE"
+	// format.
+	//
+	// +private
+	EnvVars []string
+}
+
+// UpdateResult is an updated source directory and a description of the
+// updates, ready to commit and open as a pull request.
+type UpdateResult struct {
+	// Source directory with go.mod, go.sum, and (for major updates) import
+	// paths updated
+	Source *dagger.Directory
+
+	// Markdown summary, suitable as a pull request body
+	Summary string
+
+	// Every module whose version changed, direct and indirect
+	Updates []DependencyUpdate
+
+	// Whether the tests pass with the updates (true when tests were skipped)
+	TestsPassed bool
+}
+
+// New creates a new Goupdate module instance.
+func New(
+	// The repository containing the Go module.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Go module directory, relative to source
+	// +optional
+	// +default="."
+	path string,
+
+	// Largest update allowed: "patch", "minor", or "major". Major updates
+	// move to the next "/vN" module path and rewrite imports.
+	// +optional
+	// +default="minor"
+	policy string,
+
+	// Module path prefixes that may be updated (e.g., "github.com/aws");
+	// all direct dependencies when empty
+	// +optional
+	allow []string,
+
+	// Module path prefixes that are never updated
+	// +optional
+	deny []string,
+
+	// Optional environment variables in "KEY=VALUE" format
+	// (e.g. "GOPRIVATE=github.com/acme/*")
+	// +optional
+	envVars []string,
+) *Goupdate {
+	return &Goupdate{
+		Source:  source,
+		Path:    path,
+		Policy:  policy,
+		Allow:   allow,
+		Deny:    deny,
+		EnvVars: envVars,
+	}
+}
+
+// Update updates the allowed direct dependencies as far as the policy
+// permits, tidies go.mod, optionally runs the tests, and returns the
+// updated source with a markdown summary. Failing tests are reported in the
+// summary rather than failing the call, so the update can still be opened
+// as a pull request for a human to fix.
+func (m *Goupdate) Update(
+	ctx context.Context,
+
+	// Run the tests after updating
+	// +optional
+	// +default=true
+	test bool,
+
+	// Packages to test (defaults to "./...")
+	// +optional
+	packages []string,
+) (*UpdateResult, error) {
+	switch m.Policy {
+	case "patch", "minor", "major":
+	default:
+		return nil, fmt.Errorf("invalid policy %q: must be \"patch\", \"minor\", or \"major\"", m.Policy)
+	}
+
+	ctr, err := m.container()
+	if err != nil {
+		return nil, fmt.Errorf("could not create go container: %w", err)
+	}
+	// New versions are published between runs; always query the proxy.
+	ctr = ctr.WithEnvVariable("GOUPDATE_SESSION", fmt.Sprintf("%d", time.Now().UnixNano()))
+
+	before, err := listModules(ctx, ctr)
+	if err != nil {
+		return nil, err
+	}
+
+	direct := map[string]bool{}
+	renames := map[string]string{}
+	var targets []string
+	for _, mod := range before {
+		if mod.Main || mod.Indirect {
+			continue
+		}
+		direct[mod.Path] = true
+		if !allowed(mod.Path, m.Allow, m.Deny) {
+			continue
+		}
+
+		switch m.Policy {
+		case "patch":
+			targets = append(targets, mod.Path+"@patch")
+		case "minor":
+			targets = append(targets, mod.Path+"@latest")
+		case "major":
+			next, err := latestMajorPath(ctx, ctr, mod)
+			if err != nil {
+				return nil, err
+			}
+			if next != mod.Path {
+				renames[mod.Path] = next
+			}
+			targets = append(targets, next+"@latest")
+		}
+	}
+
+	if len(targets) > 0 {
+		ctr = ctr.WithExec(append([]string{"go", "get"}, targets...))
+	}
+	// Rewrite imports of old major versions, including subpackages, in a
+	// stable order so the pipeline caches.
+	renamed := make([]string, 0, len(renames))
+	for from := range renames {
+		renamed = append(renamed, from)
+	}
+	sort.Strings(renamed)
+	for _, from := range renamed {
+		expr := fmt.Sprintf(`s#"%s(/|")#"%s\1#g`, strings.ReplaceAll(from, ".", `\.`), renames[from])
+		ctr = ctr.WithExec([]string{
+			"find", ".", "-name", "*.go", "-not", "-path", "./vendor/*",
+			"-exec", "sed", "-i", "-E", expr, "{}", "+",
+		})
+	}
+	ctr = ctr.WithExec([]string{"go", "mod", "tidy"})
+
+	after, err := listModules(ctx, ctr)
+	if err != nil {
+		return nil, err
+	}
+	updates := diffModules(before, after, renames)
+
+	passed, testOutput := true, ""
+	if test && len(updates) > 0 {
+		if len(packages) == 0 {
+			packages = []string{"./..."}
+		}
+		tested := ctr.WithExec(
+			append([]string{"go", "test"}, packages...),
+			dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny},
+		)
+		code, err := tested.ExitCode(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected error: %w", err)
+		}
+		if code != 0 {
+			passed = false
+			testOutput, err = tested.CombinedOutput(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read test output: %w", err)
+			}
+		}
+	}
+
+	return &UpdateResult{
+		Source:      ctr.Directory("/src"),
+		Summary:     summary(updates, direct, test && len(updates) > 0, passed, testOutput),
+		Updates:     updates,
+		TestsPassed: passed,
+	}, nil
+}
+
+// latestMajorPath returns the module path of the newest major version of a
+// dependency that has been published, which is its current path when no
+// newer major version exists.
+func latestMajorPath(ctx context.Context, ctr *dagger.Container, mod module) (string, error) {
+	// +incompatible versions predate modules and have no /vN path.
+	if strings.HasSuffix(mod.Version, "+incompatible") {
+		return mod.Path, nil
+	}
+
+	path := mod.Path
+	for i := 0; i < maxMajorBumps; i++ {
+		next, ok := nextMajorPath(path)
+		if !ok {
+			break
+		}
+		code, err := ctr.
+			WithExec(
+				[]string{"go", "list", "-m", next + "@latest"},
+				dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny},
+			).
+			ExitCode(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to look up %s: %w", next, err)
+		}
+		if code != 0 {
+			break
+		}
+		path = next
+	}
+	return path, nil
+}
+
+// listModules returns the build list of the module in ctr.
+func listModules(ctx context.Context, ctr *dagger.Container) ([]module, error) {
+	out, err := ctr.
+		WithExec([]string{"go", "list", "-m", "-json", "all"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list modules: %w", err)
+	}
+	return parseModules(out)
+}
+
+// container returns a Go container with the source mounted, the module
+// directory as workdir, and the environment applied.
+func (m *Goupdate) container() (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithDirectory("/src", m.Source).
+		WithWorkdir("/src/" + m.Path)
+
+	for _, env := range m.EnvVars {
+		k, v, ok := strings.Cut(env, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(k, v)
+	}
+
+	return ctr, nil
+}
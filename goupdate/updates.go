@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// majorSuffix matches the "/vN" major version suffix of a module path.
+var majorSuffix = regexp.MustCompile(`/v([2-9]|[1-9][0-9]+)$`)
+
+// DependencyUpdate is a dependency version change.
+type DependencyUpdate struct {
+	// Module path after the update (differs from From's for major updates)
+	Path string
+
+	// Module path before the update
+	FromPath string
+
+	// Version before the update
+	From string
+
+	// Version after the update
+	To string
+
+	// "major", "minor", or "patch"
+	Kind string
+}
+
+// module is an entry of "go list -m -json all".
+type module struct {
+	Path     string
+	Version  string
+	Main     bool
+	Indirect bool
+}
+
+// parseModules decodes the stream of JSON objects "go list -m -json"
+// prints.
+func parseModules(out string) ([]module, error) {
+	var modules []module
+	dec := json.NewDecoder(strings.NewReader(out))
+	for {
+		var m module
+		if err := dec.Decode(&m); err == io.EOF {
+			return modules, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse go list output: %w", err)
+		}
+		modules = append(modules, m)
+	}
+}
+
+// allowed reports whether a module path may be updated: it must match an
+// allow prefix (when any are given) and no deny prefix.
+func allowed(path string, allow, deny []string) bool {
+	for _, d := range deny {
+		if hasPathPrefix(path, d) {
+			return false
+		}
+	}
+	if len(allow) == 0 {
+		return true
+	}
+	for _, a := range allow {
+		if hasPathPrefix(path, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix reports whether path is prefix or is under it, so
+// "github.com/aws" matches "github.com/aws/aws-sdk-go-v2" but
+// "github.com/aw" does not.
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// nextMajorPath returns the module path of the next major version, or false
+// when the module cannot have one (gopkg.in paths encode the major version
+// differently).
+func nextMajorPath(path string) (string, bool) {
+	if strings.HasPrefix(path, "gopkg.in/") {
+		return "", false
+	}
+	if m := majorSuffix.FindStringSubmatch(path); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return strings.TrimSuffix(path, m[0]) + "/v" + strconv.Itoa(n+1), true
+	}
+	return path + "/v2", true
+}
+
+// updateKind classifies a version change as "major", "minor", or "patch".
+func updateKind(fromPath, toPath, from, to string) string {
+	if fromPath != toPath {
+		return "major"
+	}
+	fromMajor, fromMinor := majorMinor(from)
+	toMajor, toMinor := majorMinor(to)
+	switch {
+	case fromMajor != toMajor:
+		return "major"
+	case fromMinor != toMinor:
+		return "minor"
+	default:
+		return "patch"
+	}
+}
+
+// majorMinor returns the major and minor components of a "vX.Y.Z" version.
+func majorMinor(version string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// diffModules returns the updates between two module lists. Major updates
+// are matched through renames, a map from old to new module path.
+func diffModules(before, after []module, renames map[string]string) []DependencyUpdate {
+	versions := map[string]string{}
+	for _, m := range after {
+		versions[m.Path] = m.Version
+	}
+
+	var updates []DependencyUpdate
+	for _, m := range before {
+		if m.Main {
+			continue
+		}
+		path := m.Path
+		if renamed, ok := renames[path]; ok {
+			path = renamed
+		}
+		to, ok := versions[path]
+		if !ok || (path == m.Path && to == m.Version) {
+			continue
+		}
+		updates = append(updates, DependencyUpdate{
+			Path:     path,
+			FromPath: m.Path,
+			From:     m.Version,
+			To:       to,
+			Kind:     updateKind(m.Path, path, m.Version, to),
+		})
+	}
+	return updates
+}
+
+// summary renders updates and the test result as a markdown pull request
+// body.
+func summary(updates []DependencyUpdate, direct map[string]bool, tested, passed bool, testOutput string) string {
+	var b strings.Builder
+	b.WriteString("## Go dependency updates\n\n")
+
+	if len(updates) == 0 {
+		b.WriteString("All dependencies are up to date.\n")
+		return b.String()
+	}
+
+	b.WriteString("| Module | From | To | Update |\n")
+	b.WriteString("|--------|------|----|--------|\n")
+	indirect := 0
+	for _, u := range updates {
+		if !direct[u.FromPath] {
+			indirect++
+			continue
+		}
+		module := "`" + u.Path + "`"
+		if u.FromPath != u.Path {
+			module = "`" + u.FromPath + "` → `" + u.Path + "`"
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | `%s` | %s |\n", module, u.From, u.To, u.Kind)
+	}
+	if indirect > 0 {
+		fmt.Fprintf(&b, "\nIndirect dependencies updated as required: %d.\n", indirect)
+	}
+
+	switch {
+	case !tested:
+		b.WriteString("\nTests were not run.\n")
+	case passed:
+		b.WriteString("\n✅ Tests pass with the updated dependencies.\n")
+	default:
+		b.WriteString("\n❌ Tests fail with the updated dependencies.\n\n<details><summary>Test output</summary>\n\n```\n")
+		b.WriteString(strings.TrimSpace(testOutput))
+		b.WriteString("\n```\n\n</details>\n")
+	}
+
+	return b.String()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// levels orders govulncheck finding precision from weakest to strongest.
+var levels = map[string]int{
+	"module":  1,
+	"package": 2,
+	"symbol":  3,
+}
+
+// message is one object of the "govulncheck -format json" stream. Only the
+// fields used by this module are decoded.
+type message struct {
+	OSV *struct {
+		ID      string   `json:"id"`
+		Aliases []string `json:"aliases"`
+		Summary string   `json:"summary"`
+	} `json:"osv"`
+
+	Finding *struct {
+		OSV          string `json:"osv"`
+		FixedVersion string `json:"fixed_version"`
+		Trace        []struct {
+			Module   string `json:"module"`
+			Version  string `json:"version"`
+			Package  string `json:"package"`
+			Function string `json:"function"`
+		} `json:"trace"`
+	} `json:"finding"`
+}
+
+// vuln is a vulnerability affecting the scanned code at its strongest
+// finding level.
+type vuln struct {
+	id           string
+	aliases      []string
+	summary      string
+	level        string
+	module       string
+	version      string
+	fixedVersion string
+}
+
+// parseFindings decodes a govulncheck JSON stream into one vuln per OSV ID,
+// keeping the strongest finding level for each.
+func parseFindings(r io.Reader) ([]vuln, error) {
+	byID := map[string]*vuln{}
+	get := func(id string) *vuln {
+		v, ok := byID[id]
+		if !ok {
+			v = &vuln{id: id}
+			byID[id] = v
+		}
+		return v
+	}
+
+	found := map[string]bool{}
+	dec := json.NewDecoder(r)
+	for {
+		var msg message
+		err := dec.Decode(&msg)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode govulncheck output: %w", err)
+		}
+
+		if msg.OSV != nil {
+			v := get(msg.OSV.ID)
+			v.aliases = msg.OSV.Aliases
+			v.summary = msg.OSV.Summary
+		}
+
+		// The first trace frame is the vulnerable symbol, package, or
+		// module; the rest lead back to the caller's code.
+		if f := msg.Finding; f != nil && len(f.Trace) > 0 {
+			level := "module"
+			switch {
+			case f.Trace[0].Function != "":
+				level = "symbol"
+			case f.Trace[0].Package != "":
+				level = "package"
+			}
+
+			v := get(f.OSV)
+			found[f.OSV] = true
+			if levels[level] > levels[v.level] {
+				v.level = level
+				v.module = f.Trace[0].Module
+				v.version = f.Trace[0].Version
+				v.fixedVersion = f.FixedVersion
+			}
+		}
+	}
+
+	vulns := []vuln{}
+	for id, v := range byID {
+		if found[id] {
+			vulns = append(vulns, *v)
+		}
+	}
+	sort.Slice(vulns, func(i, j int) bool {
+		return vulns[i].id < vulns[j].id
+	})
+
+	return vulns, nil
+}
+
+// parseIgnoreFile returns the IDs listed in an ignore file: one OSV ID or
+// alias (e.g., "GO-2024-1234" or "CVE-2024-1234") per line, with "#"
+// starting a comment.
+func parseIgnoreFile(content string) map[string]bool {
+	ids := map[string]bool{}
+	for _, line := range strings.Split(content, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		if line = strings.TrimSpace(line); line != "" {
+			ids[line] = true
+		}
+	}
+	return ids
+}
+
+// ignored reports whether the vuln or any of its aliases is in ids.
+func (v vuln) ignored(ids map[string]bool) bool {
+	if ids[v.id] {
+		return true
+	}
+	for _, alias := range v.aliases {
+		if ids[alias] {
+			return true
+		}
+	}
+	return false
+}
+
+// String renders the vuln as a single summary line.
+func (v vuln) String() string {
+	fixed := "no fix available"
+	if v.fixedVersion != "" {
+		fixed = "fixed in " + v.fixedVersion
+	}
+	return fmt.Sprintf("%s [%s] %s@%s (%s): %s", v.id, v.level, v.module, v.version, fixed, v.summary)
+}
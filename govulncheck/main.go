@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dagger/govulncheck/internal/dagger"
+)
+
+const (
+	goImage     string = "golang:1.26-bookworm"
+	govulncheck string = "golang.org/x/vuln/cmd/govulncheck@v1.1.4"
+)
+
+type Govulncheck struct {
+	// Source is the Go source directory to scan.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// IgnoreFile is an optional allowlist of vulnerability IDs.
+	//
+	// +private
+	IgnoreFile *dagger.File
+}
+
+// New creates a new Govulncheck module instance.
+func New(
+	// The Go source directory to scan.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Optional allowlist of vulnerabilities to ignore: one OSV ID or alias
+	// (e.g., "GO-2024-1234" or "CVE-2024-1234") per line, "#" comments allowed.
+	// +optional
+	ignoreFile *dagger.File,
+) *Govulncheck {
+	return &Govulncheck{
+		Source:     source,
+		IgnoreFile: ignoreFile,
+	}
+}
+
+// Scan scans the source directory, and any binaries given, for known
+// vulnerabilities and fails when a vulnerability that is not ignored is found
+// at or above the fail-on level.
+//
+// The Go vulnerability database does not assign CVSS severities, so levels
+// follow govulncheck's precision instead: "symbol" means vulnerable code is
+// actually called, "package" that a vulnerable package is imported, and
+// "module" that a vulnerable module is required. Binaries are only checked
+// with the precision their build information allows.
+func (m *Govulncheck) Scan(
+	ctx context.Context,
+
+	// Weakest finding level that fails the scan: "symbol", "package", or
+	// "module"
+	// +optional
+	// +default="symbol"
+	failOn string,
+
+	// Optional directory of compiled Go binaries to scan as well
+	// +optional
+	binaries *dagger.Directory,
+) (string, error) {
+	if _, ok := levels[failOn]; !ok {
+		return "", fmt.Errorf("invalid fail-on level %q: must be \"symbol\", \"package\", or \"module\"", failOn)
+	}
+
+	ignore := map[string]bool{}
+	if m.IgnoreFile != nil {
+		content, err := m.IgnoreFile.Contents(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to read ignore file: %w", err)
+		}
+		ignore = parseIgnoreFile(content)
+	}
+
+	outputs, err := m.jsonOutputs(ctx, binaries)
+	if err != nil {
+		return "", err
+	}
+
+	targets := make([]string, 0, len(outputs))
+	for target := range outputs {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	var failing, reported, ignored []string
+	for _, target := range targets {
+		vulns, err := parseFindings(strings.NewReader(outputs[target]))
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", target, err)
+		}
+
+		for _, v := range vulns {
+			line := fmt.Sprintf("%s: %s", target, v)
+			switch {
+			case v.ignored(ignore):
+				ignored = append(ignored, line)
+			case levels[v.level] >= levels[failOn]:
+				failing = append(failing, line)
+			default:
+				reported = append(reported, line)
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{
+		{"Vulnerabilities", failing},
+		{"Below fail-on level", reported},
+		{"Ignored", ignored},
+	} {
+		if len(section.lines) > 0 {
+			fmt.Fprintf(&b, "%s:\n  %s\n", section.title, strings.Join(section.lines, "\n  "))
+		}
+	}
+
+	if len(failing) > 0 {
+		return "", fmt.Errorf("%d vulnerabilities at or above %q level\n\n%s", len(failing), failOn, b.String())
+	}
+
+	return fmt.Sprintf("✅ no vulnerabilities at or above %q level\n\n%s", failOn, b.String()), nil
+}
+
+// Check scans the source directory and fails when vulnerable code is called,
+// making this suitable for CI checks.
+//
+// +check
+func (m *Govulncheck) Check(ctx context.Context) (string, error) {
+	return m.Scan(ctx, "symbol", nil)
+}
+
+// Report scans the source directory and returns the raw govulncheck output in
+// the given format ("json", "sarif", or "text") without failing on findings,
+// e.g. for uploading SARIF to GitHub code scanning.
+func (m *Govulncheck) Report(
+	// Output format: "json", "sarif", or "text"
+	// +optional
+	// +default="sarif"
+	format string,
+) (*dagger.File, error) {
+	switch format {
+	case "json", "sarif", "text":
+	default:
+		return nil, fmt.Errorf("invalid format %q: must be \"json\", \"sarif\", or \"text\"", format)
+	}
+
+	name := "govulncheck." + format
+	if format == "text" {
+		name = "govulncheck.txt"
+	}
+
+	return m.govulncheckContainer().
+		WithExec(
+			[]string{"govulncheck", "-format", format, "./..."},
+			dagger.ContainerWithExecOpts{
+				RedirectStdout: "/" + name,
+				// Text output exits non-zero when vulnerabilities are found.
+				Expect: dagger.ReturnTypeAny,
+			},
+		).
+		File("/" + name), nil
+}
+
+// jsonOutputs runs govulncheck in JSON mode against the source directory and
+// every binary, returning the output keyed by scan target.
+func (m *Govulncheck) jsonOutputs(ctx context.Context, binaries *dagger.Directory) (map[string]string, error) {
+	ctr := m.govulncheckContainer()
+
+	out, err := ctr.WithExec([]string{"govulncheck", "-format", "json", "./..."}).Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan source: %w", err)
+	}
+	outputs := map[string]string{"source": out}
+
+	if binaries == nil {
+		return outputs, nil
+	}
+
+	files, err := binaries.Glob(ctx, "**")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list binaries: %w", err)
+	}
+
+	ctr = ctr.WithDirectory("/binaries", binaries)
+	for _, f := range files {
+		// Glob returns directory entries with a trailing slash — skip them.
+		if strings.HasSuffix(f, "/") {
+			continue
+		}
+
+		out, err := ctr.
+			WithExec([]string{"govulncheck", "-mode", "binary", "-format", "json", "/binaries/" + f}).
+			Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan binary %s: %w", f, err)
+		}
+		outputs[f] = out
+	}
+
+	return outputs, nil
+}
+
+// govulncheckContainer returns a container with govulncheck installed, the
+// source mounted, and the Go module and build caches shared with the other
+// Go modules in this daggerverse.
+func (m *Govulncheck) govulncheckContainer() *dagger.Container {
+	return dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithExec([]string{"go", "install", govulncheck}).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dagger/hadolint/internal/dagger"
+)
+
+const (
+	hadolintImage string = "hadolint/hadolint:v2.12.0-alpine"
+)
+
+// thresholds lists the hadolint failure thresholds, most severe first.
+var thresholds = []string{"error", "warning", "info", "style"}
+
+type Hadolint struct {
+	// Source is the directory containing the Dockerfiles.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Config is an optional hadolint configuration file.
+	//
+	// +private
+	Config *dagger.File
+
+	// Ignore is the list of rule IDs to skip.
+	//
+	// +private
+	Ignore []string
+
+	// Include is the list of globs selecting the Dockerfiles to lint.
+	//
+	// +private
+	Include []string
+
+	// Exclude is the list of globs removed from the selection.
+	//
+	// +private
+	Exclude []string
+}
+
+// New creates a new Hadolint module instance.
+func New(
+	// The directory containing the Dockerfiles.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// An optional hadolint configuration file. When not given, a
+	// .hadolint.yaml at the source root is used if present.
+	// +optional
+	config *dagger.File,
+
+	// Rule IDs to skip (e.g., "DL3008", "SC2086")
+	// +optional
+	ignore []string,
+
+	// Globs selecting the Dockerfiles to lint (defaults to "**/Dockerfile",
+	// "**/Dockerfile.*", and "**/*.Dockerfile")
+	// +optional
+	include []string,
+
+	// Globs removed from the selection (e.g., "vendor/**")
+	// +optional
+	exclude []string,
+) *Hadolint {
+	if len(include) == 0 {
+		include = []string{"**/Dockerfile", "**/Dockerfile.*", "**/*.Dockerfile"}
+	}
+	return &Hadolint{
+		Source:  source,
+		Config:  config,
+		Ignore:  ignore,
+		Include: include,
+		Exclude: exclude,
+	}
+}
+
+// Lint runs hadolint on the selected Dockerfiles and fails on any finding at
+// or above the failure threshold.
+func (m *Hadolint) Lint(
+	ctx context.Context,
+
+	// Minimum severity that fails the lint: "error", "warning", "info", or
+	// "style"
+	// +optional
+	// +default="info"
+	failureThreshold string,
+) (string, error) {
+	if !isThreshold(failureThreshold) {
+		return "", fmt.Errorf("invalid failure threshold %q: must be one of %s", failureThreshold, strings.Join(thresholds, ", "))
+	}
+
+	dockerfiles, err := m.dockerfiles(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(dockerfiles) == 0 {
+		return "✅ no Dockerfiles found", nil
+	}
+
+	args := m.buildArgs("--failure-threshold", failureThreshold)
+	_, err = m.hadolintContainer().
+		WithExec(append(args, dockerfiles...)).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("hadolint found problems\n\n%s", e.Stdout)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return fmt.Sprintf("✅ %d Dockerfiles passed hadolint", len(dockerfiles)), nil
+}
+
+// Check runs hadolint on the selected Dockerfiles and fails on warnings and
+// errors, making this suitable for CI checks.
+//
+// +check
+func (m *Hadolint) Check(ctx context.Context) (string, error) {
+	return m.Lint(ctx, "warning")
+}
+
+// Report runs hadolint on the selected Dockerfiles and returns its output in
+// the given format ("sarif", "json", "checkstyle", or "tty") without failing
+// on findings, e.g. for uploading SARIF to GitHub code scanning.
+func (m *Hadolint) Report(
+	ctx context.Context,
+
+	// Output format: "sarif", "json", "checkstyle", or "tty"
+	// +optional
+	// +default="sarif"
+	format string,
+) (*dagger.File, error) {
+	name := "hadolint." + format
+	switch format {
+	case "sarif", "json":
+	case "checkstyle":
+		name = "hadolint.xml"
+	case "tty":
+		name = "hadolint.txt"
+	default:
+		return nil, fmt.Errorf("invalid format %q: must be \"sarif\", \"json\", \"checkstyle\", or \"tty\"", format)
+	}
+
+	dockerfiles, err := m.dockerfiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(dockerfiles) == 0 {
+		return nil, fmt.Errorf("no Dockerfiles found")
+	}
+
+	args := m.buildArgs("--format", format, "--no-fail", "--no-color")
+	return m.hadolintContainer().
+		WithExec(
+			append(args, dockerfiles...),
+			dagger.ContainerWithExecOpts{RedirectStdout: "/" + name},
+		).
+		File("/" + name), nil
+}
+
+// buildArgs constructs the hadolint command arguments. Any extra flags
+// passed in are appended before the Dockerfiles.
+func (m *Hadolint) buildArgs(extra ...string) []string {
+	args := []string{"hadolint"}
+	if m.Config != nil {
+		args = append(args, "--config", "/hadolint.yaml")
+	}
+	for _, rule := range m.Ignore {
+		args = append(args, "--ignore", rule)
+	}
+	return append(args, extra...)
+}
+
+// dockerfiles returns the sorted relative paths of the selected Dockerfiles.
+func (m *Hadolint) dockerfiles(ctx context.Context) ([]string, error) {
+	entries, err := m.Source.
+		Filter(dagger.DirectoryFilterOpts{Include: m.Include, Exclude: m.Exclude}).
+		Glob(ctx, "**/*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Dockerfiles: %w", err)
+	}
+
+	var dockerfiles []string
+	// Glob returns directory entries with a trailing slash — skip them.
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+		dockerfiles = append(dockerfiles, entry)
+	}
+	return dockerfiles, nil
+}
+
+// hadolintContainer returns a hadolint container with the source and config
+// mounted.
+func (m *Hadolint) hadolintContainer() *dagger.Container {
+	ctr := dag.Container().
+		From(hadolintImage).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+
+	if m.Config != nil {
+		ctr = ctr.WithFile("/hadolint.yaml", m.Config)
+	}
+
+	return ctr
+}
+
+func isThreshold(threshold string) bool {
+	for _, t := range thresholds {
+		if threshold == t {
+			return true
+		}
+	}
+	return false
+}
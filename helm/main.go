@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/helm/internal/dagger"
+)
+
+const (
+	helmImage string = "alpine/helm:3.16.3"
+)
+
+type Helm struct {
+	// Chart is the chart directory (containing Chart.yaml).
+	//
+	// +private
+	Chart *dagger.Directory
+}
+
+// New creates a new Helm module instance.
+func New(
+	// The chart directory, containing Chart.yaml.
+	// +defaultPath="/"
+	chart *dagger.Directory,
+) *Helm {
+	return &Helm{
+		Chart: chart,
+	}
+}
+
+// Lint runs "helm lint" against the chart with optional values files and
+// returns the linter output. Fails the pipeline on lint errors.
+func (m *Helm) Lint(
+	ctx context.Context,
+
+	// Values files to lint with
+	// +optional
+	values []*dagger.File,
+
+	// Fail on lint warnings too
+	// +optional
+	strict bool,
+) (string, error) {
+	ctr, args := m.withValues(m.container(), []string{"helm", "lint", "."}, values)
+	if strict {
+		args = append(args, "--strict")
+	}
+
+	return ctr.WithExec(args).Stdout(ctx)
+}
+
+// Check lints the chart with its default values, making this suitable for CI
+// checks.
+//
+// +check
+func (m *Helm) Check(ctx context.Context) (string, error) {
+	return m.Lint(ctx, nil, false)
+}
+
+// Template renders the chart's manifests with "helm template", e.g. for
+// diffing or feeding into manifest validation.
+func (m *Helm) Template(
+	ctx context.Context,
+
+	// Release name
+	// +optional
+	// +default="release"
+	releaseName string,
+
+	// Namespace to render into
+	// +optional
+	namespace string,
+
+	// Values files, applied in order
+	// +optional
+	values []*dagger.File,
+
+	// Individual values in "KEY=VALUE" format (helm --set)
+	// +optional
+	set []string,
+) (string, error) {
+	ctr, args := m.withValues(m.container(), []string{"helm", "template", releaseName, "."}, values)
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	for _, kv := range set {
+		if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+			return "", fmt.Errorf("invalid value %q: must be in KEY=VALUE format", kv)
+		}
+		args = append(args, "--set", kv)
+	}
+
+	return ctr.WithExec(args).Stdout(ctx)
+}
+
+// Package packages the chart into a "<name>-<version>.tgz" archive,
+// optionally overriding the chart version and appVersion (e.g., with the
+// release tag), after fetching chart dependencies.
+func (m *Helm) Package(
+	ctx context.Context,
+
+	// Chart version override (e.g., "1.2.3")
+	// +optional
+	version string,
+
+	// appVersion override (e.g., "v1.2.3")
+	// +optional
+	appVersion string,
+) (*dagger.File, error) {
+	args := []string{"helm", "package", ".", "--destination", "/out"}
+	if version != "" {
+		args = append(args, "--version", strings.TrimPrefix(version, "v"))
+	}
+	if appVersion != "" {
+		args = append(args, "--app-version", appVersion)
+	}
+
+	out := m.container().
+		WithExec([]string{"helm", "dependency", "build"}).
+		WithExec(args).
+		Directory("/out")
+
+	files, err := out.Glob(ctx, "*.tgz")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packaged chart: %w", err)
+	}
+	if len(files) != 1 {
+		return nil, fmt.Errorf("expected one packaged chart, found %d", len(files))
+	}
+
+	return out.File(files[0]), nil
+}
+
+// Push uploads a packaged chart to an OCI registry ("oci://ghcr.io/acme/charts")
+// or a ChartMuseum server ("https://charts.acme.dev"), authenticating with
+// the given credentials, and returns the push output.
+func (m *Helm) Push(
+	ctx context.Context,
+
+	// Packaged chart (.tgz), e.g. from Package
+	chart *dagger.File,
+
+	// Destination: an "oci://" registry path or a ChartMuseum base URL
+	repository string,
+
+	// Registry or ChartMuseum username
+	// +optional
+	username string,
+
+	// Registry token or ChartMuseum password
+	// +optional
+	password *dagger.Secret,
+) (string, error) {
+	ctr := m.container().
+		WithFile("/chart.tgz", chart).
+		WithEnvVariable("HELM_REPOSITORY", repository).
+		WithEnvVariable("HELM_USERNAME", username)
+	if password != nil {
+		ctr = ctr.WithSecretVariable("HELM_PASSWORD", password)
+	}
+
+	var script string
+	switch {
+	case strings.HasPrefix(repository, "oci://"):
+		script = `
+			if [ -n "${HELM_PASSWORD:-}" ]; then
+				HOST=$(echo "${HELM_REPOSITORY#oci://}" | cut -d / -f 1)
+				echo "$HELM_PASSWORD" | helm registry login "$HOST" --username "$HELM_USERNAME" --password-stdin
+			fi
+			helm push /chart.tgz "$HELM_REPOSITORY"
+		`
+	case strings.HasPrefix(repository, "https://"), strings.HasPrefix(repository, "http://"):
+		ctr = ctr.WithExec([]string{"apk", "add", "--no-cache", "curl"})
+		script = `
+			set -- --fail-with-body --silent --show-error --data-binary @/chart.tgz
+			if [ -n "${HELM_PASSWORD:-}" ]; then
+				set -- "$@" --user "$HELM_USERNAME:$HELM_PASSWORD"
+			fi
+			curl "$@" "${HELM_REPOSITORY%/}/api/charts"
+		`
+	default:
+		return "", fmt.Errorf("unsupported repository %q: must start with oci://, https://, or http://", repository)
+	}
+
+	out, err := ctr.
+		WithExec([]string{"sh", "-ec", script}).
+		CombinedOutput(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to push chart to %s: %w", repository, err)
+	}
+
+	return out, nil
+}
+
+// withValues mounts values files into the container and appends the
+// matching --values flags.
+func (m *Helm) withValues(ctr *dagger.Container, args []string, values []*dagger.File) (*dagger.Container, []string) {
+	for i, v := range values {
+		p := fmt.Sprintf("/values/%d.yaml", i)
+		ctr = ctr.WithFile(p, v)
+		args = append(args, "--values", p)
+	}
+	return ctr, args
+}
+
+// container returns a helm container with the chart mounted.
+func (m *Helm) container() *dagger.Container {
+	return dag.Container().
+		From(helmImage).
+		WithMountedCache("/root/.cache/helm", dag.CacheVolume("helm-cache")).
+		WithWorkdir("/chart").
+		WithDirectory("/chart", m.Chart)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultDeny lists files that should never ship in an image: package
+// manager caches, VCS metadata, and credentials.
+var defaultDeny = []string{
+	"/var/cache/apk/*",
+	"/var/lib/apt/lists/*_*",
+	".git",
+	".env",
+	".netrc",
+	".npmrc",
+	".pypirc",
+	".docker/config.json",
+	".aws/credentials",
+	"id_rsa",
+	"id_ecdsa",
+	"id_ed25519",
+	"*.tfstate",
+}
+
+// analysis is the subset of dive's JSON export the budget reads.
+type analysis struct {
+	Layer []struct {
+		Index     int    `json:"index"`
+		DigestID  string `json:"digestId"`
+		SizeBytes int    `json:"sizeBytes"`
+		Command   string `json:"command"`
+	} `json:"layer"`
+	Image struct {
+		SizeBytes        int     `json:"sizeBytes"`
+		InefficientBytes int     `json:"inefficientBytes"`
+		EfficiencyScore  float64 `json:"efficiencyScore"`
+		FileReference    []struct {
+			Count     int    `json:"count"`
+			SizeBytes int    `json:"sizeBytes"`
+			File      string `json:"file"`
+		} `json:"fileReference"`
+	} `json:"image"`
+}
+
+// parseAnalysis decodes dive's JSON export.
+func parseAnalysis(data string) (*analysis, error) {
+	var a analysis
+	if err := json.Unmarshal([]byte(data), &a); err != nil {
+		return nil, fmt.Errorf("failed to parse dive output: %w", err)
+	}
+	return &a, nil
+}
+
+// deniedFile is a denied file found in a layer.
+type deniedFile struct {
+	Layer   int
+	Path    string
+	Pattern string
+}
+
+// findDenied returns the files of every layer matching a deny pattern. The
+// listing holds each layer's "tar -t" output after a "### <index>" line.
+// Files deleted by a later layer are still reported, since they still ship
+// in the earlier one. A denied directory is reported once per layer rather
+// than once per file in it.
+func findDenied(listing string, deny []string) []deniedFile {
+	var found []deniedFile
+	seen := map[string]bool{}
+	layer := -1
+	for _, line := range strings.Split(listing, "\n") {
+		if rest, ok := strings.CutPrefix(line, "### "); ok {
+			layer, _ = strconv.Atoi(rest)
+			continue
+		}
+
+		p := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(line, "./"), "/"), "/")
+		if p == "" || p == "." || strings.HasPrefix(path.Base(p), ".wh.") {
+			continue
+		}
+		for _, pattern := range deny {
+			if match, ok := denied(pattern, p); ok {
+				key := strconv.Itoa(layer) + ":" + match
+				if !seen[key] {
+					seen[key] = true
+					found = append(found, deniedFile{Layer: layer, Path: "/" + match, Pattern: pattern})
+				}
+				break
+			}
+		}
+	}
+	return found
+}
+
+// denied returns the path, or the outermost parent directory of it, that a
+// pattern matches. Patterns starting with a slash match from the image
+// root; others match at any depth, like a .gitignore.
+func denied(pattern, p string) (string, bool) {
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	depth := strings.Count(pattern, "/") + 1
+
+	parts := strings.Split(p, "/")
+	for end := 1; end <= len(parts); end++ {
+		start := end - depth
+		if start < 0 || (anchored && start != 0) {
+			continue
+		}
+		if ok, _ := path.Match(pattern, strings.Join(parts[start:end], "/")); ok {
+			return strings.Join(parts[:end], "/"), true
+		}
+	}
+	return "", false
+}
+
+// budget holds the limits an image is checked against. Zero disables a
+// limit.
+type budget struct {
+	MaxBytes       int
+	MaxWastedBytes int
+	MinEfficiency  float64
+}
+
+// report renders the analysis and denied files as markdown and returns the
+// number of budget violations.
+func report(name string, a *analysis, found []deniedFile, b budget) (string, int) {
+	var s strings.Builder
+	failures := 0
+
+	fmt.Fprintf(&s, "## Image budget: %s\n\n", name)
+
+	check := func(ok bool) string {
+		if ok {
+			return "✅"
+		}
+		failures++
+		return "❌"
+	}
+
+	s.WriteString("| | Metric | Value | Budget |\n")
+	s.WriteString("|-|--------|-------|--------|\n")
+	if b.MaxBytes > 0 {
+		fmt.Fprintf(&s, "| %s | Size | %s | %s |\n",
+			check(a.Image.SizeBytes <= b.MaxBytes), formatBytes(a.Image.SizeBytes), formatBytes(b.MaxBytes))
+	} else {
+		fmt.Fprintf(&s, "| | Size | %s | |\n", formatBytes(a.Image.SizeBytes))
+	}
+	if b.MaxWastedBytes > 0 {
+		fmt.Fprintf(&s, "| %s | Wasted | %s | %s |\n",
+			check(a.Image.InefficientBytes <= b.MaxWastedBytes), formatBytes(a.Image.InefficientBytes), formatBytes(b.MaxWastedBytes))
+	} else {
+		fmt.Fprintf(&s, "| | Wasted | %s | |\n", formatBytes(a.Image.InefficientBytes))
+	}
+	if b.MinEfficiency > 0 {
+		fmt.Fprintf(&s, "| %s | Efficiency | %.1f%% | ≥ %.1f%% |\n",
+			check(a.Image.EfficiencyScore >= b.MinEfficiency), a.Image.EfficiencyScore*100, b.MinEfficiency*100)
+	} else {
+		fmt.Fprintf(&s, "| | Efficiency | %.1f%% | |\n", a.Image.EfficiencyScore*100)
+	}
+	fmt.Fprintf(&s, "| %s | Denied files | %d | 0 |\n", check(len(found) == 0), len(found))
+
+	s.WriteString("\n### Layers\n\n")
+	s.WriteString("| # | Size | Command |\n")
+	s.WriteString("|---|------|---------|\n")
+	for _, l := range a.Layer {
+		fmt.Fprintf(&s, "| %d | %s | `%s` |\n", l.Index, formatBytes(l.SizeBytes), command(l.Command))
+	}
+
+	if len(found) > 0 {
+		s.WriteString("\n### Denied files\n\n")
+		s.WriteString("| Layer | File | Pattern |\n")
+		s.WriteString("|-------|------|---------|\n")
+		for _, f := range found {
+			fmt.Fprintf(&s, "| %d | `%s` | `%s` |\n", f.Layer, f.Path, f.Pattern)
+		}
+	}
+
+	// Largest wasted files first; duplicated or deleted-but-shipped files
+	// are what shrinking an image usually starts with.
+	wasted := a.Image.FileReference
+	sort.SliceStable(wasted, func(i, j int) bool { return wasted[i].SizeBytes > wasted[j].SizeBytes })
+	if len(wasted) > 10 {
+		wasted = wasted[:10]
+	}
+	if len(wasted) > 0 {
+		s.WriteString("\n### Largest wasted files\n\n")
+		s.WriteString("| File | Copies | Wasted |\n")
+		s.WriteString("|------|--------|--------|\n")
+		for _, f := range wasted {
+			fmt.Fprintf(&s, "| `%s` | %d | %s |\n", f.File, f.Count, formatBytes(f.SizeBytes))
+		}
+	}
+
+	return s.String(), failures
+}
+
+// command shortens a layer's build command for a table cell.
+func command(c string) string {
+	c = strings.Join(strings.Fields(c), " ")
+	c = strings.ReplaceAll(c, "|", `\|`)
+	c = strings.ReplaceAll(c, "`", "'")
+	if len(c) > 80 {
+		c = c[:77] + "..."
+	}
+	return c
+}
+
+// formatBytes renders a byte count using binary units (e.g. "12.3 MiB").
+func formatBytes(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := int64(n) / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"dagger/imagebudget/internal/dagger"
+)
+
+const (
+	alpineImage string = "alpine:3.21"
+	diveImage   string = "wagoodman/dive:v0.12.0"
+)
+
+// listScript prints the files of every layer of the docker-archive tarball
+// at /image.tar, each layer after a "### <index>" line.
+const listScript string = `set -eu
+mkdir /image
+tar -xf /image.tar -C /image
+i=0
+for layer in $(jq -r '.[0].Layers[]' /image/manifest.json); do
+	echo "### $i"
+	tar -tf "/image/$layer"
+	i=$((i+1))
+done
+`
+
+type Imagebudget struct {
+	// MaxBytes is the maximum image size in bytes.
+	//
+	// +private
+	MaxBytes int
+
+	// MaxWastedBytes is the maximum bytes wasted on duplicated or deleted
+	// files.
+	//
+	// +private
+	MaxWastedBytes int
+
+	// MinEfficiency is the minimum dive efficiency score.
+	//
+	// +private
+	MinEfficiency float64
+
+	// Deny lists patterns of files no layer may contain.
+	//
+	// +private
+	Deny []string
+}
+
+// New creates a new Imagebudget module instance.
+func New(
+	// Maximum uncompressed image size in bytes (0 disables the limit)
+	// +optional
+	maxBytes int,
+
+	// Maximum bytes wasted on files duplicated across layers or deleted by
+	// a later layer (0 disables the limit)
+	// +optional
+	maxWastedBytes int,
+
+	// Minimum efficiency score between 0 and 1, the share of image bytes
+	// not wasted (0 disables the limit)
+	// +optional
+	minEfficiency float64,
+
+	// Patterns of files no layer may contain. Patterns starting with "/"
+	// match from the image root; others match at any depth (e.g. ".git",
+	// "*.pem"). Defaults to package manager caches, VCS metadata, and
+	// common credential files.
+	// +optional
+	deny []string,
+) *Imagebudget {
+	if len(deny) == 0 {
+		deny = defaultDeny
+	}
+
+	return &Imagebudget{
+		MaxBytes:       maxBytes,
+		MaxWastedBytes: maxWastedBytes,
+		MinEfficiency:  minEfficiency,
+		Deny:           deny,
+	}
+}
+
+// Report analyzes an image with dive and returns its size, wasted bytes,
+// efficiency, layer sizes, and denied files against the budget as
+// markdown, without failing.
+func (m *Imagebudget) Report(
+	ctx context.Context,
+
+	// Image reference (e.g., "ghcr.io/acme/myapp:v1.2.3")
+	// +optional
+	imageRef string,
+
+	// Container to analyze instead of an image reference
+	// +optional
+	ctr *dagger.Container,
+) (string, error) {
+	out, _, err := m.analyze(ctx, imageRef, ctr)
+	return out, err
+}
+
+// Check analyzes an image like Report and fails with the report if it
+// exceeds the budget or contains denied files.
+func (m *Imagebudget) Check(
+	ctx context.Context,
+
+	// Image reference (e.g., "ghcr.io/acme/myapp:v1.2.3")
+	// +optional
+	imageRef string,
+
+	// Container to analyze instead of an image reference
+	// +optional
+	ctr *dagger.Container,
+) (string, error) {
+	out, failures, err := m.analyze(ctx, imageRef, ctr)
+	if err != nil {
+		return "", err
+	}
+	if failures > 0 {
+		return "", fmt.Errorf("%d image budget violation(s):\n\n%s", failures, out)
+	}
+	return out, nil
+}
+
+// analyze returns the markdown report for an image and the number of
+// budget violations.
+func (m *Imagebudget) analyze(ctx context.Context, imageRef string, ctr *dagger.Container) (string, int, error) {
+	name := imageRef
+	switch {
+	case ctr != nil:
+		if name == "" {
+			name = "container"
+		}
+	case imageRef != "":
+		ctr = dag.Container().From(imageRef)
+	default:
+		return "", 0, fmt.Errorf("either image-ref or ctr is required")
+	}
+	tarball := ctr.AsTarball()
+
+	data, err := dag.Container().
+		From(diveImage).
+		WithFile("/image.tar", tarball).
+		WithExec([]string{"/usr/local/bin/dive", "--source", "docker-archive", "/image.tar", "--json", "/dive.json"}).
+		File("/dive.json").
+		Contents(ctx)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to analyze image: %w", err)
+	}
+	a, err := parseAnalysis(data)
+	if err != nil {
+		return "", 0, err
+	}
+
+	listing, err := dag.Container().
+		From(alpineImage).
+		WithExec([]string{"apk", "add", "--no-cache", "jq", "tar"}).
+		WithFile("/image.tar", tarball).
+		WithExec([]string{"sh", "-c", listScript}).
+		Stdout(ctx)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to list image files: %w", err)
+	}
+
+	out, failures := report(name, a, findDenied(listing, m.Deny), budget{
+		MaxBytes:       m.MaxBytes,
+		MaxWastedBytes: m.MaxWastedBytes,
+		MinEfficiency:  m.MinEfficiency,
+	})
+	return out, failures, nil
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/imagebuild/internal/dagger"
+)
+
+type Imagebuild struct {
+	// Ctrs are the images being built, one per platform. A single element
+	// publishes a plain image; more publish a multi-arch image index.
+	//
+	// +private
+	Ctrs []*dagger.Container
+}
+
+// New creates a new Imagebuild module instance. Start the image with
+// FromDockerfile, FromBinary, FromBuild, or WithContainer.
+func New() *Imagebuild {
+	return &Imagebuild{}
+}
+
+// FromDockerfile builds the image from a Dockerfile, supporting multi-stage
+// builds with target stage selection and build args. Each platform is built
+// separately and published together as a multi-arch image index.
+func (m *Imagebuild) FromDockerfile(
+	// Build context directory
+	buildContext *dagger.Directory,
+
+	// Path to the Dockerfile, relative to the build context
+	// +optional
+	// +default="Dockerfile"
+	dockerfile string,
+
+	// Target stage to build in a multi-stage Dockerfile
+	// +optional
+	target string,
+
+	// Build args in "KEY=VALUE" format
+	// +optional
+	buildArgs []string,
+
+	// Target platforms (e.g., "linux/amd64", "linux/arm64"). Defaults to the
+	// engine's platform.
+	// +optional
+	platforms []string,
+) (*Imagebuild, error) {
+	args, err := parseKeyValues("build arg", buildArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	var buildArgList []dagger.BuildArg
+	for _, kv := range args {
+		buildArgList = append(buildArgList, dagger.BuildArg{Name: kv[0], Value: kv[1]})
+	}
+
+	m.Ctrs = nil
+	for _, platform := range platformsOrDefault(platforms) {
+		m.Ctrs = append(m.Ctrs, buildContext.DockerBuild(dagger.DirectoryDockerBuildOpts{
+			Dockerfile: dockerfile,
+			Target:     target,
+			BuildArgs:  buildArgList,
+			Platform:   dagger.Platform(platform),
+		}))
+	}
+	return m, nil
+}
+
+// FromBinary builds the image natively from a single binary copied onto a
+// base image and set as the entrypoint, with no Dockerfile needed.
+func (m *Imagebuild) FromBinary(
+	ctx context.Context,
+
+	// Binary to run in the image
+	binary *dagger.File,
+
+	// Base image
+	// +optional
+	// +default="gcr.io/distroless/static-debian12:nonroot"
+	base string,
+
+	// Path of the binary inside the image
+	// +optional
+	// +default="/usr/local/bin/app"
+	path string,
+
+	// Target platform (e.g., "linux/arm64"). Must match the binary.
+	// Defaults to the engine's platform.
+	// +optional
+	platform string,
+) (*Imagebuild, error) {
+	ctr, err := binaryImage(ctx, binary, base, path, dagger.Platform(platform))
+	if err != nil {
+		return nil, err
+	}
+
+	m.Ctrs = []*dagger.Container{ctr}
+	return m, nil
+}
+
+// FromBuild builds a multi-arch image from a build directory in the
+// <os>/<arch>/<binary> layout produced by the gobuild module: for each
+// platform the matching binary is copied onto the base image.
+func (m *Imagebuild) FromBuild(
+	ctx context.Context,
+
+	// Build output in <os>/<arch>/<binary> layout
+	build *dagger.Directory,
+
+	// Binary name inside each <os>/<arch> directory
+	name string,
+
+	// Base image. Must be a multi-arch image covering every platform.
+	// +optional
+	// +default="gcr.io/distroless/static-debian12:nonroot"
+	base string,
+
+	// Path of the binary inside the image
+	// +optional
+	// +default="/usr/local/bin/app"
+	path string,
+
+	// Platforms to include. Defaults to linux/amd64 and linux/arm64.
+	// +optional
+	platforms []string,
+) (*Imagebuild, error) {
+	if len(platforms) == 0 {
+		platforms = []string{"linux/amd64", "linux/arm64"}
+	}
+
+	m.Ctrs = nil
+	for _, platform := range platforms {
+		ctr, err := binaryImage(ctx, build.File(platform+"/"+name), base, path, dagger.Platform(platform))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", platform, err)
+		}
+		m.Ctrs = append(m.Ctrs, ctr)
+	}
+	return m, nil
+}
+
+// WithContainer starts from existing containers, e.g. ones assembled by
+// another module. Pass one container per platform for a multi-arch image.
+func (m *Imagebuild) WithContainer(
+	// Containers to start from, one per platform
+	ctrs []*dagger.Container,
+) *Imagebuild {
+	m.Ctrs = ctrs
+	return m
+}
+
+// WithLabels adds image config labels in "KEY=VALUE" format to every
+// platform.
+func (m *Imagebuild) WithLabels(
+	// Labels in "KEY=VALUE" format
+	labels []string,
+) (*Imagebuild, error) {
+	kvs, err := parseKeyValues("label", labels)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.apply(func(ctr *dagger.Container) *dagger.Container {
+		for _, kv := range kvs {
+			ctr = ctr.WithLabel(kv[0], kv[1])
+		}
+		return ctr
+	})
+}
+
+// WithAnnotations adds OCI manifest annotations in "KEY=VALUE" format to
+// every platform.
+func (m *Imagebuild) WithAnnotations(
+	// Annotations in "KEY=VALUE" format
+	annotations []string,
+) (*Imagebuild, error) {
+	kvs, err := parseKeyValues("annotation", annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.apply(func(ctr *dagger.Container) *dagger.Container {
+		for _, kv := range kvs {
+			ctr = ctr.WithAnnotation(kv[0], kv[1])
+		}
+		return ctr
+	})
+}
+
+// WithOciMetadata sets the standard org.opencontainers.image.* keys as both
+// labels and annotations on every platform. Empty values are skipped.
+func (m *Imagebuild) WithOciMetadata(
+	// Human-readable image title
+	// +optional
+	title string,
+
+	// Image version (e.g., "v1.2.3")
+	// +optional
+	version string,
+
+	// Source control revision the image was built from
+	// +optional
+	revision string,
+
+	// URL of the source repository
+	// +optional
+	source string,
+
+	// Build time in RFC 3339 format
+	// +optional
+	created string,
+
+	// SPDX license expression
+	// +optional
+	licenses string,
+) (*Imagebuild, error) {
+	return m.apply(func(ctr *dagger.Container) *dagger.Container {
+		for _, kv := range [][2]string{
+			{"title", title},
+			{"version", version},
+			{"revision", revision},
+			{"source", source},
+			{"created", created},
+			{"licenses", licenses},
+		} {
+			if kv[1] == "" {
+				continue
+			}
+			key := "org.opencontainers.image." + kv[0]
+			ctr = ctr.
+				WithLabel(key, kv[1]).
+				WithAnnotation(key, kv[1])
+		}
+		return ctr
+	})
+}
+
+// WithRegistryAuth authenticates to a registry for Publish.
+func (m *Imagebuild) WithRegistryAuth(
+	// Registry address (e.g., "ghcr.io")
+	address string,
+
+	// Registry username
+	username string,
+
+	// Registry token or password
+	token *dagger.Secret,
+) (*Imagebuild, error) {
+	return m.apply(func(ctr *dagger.Container) *dagger.Container {
+		return ctr.WithRegistryAuth(address, username, token)
+	})
+}
+
+// Container returns the built image. Fails for multi-arch builds; use
+// Variants instead.
+func (m *Imagebuild) Container() (*dagger.Container, error) {
+	if err := m.requireImage(); err != nil {
+		return nil, err
+	}
+	if len(m.Ctrs) > 1 {
+		return nil, fmt.Errorf("multi-arch build has %d platform variants: use variants instead", len(m.Ctrs))
+	}
+	return m.Ctrs[0], nil
+}
+
+// Variants returns the built image for every platform.
+func (m *Imagebuild) Variants() ([]*dagger.Container, error) {
+	if err := m.requireImage(); err != nil {
+		return nil, err
+	}
+	return m.Ctrs, nil
+}
+
+// Tarball returns the image, or multi-arch image index, as an OCI tarball.
+func (m *Imagebuild) Tarball() (*dagger.File, error) {
+	if err := m.requireImage(); err != nil {
+		return nil, err
+	}
+	return dag.Container().AsTarball(dagger.ContainerAsTarballOpts{
+		PlatformVariants: m.Ctrs,
+	}), nil
+}
+
+// Publish pushes the image to each reference and returns the pushed
+// references with their digests, one per line. Multi-arch builds are pushed
+// as a single image index referencing every platform.
+func (m *Imagebuild) Publish(
+	ctx context.Context,
+
+	// Image references (e.g., "ghcr.io/acme/myapp:v1.2.3")
+	refs []string,
+) (string, error) {
+	if err := m.requireImage(); err != nil {
+		return "", err
+	}
+	if len(refs) == 0 {
+		return "", fmt.Errorf("no image references given")
+	}
+
+	pushed := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		addr, err := m.Ctrs[0].Publish(ctx, ref, dagger.ContainerPublishOpts{
+			PlatformVariants: m.Ctrs[1:],
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to publish %s: %w", ref, err)
+		}
+		pushed = append(pushed, addr)
+	}
+
+	return strings.Join(pushed, "\n"), nil
+}
+
+// apply transforms every platform variant.
+func (m *Imagebuild) apply(fn func(*dagger.Container) *dagger.Container) (*Imagebuild, error) {
+	if err := m.requireImage(); err != nil {
+		return nil, err
+	}
+	for i, ctr := range m.Ctrs {
+		m.Ctrs[i] = fn(ctr)
+	}
+	return m, nil
+}
+
+// requireImage fails when no image has been started yet.
+func (m *Imagebuild) requireImage() error {
+	if len(m.Ctrs) == 0 {
+		return fmt.Errorf("no image: call from-dockerfile, from-binary, from-build, or with-container first")
+	}
+	return nil
+}
+
+// binaryImage copies a binary onto a base image for one platform, recording
+// the base image name and the digest it resolved to for that platform as
+// provenance annotations.
+func binaryImage(
+	ctx context.Context,
+	binary *dagger.File,
+	base, path string,
+	platform dagger.Platform,
+) (*dagger.Container, error) {
+	ctr := dag.Container(dagger.ContainerOpts{Platform: platform}).From(base)
+
+	ref, err := ctr.ImageRef(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve base image %s: %w", base, err)
+	}
+
+	_, digest, _ := strings.Cut(ref, "@")
+
+	return ctr.
+		WithFile(path, binary, dagger.ContainerWithFileOpts{Permissions: 0o755}).
+		WithEntrypoint([]string{path}).
+		WithLabel("org.opencontainers.image.base.name", base).
+		WithAnnotation("org.opencontainers.image.base.name", base).
+		WithAnnotation("org.opencontainers.image.base.digest", digest), nil
+}
+
+// platformsOrDefault returns platforms, or the engine's platform when empty.
+func platformsOrDefault(platforms []string) []string {
+	if len(platforms) == 0 {
+		return []string{""}
+	}
+	return platforms
+}
+
+// parseKeyValues splits "KEY=VALUE" entries, failing on malformed ones.
+func parseKeyValues(kind string, entries []string) ([][2]string, error) {
+	kvs := make([][2]string, 0, len(entries))
+	for _, entry := range entries {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid %s %q: must be in KEY=VALUE format", kind, entry)
+		}
+		kvs = append(kvs, [2]string{parts[0], parts[1]})
+	}
+	return kvs, nil
+}
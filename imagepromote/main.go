@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"dagger/imagepromote/internal/dagger"
+)
+
+const (
+	craneImage string = "gcr.io/go-containerregistry/crane:v0.20.3"
+)
+
+// signatureSuffixes are the tag suffixes cosign stores signatures,
+// attestations, and SBOMs under, next to the image as "sha256-<hex><suffix>".
+var signatureSuffixes = []string{".sig", ".att", ".sbom"}
+
+type Imagepromote struct {
+	// RegistryAddresses are the registries added with WithRegistryAuth.
+	//
+	// +private
+	RegistryAddresses []string
+
+	// RegistryUsernames are the usernames for RegistryAddresses, by index.
+	//
+	// +private
+	RegistryUsernames []string
+
+	// RegistryTokens are the tokens for RegistryAddresses, by index.
+	//
+	// +private
+	RegistryTokens []*dagger.Secret
+}
+
+// New creates a new Imagepromote module instance.
+func New() *Imagepromote {
+	return &Imagepromote{}
+}
+
+// WithRegistryAuth adds credentials for a registry, used to read, verify,
+// and push images. Chain once per registry.
+func (m *Imagepromote) WithRegistryAuth(
+	// Registry address (e.g., "ghcr.io")
+	address string,
+
+	// Registry username
+	username string,
+
+	// Registry token or password
+	token *dagger.Secret,
+) *Imagepromote {
+	m.RegistryAddresses = append(m.RegistryAddresses, address)
+	m.RegistryUsernames = append(m.RegistryUsernames, username)
+	m.RegistryTokens = append(m.RegistryTokens, token)
+	return m
+}
+
+// Digest returns the digest an image reference points to.
+func (m *Imagepromote) Digest(
+	ctx context.Context,
+
+	// Image reference (e.g., "ghcr.io/acme/myapp:staging")
+	ref string,
+) (string, error) {
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := ctr.
+		WithExec([]string{"digest", ref}, dagger.ContainerWithExecOpts{UseEntrypoint: true}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Copy copies an image, by digest, to another repository or registry and
+// returns the destination digest reference. The digest is resolved once,
+// so a source tag moving during the copy cannot change what is copied.
+func (m *Imagepromote) Copy(
+	ctx context.Context,
+
+	// Source image reference (e.g., "ghcr.io/acme/myapp:staging")
+	source string,
+
+	// Destination image reference (e.g., "registry.acme.dev/myapp:prod")
+	destination string,
+
+	// Also copy cosign signatures, attestations, and SBOMs of the image
+	// +optional
+	// +default=true
+	copySignatures bool,
+) (string, error) {
+	digest, err := m.Digest(ctx, source)
+	if err != nil {
+		return "", err
+	}
+
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	srcRepo, dstRepo := repository(source), repository(destination)
+	ctr = ctr.WithExec(
+		[]string{"copy", srcRepo + "@" + digest, destination},
+		dagger.ContainerWithExecOpts{UseEntrypoint: true},
+	)
+
+	if copySignatures {
+		for _, suffix := range signatureSuffixes {
+			tag := strings.Replace(digest, ":", "-", 1) + suffix
+			// Not every image has every kind of artifact; skip missing ones.
+			ctr = ctr.WithExec([]string{
+				"copy", srcRepo + ":" + tag, dstRepo + ":" + tag,
+			}, dagger.ContainerWithExecOpts{UseEntrypoint: true, Expect: dagger.ReturnTypeAny})
+		}
+	}
+
+	if _, err := ctr.Sync(ctx); err != nil {
+		return "", fmt.Errorf("failed to copy %s to %s: %w", source, destination, err)
+	}
+
+	return dstRepo + "@" + digest, nil
+}
+
+// Tag adds tags to an image in its own repository, e.g. "latest" to a
+// nightly build, without copying any data.
+func (m *Imagepromote) Tag(
+	ctx context.Context,
+
+	// Image reference, by tag or digest
+	ref string,
+
+	// Tags to add (e.g., "latest", "v1.2")
+	tags []string,
+) (string, error) {
+	digest, err := m.Digest(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	digestRef := repository(ref) + "@" + digest
+	for _, tag := range tags {
+		ctr = ctr.WithExec([]string{"tag", digestRef, tag}, dagger.ContainerWithExecOpts{UseEntrypoint: true})
+	}
+	if _, err := ctr.Sync(ctx); err != nil {
+		return "", fmt.Errorf("failed to tag %s: %w", ref, err)
+	}
+
+	return digestRef, nil
+}
+
+// Promote verifies the source image's signature and then copies it by
+// digest to the destination, with its signatures, and adds any extra tags
+// (e.g., staging→prod, nightly→latest). Verification uses a public key when
+// key is given, otherwise the keyless certificate identity and OIDC issuer.
+// Returns the promoted digest reference.
+func (m *Imagepromote) Promote(
+	ctx context.Context,
+
+	// Source image reference (e.g., "ghcr.io/acme/myapp:staging")
+	source string,
+
+	// Destination image reference (e.g., "ghcr.io/acme/myapp:prod")
+	destination string,
+
+	// Extra tags to add to the destination image
+	// +optional
+	tags []string,
+
+	// Public key (cosign.pub) for key-based verification
+	// +optional
+	key *dagger.File,
+
+	// Expected signer identity for keyless verification as a regular
+	// expression (e.g., "^https://github.com/acme/myapp/")
+	// +optional
+	certificateIdentityRegexp string,
+
+	// Expected OIDC issuer for keyless verification
+	// +optional
+	// +default="https://token.actions.githubusercontent.com"
+	certificateOidcIssuer string,
+
+	// Promote without verifying the signature
+	// +optional
+	insecureSkipVerify bool,
+) (string, error) {
+	digest, err := m.Digest(ctx, source)
+	if err != nil {
+		return "", err
+	}
+	digestRef := repository(source) + "@" + digest
+
+	if !insecureSkipVerify {
+		if key == nil && certificateIdentityRegexp == "" {
+			return "", fmt.Errorf("either key or certificate-identity-regexp is required to verify, or set insecure-skip-verify")
+		}
+
+		verifier := dag.Cosign()
+		for i, address := range m.RegistryAddresses {
+			verifier = verifier.WithRegistryAuth(address, m.RegistryUsernames[i], m.RegistryTokens[i])
+		}
+		if _, err := verifier.Verify(ctx, digestRef, dagger.CosignVerifyOpts{
+			Key:                       key,
+			CertificateIdentityRegexp: certificateIdentityRegexp,
+			CertificateOidcIssuer:     certificateOidcIssuer,
+		}); err != nil {
+			return "", fmt.Errorf("refusing to promote %s: %w", source, err)
+		}
+	}
+
+	promoted, err := m.Copy(ctx, digestRef, destination, true)
+	if err != nil {
+		return "", err
+	}
+
+	if len(tags) > 0 {
+		if _, err := m.Tag(ctx, promoted, tags); err != nil {
+			return "", err
+		}
+	}
+
+	return promoted, nil
+}
+
+// repository returns an image reference without its tag or digest.
+func repository(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+	return ref
+}
+
+// container returns the crane container with a Docker config.json holding
+// the registry credentials mounted as a secret.
+func (m *Imagepromote) container(ctx context.Context) (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(craneImage).
+		// Tags move between promotions; always look them up.
+		WithEnvVariable("IMAGEPROMOTE_SESSION", fmt.Sprintf("%d", time.Now().UnixNano()))
+	if len(m.RegistryAddresses) == 0 {
+		return ctr, nil
+	}
+
+	auths := map[string]map[string]string{}
+	for i, address := range m.RegistryAddresses {
+		token, err := m.RegistryTokens[i].Plaintext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token for %s: %w", address, err)
+		}
+		auths[address] = map[string]string{
+			"auth": base64.StdEncoding.EncodeToString([]byte(m.RegistryUsernames[i] + ":" + token)),
+		}
+	}
+
+	config, err := json.Marshal(map[string]any{"auths": auths})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode registry config: %w", err)
+	}
+
+	return ctr.
+		WithMountedSecret("/docker/config.json", dag.SetSecret("imagepromote-docker-config", string(config))).
+		WithEnvVariable("DOCKER_CONFIG", "/docker"), nil
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/k8svalidate/internal/dagger"
+)
+
+const (
+	alpineImage      string = "alpine:3.21"
+	kubeconformImage string = "ghcr.io/yannh/kubeconform:v0.6.7-alpine"
+
+	// crdCatalog is the community catalog of JSON schemas for popular CRDs
+	// (Argo, cert-manager, Prometheus operator, ...).
+	crdCatalog string = "https://raw.githubusercontent.com/datreeio/CRDs-catalog/main/{{.Group}}/{{.ResourceKind}}_{{.ResourceAPIVersion}}.json"
+
+	// localSchemas is where the schemas directory is mounted, laid out the
+	// same way as crdCatalog.
+	localSchemas string = "/schemas/{{.Group}}/{{.ResourceKind}}_{{.ResourceAPIVersion}}.json"
+)
+
+type K8Svalidate struct {
+	// Source is the repository containing the manifests.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Overlays are kustomization directories to build, relative to Source.
+	//
+	// +private
+	Overlays []string
+
+	// Manifests are directories of plain manifests, relative to Source.
+	//
+	// +private
+	Manifests []string
+
+	// KubernetesVersions are the versions to validate against.
+	//
+	// +private
+	KubernetesVersions []string
+
+	// SchemaLocations are extra kubeconform schema locations.
+	//
+	// +private
+	SchemaLocations []string
+
+	// Schemas is an optional directory of local CRD JSON schemas.
+	//
+	// +private
+	Schemas *dagger.Directory
+
+	// SkipKinds are resource kinds not validated.
+	//
+	// +private
+	SkipKinds []string
+
+	// IgnoreMissingSchemas skips resources without a known schema.
+	//
+	// +private
+	IgnoreMissingSchemas bool
+}
+
+// New creates a new K8Svalidate module instance.
+func New(
+	// The repository containing the manifests.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Kustomization directories to build and validate, relative to source.
+	// When neither overlays nor manifests are given, every directory with a
+	// kustomization.yaml is used.
+	// +optional
+	overlays []string,
+
+	// Directories of plain manifests to validate, relative to source
+	// +optional
+	manifests []string,
+
+	// Kubernetes versions to validate against (default: 1.31.0)
+	// +optional
+	kubernetesVersions []string,
+
+	// Extra kubeconform schema locations (URL or path templates), checked
+	// after the built-in Kubernetes schemas and the CRD catalog
+	// +optional
+	schemaLocations []string,
+
+	// Directory of CRD JSON schemas laid out as
+	// <group>/<kind>_<version>.json, e.g. generated from in-house CRDs
+	// +optional
+	schemas *dagger.Directory,
+
+	// Resource kinds to skip (e.g., "SealedSecret")
+	// +optional
+	skipKinds []string,
+
+	// Skip resources without a known schema instead of failing
+	// +optional
+	ignoreMissingSchemas bool,
+) *K8Svalidate {
+	if len(kubernetesVersions) == 0 {
+		kubernetesVersions = []string{"1.31.0"}
+	}
+	return &K8Svalidate{
+		Source:               source,
+		Overlays:             overlays,
+		Manifests:            manifests,
+		KubernetesVersions:   kubernetesVersions,
+		SchemaLocations:      schemaLocations,
+		Schemas:              schemas,
+		SkipKinds:            skipKinds,
+		IgnoreMissingSchemas: ignoreMissingSchemas,
+	}
+}
+
+// Build runs "kustomize build" for an overlay and returns the rendered
+// manifests.
+func (m *K8Svalidate) Build(
+	// Kustomization directory, relative to source (e.g., "deploy/overlays/prod")
+	overlay string,
+) *dagger.File {
+	return m.container().
+		WithExec([]string{"kustomize", "build", overlay, "--output", "/rendered.yaml"}).
+		File("/rendered.yaml")
+}
+
+// Validate builds every overlay and validates the rendered and plain
+// manifests with kubeconform in strict mode, so unknown fields fail, against
+// each Kubernetes version.
+func (m *K8Svalidate) Validate(ctx context.Context) (string, error) {
+	ctr, err := m.rendered(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	for _, version := range m.KubernetesVersions {
+		res, err := ctr.
+			WithExec(m.kubeconformArgs(version, "-summary", "-output", "text")).
+			Stdout(ctx)
+
+		var e *dagger.ExecError
+		if errors.As(err, &e) {
+			return "", fmt.Errorf("manifests are invalid for Kubernetes %s\n\n%s%s", version, e.Stdout, e.Stderr)
+		} else if err != nil {
+			return "", fmt.Errorf("unexpected error: %w", err)
+		}
+
+		fmt.Fprintf(&out, "Kubernetes %s:\n%s\n", version, res)
+	}
+
+	return out.String(), nil
+}
+
+// Check validates every overlay and manifest directory, making this
+// suitable for CI checks.
+//
+// +check
+func (m *K8Svalidate) Check(ctx context.Context) (string, error) {
+	return m.Validate(ctx)
+}
+
+// Report validates against the first Kubernetes version and returns the
+// results in the given format ("json", "junit", or "tap") without failing.
+func (m *K8Svalidate) Report(
+	ctx context.Context,
+
+	// Report format: "json", "junit", or "tap"
+	// +optional
+	// +default="junit"
+	format string,
+) (*dagger.File, error) {
+	ext := format
+	switch format {
+	case "json", "tap":
+	case "junit":
+		ext = "xml"
+	default:
+		return nil, fmt.Errorf("invalid format %q: must be \"json\", \"junit\", or \"tap\"", format)
+	}
+
+	ctr, err := m.rendered(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name := "/k8svalidate." + ext
+	return ctr.
+		WithExec(
+			m.kubeconformArgs(m.KubernetesVersions[0], "-output", format),
+			dagger.ContainerWithExecOpts{RedirectStdout: name, Expect: dagger.ReturnTypeAny},
+		).
+		File(name), nil
+}
+
+// rendered returns the container with every overlay built into
+// /rendered/<overlay>.yaml and every plain manifest directory copied under
+// /rendered.
+func (m *K8Svalidate) rendered(ctx context.Context) (*dagger.Container, error) {
+	overlays := m.Overlays
+	if len(overlays) == 0 && len(m.Manifests) == 0 {
+		found, err := m.Source.Glob(ctx, "**/kustomization.y*ml")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find kustomizations: %w", err)
+		}
+		for _, f := range found {
+			overlays = append(overlays, path.Dir(f))
+		}
+	}
+	if len(overlays) == 0 && len(m.Manifests) == 0 {
+		return nil, fmt.Errorf("no kustomizations or manifests to validate")
+	}
+
+	ctr := m.container().WithExec([]string{"mkdir", "-p", "/rendered"})
+	for _, overlay := range overlays {
+		name := strings.ReplaceAll(path.Clean(overlay), "/", "_") + ".yaml"
+		ctr = ctr.WithExec([]string{"kustomize", "build", overlay, "--output", "/rendered/" + name})
+	}
+	for _, dir := range m.Manifests {
+		ctr = ctr.WithDirectory("/rendered/"+strings.ReplaceAll(path.Clean(dir), "/", "_"), m.Source.Directory(dir))
+	}
+
+	return ctr, nil
+}
+
+// kubeconformArgs builds the kubeconform command line for a Kubernetes
+// version. Any extra flags passed in are appended.
+func (m *K8Svalidate) kubeconformArgs(version string, extra ...string) []string {
+	args := []string{
+		"kubeconform",
+		"-strict",
+		"-kubernetes-version", version,
+		"-cache", "/cache",
+		"-schema-location", "default",
+		"-schema-location", crdCatalog,
+	}
+	if m.Schemas != nil {
+		args = append(args, "-schema-location", localSchemas)
+	}
+	for _, loc := range m.SchemaLocations {
+		args = append(args, "-schema-location", loc)
+	}
+	if len(m.SkipKinds) > 0 {
+		args = append(args, "-skip", strings.Join(m.SkipKinds, ","))
+	}
+	if m.IgnoreMissingSchemas {
+		args = append(args, "-ignore-missing-schemas")
+	}
+	return append(append(args, extra...), "/rendered")
+}
+
+// container returns an Alpine container with kustomize and kubeconform, the
+// source mounted, and the schema cache.
+func (m *K8Svalidate) container() *dagger.Container {
+	ctr := dag.Container().
+		From(alpineImage).
+		WithExec([]string{"apk", "add", "--no-cache", "kustomize"}).
+		WithFile("/usr/local/bin/kubeconform", dag.Container().From(kubeconformImage).File("/kubeconform")).
+		WithMountedCache("/cache", dag.CacheVolume("kubeconform-schemas")).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+
+	if m.Schemas != nil {
+		ctr = ctr.WithDirectory("/schemas", m.Schemas)
+	}
+
+	return ctr
+}
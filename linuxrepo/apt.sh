@@ -0,0 +1,53 @@
+#!/usr/bin/env sh
+set -e
+
+# Builds a signed apt repository in /repo from the .deb packages in
+# /packages. Expects SUITE, COMPONENT, ORIGIN, and LABEL.
+#
+# Layout:
+#   pool/<component>/<package>.deb
+#   dists/<suite>/<component>/binary-<arch>/Packages{,.gz}
+#   dists/<suite>/{Release,Release.gpg,InRelease}
+#   key.gpg, key.asc
+
+mkdir -p "/repo/pool/${COMPONENT}"
+cd /repo
+
+FOUND=0
+for DEB in /packages/*.deb; do
+  [ -f "$DEB" ] || continue
+  cp "$DEB" "pool/${COMPONENT}/"
+  FOUND=1
+done
+if [ "$FOUND" -eq 0 ]; then
+  echo "no .deb packages found" >&2
+  exit 1
+fi
+
+# Architecture-independent packages are listed under every architecture.
+ARCHES=$(for DEB in pool/"${COMPONENT}"/*.deb; do dpkg-deb --field "$DEB" Architecture; done | grep -vx all | sort -u | tr '\n' ' ')
+if [ -z "$ARCHES" ]; then
+  ARCHES="amd64 arm64"
+fi
+
+for ARCH in $ARCHES; do
+  DIR="dists/${SUITE}/${COMPONENT}/binary-${ARCH}"
+  mkdir -p "$DIR"
+  apt-ftparchive --arch "$ARCH" packages "pool/${COMPONENT}" > "${DIR}/Packages"
+  gzip -9 -n -k "${DIR}/Packages"
+done
+
+apt-ftparchive \
+  -o "APT::FTPArchive::Release::Origin=${ORIGIN}" \
+  -o "APT::FTPArchive::Release::Label=${LABEL}" \
+  -o "APT::FTPArchive::Release::Suite=${SUITE}" \
+  -o "APT::FTPArchive::Release::Codename=${SUITE}" \
+  -o "APT::FTPArchive::Release::Architectures=${ARCHES% }" \
+  -o "APT::FTPArchive::Release::Components=${COMPONENT}" \
+  release "dists/${SUITE}" > /tmp/Release
+mv /tmp/Release "dists/${SUITE}/Release"
+
+. /usr/local/bin/sign.sh
+
+sign_detached "dists/${SUITE}/Release" "dists/${SUITE}/Release.gpg"
+sign_clear "dists/${SUITE}/Release" "dists/${SUITE}/InRelease"
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"dagger/linuxrepo/internal/dagger"
+)
+
+const (
+	debianImage string = "debian:bookworm-slim"
+	fedoraImage string = "fedora:41"
+)
+
+//go:embed sign.sh
+var signScript string
+
+//go:embed apt.sh
+var aptScript string
+
+//go:embed yum.sh
+var yumScript string
+
+// Linuxrepo builds signed apt and yum repositories from packages and
+// publishes them to an S3-compatible bucket.
+type Linuxrepo struct {
+	// SigningKey is the armored GPG private key used to sign repository
+	// metadata.
+	//
+	// +private
+	SigningKey *dagger.Secret
+
+	// Passphrase is the optional passphrase protecting SigningKey.
+	//
+	// +private
+	Passphrase *dagger.Secret
+}
+
+// New creates a new Linuxrepo instance with the repository signing key.
+func New(
+	// Armored GPG private key used to sign repository metadata
+	// (e.g., from "gpg --armor --export-secret-keys")
+	signingKey *dagger.Secret,
+
+	// Passphrase protecting the signing key
+	// +optional
+	passphrase *dagger.Secret,
+) *Linuxrepo {
+	return &Linuxrepo{
+		SigningKey: signingKey,
+		Passphrase: passphrase,
+	}
+}
+
+// Apt builds a signed apt repository from every .deb package in a directory
+// (e.g., the output of the nfpm module). The result has a pool/ of packages,
+// dists/<suite>/ with Packages indexes per architecture, a Release file
+// signed as Release.gpg and InRelease, and the public key as key.gpg and
+// key.asc for clients' keyrings.
+func (m *Linuxrepo) Apt(
+	// Directory of .deb packages
+	packages *dagger.Directory,
+
+	// Distribution suite clients add to their sources
+	// +optional
+	// +default="stable"
+	suite string,
+
+	// Repository component
+	// +optional
+	// +default="main"
+	component string,
+
+	// Origin field of the Release file
+	// +optional
+	origin string,
+
+	// Label field of the Release file
+	// +optional
+	label string,
+) *dagger.Directory {
+	return m.signingContainer(
+		dag.Container().
+			From(debianImage).
+			WithExec([]string{"apt-get", "update"}).
+			WithExec([]string{"apt-get", "install", "-y", "--no-install-recommends", "apt-utils", "gnupg"}),
+	).
+		WithDirectory("/packages", packages, dagger.ContainerWithDirectoryOpts{Include: []string{"*.deb"}}).
+		WithNewFile("/usr/local/bin/apt.sh", aptScript, dagger.ContainerWithNewFileOpts{Permissions: 0o755}).
+		WithEnvVariable("SUITE", suite).
+		WithEnvVariable("COMPONENT", component).
+		WithEnvVariable("ORIGIN", origin).
+		WithEnvVariable("LABEL", label).
+		WithExec([]string{"/usr/local/bin/apt.sh"}).
+		Directory("/repo")
+}
+
+// Yum builds a signed yum/dnf repository from every .rpm package in a
+// directory. The result has the packages under packages/, repodata/ generated
+// by createrepo_c with repomd.xml signed as repomd.xml.asc, and the public key
+// as key.gpg and key.asc.
+func (m *Linuxrepo) Yum(
+	// Directory of .rpm packages
+	packages *dagger.Directory,
+) *dagger.Directory {
+	return m.signingContainer(
+		dag.Container().
+			From(fedoraImage).
+			WithExec([]string{"dnf", "install", "-y", "createrepo_c", "gnupg2", "gawk"}),
+	).
+		WithDirectory("/packages", packages, dagger.ContainerWithDirectoryOpts{Include: []string{"*.rpm"}}).
+		WithNewFile("/usr/local/bin/yum.sh", yumScript, dagger.ContainerWithNewFileOpts{Permissions: 0o755}).
+		WithExec([]string{"/usr/local/bin/yum.sh"}).
+		Directory("/repo")
+}
+
+// Publish uploads a repository built by Apt or Yum to an S3-compatible
+// bucket under the given prefix, via the bucketupload module.
+func (m *Linuxrepo) Publish(
+	ctx context.Context,
+
+	// Repository directory built by Apt or Yum
+	repo *dagger.Directory,
+
+	// Bucket endpoint URL
+	endpoint *dagger.Secret,
+
+	// Bucket name
+	bucket *dagger.Secret,
+
+	// Bucket access key ID
+	accessKeyID *dagger.Secret,
+
+	// Bucket secret access key
+	secretAccessKey *dagger.Secret,
+
+	// Bucket key prefix (e.g., "apt" or "yum")
+	// +optional
+	prefix string,
+) error {
+	err := dag.Bucketuploader(endpoint, bucket, accessKeyID, secretAccessKey).
+		UploadTree(ctx, repo, dagger.BucketuploaderUploadTreeOpts{Prefix: prefix})
+	if err != nil {
+		return fmt.Errorf("failed to publish repository: %w", err)
+	}
+
+	return nil
+}
+
+// signingContainer mounts the signing key, passphrase, and signing helpers
+// into a container that already has gpg installed.
+func (m *Linuxrepo) signingContainer(ctr *dagger.Container) *dagger.Container {
+	ctr = ctr.
+		WithMountedSecret("/keys/signing.asc", m.SigningKey).
+		WithNewFile("/usr/local/bin/sign.sh", signScript, dagger.ContainerWithNewFileOpts{Permissions: 0o755})
+
+	if m.Passphrase != nil {
+		ctr = ctr.WithMountedSecret("/keys/passphrase", m.Passphrase)
+	}
+
+	return ctr
+}
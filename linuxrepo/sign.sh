@@ -0,0 +1,39 @@
+#!/usr/bin/env sh
+set -e
+
+# Imports the signing key from /keys/signing.asc (with the optional
+# passphrase in /keys/passphrase) and defines sign_detached and sign_clear
+# helpers for the repository scripts. Also exports the public key as
+# key.gpg (binary) and key.asc (armored) into the current directory.
+
+export GNUPGHOME=/tmp/gnupg
+mkdir -p "$GNUPGHOME"
+chmod 700 "$GNUPGHOME"
+
+gpg --batch --import /keys/signing.asc
+
+KEY_ID=$(gpg --batch --list-secret-keys --with-colons | awk -F: '/^sec/ { print $5; exit }')
+if [ -z "$KEY_ID" ]; then
+  echo "no secret key found in signing key" >&2
+  exit 1
+fi
+
+PASSPHRASE_ARGS=""
+if [ -s /keys/passphrase ]; then
+  PASSPHRASE_ARGS="--pinentry-mode loopback --passphrase-file /keys/passphrase"
+fi
+
+gpg --batch --export "$KEY_ID" > key.gpg
+gpg --batch --armor --export "$KEY_ID" > key.asc
+
+# sign_detached <file> <output>: writes an armored detached signature.
+sign_detached() {
+  # shellcheck disable=SC2086
+  gpg --batch --yes $PASSPHRASE_ARGS --local-user "$KEY_ID" --armor --detach-sign --output "$2" "$1"
+}
+
+# sign_clear <file> <output>: writes a clear-signed copy.
+sign_clear() {
+  # shellcheck disable=SC2086
+  gpg --batch --yes $PASSPHRASE_ARGS --local-user "$KEY_ID" --clearsign --output "$2" "$1"
+}
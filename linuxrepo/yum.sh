@@ -0,0 +1,26 @@
+#!/usr/bin/env sh
+set -e
+
+# Builds a signed yum/dnf repository in /repo from the .rpm packages in
+# /packages, with repodata/repomd.xml signed as repodata/repomd.xml.asc and
+# the public key exported as key.gpg and key.asc.
+
+mkdir -p /repo/packages
+cd /repo
+
+FOUND=0
+for RPM in /packages/*.rpm; do
+  [ -f "$RPM" ] || continue
+  cp "$RPM" packages/
+  FOUND=1
+done
+if [ "$FOUND" -eq 0 ]; then
+  echo "no .rpm packages found" >&2
+  exit 1
+fi
+
+createrepo_c --no-database --simple-md-filenames .
+
+. /usr/local/bin/sign.sh
+
+sign_detached repodata/repomd.xml repodata/repomd.xml.asc
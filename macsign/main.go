@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/macsign/internal/dagger"
+)
+
+const (
+	debianImage      string = "debian:bookworm-slim"
+	rcodesignVersion string = "0.29.0"
+)
+
+// stapleable are the artifact types a notarization ticket can be stapled
+// to. Bare Mach-O binaries cannot hold a ticket; Gatekeeper checks them
+// online instead.
+var stapleable = []string{".app", ".dmg", ".pkg"}
+
+type Macsign struct {
+	// Certificate is the Developer ID certificate and key as a .p12 file.
+	//
+	// +private
+	Certificate *dagger.Secret
+
+	// CertificatePassword is the password of Certificate.
+	//
+	// +private
+	CertificatePassword *dagger.Secret
+
+	// APIKey is the App Store Connect API key used for notarization.
+	//
+	// +private
+	APIKey *dagger.Secret
+}
+
+// New creates a new Macsign module instance.
+func New(
+	// Developer ID Application certificate and private key as a .p12 file
+	certificate *dagger.Secret,
+
+	// Password of the .p12 file
+	// +optional
+	certificatePassword *dagger.Secret,
+
+	// App Store Connect API key JSON, as written by
+	// "rcodesign encode-app-store-connect-api-key", needed to notarize
+	// +optional
+	apiKey *dagger.Secret,
+) *Macsign {
+	return &Macsign{
+		Certificate:         certificate,
+		CertificatePassword: certificatePassword,
+		APIKey:              apiKey,
+	}
+}
+
+// Sign codesigns every darwin artifact at the top level of a directory —
+// Mach-O binaries, .app bundles, .dmg images, and .pkg installers — with
+// the hardened runtime and a secure timestamp, as notarization requires,
+// and returns the signed directory.
+func (m *Macsign) Sign(
+	ctx context.Context,
+
+	// Directory of darwin artifacts
+	artifacts *dagger.Directory,
+
+	// Entitlements plist to embed in signed binaries
+	// +optional
+	entitlements *dagger.File,
+) (*dagger.Directory, error) {
+	entries, err := m.entries(ctx, artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr := m.container().
+		WithMountedSecret("/codesign/cert.p12", m.Certificate).
+		WithDirectory("/artifacts", artifacts)
+
+	args := []string{"rcodesign", "sign", "--p12-file", "/codesign/cert.p12", "--for-notarization"}
+	if m.CertificatePassword != nil {
+		ctr = ctr.WithMountedSecret("/codesign/cert.pass", m.CertificatePassword)
+		args = append(args, "--p12-password-file", "/codesign/cert.pass")
+	} else {
+		args = append(args, "--p12-password", "")
+	}
+	if entitlements != nil {
+		ctr = ctr.WithFile("/codesign/entitlements.plist", entitlements)
+		args = append(args, "--entitlements-xml-file", "/codesign/entitlements.plist")
+	}
+
+	for _, entry := range entries {
+		// Copy args: WithExec keeps the slice until the pipeline runs.
+		cmd := append(append([]string{}, args...), "/artifacts/"+entry)
+		ctr = ctr.WithExec(cmd)
+	}
+
+	return ctr.Directory("/artifacts"), nil
+}
+
+// Notarize submits every artifact at the top level of a signed directory to
+// Apple's notary service (the API notarytool uses), waits for approval, and
+// staples the ticket to .app, .dmg, and .pkg artifacts. Bare binaries are
+// submitted together as one zip. Returns the directory with tickets
+// stapled.
+func (m *Macsign) Notarize(
+	ctx context.Context,
+
+	// Directory of signed darwin artifacts
+	artifacts *dagger.Directory,
+) (*dagger.Directory, error) {
+	if m.APIKey == nil {
+		return nil, fmt.Errorf("api-key is required to notarize")
+	}
+
+	entries, err := m.entries(ctx, artifacts)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr := m.container().
+		WithMountedSecret("/codesign/api-key.json", m.APIKey).
+		WithDirectory("/artifacts", artifacts)
+	submit := func(args ...string) []string {
+		return append([]string{"rcodesign", "notary-submit", "--api-key-file", "/codesign/api-key.json"}, args...)
+	}
+
+	var binaries []string
+	for _, entry := range entries {
+		if isStapleable(entry) {
+			ctr = ctr.WithExec(submit("--staple", "/artifacts/"+entry))
+		} else {
+			binaries = append(binaries, "/artifacts/"+entry)
+		}
+	}
+
+	if len(binaries) > 0 {
+		ctr = ctr.
+			WithExec(append([]string{"zip", "-j", "/tmp/binaries.zip"}, binaries...)).
+			WithExec(submit("--wait", "/tmp/binaries.zip"))
+	}
+
+	return ctr.Directory("/artifacts"), nil
+}
+
+// SignAndNotarize signs and then notarizes every artifact in a directory,
+// returning the release-ready artifacts.
+func (m *Macsign) SignAndNotarize(
+	ctx context.Context,
+
+	// Directory of darwin artifacts
+	artifacts *dagger.Directory,
+
+	// Entitlements plist to embed in signed binaries
+	// +optional
+	entitlements *dagger.File,
+) (*dagger.Directory, error) {
+	signed, err := m.Sign(ctx, artifacts, entitlements)
+	if err != nil {
+		return nil, err
+	}
+	return m.Notarize(ctx, signed)
+}
+
+// entries returns the top-level artifacts of a directory: files and .app
+// bundles. Other directories are skipped.
+func (m *Macsign) entries(ctx context.Context, artifacts *dagger.Directory) ([]string, error) {
+	found, err := artifacts.Glob(ctx, "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	var entries []string
+	for _, entry := range found {
+		if dir, ok := strings.CutSuffix(entry, "/"); ok {
+			if strings.HasSuffix(dir, ".app") {
+				entries = append(entries, dir)
+			}
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no artifacts to sign")
+	}
+	return entries, nil
+}
+
+// isStapleable reports whether a notarization ticket can be stapled to an
+// artifact.
+func isStapleable(name string) bool {
+	for _, ext := range stapleable {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// container returns a Debian container with rcodesign, Apple code signing
+// and notarization for non-macOS hosts, and zip.
+func (m *Macsign) container() *dagger.Container {
+	url := fmt.Sprintf(
+		"https://github.com/indygreg/apple-platform-rs/releases/download/apple-codesign%%2F%s/apple-codesign-%s-x86_64-unknown-linux-musl.tar.gz",
+		rcodesignVersion, rcodesignVersion,
+	)
+	return dag.Container().
+		From(debianImage).
+		WithMountedCache("/var/cache/apt", dag.CacheVolume("macsign-apt")).
+		WithExec([]string{"apt-get", "update"}).
+		WithExec([]string{"apt-get", "install", "-y", "--no-install-recommends", "ca-certificates", "zip"}).
+		WithFile("/tmp/rcodesign.tar.gz", dag.HTTP(url)).
+		WithExec([]string{"tar", "-xzf", "/tmp/rcodesign.tar.gz", "-C", "/usr/local/bin", "--strip-components=1", "--wildcards", "*/rcodesign"})
+}
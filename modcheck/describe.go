@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// undescribed returns the functions, arguments, and fields of a Go SDK
+// module that have no description, as "<file>:<line>: <what>". Files maps
+// file names to their contents.
+//
+// Functions are the exported methods of exported types and the New
+// constructor; fields are the exported fields of exported structs that are
+// not marked +private. The Go SDK takes their descriptions from their doc
+// comments, without the +pragma lines.
+func undescribed(files map[string]string) ([]string, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []string
+	fset := token.NewFileSet()
+	for _, name := range names {
+		f, err := parser.ParseFile(fset, name, files[name], parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", name, err)
+		}
+
+		report := func(pos token.Pos, format string, args ...any) {
+			problems = append(problems, fmt.Sprintf("%s:%d: %s", name, fset.Position(pos).Line, fmt.Sprintf(format, args...)))
+		}
+
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				fn, ok := functionName(d)
+				if !ok {
+					continue
+				}
+				if !described(d.Doc) {
+					report(d.Pos(), "function %s has no description", fn)
+				}
+				for _, param := range d.Type.Params.List {
+					if isContext(param.Type) || described(commentBefore(fset, f, param)) {
+						continue
+					}
+					for _, n := range param.Names {
+						report(param.Pos(), "argument %s of %s has no description", n.Name, fn)
+					}
+				}
+
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok || !ts.Name.IsExported() {
+						continue
+					}
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					for _, field := range st.Fields.List {
+						if private(field.Doc) || described(field.Doc) {
+							continue
+						}
+						for _, n := range field.Names {
+							if n.IsExported() {
+								report(field.Pos(), "field %s.%s has no description", ts.Name.Name, n.Name)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return problems, nil
+}
+
+// functionName returns the name a function is reported under, and whether
+// the Go SDK exposes it.
+func functionName(d *ast.FuncDecl) (string, bool) {
+	if d.Recv == nil {
+		return "New", d.Name.Name == "New"
+	}
+	if !d.Name.IsExported() || len(d.Recv.List) == 0 {
+		return "", false
+	}
+	typ := d.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	ident, ok := typ.(*ast.Ident)
+	if !ok || !ident.IsExported() {
+		return "", false
+	}
+	return ident.Name + "." + d.Name.Name, true
+}
+
+// commentBefore returns the comment group ending on the line before field,
+// which is where the Go SDK reads argument descriptions from.
+func commentBefore(fset *token.FileSet, f *ast.File, field *ast.Field) *ast.CommentGroup {
+	line := fset.Position(field.Pos()).Line
+	for _, cg := range f.Comments {
+		if cg.End() < field.Pos() && fset.Position(cg.End()).Line == line-1 {
+			return cg
+		}
+	}
+	return nil
+}
+
+// described reports whether a comment has text other than +pragmas.
+func described(cg *ast.CommentGroup) bool {
+	if cg == nil {
+		return false
+	}
+	for _, line := range strings.Split(cg.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "+") {
+			return true
+		}
+	}
+	return false
+}
+
+// private reports whether a comment marks its field +private.
+func private(cg *ast.CommentGroup) bool {
+	if cg == nil {
+		return false
+	}
+	for _, line := range strings.Split(cg.Text(), "\n") {
+		if strings.TrimSpace(line) == "+private" {
+			return true
+		}
+	}
+	return false
+}
+
+func isContext(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "context" && sel.Sel.Name == "Context"
+}
@@ -0,0 +1,15 @@
+#!/usr/bin/env sh
+set -u
+
+# Load each module with "dagger develop" and "dagger functions", recording
+# the output and exit status of each one under /out/<module>, so that one
+# broken module does not hide the others.
+for mod in "$@"; do
+  mkdir -p "/out/${mod}"
+  (
+    cd "/src/${mod}" &&
+      dagger --silent develop &&
+      dagger --silent functions
+  ) >"/out/${mod}/log" 2>&1
+  echo $? >"/out/${mod}/status"
+done
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"dagger/modcheck/internal/dagger"
+)
+
+const (
+	alpineImage string = "alpine:3.21"
+
+	// logLines is how many lines of a broken module's output are reported.
+	logLines int = 40
+)
+
+//go:embed load-modules.sh
+var loadModulesScript string
+
+// Modcheck checks that every Dagger module in a daggerverse loads and
+// documents its functions and arguments.
+type Modcheck struct {
+	// Source is the repository holding the modules.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Modules are the module directories to check. Empty means every module.
+	//
+	// +private
+	Modules []string
+
+	// Exclude are module directories to skip.
+	//
+	// +private
+	Exclude []string
+
+	// DaggerVersion is the version of the dagger CLI that loads the modules.
+	//
+	// +private
+	DaggerVersion string
+}
+
+// New creates a new Modcheck module instance.
+func New(
+	// The repository holding the modules.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Module directories to check, relative to the source directory.
+	// Defaults to every directory with a dagger.json.
+	// +optional
+	modules []string,
+
+	// Module directories to skip, relative to the source directory
+	// +optional
+	exclude []string,
+
+	// Version of the dagger CLI that loads the modules. Match the engine
+	// version the modules target.
+	// +optional
+	// +default="0.20.8"
+	daggerVersion string,
+) *Modcheck {
+	return &Modcheck{
+		Source:        source,
+		Modules:       modules,
+		Exclude:       exclude,
+		DaggerVersion: daggerVersion,
+	}
+}
+
+// List returns the module directories that are checked.
+func (m *Modcheck) List(ctx context.Context) ([]string, error) {
+	if len(m.Modules) > 0 {
+		var mods []string
+		for _, mod := range m.Modules {
+			mod = path.Clean(strings.TrimPrefix(mod, "/"))
+			if !slices.Contains(m.Exclude, mod) {
+				mods = append(mods, mod)
+			}
+		}
+		return mods, nil
+	}
+
+	configs, err := m.Source.Glob(ctx, "**/dagger.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find modules: %w", err)
+	}
+
+	var mods []string
+	for _, config := range configs {
+		mod := path.Dir(config)
+		if slices.Contains(m.Exclude, mod) {
+			continue
+		}
+		mods = append(mods, mod)
+	}
+	slices.Sort(mods)
+	return mods, nil
+}
+
+// Descriptions lists the functions, arguments, and fields of the Go modules
+// that have no description, without failing.
+func (m *Modcheck) Descriptions(ctx context.Context) (string, error) {
+	mods, err := m.List(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	problems, err := m.undescribed(ctx, mods)
+	if err != nil {
+		return "", err
+	}
+	if len(problems) == 0 {
+		return "✅ every function and argument is described", nil
+	}
+	return strings.Join(problems, "\n"), nil
+}
+
+// Check loads every module with "dagger develop" and "dagger functions" and
+// checks the Go modules for functions, arguments, and fields without a
+// description. It fails listing every broken module and missing
+// description.
+//
+// +check
+func (m *Modcheck) Check(ctx context.Context) (string, error) {
+	mods, err := m.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(mods) == 0 {
+		return "", errors.New("no modules found")
+	}
+
+	broken, err := m.load(ctx, mods)
+	if err != nil {
+		return "", err
+	}
+
+	problems, err := m.undescribed(ctx, mods)
+	if err != nil {
+		return "", err
+	}
+
+	if len(broken) == 0 && len(problems) == 0 {
+		return fmt.Sprintf("✅ %d modules load and describe their functions", len(mods)), nil
+	}
+
+	var report []string
+	if len(broken) > 0 {
+		report = append(report, fmt.Sprintf("%d of %d modules failed to load", len(broken), len(mods)))
+		report = append(report, broken...)
+	}
+	if len(problems) > 0 {
+		report = append(report, fmt.Sprintf("%d missing descriptions", len(problems)))
+		report = append(report, strings.Join(problems, "\n"))
+	}
+	return "", fmt.Errorf("modules are broken\n\n%s", strings.Join(report, "\n\n"))
+}
+
+// load runs the load-modules script over mods and returns a report of each
+// module that failed to load, with the end of its output.
+func (m *Modcheck) load(ctx context.Context, mods []string) ([]string, error) {
+	out, err := dag.Container().
+		From(alpineImage).
+		WithExec([]string{"apk", "add", "--no-cache", "curl"}).
+		WithEnvVariable("DAGGER_VERSION", m.DaggerVersion).
+		WithEnvVariable("BIN_DIR", "/usr/local/bin").
+		WithExec([]string{"sh", "-c", "curl -fsSL https://dl.dagger.io/dagger/install.sh | sh"}).
+		WithNewFile("/usr/local/bin/load-modules.sh", loadModulesScript, dagger.ContainerWithNewFileOpts{Permissions: 0o755}).
+		WithDirectory("/src", m.Source).
+		WithExec(
+			append([]string{"/usr/local/bin/load-modules.sh"}, mods...),
+			dagger.ContainerWithExecOpts{ExperimentalPrivilegedNesting: true},
+		).
+		Directory("/out").
+		Sync(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return nil, fmt.Errorf("failed to load modules\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return nil, fmt.Errorf("unexpected error: %w", err)
+	}
+
+	var broken []string
+	for _, mod := range mods {
+		status, err := out.File(path.Join(mod, "status")).Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read status of %s: %w", mod, err)
+		}
+		if strings.TrimSpace(status) == "0" {
+			continue
+		}
+
+		log, err := out.File(path.Join(mod, "log")).Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read output of %s: %w", mod, err)
+		}
+		broken = append(broken, fmt.Sprintf("%s:\n%s", mod, indent(tail(log, logLines))))
+	}
+	return broken, nil
+}
+
+// undescribed returns the missing descriptions of the Go modules in mods,
+// prefixed with the module's directory. Modules in other SDKs are skipped.
+func (m *Modcheck) undescribed(ctx context.Context, mods []string) ([]string, error) {
+	var problems []string
+	for _, mod := range mods {
+		dir, ok, err := m.goSource(ctx, mod)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		// Only the top-level files are module code: the generated client
+		// lives under internal/.
+		names, err := m.Source.Directory(dir).Glob(ctx, "*.go")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Go files of %s: %w", mod, err)
+		}
+
+		files := map[string]string{}
+		for _, name := range names {
+			if name == "dagger.gen.go" || strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			contents, err := m.Source.Directory(dir).File(name).Contents(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", path.Join(dir, name), err)
+			}
+			files[name] = contents
+		}
+
+		found, err := undescribed(files)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check %s: %w", mod, err)
+		}
+		for _, p := range found {
+			problems = append(problems, path.Join(dir, p))
+		}
+	}
+	return problems, nil
+}
+
+// goSource returns the directory holding a module's source code, and
+// whether the module uses the Go SDK.
+func (m *Modcheck) goSource(ctx context.Context, mod string) (string, bool, error) {
+	contents, err := m.Source.File(path.Join(mod, "dagger.json")).Contents(ctx)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s/dagger.json: %w", mod, err)
+	}
+
+	var config struct {
+		Source string          `json:"source"`
+		SDK    json.RawMessage `json:"sdk"`
+	}
+	if err := json.Unmarshal([]byte(contents), &config); err != nil {
+		return "", false, fmt.Errorf("invalid %s/dagger.json: %w", mod, err)
+	}
+
+	// The SDK is either a string or an object with a source.
+	var sdk string
+	if err := json.Unmarshal(config.SDK, &sdk); err != nil {
+		var obj struct {
+			Source string `json:"source"`
+		}
+		if err := json.Unmarshal(config.SDK, &obj); err == nil {
+			sdk = obj.Source
+		}
+	}
+	return path.Join(mod, config.Source), sdk == "go", nil
+}
+
+// tail returns the last n lines of s.
+func tail(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indent indents every line of s by two spaces.
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(s, "\n", "\n  ")
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// api is the API a Go SDK module exposes: its constructor and the
+// functions of its main object and the other objects it returns.
+type api struct {
+	// main is the object named after the module.
+	main *object
+	// constructor is New, or nil when the module has none.
+	constructor *function
+	// objects are the other exported types with functions, by name.
+	objects []*object
+}
+
+type object struct {
+	name      string
+	doc       string
+	functions []*function
+}
+
+type function struct {
+	name string
+	doc  string
+	args []*arg
+}
+
+type arg struct {
+	name        string
+	typ         string
+	doc         string
+	optional    bool
+	def         string
+	defaultPath string
+}
+
+// required reports whether a caller must pass the argument.
+func (a *arg) required() bool {
+	return !a.optional && a.def == "" && a.defaultPath == ""
+}
+
+// introspect parses the Go files of the module named name. Files maps file
+// names to their contents.
+func introspect(name string, files map[string]string) (*api, error) {
+	names := make([]string, 0, len(files))
+	for n := range files {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	objects := map[string]*object{}
+	obj := func(name string) *object {
+		if objects[name] == nil {
+			objects[name] = &object{name: name}
+		}
+		return objects[name]
+	}
+
+	a := &api{}
+	fset := token.NewFileSet()
+	for _, n := range names {
+		f, err := parser.ParseFile(fset, n, files[n], parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", n, err)
+		}
+
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Recv == nil {
+					if d.Name.Name == "New" {
+						a.constructor = newFunction(fset, f, d)
+					}
+					continue
+				}
+				recv, ok := receiver(d)
+				if !ok || !d.Name.IsExported() {
+					continue
+				}
+				o := obj(recv)
+				o.functions = append(o.functions, newFunction(fset, f, d))
+
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					ts, ok := spec.(*ast.TypeSpec)
+					if !ok || !ts.Name.IsExported() {
+						continue
+					}
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					o := obj(ts.Name.Name)
+					doc := ts.Doc
+					if doc == nil && len(d.Specs) == 1 {
+						doc = d.Doc
+					}
+					o.doc = text(doc)
+
+					// Exported fields that are not +private are exposed
+					// as functions without arguments.
+					for _, field := range st.Fields.List {
+						if pragmas(field.Doc)["private"] != nil {
+							continue
+						}
+						for _, fn := range field.Names {
+							if fn.IsExported() {
+								o.functions = append(o.functions, &function{name: fn.Name, doc: text(field.Doc)})
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	main := pascal(name)
+	for n, o := range objects {
+		switch {
+		case n == main:
+			a.main = o
+		case len(o.functions) > 0:
+			a.objects = append(a.objects, o)
+		}
+	}
+	if a.main == nil {
+		return nil, fmt.Errorf("no %s type found", main)
+	}
+	sort.Slice(a.objects, func(i, j int) bool { return a.objects[i].name < a.objects[j].name })
+	return a, nil
+}
+
+func newFunction(fset *token.FileSet, f *ast.File, d *ast.FuncDecl) *function {
+	fn := &function{name: d.Name.Name, doc: text(d.Doc)}
+	for _, param := range d.Type.Params.List {
+		if isContext(param.Type) {
+			continue
+		}
+		cg := commentBefore(fset, f, param)
+		p := pragmas(cg)
+		for _, n := range param.Names {
+			a := &arg{
+				name:     n.Name,
+				typ:      typeName(param.Type),
+				doc:      text(cg),
+				optional: p["optional"] != nil,
+			}
+			if v := p["default"]; v != nil {
+				a.def = unquote(*v)
+			}
+			if v := p["defaultPath"]; v != nil {
+				a.defaultPath = unquote(*v)
+			}
+			fn.args = append(fn.args, a)
+		}
+	}
+	return fn
+}
+
+// receiver returns the type name of a method's receiver, if it is
+// exported.
+func receiver(d *ast.FuncDecl) (string, bool) {
+	if len(d.Recv.List) == 0 {
+		return "", false
+	}
+	typ := d.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	ident, ok := typ.(*ast.Ident)
+	if !ok || !ident.IsExported() {
+		return "", false
+	}
+	return ident.Name, true
+}
+
+// commentBefore returns the comment group ending on the line before field,
+// which is where the Go SDK reads argument descriptions from.
+func commentBefore(fset *token.FileSet, f *ast.File, field *ast.Field) *ast.CommentGroup {
+	line := fset.Position(field.Pos()).Line
+	for _, cg := range f.Comments {
+		if cg.End() < field.Pos() && fset.Position(cg.End()).Line == line-1 {
+			return cg
+		}
+	}
+	return nil
+}
+
+// text returns a comment without its +pragma lines.
+func text(cg *ast.CommentGroup) string {
+	if cg == nil {
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(cg.Text(), "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "+") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// pragmas returns the +pragmas of a comment by name, with their value when
+// they have one.
+func pragmas(cg *ast.CommentGroup) map[string]*string {
+	p := map[string]*string{}
+	if cg == nil {
+		return p
+	}
+	for _, line := range strings.Split(cg.Text(), "\n") {
+		line, ok := strings.CutPrefix(strings.TrimSpace(line), "+")
+		if !ok {
+			continue
+		}
+		name, value, _ := strings.Cut(line, "=")
+		p[name] = &value
+	}
+	return p
+}
+
+func unquote(s string) string {
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// typeName returns the GraphQL name of a Go argument type, as shown by
+// "dagger functions" (e.g., "[String]" for []string).
+func typeName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return typeName(t.X)
+	case *ast.ArrayType:
+		return "[" + typeName(t.Elt) + "]"
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return "String"
+		case "int", "int64", "int32":
+			return "Int"
+		case "bool":
+			return "Boolean"
+		case "float64", "float32":
+			return "Float"
+		}
+		return t.Name
+	}
+	return "?"
+}
+
+func isContext(expr ast.Expr) bool {
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "context" && sel.Sel.Name == "Context"
+}
+
+// pascal returns the Go type name of a module's main object (e.g.,
+// "Mod" for "mod", "MyMod" for "my-mod", "K8Svalidate" for "k8svalidate").
+// Like the Dagger codegen, a new word starts after a separator or a digit.
+func pascal(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '-' || r == '_' || r == ' ' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+		}
+		b.WriteRune(r)
+		upper = unicode.IsDigit(r)
+	}
+	return b.String()
+}
+
+// kebab returns the CLI name of a function or argument (e.g.,
+// "with-base-url" for "WithBaseURL").
+func kebab(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				b.WriteByte('-')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"dagger/moddocs/internal/dagger"
+)
+
+// Moddocs generates the function and argument reference of every Go module
+// in a daggerverse into the modules' READMEs, from their doc comments.
+type Moddocs struct {
+	// Source is the repository holding the modules.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Prefix is the module path the modules are published under.
+	//
+	// +private
+	Prefix string
+
+	// Modules are the module directories to document. Empty means every
+	// module.
+	//
+	// +private
+	Modules []string
+
+	// Exclude are module directories to skip.
+	//
+	// +private
+	Exclude []string
+}
+
+// goModule is a Go SDK module in the source.
+type goModule struct {
+	// dir is the module's directory, holding its dagger.json and README.
+	dir string
+	// name is the module name from dagger.json.
+	name string
+	// source is the directory holding the module's Go code.
+	source string
+}
+
+// New creates a new Moddocs module instance.
+func New(
+	// The repository holding the modules.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Module path the modules are published under, used in the "dagger
+	// call -m" examples
+	// +optional
+	// +default="github.com/papercomputeco/daggerverse"
+	prefix string,
+
+	// Module directories to document, relative to the source directory.
+	// Defaults to every directory with a dagger.json.
+	// +optional
+	modules []string,
+
+	// Module directories to skip, relative to the source directory
+	// +optional
+	exclude []string,
+) *Moddocs {
+	return &Moddocs{
+		Source:  source,
+		Prefix:  prefix,
+		Modules: modules,
+		Exclude: exclude,
+	}
+}
+
+// Markdown returns the generated README section of one module, between
+// the moddocs markers.
+func (m *Moddocs) Markdown(
+	ctx context.Context,
+
+	// Module directory, relative to the source directory
+	module string,
+) (string, error) {
+	mod, ok, err := m.goModule(ctx, path.Clean(strings.TrimPrefix(module, "/")))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%s is not a Go module", module)
+	}
+	return m.markdown(ctx, mod)
+}
+
+// Generate regenerates the section between the moddocs markers of every
+// module's README and returns the source directory with the updated
+// READMEs. Modules without a README get one, and READMEs without the
+// markers get the section appended.
+func (m *Moddocs) Generate(ctx context.Context) (*dagger.Directory, error) {
+	mods, err := m.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := m.Source
+	for _, mod := range mods {
+		readme, changed, _, err := m.readme(ctx, mod)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			dir = dir.WithNewFile(path.Join(mod.dir, "README.md"), readme)
+		}
+	}
+	return dir, nil
+}
+
+// Check fails when the generated section of any module's README is out of
+// date with its code or missing, listing the modules to regenerate.
+//
+// +check
+func (m *Moddocs) Check(ctx context.Context) (string, error) {
+	mods, err := m.list(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var stale, unmarked []string
+	for _, mod := range mods {
+		_, changed, marked, err := m.readme(ctx, mod)
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case !marked:
+			unmarked = append(unmarked, mod.dir)
+		case changed:
+			stale = append(stale, mod.dir)
+		}
+	}
+
+	if len(stale) == 0 && len(unmarked) == 0 {
+		return fmt.Sprintf("✅ %d module READMEs are up to date", len(mods)), nil
+	}
+
+	var b strings.Builder
+	b.WriteString("module docs are out of date: run 'dagger call -m ./moddocs generate export --path .' and commit the changes")
+	if len(stale) > 0 {
+		fmt.Fprintf(&b, "\n\nStale:\n%s", strings.Join(stale, "\n"))
+	}
+	if len(unmarked) > 0 {
+		fmt.Fprintf(&b, "\n\nMissing the moddocs markers:\n%s", strings.Join(unmarked, "\n"))
+	}
+	return "", errors.New(b.String())
+}
+
+// readme returns a module's README with its generated section updated,
+// whether that changed it, and whether it has the moddocs markers.
+func (m *Moddocs) readme(ctx context.Context, mod goModule) (string, bool, bool, error) {
+	section, err := m.markdown(ctx, mod)
+	if err != nil {
+		return "", false, false, err
+	}
+
+	existing, err := m.Source.Glob(ctx, path.Join(mod.dir, "README.md"))
+	if err != nil {
+		return "", false, false, fmt.Errorf("failed to look up README of %s: %w", mod.dir, err)
+	}
+	if len(existing) == 0 {
+		readme := fmt.Sprintf("# %s/%s\n\n%s", strings.TrimSuffix(m.Prefix, "/"), mod.dir, section)
+		return readme, true, true, nil
+	}
+
+	current, err := m.Source.File(path.Join(mod.dir, "README.md")).Contents(ctx)
+	if err != nil {
+		return "", false, false, fmt.Errorf("failed to read README of %s: %w", mod.dir, err)
+	}
+	readme, marked := splice(current, section)
+	if !marked {
+		// Without markers there is nowhere better to put the section than
+		// the end of the README; move the markers to taste afterwards.
+		readme = strings.TrimRight(current, "\n") + "\n\n" + section
+	}
+	return readme, readme != current, marked, nil
+}
+
+// markdown introspects a module's Go code and renders its section.
+func (m *Moddocs) markdown(ctx context.Context, mod goModule) (string, error) {
+	src := m.Source.Directory(mod.source)
+
+	// "*.go" does not descend into internal/, so the generated client is
+	// never parsed as if it were part of the module's API.
+	entries, err := src.Glob(ctx, "*.go")
+	if err != nil {
+		return "", fmt.Errorf("failed to list Go files of %s: %w", mod.dir, err)
+	}
+
+	files := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if !moduleFile(entry) {
+			continue
+		}
+		if files[entry], err = src.File(entry).Contents(ctx); err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", path.Join(mod.source, entry), err)
+		}
+	}
+
+	a, err := introspect(mod.name, files)
+	if err != nil {
+		return "", fmt.Errorf("failed to introspect %s: %w", mod.dir, err)
+	}
+	return markdown(m.Prefix, mod.dir, a), nil
+}
+
+// moduleFile reports whether a Go file declares the module's API, as
+// opposed to generated bindings or tests.
+func moduleFile(name string) bool {
+	return name != "dagger.gen.go" && !strings.HasSuffix(name, "_test.go")
+}
+
+// list returns the Go modules to document.
+func (m *Moddocs) list(ctx context.Context) ([]goModule, error) {
+	dirs := m.Modules
+	if len(dirs) == 0 {
+		configs, err := m.Source.Glob(ctx, "**/dagger.json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to find modules: %w", err)
+		}
+		for _, config := range configs {
+			dirs = append(dirs, path.Dir(config))
+		}
+		slices.Sort(dirs)
+	}
+
+	var mods []goModule
+	for _, dir := range dirs {
+		dir = path.Clean(strings.TrimPrefix(dir, "/"))
+		if slices.Contains(m.Exclude, dir) {
+			continue
+		}
+		mod, ok, err := m.goModule(ctx, dir)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			mods = append(mods, mod)
+		}
+	}
+	if len(mods) == 0 {
+		return nil, errors.New("no Go modules found")
+	}
+	return mods, nil
+}
+
+// goModule reads a module's dagger.json, and reports whether it uses the
+// Go SDK.
+func (m *Moddocs) goModule(ctx context.Context, dir string) (goModule, bool, error) {
+	config := path.Join(dir, "dagger.json")
+	contents, err := m.Source.File(config).Contents(ctx)
+	if err != nil {
+		return goModule{}, false, fmt.Errorf("failed to read %s: %w", config, err)
+	}
+
+	var module struct {
+		Name   string `json:"name"`
+		Source string `json:"source"`
+		SDK    any    `json:"sdk"`
+	}
+	if err := json.Unmarshal([]byte(contents), &module); err != nil {
+		return goModule{}, false, fmt.Errorf("invalid %s: %w", config, err)
+	}
+
+	// Modules written before SDK configuration existed name the SDK
+	// directly; newer ones nest it as {"source": "go", ...}.
+	sdk, _ := module.SDK.(string)
+	if nested, ok := module.SDK.(map[string]any); ok {
+		sdk, _ = nested["source"].(string)
+	}
+
+	return goModule{
+		dir:    dir,
+		name:   module.Name,
+		source: path.Join(dir, module.Source),
+	}, sdk == "go", nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	startMarker string = "<!-- moddocs:start -->"
+	endMarker   string = "<!-- moddocs:end -->"
+)
+
+// markdown renders the generated README section of module mod, published
+// under prefix, between the moddocs markers.
+func markdown(prefix, mod string, a *api) string {
+	var b strings.Builder
+	b.WriteString(startMarker + "\n")
+	b.WriteString("<!-- Generated by moddocs from the module's doc comments. Edit those, not this section. -->\n\n")
+
+	functionTable(&b, a.main)
+	for _, o := range a.objects {
+		fmt.Fprintf(&b, "\n### `%s` functions\n\n", o.name)
+		functionTable(&b, o)
+	}
+
+	if a.constructor != nil && len(a.constructor.args) > 0 {
+		b.WriteString("\n\n## Constructor arguments\n\n")
+		argTable(&b, a.constructor.args)
+	}
+
+	b.WriteString("\n\n## Function reference\n")
+	for _, fn := range a.main.functions {
+		fmt.Fprintf(&b, "\n### `%s`\n\n", kebab(fn.name))
+		if doc := strip(fn.name, fn.doc); doc != "" {
+			b.WriteString(prose(doc) + "\n\n")
+		}
+		if len(fn.args) > 0 {
+			argTable(&b, fn.args)
+			b.WriteString("\n")
+		}
+		b.WriteString("```sh\n" + example(prefix, mod, a.constructor, fn) + "\n```\n")
+	}
+	for _, o := range a.objects {
+		for _, fn := range o.functions {
+			if len(fn.args) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "\n### `%s` `%s`\n\n", o.name, kebab(fn.name))
+			if doc := strip(fn.name, fn.doc); doc != "" {
+				b.WriteString(prose(doc) + "\n\n")
+			}
+			argTable(&b, fn.args)
+		}
+	}
+
+	b.WriteString("\n" + endMarker + "\n")
+	return b.String()
+}
+
+func functionTable(b *strings.Builder, o *object) {
+	b.WriteString("| Function | Description |\n")
+	b.WriteString("|----------|-------------|\n")
+	for _, fn := range o.functions {
+		fmt.Fprintf(b, "| `%s` | %s |\n", kebab(fn.name), cell(firstParagraph(strip(fn.name, fn.doc))))
+	}
+}
+
+func argTable(b *strings.Builder, args []*arg) {
+	b.WriteString("| Argument | Type | Description |\n")
+	b.WriteString("|----------|------|-------------|\n")
+	for _, a := range args {
+		desc := a.doc
+		switch {
+		case a.defaultPath != "":
+			desc += fmt.Sprintf(" (default `%s` in the calling repository)", a.defaultPath)
+		case a.def != "":
+			desc += fmt.Sprintf(" (default `%s`)", a.def)
+		case a.required():
+			desc += " (required)"
+		}
+		fmt.Fprintf(b, "| `--%s` | `%s` | %s |\n", kebab(a.name), a.typ, cell(desc))
+	}
+}
+
+// example returns a "dagger call" of fn with the required constructor and
+// function arguments.
+func example(prefix, mod string, constructor, fn *function) string {
+	lines := []string{"dagger call", "  -m " + strings.TrimSuffix(prefix, "/") + "/" + mod}
+	if constructor != nil {
+		for _, a := range constructor.args {
+			if a.required() {
+				lines = append(lines, fmt.Sprintf("  --%s %s", kebab(a.name), placeholder(a)))
+			}
+		}
+	}
+	lines = append(lines, "  "+kebab(fn.name))
+	for _, a := range fn.args {
+		if a.required() {
+			lines = append(lines, fmt.Sprintf("    --%s %s", kebab(a.name), placeholder(a)))
+		}
+	}
+	return strings.Join(lines, " \\\n")
+}
+
+// placeholder returns an example value for a required argument.
+func placeholder(a *arg) string {
+	switch a.typ {
+	case "Secret":
+		return "env:" + strings.ToUpper(strings.ReplaceAll(kebab(a.name), "-", "_"))
+	case "Directory":
+		return "."
+	case "File":
+		return "./" + kebab(a.name)
+	case "Boolean":
+		return "true"
+	}
+	return fmt.Sprintf(`"<%s>"`, kebab(a.name))
+}
+
+// strip turns a Go doc comment starting with the function's name into a
+// description starting with its verb (e.g., "Build builds ..." into
+// "Builds ...").
+func strip(name, doc string) string {
+	rest, ok := strings.CutPrefix(doc, name+" ")
+	if !ok || rest == "" {
+		return doc
+	}
+	return strings.ToUpper(rest[:1]) + rest[1:]
+}
+
+func firstParagraph(doc string) string {
+	p, _, _ := strings.Cut(doc, "\n\n")
+	return p
+}
+
+// cell joins text onto one line for a table cell.
+func cell(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.NewReplacer("|", `\|`, "<", "&lt;").Replace(s)
+}
+
+// prose escapes a doc comment for markdown. Indented lines are code blocks
+// in both, so they are left alone.
+func prose(doc string) string {
+	lines := strings.Split(doc, "\n")
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "\t") && !strings.HasPrefix(line, "    ") {
+			lines[i] = strings.ReplaceAll(line, "<", "&lt;")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splice replaces the generated section of readme with section, and
+// reports whether readme has one. The markers must be on lines of their
+// own, so READMEs can mention them.
+func splice(readme, section string) (string, bool) {
+	start := markerLine(readme, startMarker, 0)
+	if start < 0 {
+		return readme, false
+	}
+	end := markerLine(readme, endMarker, start)
+	if end < 0 {
+		return readme, false
+	}
+	end += len(endMarker)
+	if end < len(readme) && readme[end] == '\n' {
+		end++
+	}
+	return readme[:start] + section + readme[end:], true
+}
+
+// markerLine returns the offset of the first line of s from offset from on
+// that is exactly marker, or -1.
+func markerLine(s, marker string, from int) int {
+	for i := from; i < len(s); {
+		line, _, _ := strings.Cut(s[i:], "\n")
+		if strings.TrimRight(line, "\r") == marker {
+			return i
+		}
+		i += len(line) + 1
+	}
+	return -1
+}
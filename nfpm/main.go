@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/nfpm/internal/dagger"
+)
+
+const (
+	nfpmImage string = "goreleaser/nfpm:v2.41.1"
+)
+
+// packagers are the package formats nfpm can build.
+var packagers = map[string]bool{
+	"deb": true,
+	"rpm": true,
+	"apk": true,
+}
+
+type Nfpm struct{}
+
+// nfpmConfig is the subset of the nfpm configuration generated from
+// parameters. JSON is valid YAML, so it is written with encoding/json.
+type nfpmConfig struct {
+	Name          string        `json:"name"`
+	Arch          string        `json:"arch"`
+	Platform      string        `json:"platform"`
+	Version       string        `json:"version"`
+	VersionSchema string        `json:"version_schema"`
+	Maintainer    string        `json:"maintainer"`
+	Description   string        `json:"description,omitempty"`
+	Homepage      string        `json:"homepage,omitempty"`
+	License       string        `json:"license,omitempty"`
+	Depends       []string      `json:"depends,omitempty"`
+	Contents      []nfpmContent `json:"contents"`
+}
+
+// nfpmContent is a file installed by a package.
+type nfpmContent struct {
+	Src      string        `json:"src"`
+	Dst      string        `json:"dst"`
+	Type     string        `json:"type,omitempty"`
+	FileInfo *nfpmFileInfo `json:"file_info,omitempty"`
+}
+
+// nfpmFileInfo sets the ownership and permissions of an installed file.
+type nfpmFileInfo struct {
+	Mode int `json:"mode"`
+}
+
+// Package builds .deb, .rpm, and .apk packages for every linux architecture
+// of a build directory in the <os>/<arch>/<binary> layout produced by the
+// gobuild module, and returns a flat directory of packages with nfpm's
+// conventional file names (e.g., "myapp_1.2.3_amd64.deb").
+//
+// Without a config the package metadata is generated from the parameters. A
+// custom nfpm config can instead reference ${NAME}, ${VERSION}, ${GOARCH},
+// and ${BINARY}, the path of the binary being packaged.
+func (m *Nfpm) Package(
+	ctx context.Context,
+
+	// Build output in <os>/<arch>/<binary> layout
+	build *dagger.Directory,
+
+	// Binary name inside each <os>/<arch> directory, also the package name
+	name string,
+
+	// Package version (e.g., "v1.2.3"); a leading "v" is dropped
+	version string,
+
+	// Package maintainer (e.g., "Acme <ops@acme.dev>"), required by deb
+	// +optional
+	maintainer string,
+
+	// Package description
+	// +optional
+	description string,
+
+	// Project homepage URL
+	// +optional
+	homepage string,
+
+	// Package license (e.g., "Apache-2.0")
+	// +optional
+	license string,
+
+	// Package dependencies (e.g., "ca-certificates")
+	// +optional
+	depends []string,
+
+	// Install path of the binary
+	// +optional
+	// +default="/usr/bin"
+	binDir string,
+
+	// Package formats to build. Defaults to deb, rpm, and apk.
+	// +optional
+	formats []string,
+
+	// Architectures to package. Defaults to every linux/<arch> directory in
+	// the build.
+	// +optional
+	arches []string,
+
+	// Optional nfpm config that replaces the generated one
+	// +optional
+	config *dagger.File,
+) (*dagger.Directory, error) {
+	if len(formats) == 0 {
+		formats = []string{"deb", "rpm", "apk"}
+	}
+	for _, f := range formats {
+		if !packagers[f] {
+			return nil, fmt.Errorf("unsupported format %q: must be \"deb\", \"rpm\", or \"apk\"", f)
+		}
+	}
+
+	if len(arches) == 0 {
+		entries, err := build.Directory("linux").Entries(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list linux architectures: %w", err)
+		}
+		for _, e := range entries {
+			arches = append(arches, strings.TrimSuffix(e, "/"))
+		}
+	}
+	if len(arches) == 0 {
+		return nil, fmt.Errorf("no linux builds found: expected linux/<arch>/%s", name)
+	}
+
+	version = strings.TrimPrefix(version, "v")
+
+	ctr := dag.Container().
+		From(nfpmImage).
+		WithDirectory("/build", build).
+		WithDirectory("/out", dag.Directory()).
+		WithEnvVariable("NAME", name).
+		WithEnvVariable("VERSION", version)
+
+	out := dag.Directory()
+	for _, arch := range arches {
+		binary := path.Join("/build/linux", arch, name)
+
+		archCtr := ctr.
+			WithEnvVariable("GOARCH", arch).
+			WithEnvVariable("BINARY", binary)
+
+		if config != nil {
+			archCtr = archCtr.WithFile("/nfpm.yaml", config)
+		} else {
+			data, err := json.MarshalIndent(nfpmConfig{
+				Name:          name,
+				Arch:          arch,
+				Platform:      "linux",
+				Version:       version,
+				VersionSchema: "semver",
+				Maintainer:    maintainer,
+				Description:   description,
+				Homepage:      homepage,
+				License:       license,
+				Depends:       depends,
+				Contents: []nfpmContent{{
+					Src:      binary,
+					Dst:      path.Join(binDir, name),
+					FileInfo: &nfpmFileInfo{Mode: 0o755},
+				}},
+			}, "", "  ")
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode nfpm config: %w", err)
+			}
+			archCtr = archCtr.WithNewFile("/nfpm.yaml", string(data))
+		}
+
+		for _, format := range formats {
+			archCtr = archCtr.WithExec(
+				[]string{"package", "--config", "/nfpm.yaml", "--packager", format, "--target", "/out/"},
+				dagger.ContainerWithExecOpts{UseEntrypoint: true},
+			)
+		}
+
+		out = out.WithDirectory(".", archCtr.Directory("/out"))
+	}
+
+	return out, nil
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"dagger/nightly/internal/dagger"
+)
+
+// titles maps the overall status to the notification headline suffix.
+var titles = map[string]string{
+	success: "passed",
+	warning: "passed with warnings",
+	failure: "failed",
+}
+
+// Nightly runs a Go project's nightly pipeline — build, test, checksum,
+// upload, and notify — by composing the gobuild, gotest, checksum,
+// bucketupload, and notify modules, and reports the status of every step.
+type Nightly struct {
+	// Go source directory to build and test
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Name of the binary
+	//
+	// +private
+	Name string
+
+	// Version stamped into the binary
+	//
+	// +private
+	Version string
+
+	// Main package to build
+	//
+	// +private
+	Pkg string
+
+	// GOOS/GOARCH targets
+	//
+	// +private
+	Targets []string
+
+	// Extra linker flags
+	//
+	// +private
+	Ldflags []string
+
+	// Steps whose failure does not stop the pipeline
+	//
+	// +private
+	ContinueOnError []string
+
+	// Bucket endpoint URL
+	//
+	// +private
+	BucketEndpoint *dagger.Secret
+
+	// Bucket name
+	//
+	// +private
+	BucketName *dagger.Secret
+
+	// Bucket access key ID
+	//
+	// +private
+	BucketAccessKeyID *dagger.Secret
+
+	// Bucket secret access key
+	//
+	// +private
+	BucketSecretAccessKey *dagger.Secret
+
+	// Public URL the bucket is served from
+	//
+	// +private
+	BaseURL string
+
+	// Slack incoming webhook URL
+	//
+	// +private
+	SlackWebhook *dagger.Secret
+
+	// Discord webhook URL
+	//
+	// +private
+	DiscordWebhook *dagger.Secret
+}
+
+// New creates a nightly pipeline. Chain WithBucket to upload the build and
+// WithSlack and/or WithDiscord to announce the result, then call Run.
+func New(
+	// The Go source directory to build and test.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Name of the binary
+	name string,
+
+	// Version stamped into the binary as main.version (e.g.,
+	// "nightly-20240601")
+	// +optional
+	// +default="nightly"
+	version string,
+
+	// Main package to build, relative to the source directory
+	// +optional
+	// +default="."
+	pkg string,
+
+	// GOOS/GOARCH targets. Defaults to gobuild's linux, darwin, and windows
+	// matrix.
+	// +optional
+	targets []string,
+
+	// Extra linker flags (e.g., "-X main.commit=abc123")
+	// +optional
+	ldflags []string,
+
+	// Steps whose failure is reported but does not stop the pipeline: any
+	// of "build", "test", "checksum", "upload", and "notify"
+	// +optional
+	continueOnError []string,
+) (*Nightly, error) {
+	for _, step := range continueOnError {
+		if !slices.Contains(steps, step) {
+			return nil, fmt.Errorf("invalid step %q: must be one of %s", step, strings.Join(steps, ", "))
+		}
+	}
+	return &Nightly{
+		Source:          source,
+		Name:            name,
+		Version:         version,
+		Pkg:             pkg,
+		Targets:         targets,
+		Ldflags:         ldflags,
+		ContinueOnError: continueOnError,
+	}, nil
+}
+
+// WithBucket uploads the binaries and their checksums under the bucket's
+// "nightly" prefix.
+func (m *Nightly) WithBucket(
+	// Bucket endpoint URL
+	endpoint *dagger.Secret,
+
+	// Bucket name
+	bucket *dagger.Secret,
+
+	// Bucket access key ID
+	accessKeyID *dagger.Secret,
+
+	// Bucket secret access key
+	secretAccessKey *dagger.Secret,
+
+	// Public URL the bucket is served from, used to link the artifacts in
+	// notifications (e.g., "https://downloads.example.com")
+	// +optional
+	baseURL string,
+) *Nightly {
+	m.BucketEndpoint = endpoint
+	m.BucketName = bucket
+	m.BucketAccessKeyID = accessKeyID
+	m.BucketSecretAccessKey = secretAccessKey
+	m.BaseURL = baseURL
+	return m
+}
+
+// WithSlack announces the result to a Slack incoming webhook.
+func (m *Nightly) WithSlack(
+	// Slack incoming webhook URL
+	webhook *dagger.Secret,
+) *Nightly {
+	m.SlackWebhook = webhook
+	return m
+}
+
+// WithDiscord announces the result to a Discord webhook.
+func (m *Nightly) WithDiscord(
+	// Discord webhook URL
+	webhook *dagger.Secret,
+) *Nightly {
+	m.DiscordWebhook = webhook
+	return m
+}
+
+// Run builds and tests the project, checksums the binaries, uploads them
+// to the bucket, and sends the notifications, then returns a markdown
+// report of every step.
+//
+// A failing step skips the steps after it, unless it is listed in
+// continue-on-error. Notifications are sent either way, so failures are
+// announced. Steps that need missing output (checksums without binaries,
+// an upload without checksums) or configuration are skipped. Run fails
+// when any step outside continue-on-error fails.
+func (m *Nightly) Run(
+	ctx context.Context,
+
+	// Link to the pipeline run, included in notifications
+	// +optional
+	url string,
+) (string, error) {
+	p := &pipeline{continueOnError: m.ContinueOnError}
+
+	var dist *dagger.Directory
+	built := p.step(ctx, stepBuild, func(ctx context.Context) (string, error) {
+		build := dag.Gobuild(dagger.GobuildOpts{Source: m.Source}).
+			Build(dagger.GobuildBuildOpts{
+				Name:    m.Name,
+				Pkg:     m.Pkg,
+				Targets: m.Targets,
+				Version: m.Version,
+				Ldflags: m.Ldflags,
+			})
+		d, err := dag.Utilsverse().FlattenNameOsArch(build).Sync(ctx)
+		if err != nil {
+			return "", err
+		}
+		entries, err := d.Entries(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to list binaries: %w", err)
+		}
+		dist = d
+		return fmt.Sprintf("%d binaries", len(entries)), nil
+	})
+
+	p.step(ctx, stepTest, func(ctx context.Context) (string, error) {
+		res := dag.Gotest(dagger.GotestOpts{Source: m.Source}).Test()
+		ok, err := res.Passed(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			output, err := res.Output(ctx)
+			if err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("tests failed\n\n%s", output)
+		}
+		return "all tests passed", nil
+	})
+
+	checksummed := false
+	if built {
+		checksummed = p.step(ctx, stepChecksum, func(ctx context.Context) (string, error) {
+			d, err := dag.Checksumer().Checksum(dist, dagger.ChecksumerChecksumOpts{Aggregate: true}).Sync(ctx)
+			if err != nil {
+				return "", err
+			}
+			dist = d
+			return "SHA256SUMS written", nil
+		})
+	} else {
+		p.skip(stepChecksum, "nothing was built")
+	}
+
+	uploaded := false
+	switch {
+	case m.BucketEndpoint == nil:
+		p.skip(stepUpload, "no bucket configured")
+	case !checksummed:
+		p.skip(stepUpload, "no checksummed binaries")
+	default:
+		uploaded = p.step(ctx, stepUpload, func(ctx context.Context) (string, error) {
+			uploader := dag.Bucketuploader(m.BucketEndpoint, m.BucketName, m.BucketAccessKeyID, m.BucketSecretAccessKey)
+			if err := uploader.UploadNightly(ctx, dist); err != nil {
+				return "", err
+			}
+			if m.BaseURL != "" {
+				return "uploaded to " + m.nightlyURL(), nil
+			}
+			return "uploaded under nightly/", nil
+		})
+	}
+
+	if m.SlackWebhook == nil && m.DiscordWebhook == nil {
+		p.skip(stepNotify, "no webhook configured")
+	} else {
+		// Notifications go out even when an earlier step halted the run.
+		status := overall(p.results)
+		n := dag.Notify(fmt.Sprintf("%s nightly %s", m.Name, titles[status]), dagger.NotifyOpts{Status: status}).
+			WithVersion(m.Version).
+			WithMessage(summary(p.results))
+		if url != "" {
+			n = n.WithURL(url)
+		}
+		if uploaded {
+			n = n.WithChecksums(dist.File("SHA256SUMS"), dagger.NotifyWithChecksumsOpts{BaseURL: m.nightlyURL()})
+		}
+
+		p.run(ctx, stepNotify, func(ctx context.Context) (string, error) {
+			var sent []string
+			if m.SlackWebhook != nil {
+				if _, err := n.Slack(ctx, m.SlackWebhook); err != nil {
+					return "", err
+				}
+				sent = append(sent, "Slack")
+			}
+			if m.DiscordWebhook != nil {
+				if _, err := n.Discord(ctx, m.DiscordWebhook); err != nil {
+					return "", err
+				}
+				sent = append(sent, "Discord")
+			}
+			return "sent to " + strings.Join(sent, " and "), nil
+		})
+	}
+
+	report := markdown(m.Name, m.Version, p.results)
+	if overall(p.results) == failure {
+		return "", fmt.Errorf("nightly failed\n\n%s", report)
+	}
+	return report, nil
+}
+
+// nightlyURL returns the URL the nightly artifacts are served under, or ""
+// when no base URL is set.
+func (m *Nightly) nightlyURL() string {
+	if m.BaseURL == "" {
+		return ""
+	}
+	return strings.TrimSuffix(m.BaseURL, "/") + "/nightly"
+}
+
+// pipeline records step results and whether a failure has halted the run.
+type pipeline struct {
+	continueOnError []string
+	results         []stepResult
+	halted          bool
+}
+
+// step runs fn as the named step, or skips it when an earlier step halted
+// the run, and reports whether it passed.
+func (p *pipeline) step(ctx context.Context, name string, fn func(context.Context) (string, error)) bool {
+	if p.halted {
+		p.skip(name, "an earlier step failed")
+		return false
+	}
+	return p.run(ctx, name, fn)
+}
+
+// run runs fn as the named step and reports whether it passed. A failure
+// halts the run unless the step is listed in continue-on-error.
+func (p *pipeline) run(ctx context.Context, name string, fn func(context.Context) (string, error)) bool {
+	start := time.Now()
+	detail, err := fn(ctx)
+	res := stepResult{name: name, status: passed, detail: detail, duration: time.Since(start).Round(time.Second)}
+	if err != nil {
+		res.detail = errorDetail(err)
+		if slices.Contains(p.continueOnError, name) {
+			res.status = tolerated
+		} else {
+			res.status = failed
+			p.halted = true
+		}
+	}
+	p.results = append(p.results, res)
+	return err == nil
+}
+
+func (p *pipeline) skip(name, reason string) {
+	p.results = append(p.results, stepResult{name: name, status: skipped, detail: reason})
+}
+
+// errorDetail returns a failed command's output, or the error itself.
+func errorDetail(err error) string {
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return e.Stdout + e.Stderr
+	}
+	return err.Error()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Step names, in the order they run.
+const (
+	stepBuild    = "build"
+	stepTest     = "test"
+	stepChecksum = "checksum"
+	stepUpload   = "upload"
+	stepNotify   = "notify"
+)
+
+var steps = []string{stepBuild, stepTest, stepChecksum, stepUpload, stepNotify}
+
+// Step statuses.
+const (
+	passed    = "passed"
+	failed    = "failed"
+	tolerated = "failed (continued)"
+	skipped   = "skipped"
+)
+
+// Overall statuses, matching the notify module's.
+const (
+	success = "success"
+	failure = "failure"
+	warning = "warning"
+)
+
+// detailLines bounds how much of a failing step's output is reported.
+const detailLines = 40
+
+// stepResult is the outcome of one step.
+type stepResult struct {
+	name     string
+	status   string
+	detail   string
+	duration time.Duration
+}
+
+// overall returns "failure" when a step failed, "warning" when only steps
+// allowed to fail did, and "success" otherwise.
+func overall(results []stepResult) string {
+	status := success
+	for _, r := range results {
+		switch r.status {
+		case failed:
+			return failure
+		case tolerated:
+			status = warning
+		}
+	}
+	return status
+}
+
+// summary returns one line per step, for notifications.
+func summary(results []stepResult) string {
+	var b strings.Builder
+	for _, r := range results {
+		fmt.Fprintf(&b, "%s *%s* %s", icon(r.status), r.name, r.status)
+		if r.status == skipped {
+			fmt.Fprintf(&b, ": %s", r.detail)
+		} else {
+			fmt.Fprintf(&b, " (%s)", r.duration)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// markdown renders the aggregated status report.
+func markdown(name, version string, results []stepResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s %s: %s\n\n", name, version, overall(results))
+	b.WriteString("| Step | Status | Duration | Detail |\n")
+	b.WriteString("|------|--------|----------|--------|\n")
+	for _, r := range results {
+		detail := r.detail
+		if r.status == failed || r.status == tolerated {
+			detail = "see below"
+		}
+		duration := "-"
+		if r.status != skipped {
+			duration = r.duration.String()
+		}
+		fmt.Fprintf(&b, "| %s | %s %s | %s | %s |\n", r.name, icon(r.status), r.status, duration, detail)
+	}
+
+	for _, r := range results {
+		if r.status != failed && r.status != tolerated {
+			continue
+		}
+		fmt.Fprintf(&b, "\n### %s\n\n```\n%s\n```\n", r.name, tail(r.detail, detailLines))
+	}
+
+	return b.String()
+}
+
+func icon(status string) string {
+	switch status {
+	case passed:
+		return "✅"
+	case failed:
+		return "❌"
+	case tolerated:
+		return "⚠️"
+	default:
+		return "⏭️"
+	}
+}
+
+// tail returns the last n lines of s.
+func tail(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) <= n {
+		return strings.Join(lines, "\n")
+	}
+	return fmt.Sprintf("... %d lines omitted\n%s", len(lines)-n, strings.Join(lines[len(lines)-n:], "\n"))
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"dagger/notify/internal/dagger"
+)
+
+// supportedStatuses lists the statuses a notification can carry. Each one
+// maps to a color on Discord and an emoji on Slack.
+var supportedStatuses = []string{"success", "failure", "warning", "info"}
+
+type Notify struct {
+	// Headline of the notification (e.g., "myproject v1.2.0 released")
+	// +private
+	Title string
+
+	// One of "success", "failure", "warning", or "info"
+	// +private
+	Status string
+
+	// Version the notification refers to
+	// +private
+	Version string
+
+	// Link to the release, build log, or pipeline run
+	// +private
+	URL string
+
+	// Message body, or the template source when IsTemplate is set
+	// +private
+	Message string
+
+	// Whether Message is a Go text/template
+	// +private
+	IsTemplate bool
+
+	// Artifacts listed in the notification
+	// +private
+	Artifacts []Artifact
+}
+
+// Artifact is a published file listed in a notification, mirroring the
+// per-file entries used by the ghrelease and bucketupload modules.
+type Artifact struct {
+	// File name or path relative to the release (e.g., "tapes-linux-amd64")
+	Name string
+
+	// Download URL
+	// +optional
+	URL string
+
+	// Hex-encoded SHA-256 checksum of the file
+	// +optional
+	SHA256 string
+
+	// Size in bytes
+	// +optional
+	Size int
+}
+
+// WithURL sets the artifact's download URL.
+func (a *Artifact) WithURL(
+	// Download URL
+	url string,
+) *Artifact {
+	a.URL = url
+	return a
+}
+
+// WithSHA256 sets the artifact's hex-encoded SHA-256 checksum.
+func (a *Artifact) WithSHA256(
+	// Hex-encoded SHA-256 checksum
+	checksum string,
+) *Artifact {
+	a.SHA256 = checksum
+	return a
+}
+
+// WithSize sets the artifact's size in bytes.
+func (a *Artifact) WithSize(
+	// Size in bytes
+	size int,
+) *Artifact {
+	a.Size = size
+	return a
+}
+
+// New creates a notification. Chain the With functions to add details,
+// then send it with Slack or Discord.
+func New(
+	// Headline of the notification (e.g., "myproject v1.2.0 released")
+	title string,
+
+	// One of "success", "failure", "warning", or "info"
+	// +default="success"
+	status string,
+) (*Notify, error) {
+	if err := validateStatus(status); err != nil {
+		return nil, err
+	}
+	return &Notify{
+		Title:  title,
+		Status: status,
+	}, nil
+}
+
+// NewArtifact returns an artifact entry to pass to WithArtifacts.
+func (m *Notify) NewArtifact(
+	// File name or path relative to the release
+	name string,
+) *Artifact {
+	return &Artifact{Name: name}
+}
+
+// WithStatus overrides the notification status.
+func (m *Notify) WithStatus(
+	// One of "success", "failure", "warning", or "info"
+	status string,
+) (*Notify, error) {
+	if err := validateStatus(status); err != nil {
+		return nil, err
+	}
+	m.Status = status
+	return m, nil
+}
+
+// WithVersion sets the version shown alongside the message.
+func (m *Notify) WithVersion(
+	// Version (e.g., "v1.2.0")
+	version string,
+) *Notify {
+	m.Version = version
+	return m
+}
+
+// WithURL sets the link to the release, build log, or pipeline run.
+func (m *Notify) WithURL(
+	// Link target
+	url string,
+) *Notify {
+	m.URL = url
+	return m
+}
+
+// WithMessage sets the message body. Slack renders it as mrkdwn and Discord
+// as markdown.
+func (m *Notify) WithMessage(
+	// Message body
+	message string,
+) *Notify {
+	m.Message = message
+	m.IsTemplate = false
+	return m
+}
+
+// WithTemplate sets the message body to a Go text/template rendered against
+// the notification when it is sent. The template can reference .Title,
+// .Status, .Version, .URL, and .Artifacts (each with .Name, .URL, .SHA256,
+// and .Size).
+func (m *Notify) WithTemplate(
+	// Go text/template source
+	tmpl string,
+) (*Notify, error) {
+	if _, err := template.New("message").Parse(tmpl); err != nil {
+		return nil, fmt.Errorf("invalid message template: %w", err)
+	}
+	m.Message = tmpl
+	m.IsTemplate = true
+	return m, nil
+}
+
+// WithArtifacts appends artifacts to the notification.
+func (m *Notify) WithArtifacts(
+	// Artifacts built with NewArtifact
+	artifacts []Artifact,
+) *Notify {
+	m.Artifacts = append(m.Artifacts, artifacts...)
+	return m
+}
+
+// WithChecksums appends one artifact per line of a sha256sum-format file
+// (such as the SHA256SUMS file written by the checksum module). When
+// baseURL is set, each artifact links to "<baseURL>/<path>".
+func (m *Notify) WithChecksums(
+	ctx context.Context,
+
+	// Checksums file in "<sha256>  <path>" format
+	sums *dagger.File,
+
+	// Base URL the artifacts are published under
+	// +optional
+	baseURL string,
+) (*Notify, error) {
+	contents, err := sums.Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums file: %w", err)
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		sum, path, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("invalid checksum line %q: must be in \"<sha256>  <path>\" format", line)
+		}
+		// sha256sum marks binary-mode entries with a leading "*".
+		path = strings.TrimPrefix(strings.TrimSpace(path), "*")
+
+		artifact := Artifact{Name: path, SHA256: sum}
+		if baseURL != "" {
+			artifact.URL = strings.TrimSuffix(baseURL, "/") + "/" + path
+		}
+		m.Artifacts = append(m.Artifacts, artifact)
+	}
+
+	return m, nil
+}
+
+// Slack posts the notification to a Slack incoming webhook.
+func (m *Notify) Slack(
+	ctx context.Context,
+
+	// Slack incoming webhook URL
+	webhook *dagger.Secret,
+) (string, error) {
+	body, err := m.body()
+	if err != nil {
+		return "", err
+	}
+	payload, err := m.slackPayload(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode Slack payload: %w", err)
+	}
+	if err := post(ctx, webhook, payload); err != nil {
+		return "", fmt.Errorf("failed to post Slack notification: %w", err)
+	}
+	return "✅ Slack notification sent", nil
+}
+
+// Discord posts the notification to a Discord webhook.
+func (m *Notify) Discord(
+	ctx context.Context,
+
+	// Discord webhook URL
+	webhook *dagger.Secret,
+) (string, error) {
+	body, err := m.body()
+	if err != nil {
+		return "", err
+	}
+	payload, err := m.discordPayload(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode Discord payload: %w", err)
+	}
+	if err := post(ctx, webhook, payload); err != nil {
+		return "", fmt.Errorf("failed to post Discord notification: %w", err)
+	}
+	return "✅ Discord notification sent", nil
+}
+
+// body returns the message body, rendering it first when it is a template.
+func (m *Notify) body() (string, error) {
+	if !m.IsTemplate {
+		return m.Message, nil
+	}
+
+	tmpl, err := template.New("message").Parse(m.Message)
+	if err != nil {
+		return "", fmt.Errorf("invalid message template: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, m); err != nil {
+		return "", fmt.Errorf("failed to render message template: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// post sends payload as JSON to the webhook URL. The URL is only ever
+// exposed to curl through a secret environment variable.
+func post(ctx context.Context, webhook *dagger.Secret, payload []byte) error {
+	_, err := dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "curl"}).
+		WithSecretVariable("WEBHOOK_URL", webhook).
+		WithNewFile("/payload.json", string(payload)).
+		// Repeating a notification must send it again.
+		WithEnvVariable("NOTIFY_SESSION", fmt.Sprintf("%d", time.Now().UnixNano())).
+		WithExec([]string{"sh", "-c",
+			`curl -sS --fail-with-body -H 'Content-Type: application/json' --data @/payload.json "$WEBHOOK_URL"`,
+		}).
+		Sync(ctx)
+	return err
+}
+
+func validateStatus(status string) error {
+	for _, s := range supportedStatuses {
+		if status == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid status %q: must be one of %s", status, strings.Join(supportedStatuses, ", "))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Discord and Slack limit the size of message bodies.
+const (
+	slackTextLimit   = 3000
+	discordTextLimit = 4096
+)
+
+// statusColors maps notification statuses to Discord embed colors.
+var statusColors = map[string]int{
+	"success": 0x2EB67D,
+	"failure": 0xE01E5A,
+	"warning": 0xECB22E,
+	"info":    0x5865F2,
+}
+
+// statusEmoji maps notification statuses to Slack emoji.
+var statusEmoji = map[string]string{
+	"success": "✅",
+	"failure": "❌",
+	"warning": "⚠️",
+	"info":    "ℹ️",
+}
+
+// slackPayload renders the notification as a Slack Block Kit message.
+func (m *Notify) slackPayload(body string) ([]byte, error) {
+	title := strings.TrimSpace(statusEmoji[m.Status] + " " + m.Title)
+
+	blocks := []map[string]any{
+		{
+			"type": "header",
+			"text": map[string]any{"type": "plain_text", "text": truncate(title, 150)},
+		},
+	}
+	if body != "" {
+		blocks = append(blocks, map[string]any{
+			"type": "section",
+			"text": map[string]any{"type": "mrkdwn", "text": truncate(body, slackTextLimit)},
+		})
+	}
+	if fields := m.fields(); len(fields) > 0 {
+		var slackFields []map[string]any
+		for _, f := range fields {
+			slackFields = append(slackFields, map[string]any{
+				"type": "mrkdwn",
+				"text": fmt.Sprintf("*%s*\n%s", f[0], f[1]),
+			})
+		}
+		blocks = append(blocks, map[string]any{"type": "section", "fields": slackFields})
+	}
+	if artifacts := m.artifactLines(true); artifacts != "" {
+		blocks = append(blocks, map[string]any{
+			"type": "section",
+			"text": map[string]any{"type": "mrkdwn", "text": truncate("*Artifacts*\n"+artifacts, slackTextLimit)},
+		})
+	}
+	if m.URL != "" {
+		blocks = append(blocks, map[string]any{
+			"type": "actions",
+			"elements": []map[string]any{{
+				"type": "button",
+				"text": map[string]any{"type": "plain_text", "text": "View"},
+				"url":  m.URL,
+			}},
+		})
+	}
+
+	return json.Marshal(map[string]any{
+		// Fallback for notifications and clients without Block Kit.
+		"text":   title,
+		"blocks": blocks,
+	})
+}
+
+// discordPayload renders the notification as a Discord embed.
+func (m *Notify) discordPayload(body string) ([]byte, error) {
+	description := body
+	if artifacts := m.artifactLines(false); artifacts != "" {
+		description = strings.TrimSpace(description + "\n\n**Artifacts**\n" + artifacts)
+	}
+
+	embed := map[string]any{
+		"title":       truncate(m.Title, 256),
+		"description": truncate(description, discordTextLimit),
+		"color":       statusColors[m.Status],
+	}
+	if m.URL != "" {
+		embed["url"] = m.URL
+	}
+
+	var fields []map[string]any
+	for _, f := range m.fields() {
+		fields = append(fields, map[string]any{"name": f[0], "value": f[1], "inline": true})
+	}
+	if len(fields) > 0 {
+		embed["fields"] = fields
+	}
+
+	return json.Marshal(map[string]any{"embeds": []map[string]any{embed}})
+}
+
+// fields returns the short name/value pairs shown alongside the message.
+func (m *Notify) fields() [][2]string {
+	var fields [][2]string
+	if m.Version != "" {
+		fields = append(fields, [2]string{"Version", m.Version})
+	}
+	if m.Status != "" {
+		fields = append(fields, [2]string{"Status", m.Status})
+	}
+	return fields
+}
+
+// artifactLines renders one line per artifact with its link, size, and
+// checksum, using Slack ("<url|name>") or markdown ("[name](url)") links.
+func (m *Notify) artifactLines(slack bool) string {
+	var lines []string
+	for _, a := range m.Artifacts {
+		name := "`" + a.Name + "`"
+		if a.URL != "" {
+			if slack {
+				name = fmt.Sprintf("<%s|%s>", a.URL, a.Name)
+			} else {
+				name = fmt.Sprintf("[%s](%s)", a.Name, a.URL)
+			}
+		}
+
+		line := "• " + name
+		if a.Size > 0 {
+			line += " (" + formatBytes(a.Size) + ")"
+		}
+		if a.SHA256 != "" {
+			line += "\n  sha256 `" + a.SHA256 + "`"
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// formatBytes renders a byte count in binary units.
+func formatBytes(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := int64(n) / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
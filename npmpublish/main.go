@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"dagger/npmpublish/internal/dagger"
+)
+
+const (
+	nodeImage string = "node:22-bookworm"
+)
+
+type Npmpublish struct {
+	// Source is the repository containing the package.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Path is the package directory, relative to Source.
+	//
+	// +private
+	Path string
+
+	// EnvVars are extra environment variables in KEY=VALUE format.
+	//
+	// +private
+	EnvVars []string
+}
+
+// New creates a new Npmpublish module instance.
+func New(
+	// The repository containing the package. The package manager is
+	// detected from the lockfile at its root.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Package directory, relative to source (e.g., "packages/client")
+	// +optional
+	// +default="."
+	path string,
+
+	// Environment variables in KEY=VALUE format. For provenance from GitHub
+	// Actions, pass the GITHUB_* and RUNNER_* context variables.
+	// +optional
+	envVars []string,
+) *Npmpublish {
+	return &Npmpublish{
+		Source:  source,
+		Path:    path,
+		EnvVars: envVars,
+	}
+}
+
+// Build installs dependencies, optionally runs the package's "test"
+// script, runs each of the given package.json scripts, and returns the
+// package directory. Scripts the package does not define are skipped.
+func (m *Npmpublish) Build(
+	ctx context.Context,
+
+	// Run the "test" script before building
+	// +optional
+	// +default=true
+	test bool,
+
+	// Scripts to run, in order (default: "build")
+	// +optional
+	scripts []string,
+) (*dagger.Directory, error) {
+	ctr, err := m.build(ctx, test, scripts)
+	if err != nil {
+		return nil, err
+	}
+	return ctr.Directory("."), nil
+}
+
+// Pack builds the package and returns the tarball "npm pack" produces,
+// e.g. for attaching to a release.
+func (m *Npmpublish) Pack(
+	ctx context.Context,
+
+	// Version to set in package.json before packing (e.g., "v1.2.3")
+	// +optional
+	version string,
+
+	// Run the "test" script before building
+	// +optional
+	// +default=true
+	test bool,
+) (*dagger.File, error) {
+	ctr, err := m.build(ctx, test, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	out := withVersion(ctr, version).
+		WithExec([]string{"mkdir", "-p", "/out"}).
+		WithExec([]string{"npm", "pack", "--pack-destination", "/out"}).
+		Directory("/out")
+
+	files, err := out.Glob(ctx, "*.tgz")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list packed tarball: %w", err)
+	}
+	if len(files) != 1 {
+		return nil, fmt.Errorf("expected one packed tarball, found %d", len(files))
+	}
+
+	return out.File(files[0]), nil
+}
+
+// Publish builds the package, sets its version, and publishes it with
+// "npm publish" to npm or a private registry. Yarn and pnpm projects are
+// built with their own package manager but always published with npm, which
+// is what supports provenance.
+//
+// Provenance needs the CI's OIDC credentials: on GitHub Actions, pass
+// oidcRequestURL and oidcRequestToken from ACTIONS_ID_TOKEN_REQUEST_URL and
+// ACTIONS_ID_TOKEN_REQUEST_TOKEN, and the GITHUB_* context as envVars.
+func (m *Npmpublish) Publish(
+	ctx context.Context,
+
+	// Registry auth token
+	token *dagger.Secret,
+
+	// Version to publish (e.g., "v1.2.3"). Defaults to package.json's version.
+	// +optional
+	version string,
+
+	// Registry URL
+	// +optional
+	// +default="https://registry.npmjs.org/"
+	registry string,
+
+	// Dist-tag to publish under
+	// +optional
+	// +default="latest"
+	tag string,
+
+	// Package access for scoped packages: "public" or "restricted"
+	// +optional
+	access string,
+
+	// Publish with a signed provenance statement (npm publish --provenance)
+	// +optional
+	provenance bool,
+
+	// OIDC token request URL (ACTIONS_ID_TOKEN_REQUEST_URL on GitHub Actions)
+	// +optional
+	oidcRequestURL string,
+
+	// OIDC token request token (ACTIONS_ID_TOKEN_REQUEST_TOKEN on GitHub Actions)
+	// +optional
+	oidcRequestToken *dagger.Secret,
+
+	// Run the "test" script before building
+	// +optional
+	// +default=true
+	test bool,
+
+	// Run "npm publish --dry-run" instead of publishing
+	// +optional
+	dryRun bool,
+) (string, error) {
+	u, err := url.Parse(registry)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid registry %q: must be a URL", registry)
+	}
+	if access != "" && access != "public" && access != "restricted" {
+		return "", fmt.Errorf("invalid access %q: must be \"public\" or \"restricted\"", access)
+	}
+	if provenance && (oidcRequestURL == "" || oidcRequestToken == nil) {
+		return "", fmt.Errorf("provenance requires oidc-request-url and oidc-request-token")
+	}
+
+	ctr, err := m.build(ctx, test, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// The token stays in the environment; .npmrc only references it.
+	authKey := "//" + u.Host + strings.TrimSuffix(u.Path, "/") + "/:_authToken"
+	ctr = withVersion(ctr, version).
+		WithNewFile("/root/.npmrc", authKey+"=${NODE_AUTH_TOKEN}\n").
+		WithSecretVariable("NODE_AUTH_TOKEN", token)
+
+	args := []string{"npm", "publish", "--registry", registry, "--tag", tag}
+	if access != "" {
+		args = append(args, "--access", access)
+	}
+	if provenance {
+		ctr = ctr.
+			WithEnvVariable("ACTIONS_ID_TOKEN_REQUEST_URL", oidcRequestURL).
+			WithSecretVariable("ACTIONS_ID_TOKEN_REQUEST_TOKEN", oidcRequestToken)
+		args = append(args, "--provenance")
+	}
+	if dryRun {
+		args = append(args, "--dry-run")
+	}
+
+	out, err := ctr.WithExec(args).CombinedOutput(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("npm publish failed\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return out, nil
+}
+
+// build returns the container after installing dependencies and running
+// the test and build scripts, with the package directory as workdir.
+func (m *Npmpublish) build(ctx context.Context, test bool, scripts []string) (*dagger.Container, error) {
+	if len(scripts) == 0 {
+		scripts = []string{"build"}
+	}
+	if test {
+		scripts = append([]string{"test"}, scripts...)
+	}
+
+	manifest, err := m.Source.File(m.Path + "/package.json").Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package.json: %w", err)
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal([]byte(manifest), &pkg); err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	pm, install, err := m.packageManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr := dag.Container().
+		From(nodeImage).
+		WithExec([]string{"corepack", "enable"}).
+		WithMountedCache("/root/.npm", dag.CacheVolume("npm")).
+		WithMountedCache("/root/.cache/yarn", dag.CacheVolume("yarn")).
+		WithMountedCache("/root/.local/share/pnpm/store", dag.CacheVolume("pnpm")).
+		WithDirectory("/src", m.Source, dagger.ContainerWithDirectoryOpts{
+			Exclude: []string{"**/node_modules"},
+		}).
+		WithWorkdir("/src").
+		WithExec(install).
+		WithWorkdir("/src/" + m.Path)
+
+	for _, env := range m.EnvVars {
+		k, v, ok := strings.Cut(env, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(k, v)
+	}
+
+	for _, script := range scripts {
+		if _, ok := pkg.Scripts[script]; !ok {
+			continue
+		}
+		ctr = ctr.WithExec([]string{pm, "run", script})
+	}
+
+	return ctr, nil
+}
+
+// packageManager returns the package manager whose lockfile is present at
+// the source root and its install command.
+func (m *Npmpublish) packageManager(ctx context.Context) (string, []string, error) {
+	lockfiles := []struct {
+		file    string
+		pm      string
+		install []string
+	}{
+		{"pnpm-lock.yaml", "pnpm", []string{"pnpm", "install", "--frozen-lockfile"}},
+		{"yarn.lock", "yarn", []string{"yarn", "install", "--frozen-lockfile"}},
+		{"package-lock.json", "npm", []string{"npm", "ci"}},
+	}
+	for _, l := range lockfiles {
+		ok, err := m.Source.Exists(ctx, l.file)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to inspect source directory: %w", err)
+		}
+		if ok {
+			return l.pm, l.install, nil
+		}
+	}
+	return "npm", []string{"npm", "install"}, nil
+}
+
+// withVersion sets the package.json version without creating a git tag.
+func withVersion(ctr *dagger.Container, version string) *dagger.Container {
+	if version == "" {
+		return ctr
+	}
+	return ctr.WithExec([]string{
+		"npm", "version", strings.TrimPrefix(version, "v"),
+		"--no-git-tag-version", "--allow-same-version",
+	})
+}
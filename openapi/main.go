@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/openapi/internal/dagger"
+)
+
+const (
+	goImage       string = "golang:1.26-bookworm"
+	gitImage      string = "alpine/git:v2.47.1"
+	spectralImage string = "stoplight/spectral:6.14.2"
+	redoclyImage  string = "redocly/cli:1.34.0"
+	oasdiffImage  string = "tufin/oasdiff:v1.10.25"
+
+	oapiCodegen string = "github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen@v2.4.1"
+)
+
+// Linters.
+const (
+	linterSpectral string = "spectral"
+	linterRedocly  string = "redocly"
+)
+
+// spectralRuleset is used when the source has no ruleset of its own.
+const spectralRuleset string = `extends: ["spectral:oas"]
+`
+
+type Openapi struct {
+	// Source is the repository containing the spec.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Spec is the OpenAPI spec, relative to Source.
+	//
+	// +private
+	Spec string
+
+	// Linter is the spec linter: "spectral" or "redocly".
+	//
+	// +private
+	Linter string
+
+	// Configs are oapi-codegen config files, relative to Source.
+	//
+	// +private
+	Configs []string
+}
+
+// New creates a new Openapi module instance.
+func New(
+	// The repository containing the spec.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// OpenAPI spec, relative to source
+	// +optional
+	// +default="openapi.yaml"
+	spec string,
+
+	// Spec linter: "spectral" or "redocly"
+	// +optional
+	// +default="spectral"
+	linter string,
+
+	// oapi-codegen config files, relative to source, each generating
+	// clients, servers, or types (e.g. "api/client.cfg.yaml")
+	// +optional
+	configs []string,
+) *Openapi {
+	return &Openapi{
+		Source:  source,
+		Spec:    spec,
+		Linter:  linter,
+		Configs: configs,
+	}
+}
+
+// Lint lints the spec and fails on any error.
+//
+// Spectral uses the source's .spectral.yaml ruleset, or the built-in
+// "spectral:oas" ruleset when there is none. Redocly uses the source's
+// redocly.yaml, or its recommended rules.
+func (m *Openapi) Lint(ctx context.Context) (string, error) {
+	var ctr *dagger.Container
+	switch m.Linter {
+	case linterSpectral:
+		args := []string{"spectral", "lint", "--fail-severity", "error", m.Spec}
+
+		ruleset := false
+		for _, name := range []string{".spectral.yaml", ".spectral.yml", ".spectral.json", ".spectral.js"} {
+			ok, err := m.Source.Exists(ctx, name)
+			if err != nil {
+				return "", fmt.Errorf("failed to inspect source directory: %w", err)
+			}
+			ruleset = ruleset || ok
+		}
+
+		ctr = dag.Container().From(spectralImage)
+		if !ruleset {
+			ctr = ctr.WithNewFile("/spectral/ruleset.yaml", spectralRuleset)
+			args = append(args, "--ruleset", "/spectral/ruleset.yaml")
+		}
+		ctr = ctr.
+			WithDirectory("/src", m.Source).
+			WithWorkdir("/src").
+			WithExec(args)
+
+	case linterRedocly:
+		ctr = dag.Container().
+			From(redoclyImage).
+			WithDirectory("/src", m.Source).
+			WithWorkdir("/src").
+			WithExec([]string{"redocly", "lint", m.Spec})
+
+	default:
+		return "", fmt.Errorf("invalid linter %q: must be %q or %q", m.Linter, linterSpectral, linterRedocly)
+	}
+
+	out, err := ctr.CombinedOutput(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("%s found problems in %s\n\n%s%s", m.Linter, m.Spec, e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return out, nil
+}
+
+// Breaking fails when the spec contains breaking changes compared to a
+// previous version, using oasdiff.
+//
+// The previous version is either the against directory (a checkout of the
+// same repository) or, when it is not given, the git ref against read from
+// the source's .git directory.
+func (m *Openapi) Breaking(
+	ctx context.Context,
+
+	// Git ref to compare against (e.g., "main" or "v1.2.0")
+	// +optional
+	// +default="main"
+	against string,
+
+	// Checkout of the previous version to compare against instead of a git
+	// ref
+	// +optional
+	againstSource *dagger.Directory,
+
+	// Lowest severity that fails: "ERR" or "WARN"
+	// +optional
+	// +default="ERR"
+	failOn string,
+) (string, error) {
+	if againstSource == nil {
+		// Export the whole tree so specs split across files still resolve
+		// their $refs.
+		againstSource = dag.Container().
+			From(gitImage).
+			WithDirectory("/src", m.Source).
+			WithWorkdir("/src").
+			// The mounted source may be owned by another user.
+			WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "/src"}).
+			WithExec([]string{"mkdir", "/against"}).
+			WithExec([]string{"sh", "-c", `git archive "$1" | tar -x -C /against`, "sh", against}).
+			Directory("/against")
+	}
+
+	out, err := dag.Container().
+		From(oasdiffImage).
+		WithDirectory("/against", againstSource).
+		WithDirectory("/src", m.Source).
+		WithExec([]string{
+			"oasdiff", "breaking",
+			"/against/" + m.Spec, "/src/" + m.Spec,
+			"--fail-on", failOn,
+		}).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("breaking changes found\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return fmt.Sprintf("✅ no breaking changes\n%s", out), nil
+}
+
+// Generate runs oapi-codegen for every config and returns the source
+// directory with the generated code written.
+//
+// Each config runs from its own directory, so its output path is relative
+// to the config file, as with a "go:generate" directive next to it.
+func (m *Openapi) Generate() (*dagger.Directory, error) {
+	ctr, err := m.generateContainer()
+	if err != nil {
+		return nil, err
+	}
+	return ctr.Directory("/src"), nil
+}
+
+// Drift runs oapi-codegen for every config and fails with the resulting
+// diff if it changes any committed file, indicating that the spec changed
+// without regenerating the code.
+func (m *Openapi) Drift(ctx context.Context) (string, error) {
+	ctr, err := m.generateContainer()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = ctr.
+		WithDirectory("/committed", m.Source).
+		WithExec([]string{"diff", "-ruN", "--exclude=.git", "/committed", "/src"}).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("generated code is out of date with %s: run the generate function and commit the changes\n\n%s", m.Spec, e.Stdout)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return "✅ generated code is up to date", nil
+}
+
+// Check lints the spec and, when oapi-codegen configs are given, checks
+// the generated code for drift, making this suitable for CI checks.
+//
+// +check
+func (m *Openapi) Check(ctx context.Context) (string, error) {
+	out, err := m.Lint(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(m.Configs) == 0 {
+		return out, nil
+	}
+
+	driftOut, err := m.Drift(ctx)
+	if err != nil {
+		return "", err
+	}
+	return out + "\n" + driftOut, nil
+}
+
+// generateContainer returns a container that has run oapi-codegen for every
+// config, with the Go module and build caches shared with the other Go
+// modules in this daggerverse.
+func (m *Openapi) generateContainer() (*dagger.Container, error) {
+	if len(m.Configs) == 0 {
+		return nil, fmt.Errorf("no oapi-codegen configs given")
+	}
+
+	ctr := dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithExec([]string{"go", "install", oapiCodegen}).
+		WithDirectory("/src", m.Source)
+
+	spec := "/src/" + strings.TrimPrefix(m.Spec, "/")
+	for _, config := range m.Configs {
+		config = strings.TrimPrefix(config, "/")
+		ctr = ctr.
+			WithWorkdir("/src/" + path.Dir(config)).
+			WithExec([]string{"oapi-codegen", "-config", path.Base(config), spec})
+	}
+
+	return ctr, nil
+}
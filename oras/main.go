@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"dagger/oras/internal/dagger"
+)
+
+const (
+	orasImage string = "ghcr.io/oras-project/oras:v1.2.2"
+)
+
+type Oras struct {
+	// PlainHTTP talks to registries over plain HTTP.
+	//
+	// +private
+	PlainHTTP bool
+
+	// RegistryAddresses are the registries added with WithRegistryAuth.
+	//
+	// +private
+	RegistryAddresses []string
+
+	// RegistryUsernames are the usernames for RegistryAddresses, by index.
+	//
+	// +private
+	RegistryUsernames []string
+
+	// RegistryTokens are the tokens for RegistryAddresses, by index.
+	//
+	// +private
+	RegistryTokens []*dagger.Secret
+}
+
+// New creates a new Oras module instance.
+func New(
+	// Talk to registries over plain HTTP, e.g. a local test registry
+	// +optional
+	plainHTTP bool,
+) *Oras {
+	return &Oras{
+		PlainHTTP: plainHTTP,
+	}
+}
+
+// WithRegistryAuth adds credentials for a registry, used to push and pull
+// artifacts. Chain once per registry.
+func (m *Oras) WithRegistryAuth(
+	// Registry address (e.g., "ghcr.io")
+	address string,
+
+	// Registry username
+	username string,
+
+	// Registry token or password
+	token *dagger.Secret,
+) *Oras {
+	m.RegistryAddresses = append(m.RegistryAddresses, address)
+	m.RegistryUsernames = append(m.RegistryUsernames, username)
+	m.RegistryTokens = append(m.RegistryTokens, token)
+	return m
+}
+
+// Push pushes every file in a directory as a layer of an OCI artifact and
+// returns the manifest digest. Files keep their relative paths as layer
+// titles, so Pull restores the same tree.
+func (m *Oras) Push(
+	ctx context.Context,
+
+	// Artifact reference (e.g., "ghcr.io/org/myapp-sbom:v1.2.3")
+	ref string,
+
+	// Directory of files to push
+	artifacts *dagger.Directory,
+
+	// Artifact type of the manifest (e.g., "application/spdx+json")
+	// +optional
+	artifactType string,
+
+	// Media type of every layer without an entry in mediaTypes
+	// +optional
+	// +default="application/octet-stream"
+	defaultMediaType string,
+
+	// Per-file media types in "PATH=MEDIATYPE" format
+	// (e.g., "app.wasm=application/wasm")
+	// +optional
+	mediaTypes []string,
+
+	// Manifest annotations in "KEY=VALUE" format
+	// (e.g., "org.opencontainers.image.source=https://github.com/org/repo")
+	// +optional
+	annotations []string,
+) (string, error) {
+	types := map[string]string{}
+	for _, mt := range mediaTypes {
+		path, mediaType, ok := strings.Cut(mt, "=")
+		if !ok || path == "" || mediaType == "" {
+			return "", fmt.Errorf("invalid media type %q: must be in PATH=MEDIATYPE format", mt)
+		}
+		types[strings.TrimPrefix(path, "./")] = mediaType
+	}
+
+	entries, err := artifacts.Glob(ctx, "**/*")
+	if err != nil {
+		return "", fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	args := m.args("push", ref, "--format", "json")
+	if artifactType != "" {
+		args = append(args, "--artifact-type", artifactType)
+	}
+	for _, annotation := range annotations {
+		if k, _, ok := strings.Cut(annotation, "="); !ok || k == "" {
+			return "", fmt.Errorf("invalid annotation %q: must be in KEY=VALUE format", annotation)
+		}
+		args = append(args, "--annotation", annotation)
+	}
+
+	files := 0
+	// Glob returns directory entries with a trailing slash — skip them.
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+		mediaType, ok := types[entry]
+		if !ok {
+			mediaType = defaultMediaType
+		}
+		delete(types, entry)
+		args = append(args, entry+":"+mediaType)
+		files++
+	}
+	if files == 0 {
+		return "", fmt.Errorf("no artifacts to push")
+	}
+	for path := range types {
+		return "", fmt.Errorf("media type given for %q, which is not in the artifacts directory", path)
+	}
+
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := ctr.
+		WithDirectory("/artifacts", artifacts).
+		WithWorkdir("/artifacts").
+		WithExec(args).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to push %s: %w", ref, err)
+	}
+
+	var result struct {
+		Digest string `json:"digest"`
+	}
+	if err := json.Unmarshal([]byte(out), &result); err != nil {
+		return "", fmt.Errorf("failed to parse push output: %w", err)
+	}
+
+	return result.Digest, nil
+}
+
+// Pull pulls an OCI artifact and returns its files. ORAS verifies every
+// layer against its digest while pulling.
+func (m *Oras) Pull(
+	ctx context.Context,
+
+	// Artifact reference, by tag or digest
+	ref string,
+) (*dagger.Directory, error) {
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ctr.
+		WithExec(m.args("pull", ref, "--output", "/out")).
+		Directory("/out"), nil
+}
+
+// Resolve returns the manifest digest a reference points to.
+func (m *Oras) Resolve(
+	ctx context.Context,
+
+	// Artifact reference (e.g., "ghcr.io/org/myapp-sbom:v1.2.3")
+	ref string,
+) (string, error) {
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := ctr.WithExec(m.args("resolve", ref)).Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Verify checks that a reference points to the expected manifest digest and
+// that every layer can be pulled and matches its digest.
+func (m *Oras) Verify(
+	ctx context.Context,
+
+	// Artifact reference, by tag
+	ref string,
+
+	// Expected manifest digest (e.g., the output of push)
+	digest string,
+) (string, error) {
+	resolved, err := m.Resolve(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if resolved != digest {
+		return "", fmt.Errorf("%s resolves to %s, expected %s", ref, resolved, digest)
+	}
+
+	repository := ref
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		repository = ref[:i]
+	}
+	pulled, err := m.Pull(ctx, repository+"@"+digest)
+	if err != nil {
+		return "", err
+	}
+	if _, err := pulled.Sync(ctx); err != nil {
+		return "", fmt.Errorf("failed to pull %s@%s: %w", repository, digest, err)
+	}
+
+	return fmt.Sprintf("✅ %s verified at %s", ref, digest), nil
+}
+
+// args builds an oras command line for the given subcommand.
+func (m *Oras) args(command, ref string, extra ...string) []string {
+	args := []string{"oras", command, ref}
+	if m.PlainHTTP {
+		args = append(args, "--plain-http")
+	}
+	return append(args, extra...)
+}
+
+// container returns the oras container with a Docker config.json holding
+// the registry credentials mounted as a secret.
+func (m *Oras) container(ctx context.Context) (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(orasImage).
+		// Tags move and registries change between calls; always hit them.
+		WithEnvVariable("ORAS_SESSION", fmt.Sprintf("%d", time.Now().UnixNano()))
+	if len(m.RegistryAddresses) == 0 {
+		return ctr, nil
+	}
+
+	auths := map[string]map[string]string{}
+	for i, address := range m.RegistryAddresses {
+		token, err := m.RegistryTokens[i].Plaintext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read token for %s: %w", address, err)
+		}
+		auths[address] = map[string]string{
+			"auth": base64.StdEncoding.EncodeToString([]byte(m.RegistryUsernames[i] + ":" + token)),
+		}
+	}
+
+	config, err := json.Marshal(map[string]any{"auths": auths})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode registry config: %w", err)
+	}
+
+	return ctr.
+		WithMountedSecret("/docker/config.json", dag.SetSecret("oras-docker-config", string(config))).
+		WithEnvVariable("DOCKER_CONFIG", "/docker"), nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// checksumTools maps checksum file extensions to the tool that verifies
+// them, as written by the checksum module.
+var checksumTools = map[string]string{
+	".md5":    "md5sum",
+	".sha1":   "sha1sum",
+	".sha256": "sha256sum",
+	".sha512": "sha512sum",
+}
+
+// aggregateSums matches the aggregated sums files the checksum module
+// writes (e.g., "SHA256SUMS").
+var aggregateSums = regexp.MustCompile(`^(MD5|SHA1|SHA256|SHA512)SUMS$`)
+
+// signatureSuffixes are the extensions of cosign blob signatures: Sigstore
+// bundles, detached signatures, and the certificates of keyless ones.
+var signatureSuffixes = []string{".sigstore.json", ".sig", ".pem", ".cert"}
+
+// signature is the signature files of one signed file.
+type signature struct {
+	bundle      string
+	sig         string
+	certificate string
+}
+
+// inventory sorts a build's files into artifacts, checksum files, and
+// signatures.
+type inventory struct {
+	artifacts []string
+	checksums []string
+	// aggregates are the checksums that list many files (e.g., SHA256SUMS).
+	aggregates []string
+	// signatures maps each signed file to its signature files.
+	signatures map[string]*signature
+}
+
+// classify sorts files. A file only counts as a signature when the file it
+// signs is present.
+func classify(files []string) *inventory {
+	present := map[string]bool{}
+	for _, f := range files {
+		present[f] = true
+	}
+
+	inv := &inventory{signatures: map[string]*signature{}}
+	for _, f := range files {
+		if owner, suffix, ok := signatureOwner(f); ok && present[owner] {
+			s := inv.signatures[owner]
+			if s == nil {
+				s = &signature{}
+				inv.signatures[owner] = s
+			}
+			switch suffix {
+			case ".sigstore.json":
+				s.bundle = f
+			case ".sig":
+				s.sig = f
+			default:
+				s.certificate = f
+			}
+			continue
+		}
+		if _, ok := checksumTool(f); ok {
+			inv.checksums = append(inv.checksums, f)
+			if aggregateSums.MatchString(path.Base(f)) {
+				inv.aggregates = append(inv.aggregates, f)
+			}
+			continue
+		}
+		inv.artifacts = append(inv.artifacts, f)
+	}
+
+	// A certificate without a signature is not a signature.
+	for owner, s := range inv.signatures {
+		if s.bundle == "" && s.sig == "" {
+			delete(inv.signatures, owner)
+		}
+	}
+	return inv
+}
+
+// checksumTool returns the tool that verifies checksum file f.
+func checksumTool(f string) (string, bool) {
+	if m := aggregateSums.FindStringSubmatch(path.Base(f)); m != nil {
+		return strings.ToLower(m[1]) + "sum", true
+	}
+	tool, ok := checksumTools[path.Ext(f)]
+	return tool, ok
+}
+
+// signatureOwner returns the file signature file f signs, and the
+// signature suffix.
+func signatureOwner(f string) (string, string, bool) {
+	for _, suffix := range signatureSuffixes {
+		if owner, ok := strings.CutSuffix(f, suffix); ok && owner != "" {
+			return owner, suffix, true
+		}
+	}
+	return "", "", false
+}
+
+// sumsNames returns the file names listed in a sums file in
+// "<hex>  <path>" format.
+func sumsNames(contents string) []string {
+	var names []string
+	for _, line := range strings.Split(contents, "\n") {
+		_, name, ok := strings.Cut(strings.TrimRight(line, "\r"), " ")
+		if !ok {
+			continue
+		}
+		name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+		names = append(names, strings.TrimPrefix(name, "./"))
+	}
+	return names
+}
+
+// unchecked returns the artifacts that no checksum file covers, given the
+// names listed in the aggregated sums files.
+func unchecked(inv *inventory, listed []string) []string {
+	covered := map[string]bool{}
+	for _, name := range listed {
+		covered[name] = true
+	}
+	for _, f := range inv.checksums {
+		covered[strings.TrimSuffix(f, path.Ext(f))] = true
+	}
+
+	var missing []string
+	for _, a := range inv.artifacts {
+		if !covered[a] {
+			missing = append(missing, a)
+		}
+	}
+	return missing
+}
+
+// unsigned returns the artifacts that are neither signed themselves nor
+// listed in a signed sums file, given each aggregated sums file's names.
+func unsigned(inv *inventory, listed map[string][]string) []string {
+	covered := map[string]bool{}
+	for sums, names := range listed {
+		if inv.signatures[sums] == nil {
+			continue
+		}
+		for _, name := range names {
+			covered[name] = true
+		}
+	}
+
+	var missing []string
+	for _, a := range inv.artifacts {
+		if inv.signatures[a] == nil && !covered[a] {
+			missing = append(missing, a)
+		}
+	}
+	return missing
+}
+
+// renamePath replaces from with to in the file name of p.
+func renamePath(p, from, to string) string {
+	dir, base := path.Split(p)
+	return dir + strings.ReplaceAll(base, from, to)
+}
+
+// renameSums rewrites the file names listed in a sums file with renames.
+func renameSums(contents string, renames map[string]string) string {
+	lines := strings.Split(contents, "\n")
+	for i, line := range lines {
+		hash, name, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		sep := " "
+		if rest, ok := strings.CutPrefix(name, " "); ok {
+			sep, name = "  ", rest
+		}
+		mode := ""
+		if rest, ok := strings.CutPrefix(name, "*"); ok {
+			mode, name = "*", rest
+		}
+		if renamed, ok := renames[name]; ok {
+			lines[i] = hash + sep + mode + renamed
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// signedFiles returns the signed files in sorted order.
+func signedFiles(inv *inventory) []string {
+	files := make([]string, 0, len(inv.signatures))
+	for f := range inv.signatures {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	return files
+}
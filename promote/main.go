@@ -0,0 +1,460 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"dagger/promote/internal/dagger"
+)
+
+const (
+	awsImage    string = "amazon/aws-cli:latest"
+	alpineImage string = "alpine:3.21"
+	cosignImage string = "gcr.io/projectsigstore/cosign:v2.4.1"
+)
+
+// Promote republishes a tested build from a bucket as a stable release
+// without rebuilding it, by composing the ghrelease and bucketupload
+// modules.
+type Promote struct {
+	// Bucket endpoint URL
+	//
+	// +private
+	BucketEndpoint *dagger.Secret
+
+	// Bucket name
+	//
+	// +private
+	BucketName *dagger.Secret
+
+	// Bucket access key ID
+	//
+	// +private
+	BucketAccessKeyID *dagger.Secret
+
+	// Bucket secret access key
+	//
+	// +private
+	BucketSecretAccessKey *dagger.Secret
+
+	// GitHub token for release uploads
+	//
+	// +private
+	GithubToken *dagger.Secret
+
+	// GitHub repository in owner/repo format
+	//
+	// +private
+	GithubRepo string
+
+	// Whether to republish to the bucket
+	//
+	// +private
+	Republish bool
+
+	// Bucket key prefix the version directory is created under
+	//
+	// +private
+	BucketPrefix string
+
+	// Whether to also upload under "<prefix>/latest"
+	//
+	// +private
+	BucketLatest bool
+}
+
+// New creates a promotion from the S3-compatible bucket the builds are
+// published to. Chain WithGithub and/or WithBucket to choose destinations,
+// then call Promote.
+func New(
+	// Bucket endpoint URL
+	endpoint *dagger.Secret,
+
+	// Bucket name
+	bucket *dagger.Secret,
+
+	// Bucket access key ID
+	accessKeyID *dagger.Secret,
+
+	// Bucket secret access key
+	secretAccessKey *dagger.Secret,
+) *Promote {
+	return &Promote{
+		BucketEndpoint:        endpoint,
+		BucketName:            bucket,
+		BucketAccessKeyID:     accessKeyID,
+		BucketSecretAccessKey: secretAccessKey,
+	}
+}
+
+// WithGithub publishes the promoted artifacts to the GitHub release tagged
+// with the version. The release must already exist, e.g. created by
+// ghrelease's Create.
+func (m *Promote) WithGithub(
+	// GitHub token with permissions to upload release assets
+	token *dagger.Secret,
+
+	// GitHub repository in owner/repo format
+	repo string,
+) *Promote {
+	m.GithubToken = token
+	m.GithubRepo = repo
+	return m
+}
+
+// WithBucket republishes the promoted artifacts to the same bucket under
+// "<prefix>/<version>", and also "<prefix>/latest" when latest is set.
+func (m *Promote) WithBucket(
+	// Bucket key prefix. Use "" for the bucket root.
+	// +optional
+	prefix string,
+
+	// Also upload under "<prefix>/latest"
+	// +optional
+	latest bool,
+) *Promote {
+	m.Republish = true
+	m.BucketPrefix = prefix
+	m.BucketLatest = latest
+	return m
+}
+
+// Download returns the files published under a bucket prefix, such as a
+// nightly build.
+func (m *Promote) Download(
+	ctx context.Context,
+
+	// Bucket key prefix of the build (e.g., "nightly" or "myapp/nightly")
+	// +optional
+	// +default="nightly"
+	from string,
+) (*dagger.Directory, error) {
+	dist, err := dag.Container().
+		From(awsImage).
+		WithSecretVariable("AWS_ACCESS_KEY_ID", m.BucketAccessKeyID).
+		WithSecretVariable("AWS_SECRET_ACCESS_KEY", m.BucketSecretAccessKey).
+		WithEnvVariable("AWS_DEFAULT_REGION", "auto").
+		WithSecretVariable("BUCKET_ENDPOINT", m.BucketEndpoint).
+		WithSecretVariable("BUCKET_NAME", m.BucketName).
+		// The prefix is overwritten by every build; always fetch it.
+		WithEnvVariable("PROMOTE_SESSION", fmt.Sprintf("%d", time.Now().UnixNano())).
+		WithExec([]string{"sh", "-c",
+			`aws s3 sync "s3://$BUCKET_NAME/$1" /out --endpoint-url "$BUCKET_ENDPOINT"`,
+			"sh", strings.Trim(from, "/"),
+		}).
+		Directory("/out").
+		Sync(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return nil, fmt.Errorf("failed to download %s\n\n%s%s", from, e.Stdout, e.Stderr)
+	} else if err != nil {
+		return nil, fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return dist, nil
+}
+
+// Verify checks a build's checksums and cosign blob signatures.
+//
+// Every artifact must be listed in a checksum file (a SHA256SUMS-style
+// file or a sibling .sha256), and every checksum file is checked. Unless
+// insecureSkipVerify is set, every artifact must also be signed, either
+// directly (<file>.sigstore.json, or <file>.sig with <file>.pem for
+// keyless signatures) or through a signed sums file that lists it, and
+// every signature is verified.
+func (m *Promote) Verify(
+	ctx context.Context,
+
+	// Build to verify, e.g. the output of download
+	artifacts *dagger.Directory,
+
+	// Public key (cosign.pub) for key-based verification
+	// +optional
+	key *dagger.File,
+
+	// Expected signer identity for keyless verification as a regular
+	// expression (e.g., "^https://github.com/acme/myapp/")
+	// +optional
+	certificateIdentityRegexp string,
+
+	// Expected OIDC issuer for keyless verification
+	// +optional
+	// +default="https://token.actions.githubusercontent.com"
+	certificateOidcIssuer string,
+
+	// Verify checksums only
+	// +optional
+	insecureSkipVerify bool,
+) (string, error) {
+	var identity []string
+	switch {
+	case insecureSkipVerify:
+	case key != nil:
+		identity = []string{"--key", "/cosign.pub"}
+	case certificateIdentityRegexp != "":
+		identity = []string{
+			"--certificate-identity-regexp", certificateIdentityRegexp,
+			"--certificate-oidc-issuer", certificateOidcIssuer,
+		}
+	default:
+		return "", fmt.Errorf("either key or certificate-identity-regexp is required to verify, or set insecure-skip-verify")
+	}
+
+	files, err := listFiles(ctx, artifacts)
+	if err != nil {
+		return "", err
+	}
+	inv := classify(files)
+	if len(inv.artifacts) == 0 {
+		return "", fmt.Errorf("no artifacts found")
+	}
+
+	listed, err := readSums(ctx, artifacts, inv)
+	if err != nil {
+		return "", err
+	}
+	var allListed []string
+	for _, names := range listed {
+		allListed = append(allListed, names...)
+	}
+	if missing := unchecked(inv, allListed); len(missing) > 0 {
+		return "", fmt.Errorf("no checksum for %s", strings.Join(missing, ", "))
+	}
+
+	ctr := dag.Container().
+		From(alpineImage).
+		WithMountedDirectory("/artifacts", artifacts).
+		WithWorkdir("/artifacts")
+	// Checksum files list paths relative to the build root.
+	for _, f := range inv.checksums {
+		tool, _ := checksumTool(f)
+		ctr = ctr.WithExec([]string{tool, "-c", f})
+	}
+	if _, err := ctr.Sync(ctx); err != nil {
+		var e *dagger.ExecError
+		if errors.As(err, &e) {
+			return "", fmt.Errorf("checksum verification failed\n\n%s%s", e.Stdout, e.Stderr)
+		}
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	if insecureSkipVerify {
+		return fmt.Sprintf("✅ Verified checksums of %d artifacts (signatures skipped)", len(inv.artifacts)), nil
+	}
+
+	if missing := unsigned(inv, listed); len(missing) > 0 {
+		return "", fmt.Errorf("no signature for %s", strings.Join(missing, ", "))
+	}
+
+	cosign := dag.Container().
+		From(cosignImage).
+		WithMountedDirectory("/artifacts", artifacts).
+		WithWorkdir("/artifacts")
+	if key != nil {
+		cosign = cosign.WithFile("/cosign.pub", key)
+	}
+	signed := signedFiles(inv)
+	for _, f := range signed {
+		s := inv.signatures[f]
+		args := []string{"verify-blob"}
+		if s.bundle != "" {
+			args = append(args, "--bundle", s.bundle)
+		} else {
+			args = append(args, "--signature", s.sig)
+			if s.certificate != "" {
+				args = append(args, "--certificate", s.certificate)
+			}
+		}
+		args = append(append(args, identity...), f)
+		cosign = cosign.WithExec(args, dagger.ContainerWithExecOpts{UseEntrypoint: true})
+	}
+	if _, err := cosign.Sync(ctx); err != nil {
+		var e *dagger.ExecError
+		if errors.As(err, &e) {
+			return "", fmt.Errorf("signature verification failed\n\n%s%s", e.Stdout, e.Stderr)
+		}
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return fmt.Sprintf("✅ Verified checksums of %d artifacts and %d signatures", len(inv.artifacts), len(signed)), nil
+}
+
+// Promote downloads a build from the bucket, verifies its checksums and
+// signatures, and republishes the exact same files as version to every
+// configured destination.
+//
+// When fromVersion is set, it is replaced with version in every file name
+// and in the checksum files. Signatures of checksum files whose contents
+// change are dropped, since they no longer match; artifact signatures are
+// kept. The binaries themselves are never modified, so they report the
+// version they were built with.
+func (m *Promote) Promote(
+	ctx context.Context,
+
+	// Stable version to publish as (e.g., "v1.2.3")
+	version string,
+
+	// Bucket key prefix of the build to promote
+	// +optional
+	// +default="nightly"
+	from string,
+
+	// Version string in the build's file names to replace with version
+	// (e.g., "nightly")
+	// +optional
+	fromVersion string,
+
+	// Public key (cosign.pub) for key-based verification
+	// +optional
+	key *dagger.File,
+
+	// Expected signer identity for keyless verification as a regular
+	// expression (e.g., "^https://github.com/acme/myapp/")
+	// +optional
+	certificateIdentityRegexp string,
+
+	// Expected OIDC issuer for keyless verification
+	// +optional
+	// +default="https://token.actions.githubusercontent.com"
+	certificateOidcIssuer string,
+
+	// Promote with verified checksums only
+	// +optional
+	insecureSkipVerify bool,
+) (string, error) {
+	if m.GithubToken == nil && !m.Republish {
+		return "", fmt.Errorf("no destinations set: call WithGithub and/or WithBucket before Promote")
+	}
+
+	dist, err := m.Download(ctx, from)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := m.Verify(ctx, dist, key, certificateIdentityRegexp, certificateOidcIssuer, insecureSkipVerify); err != nil {
+		return "", fmt.Errorf("refusing to promote %s: %w", from, err)
+	}
+
+	var dropped []string
+	if fromVersion != "" && fromVersion != version {
+		dist, dropped, err = rename(ctx, dist, fromVersion, version)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var published []string
+
+	if m.GithubToken != nil {
+		err := dag.Ghrelease(m.GithubToken).
+			WithRepo(m.GithubRepo).
+			WithAssets(dist).
+			WithTag(version).
+			Upload(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to publish to GitHub: %w", err)
+		}
+		published = append(published, fmt.Sprintf("github.com/%s@%s", m.GithubRepo, version))
+	}
+
+	if m.Republish {
+		prefixes := []string{path.Join(m.BucketPrefix, version)}
+		if m.BucketLatest {
+			prefixes = append(prefixes, path.Join(m.BucketPrefix, "latest"))
+		}
+
+		uploader := dag.Bucketuploader(m.BucketEndpoint, m.BucketName, m.BucketAccessKeyID, m.BucketSecretAccessKey)
+		for _, prefix := range prefixes {
+			err := uploader.UploadTree(ctx, dist, dagger.BucketuploaderUploadTreeOpts{Prefix: prefix})
+			if err != nil {
+				return "", fmt.Errorf("failed to publish to bucket under %q: %w", prefix, err)
+			}
+			published = append(published, "bucket:"+prefix)
+		}
+	}
+
+	out := fmt.Sprintf("✅ Promoted %s to %s at %s", from, version, strings.Join(published, ", "))
+	if len(dropped) > 0 {
+		out += fmt.Sprintf("\n⚠️ Dropped signatures of rewritten checksum files: %s", strings.Join(dropped, ", "))
+	}
+	return out, nil
+}
+
+// rename replaces from with to in every file name and in the checksum
+// files, and drops the signatures of checksum files it rewrites.
+func rename(ctx context.Context, dist *dagger.Directory, from, to string) (*dagger.Directory, []string, error) {
+	files, err := listFiles(ctx, dist)
+	if err != nil {
+		return nil, nil, err
+	}
+	inv := classify(files)
+
+	renames := map[string]string{}
+	for _, f := range files {
+		if renamed := renamePath(f, from, to); renamed != f {
+			renames[f] = renamed
+		}
+	}
+
+	out := dag.Directory()
+	rewritten := map[string]bool{}
+	for _, f := range inv.checksums {
+		contents, err := dist.File(f).Contents(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", f, err)
+		}
+		updated := renameSums(contents, renames)
+		rewritten[f] = updated != contents
+		out = out.WithNewFile(renamePath(f, from, to), updated)
+	}
+
+	var dropped []string
+	for _, f := range files {
+		if _, ok := checksumTool(f); ok {
+			continue
+		}
+		if owner, _, ok := signatureOwner(f); ok && rewritten[owner] {
+			dropped = append(dropped, f)
+			continue
+		}
+		out = out.WithFile(renamePath(f, from, to), dist.File(f))
+	}
+
+	return out, dropped, nil
+}
+
+// readSums returns the names listed in each aggregated sums file.
+func readSums(ctx context.Context, dir *dagger.Directory, inv *inventory) (map[string][]string, error) {
+	listed := map[string][]string{}
+	for _, f := range inv.aggregates {
+		contents, err := dir.File(f).Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f, err)
+		}
+		listed[f] = sumsNames(contents)
+	}
+	return listed, nil
+}
+
+// listFiles returns the paths of every file in dir.
+func listFiles(ctx context.Context, dir *dagger.Directory) ([]string, error) {
+	entries, err := dir.Glob(ctx, "**/*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	var files []string
+	// Glob returns directory entries with a trailing slash — skip them.
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+		files = append(files, entry)
+	}
+	return files, nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/provenance/internal/dagger"
+)
+
+const (
+	cosignImage string = "gcr.io/projectsigstore/cosign:v2.4.1"
+)
+
+type Provenance struct {
+	// BuilderID identifies the build platform (e.g., the CI workflow URI)
+	//
+	// +private
+	BuilderID string
+
+	// BuildType is the URI describing how ExternalParameters are interpreted
+	//
+	// +private
+	BuildType string
+
+	// ExternalParameters are the user-controlled build inputs, in
+	// KEY=VALUE format
+	//
+	// +private
+	ExternalParameters []string
+
+	// InternalParameters are the builder-controlled build inputs, in
+	// KEY=VALUE format
+	//
+	// +private
+	InternalParameters []string
+
+	// DependencyURIs are the resolved dependencies' URIs
+	//
+	// +private
+	DependencyURIs []string
+
+	// DependencyDigests are the digests for DependencyURIs, by index
+	//
+	// +private
+	DependencyDigests []string
+
+	// InvocationID identifies this build run (e.g., the CI run URL)
+	//
+	// +private
+	InvocationID string
+
+	// StartedOn is the RFC 3339 time the build started
+	//
+	// +private
+	StartedOn string
+
+	// FinishedOn is the RFC 3339 time the build finished
+	//
+	// +private
+	FinishedOn string
+}
+
+// New creates a new Provenance module instance.
+func New(
+	// Builder identity (e.g.,
+	// "https://github.com/acme/myapp/.github/workflows/release.yml@refs/heads/main")
+	builderID string,
+
+	// Build type URI describing how the external parameters are interpreted
+	// +optional
+	buildType string,
+) *Provenance {
+	if buildType == "" {
+		buildType = defaultBuildType
+	}
+	return &Provenance{
+		BuilderID: builderID,
+		BuildType: buildType,
+	}
+}
+
+// WithExternalParameters adds user-controlled build inputs (e.g., the
+// source repository, ref, and build targets) in KEY=VALUE format.
+func (m *Provenance) WithExternalParameters(
+	// External parameters in "KEY=VALUE" format
+	params []string,
+) *Provenance {
+	m.ExternalParameters = append(m.ExternalParameters, params...)
+	return m
+}
+
+// WithInternalParameters adds builder-controlled build inputs (e.g., the
+// toolchain image) in KEY=VALUE format.
+func (m *Provenance) WithInternalParameters(
+	// Internal parameters in "KEY=VALUE" format
+	params []string,
+) *Provenance {
+	m.InternalParameters = append(m.InternalParameters, params...)
+	return m
+}
+
+// WithResolvedDependency adds an input artifact the build fetched, such as
+// the source commit or a base image.
+func (m *Provenance) WithResolvedDependency(
+	// Dependency URI (e.g., "git+https://github.com/acme/myapp@refs/tags/v1.2.3")
+	uri string,
+
+	// Dependency digest in "ALGORITHM:HEX" format (e.g., "gitCommit:abc123...")
+	digest string,
+) *Provenance {
+	m.DependencyURIs = append(m.DependencyURIs, uri)
+	m.DependencyDigests = append(m.DependencyDigests, digest)
+	return m
+}
+
+// WithInvocation sets the run metadata recorded in the provenance.
+func (m *Provenance) WithInvocation(
+	// Identifier of this build run (e.g., the CI run URL)
+	// +optional
+	id string,
+
+	// RFC 3339 time the build started
+	// +optional
+	startedOn string,
+
+	// RFC 3339 time the build finished
+	// +optional
+	finishedOn string,
+) *Provenance {
+	m.InvocationID = id
+	m.StartedOn = startedOn
+	m.FinishedOn = finishedOn
+	return m
+}
+
+// Generate returns an unsigned SLSA v1 provenance statement, named
+// <name>.intoto.json, with every file in the artifacts directory as a
+// subject identified by its relative path and SHA-256 digest.
+func (m *Provenance) Generate(
+	ctx context.Context,
+
+	// Directory of artifacts to describe (e.g., a release dist directory)
+	artifacts *dagger.Directory,
+
+	// Base name of the provenance file
+	// +optional
+	// +default="provenance"
+	name string,
+) (*dagger.File, error) {
+	if m.BuilderID == "" {
+		return nil, fmt.Errorf("builder-id is required")
+	}
+
+	entries, err := artifacts.Glob(ctx, "**/*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	var subjects []resource
+	// Glob returns directory entries with a trailing slash — skip them.
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+		digest, err := artifacts.File(entry).Digest(ctx, dagger.FileDigestOpts{ExcludeMetadata: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to digest %s: %w", entry, err)
+		}
+		subjects = append(subjects, resource{
+			Name:   entry,
+			Digest: map[string]string{"sha256": strings.TrimPrefix(digest, "sha256:")},
+		})
+	}
+	if len(subjects) == 0 {
+		return nil, fmt.Errorf("no artifacts to describe")
+	}
+
+	external, err := parseParameters(m.ExternalParameters)
+	if err != nil {
+		return nil, err
+	}
+	internal, err := parseParameters(m.InternalParameters)
+	if err != nil {
+		return nil, err
+	}
+
+	var deps []resource
+	for i, uri := range m.DependencyURIs {
+		digest, err := parseDigest(m.DependencyDigests[i])
+		if err != nil {
+			return nil, fmt.Errorf("dependency %s: %w", uri, err)
+		}
+		deps = append(deps, resource{URI: uri, Digest: digest})
+	}
+
+	s := statement{
+		Type:          statementType,
+		Subject:       subjects,
+		PredicateType: slsaPredicateType,
+		Predicate: predicate{
+			BuildDefinition: buildDefinition{
+				BuildType:            m.BuildType,
+				ExternalParameters:   external,
+				ResolvedDependencies: deps,
+			},
+			RunDetails: runDetails{
+				Builder: builder{ID: m.BuilderID},
+			},
+		},
+	}
+	if len(internal) > 0 {
+		s.Predicate.BuildDefinition.InternalParameters = internal
+	}
+	if m.InvocationID != "" || m.StartedOn != "" || m.FinishedOn != "" {
+		s.Predicate.RunDetails.Metadata = &metadata{
+			InvocationID: m.InvocationID,
+			StartedOn:    m.StartedOn,
+			FinishedOn:   m.FinishedOn,
+		}
+	}
+
+	out, err := marshalStatement(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode provenance: %w", err)
+	}
+
+	return dag.Directory().
+		WithNewFile(name+provenanceFileExt, string(out)).
+		File(name + provenanceFileExt), nil
+}
+
+// Sign generates the provenance statement and signs it with cosign, with a
+// private key when key is given or otherwise keylessly through Fulcio using
+// identityToken. Returns a directory holding <name>.intoto.json and its
+// Sigstore bundle <name>.intoto.json.sigstore.json, ready to attach to a
+// GitHub release or upload to a bucket alongside the artifacts.
+func (m *Provenance) Sign(
+	ctx context.Context,
+
+	// Directory of artifacts to describe (e.g., a release dist directory)
+	artifacts *dagger.Directory,
+
+	// Base name of the provenance file
+	// +optional
+	// +default="provenance"
+	name string,
+
+	// Private key (cosign.key) for key-based signing
+	// +optional
+	key *dagger.Secret,
+
+	// Password protecting the private key
+	// +optional
+	password *dagger.Secret,
+
+	// OIDC identity token for keyless signing
+	// +optional
+	identityToken *dagger.Secret,
+) (*dagger.Directory, error) {
+	file, err := m.Generate(ctx, artifacts, name)
+	if err != nil {
+		return nil, err
+	}
+
+	statementFile := name + provenanceFileExt
+	bundleFile := statementFile + bundleFileExt
+	args := []string{"sign-blob", "--yes", "--bundle", "/out/" + bundleFile}
+
+	ctr := dag.Container().
+		From(cosignImage).
+		WithFile("/out/"+statementFile, file)
+
+	switch {
+	case key != nil:
+		ctr = ctr.WithMountedSecret("/cosign.key", key)
+		if password != nil {
+			ctr = ctr.WithSecretVariable("COSIGN_PASSWORD", password)
+		} else {
+			ctr = ctr.WithEnvVariable("COSIGN_PASSWORD", "")
+		}
+		args = append(args, "--key", "/cosign.key")
+	case identityToken != nil:
+		// --identity-token accepts a path to a file containing the token.
+		ctr = ctr.WithMountedSecret("/identity-token", identityToken)
+		args = append(args, "--identity-token", "/identity-token")
+	default:
+		return nil, fmt.Errorf("either key or identity-token is required to sign")
+	}
+
+	return ctr.
+		WithExec(append(args, "/out/"+statementFile), dagger.ContainerWithExecOpts{UseEntrypoint: true}).
+		Directory("/out"), nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	statementType     string = "https://in-toto.io/Statement/v1"
+	slsaPredicateType string = "https://slsa.dev/provenance/v1"
+	provenanceFileExt string = ".intoto.json"
+	bundleFileExt     string = ".sigstore.json"
+	defaultBuildType  string = "https://github.com/papercomputeco/daggerverse/provenance/dagger@v1"
+)
+
+// statement is an in-toto v1 statement carrying a SLSA v1 provenance
+// predicate.
+type statement struct {
+	Type          string     `json:"_type"`
+	Subject       []resource `json:"subject"`
+	PredicateType string     `json:"predicateType"`
+	Predicate     predicate  `json:"predicate"`
+}
+
+type resource struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest"`
+}
+
+type predicate struct {
+	BuildDefinition buildDefinition `json:"buildDefinition"`
+	RunDetails      runDetails      `json:"runDetails"`
+}
+
+type buildDefinition struct {
+	BuildType            string            `json:"buildType"`
+	ExternalParameters   map[string]string `json:"externalParameters"`
+	InternalParameters   map[string]string `json:"internalParameters,omitempty"`
+	ResolvedDependencies []resource        `json:"resolvedDependencies,omitempty"`
+}
+
+type runDetails struct {
+	Builder  builder   `json:"builder"`
+	Metadata *metadata `json:"metadata,omitempty"`
+}
+
+type builder struct {
+	ID string `json:"id"`
+}
+
+type metadata struct {
+	InvocationID string `json:"invocationId,omitempty"`
+	StartedOn    string `json:"startedOn,omitempty"`
+	FinishedOn   string `json:"finishedOn,omitempty"`
+}
+
+// parseParameters parses "KEY=VALUE" pairs into a map.
+func parseParameters(params []string) (map[string]string, error) {
+	parsed := map[string]string{}
+	for _, p := range params {
+		k, v, ok := strings.Cut(p, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid parameter %q: must be in KEY=VALUE format", p)
+		}
+		parsed[k] = v
+	}
+	return parsed, nil
+}
+
+// parseDigest parses an "ALGORITHM:HEX" digest (e.g., "sha256:abc...",
+// "gitCommit:abc...") into an in-toto digest set.
+func parseDigest(digest string) (map[string]string, error) {
+	alg, value, ok := strings.Cut(digest, ":")
+	if !ok || alg == "" || value == "" {
+		return nil, fmt.Errorf("invalid digest %q: must be in ALGORITHM:HEX format", digest)
+	}
+	return map[string]string{alg: value}, nil
+}
+
+// marshalStatement encodes a statement with its subjects sorted by name, so
+// the same artifacts always produce the same file.
+func marshalStatement(s statement) ([]byte, error) {
+	sort.Slice(s.Subject, func(i, j int) bool {
+		return s.Subject[i].Name < s.Subject[j].Name
+	})
+	return json.MarshalIndent(s, "", "  ")
+}
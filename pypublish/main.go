@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dagger/pypublish/internal/dagger"
+)
+
+const (
+	uvImage     string = "ghcr.io/astral-sh/uv:0.6.14-python3.13-bookworm-slim"
+	twine       string = "twine@6.1.0"
+	pypiURL     string = "https://upload.pypi.org/legacy/"
+	testPypiURL string = "https://test.pypi.org/legacy/"
+)
+
+type Pypublish struct {
+	// Source is the repository containing the package.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Path is the package directory (containing pyproject.toml), relative
+	// to Source.
+	//
+	// +private
+	Path string
+
+	// Builder is the build frontend: "uv" or "build".
+	//
+	// +private
+	Builder string
+}
+
+// New creates a new Pypublish module instance.
+func New(
+	// The repository containing the package.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Package directory containing pyproject.toml, relative to source
+	// (e.g., "sdk/python")
+	// +optional
+	// +default="."
+	path string,
+
+	// Build frontend: "uv" (uv build) or "build" (python -m build)
+	// +optional
+	// +default="uv"
+	builder string,
+) *Pypublish {
+	return &Pypublish{
+		Source:  source,
+		Path:    path,
+		Builder: builder,
+	}
+}
+
+// Build builds the sdist and wheel and returns the dist directory.
+func (m *Pypublish) Build() (*dagger.Directory, error) {
+	var args []string
+	switch m.Builder {
+	case "uv":
+		args = []string{"uv", "build", "--sdist", "--wheel", "--out-dir", "/dist"}
+	case "build":
+		args = []string{"uvx", "--from", "build", "pyproject-build", "--installer", "uv", "--outdir", "/dist"}
+	default:
+		return nil, fmt.Errorf("invalid builder %q: must be \"uv\" or \"build\"", m.Builder)
+	}
+
+	return m.container().
+		WithExec(args).
+		Directory("/dist"), nil
+}
+
+// Check builds the package and runs "twine check" on the distributions,
+// catching metadata problems (e.g., a README PyPI cannot render) before
+// upload.
+//
+// +check
+func (m *Pypublish) Check(ctx context.Context) (string, error) {
+	dist, err := m.Build()
+	if err != nil {
+		return "", err
+	}
+	return m.twine(ctx, dag.Container().From(uvImage), dist, "check", "--strict")
+}
+
+// Publish builds the package, checks it with twine, and uploads the
+// distributions to PyPI, TestPyPI, or another index with an API token.
+func (m *Pypublish) Publish(
+	ctx context.Context,
+
+	// API token for the index
+	token *dagger.Secret,
+
+	// Index to upload to: "pypi", "testpypi", or an upload URL
+	// +optional
+	// +default="pypi"
+	repository string,
+
+	// Skip distributions that already exist on the index instead of failing
+	// +optional
+	skipExisting bool,
+) (string, error) {
+	url := repository
+	switch repository {
+	case "pypi":
+		url = pypiURL
+	case "testpypi":
+		url = testPypiURL
+	default:
+		if !strings.HasPrefix(repository, "https://") && !strings.HasPrefix(repository, "http://") {
+			return "", fmt.Errorf("invalid repository %q: must be \"pypi\", \"testpypi\", or a URL", repository)
+		}
+	}
+
+	dist, err := m.Build()
+	if err != nil {
+		return "", err
+	}
+
+	checkOut, err := m.twine(ctx, dag.Container().From(uvImage), dist, "check", "--strict")
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"upload", "--non-interactive", "--repository-url", url}
+	if skipExisting {
+		args = append(args, "--skip-existing")
+	}
+
+	// Tokens authenticate with the literal username "__token__".
+	ctr := dag.Container().
+		From(uvImage).
+		WithEnvVariable("TWINE_USERNAME", "__token__").
+		WithSecretVariable("TWINE_PASSWORD", token)
+
+	uploadOut, err := m.twine(ctx, ctr, dist, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return checkOut + uploadOut, nil
+}
+
+// twine runs a twine command on every wheel and sdist in dist, inside ctr.
+func (m *Pypublish) twine(ctx context.Context, ctr *dagger.Container, dist *dagger.Directory, args ...string) (string, error) {
+	var files []string
+	for _, pattern := range []string{"*.whl", "*.tar.gz"} {
+		matches, err := dist.Glob(ctx, pattern)
+		if err != nil {
+			return "", fmt.Errorf("failed to list distributions: %w", err)
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no distributions were built")
+	}
+	for _, f := range files {
+		args = append(args, "/dist/"+f)
+	}
+
+	out, err := ctr.
+		WithMountedCache("/root/.cache/uv", dag.CacheVolume("uv")).
+		WithDirectory("/dist", dist).
+		WithExec(append([]string{"uvx", twine}, args...)).
+		CombinedOutput(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("twine %s failed\n\n%s%s", args[0], e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return out, nil
+}
+
+// container returns a uv container with the source mounted and the package
+// directory as workdir.
+func (m *Pypublish) container() *dagger.Container {
+	return dag.Container().
+		From(uvImage).
+		WithMountedCache("/root/.cache/uv", dag.CacheVolume("uv")).
+		WithDirectory("/src", m.Source, dagger.ContainerWithDirectoryOpts{
+			Exclude: []string{"**/.venv", "**/__pycache__"},
+		}).
+		WithWorkdir("/src/" + m.Path)
+}
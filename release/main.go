@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/release/internal/dagger"
+)
+
+// Release builds, packages, checksums, and publishes a Go project's release
+// artifacts by composing the gobuild, utils, checksum, ghrelease, and
+// bucketupload modules.
+type Release struct {
+	// Go source directory to build
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Name of the binary and archive prefix
+	//
+	// +private
+	Name string
+
+	// Release version (e.g., "v1.2.3")
+	//
+	// +private
+	Version string
+
+	// Main package to build
+	//
+	// +private
+	Pkg string
+
+	// GOOS/GOARCH targets
+	//
+	// +private
+	Targets []string
+
+	// Extra linker flags
+	//
+	// +private
+	Ldflags []string
+
+	// Whether to package each platform into an archive instead of
+	// publishing flattened binaries
+	//
+	// +private
+	Archive bool
+
+	// Extra files added to every archive
+	//
+	// +private
+	ExtraFiles []*dagger.File
+
+	// GitHub token for release uploads
+	//
+	// +private
+	GithubToken *dagger.Secret
+
+	// GitHub repository in owner/repo format
+	//
+	// +private
+	GithubRepo string
+
+	// Bucket endpoint URL
+	//
+	// +private
+	BucketEndpoint *dagger.Secret
+
+	// Bucket name
+	//
+	// +private
+	BucketName *dagger.Secret
+
+	// Bucket access key ID
+	//
+	// +private
+	BucketAccessKeyID *dagger.Secret
+
+	// Bucket secret access key
+	//
+	// +private
+	BucketSecretAccessKey *dagger.Secret
+
+	// Bucket key prefix the version directory is created under
+	//
+	// +private
+	BucketPrefix string
+
+	// Whether to also upload under "<prefix>/latest"
+	//
+	// +private
+	BucketLatest bool
+}
+
+// New creates a release configuration. Chain WithArchives to package the
+// binaries, then WithGithub and/or WithBucket to choose destinations.
+func New(
+	// The Go source directory to build.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Name of the binary, also used as the archive prefix
+	name string,
+
+	// Release version, stamped into the binary as main.version (e.g.,
+	// "v1.2.3")
+	version string,
+
+	// Main package to build, relative to the source directory
+	// +optional
+	// +default="."
+	pkg string,
+
+	// GOOS/GOARCH targets. Defaults to gobuild's linux, darwin, and windows
+	// matrix.
+	// +optional
+	targets []string,
+
+	// Extra linker flags (e.g., "-X main.commit=abc123")
+	// +optional
+	ldflags []string,
+) *Release {
+	return &Release{
+		Source:  source,
+		Name:    name,
+		Version: version,
+		Pkg:     pkg,
+		Targets: targets,
+		Ldflags: ldflags,
+	}
+}
+
+// WithArchives packages each platform into <name>-<os>-<arch>.tar.gz (or
+// .zip for windows) instead of publishing the flattened binaries.
+func (m *Release) WithArchives(
+	// Extra files to include in every archive (e.g., LICENSE, README.md)
+	// +optional
+	extraFiles []*dagger.File,
+) *Release {
+	m.Archive = true
+	m.ExtraFiles = extraFiles
+	return m
+}
+
+// WithGithub publishes the artifacts to the GitHub release tagged with the
+// version. The release must already exist, e.g. created by ghrelease's
+// Create.
+func (m *Release) WithGithub(
+	// GitHub token with permissions to upload release assets
+	token *dagger.Secret,
+
+	// GitHub repository in owner/repo format
+	repo string,
+) *Release {
+	m.GithubToken = token
+	m.GithubRepo = repo
+	return m
+}
+
+// WithBucket publishes the artifacts to an S3-compatible bucket under
+// "<prefix>/<version>", and also "<prefix>/latest" when latest is set.
+func (m *Release) WithBucket(
+	// Bucket endpoint URL
+	endpoint *dagger.Secret,
+
+	// Bucket name
+	bucket *dagger.Secret,
+
+	// Bucket access key ID
+	accessKeyID *dagger.Secret,
+
+	// Bucket secret access key
+	secretAccessKey *dagger.Secret,
+
+	// Bucket key prefix. Use "" for the bucket root.
+	// +optional
+	prefix string,
+
+	// Also upload under "<prefix>/latest"
+	// +optional
+	latest bool,
+) *Release {
+	m.BucketEndpoint = endpoint
+	m.BucketName = bucket
+	m.BucketAccessKeyID = accessKeyID
+	m.BucketSecretAccessKey = secretAccessKey
+	m.BucketPrefix = prefix
+	m.BucketLatest = latest
+	return m
+}
+
+// Dist builds the binaries and returns the flat directory of release
+// artifacts: archives or flattened binaries, each with a .sha256 checksum,
+// plus an aggregated SHA256SUMS file.
+func (m *Release) Dist() *dagger.Directory {
+	build := dag.Gobuild(dagger.GobuildOpts{Source: m.Source}).
+		Build(dagger.GobuildBuildOpts{
+			Name:    m.Name,
+			Pkg:     m.Pkg,
+			Targets: m.Targets,
+			Version: m.Version,
+			Ldflags: m.Ldflags,
+		})
+
+	var artifacts *dagger.Directory
+	if m.Archive {
+		artifacts = dag.Utilsverse().Archive(build, m.Name, dagger.UtilsverseArchiveOpts{
+			ExtraFiles: m.ExtraFiles,
+		})
+	} else {
+		artifacts = dag.Utilsverse().FlattenNameOsArch(build)
+	}
+
+	return dag.Checksumer().Checksum(artifacts, dagger.ChecksumerChecksumOpts{
+		Aggregate: true,
+	})
+}
+
+// Publish builds the release artifacts and uploads them to every configured
+// destination.
+func (m *Release) Publish(ctx context.Context) (string, error) {
+	if m.GithubToken == nil && m.BucketEndpoint == nil {
+		return "", fmt.Errorf("no destinations set: call WithGithub and/or WithBucket before Publish")
+	}
+
+	dist, err := m.Dist().Sync(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to build release artifacts: %w", err)
+	}
+
+	var published []string
+
+	if m.GithubToken != nil {
+		err := dag.Ghrelease(m.GithubToken).
+			WithRepo(m.GithubRepo).
+			WithAssets(dist).
+			WithTag(m.Version).
+			Upload(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to publish to GitHub: %w", err)
+		}
+		published = append(published, fmt.Sprintf("github.com/%s@%s", m.GithubRepo, m.Version))
+	}
+
+	if m.BucketEndpoint != nil {
+		prefixes := []string{path.Join(m.BucketPrefix, m.Version)}
+		if m.BucketLatest {
+			prefixes = append(prefixes, path.Join(m.BucketPrefix, "latest"))
+		}
+
+		uploader := dag.Bucketuploader(m.BucketEndpoint, m.BucketName, m.BucketAccessKeyID, m.BucketSecretAccessKey)
+		for _, prefix := range prefixes {
+			err := uploader.UploadTree(ctx, dist, dagger.BucketuploaderUploadTreeOpts{Prefix: prefix})
+			if err != nil {
+				return "", fmt.Errorf("failed to publish to bucket under %q: %w", prefix, err)
+			}
+			published = append(published, "bucket:"+prefix)
+		}
+	}
+
+	return fmt.Sprintf("✅ Published %s %s to %s", m.Name, m.Version, strings.Join(published, ", ")), nil
+}
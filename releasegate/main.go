@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"dagger/releasegate/internal/dagger"
+)
+
+const (
+	yqImage string = "mikefarah/yq:4.45.1"
+)
+
+type Releasegate struct {
+	// Source is the repository being released.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Policy is the release policy file, relative to Source.
+	//
+	// +private
+	Policy string
+
+	// Token is the GitHub token used to count approvals.
+	//
+	// +private
+	Token *dagger.Secret
+
+	// Repo is the GitHub repository ("owner/repo").
+	//
+	// +private
+	Repo string
+}
+
+// GateResult is the outcome of one release gate.
+type GateResult struct {
+	// Gate name
+	Name string
+
+	// Whether the gate allows the release
+	Passed bool
+
+	// Why the gate passed or failed
+	Detail string
+}
+
+// Decision is the outcome of every release gate.
+type Decision struct {
+	// Whether every gate allows the release
+	Allowed bool
+
+	// Outcome of each gate
+	Gates []GateResult
+
+	// Outcome of each gate as markdown
+	Report string
+}
+
+// New creates a new Releasegate module instance.
+func New(
+	// The repository being released.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Release policy file (YAML or JSON), relative to source
+	// +optional
+	// +default=".releasegate.yaml"
+	policy string,
+
+	// GitHub token, required when the policy requires approvals
+	// +optional
+	token *dagger.Secret,
+
+	// GitHub repository (e.g. "owner/repo"), required when the policy
+	// requires approvals
+	// +optional
+	repo string,
+) *Releasegate {
+	return &Releasegate{
+		Source: source,
+		Policy: policy,
+		Token:  token,
+		Repo:   repo,
+	}
+}
+
+// Evaluate checks the release against the policy's freeze windows,
+// required approvals, and changelog, and returns the decision without
+// failing.
+func (m *Releasegate) Evaluate(
+	ctx context.Context,
+
+	// Version being released (e.g., "v1.2.3"), required when the policy
+	// requires a changelog entry
+	// +optional
+	version string,
+
+	// Commit being released; approvals are counted on the pull request
+	// that introduced it
+	// +optional
+	sha string,
+
+	// Pull request to count approvals on, instead of looking it up from
+	// sha
+	// +optional
+	pr int,
+
+	// Time to evaluate freeze windows at, in RFC 3339 format (defaults to
+	// now)
+	// +optional
+	at string,
+
+	// Release during a freeze anyway, for this reason (e.g., "hotfix for
+	// INC-42"). Other gates still apply.
+	// +optional
+	overrideReason string,
+) (*Decision, error) {
+	data, err := dag.Container().
+		From(yqImage).
+		WithFile("/policy", m.Source.File(m.Policy)).
+		WithExec([]string{"yq", "--output-format", "json", ".", "/policy"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release policy %s: %w", m.Policy, err)
+	}
+	p, loc, err := parsePolicy(data)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if at != "" {
+		now, err = time.Parse(time.RFC3339, at)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q: must be in RFC 3339 format", at)
+		}
+	}
+
+	var gates []GateResult
+
+	freeze, until, err := activeFreeze(p.Freezes, now, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid freeze window: %w", err)
+	}
+	switch {
+	case freeze == nil:
+		gates = append(gates, GateResult{Name: "Freeze windows", Passed: true, Detail: "no freeze in effect"})
+	case overrideReason != "":
+		gates = append(gates, GateResult{
+			Name:   "Freeze windows",
+			Passed: true,
+			Detail: fmt.Sprintf("⚠️ %s overridden: %s", freeze.Name, overrideReason),
+		})
+	default:
+		gates = append(gates, GateResult{
+			Name:   "Freeze windows",
+			Detail: fmt.Sprintf("%s in effect until %s", freeze.Name, until.Format("Mon 2006-01-02 15:04 MST")),
+		})
+	}
+
+	if p.Approvals.Required > 0 {
+		gate, err := m.approvals(ctx, p.Approvals.Required, sha, pr)
+		if err != nil {
+			return nil, err
+		}
+		gates = append(gates, gate)
+	}
+
+	if p.Changelog.File != "" {
+		gate, err := m.changelog(ctx, p.Changelog.File, version)
+		if err != nil {
+			return nil, err
+		}
+		gates = append(gates, gate)
+	}
+
+	d := &Decision{Allowed: true, Gates: gates}
+	var b strings.Builder
+	b.WriteString("## Release gate")
+	if version != "" {
+		b.WriteString(" " + version)
+	}
+	b.WriteString("\n\n")
+	for _, g := range gates {
+		status := "✅"
+		if !g.Passed {
+			status = "❌"
+			d.Allowed = false
+		}
+		fmt.Fprintf(&b, "- %s **%s**: %s\n", status, g.Name, g.Detail)
+	}
+	d.Report = b.String()
+
+	return d, nil
+}
+
+// Check evaluates the release like Evaluate and fails with the report
+// when any gate blocks it. Run it before any publishing step.
+func (m *Releasegate) Check(
+	ctx context.Context,
+
+	// Version being released (e.g., "v1.2.3"), required when the policy
+	// requires a changelog entry
+	// +optional
+	version string,
+
+	// Commit being released; approvals are counted on the pull request
+	// that introduced it
+	// +optional
+	sha string,
+
+	// Pull request to count approvals on, instead of looking it up from
+	// sha
+	// +optional
+	pr int,
+
+	// Time to evaluate freeze windows at, in RFC 3339 format (defaults to
+	// now)
+	// +optional
+	at string,
+
+	// Release during a freeze anyway, for this reason (e.g., "hotfix for
+	// INC-42"). Other gates still apply.
+	// +optional
+	overrideReason string,
+) (string, error) {
+	d, err := m.Evaluate(ctx, version, sha, pr, at, overrideReason)
+	if err != nil {
+		return "", err
+	}
+	if !d.Allowed {
+		return "", fmt.Errorf("release blocked\n\n%s", d.Report)
+	}
+	return d.Report, nil
+}
+
+// approvals counts the approving reviews of the pull request.
+func (m *Releasegate) approvals(ctx context.Context, required int, sha string, pr int) (GateResult, error) {
+	gate := GateResult{Name: "Approvals"}
+	if m.Token == nil || m.Repo == "" {
+		return gate, fmt.Errorf("the policy requires approvals: token and repo are required")
+	}
+
+	ctr := dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "github-cli"}).
+		WithSecretVariable("GH_TOKEN", m.Token).
+		// Reviews change; always fetch them again.
+		WithEnvVariable("RELEASEGATE_SESSION", strconv.FormatInt(time.Now().UnixNano(), 10))
+
+	if pr == 0 {
+		if sha == "" {
+			return gate, fmt.Errorf("the policy requires approvals: sha or pr is required")
+		}
+		out, err := ctr.
+			WithExec([]string{"gh", "api", fmt.Sprintf("repos/%s/commits/%s/pulls", m.Repo, sha), "--jq", ".[0].number // empty"}).
+			Stdout(ctx)
+		if err != nil {
+			return gate, fmt.Errorf("failed to find the pull request of %s: %w", sha, err)
+		}
+		if strings.TrimSpace(out) == "" {
+			gate.Detail = fmt.Sprintf("no pull request introduced %s", sha)
+			return gate, nil
+		}
+		pr, err = strconv.Atoi(strings.TrimSpace(out))
+		if err != nil {
+			return gate, fmt.Errorf("unexpected pull request number %q", out)
+		}
+	}
+
+	out, err := ctr.
+		WithExec([]string{"gh", "api", "--paginate", fmt.Sprintf("repos/%s/pulls/%d/reviews", m.Repo, pr), "--jq", ".[]"}).
+		Stdout(ctx)
+	if err != nil {
+		return gate, fmt.Errorf("failed to list reviews of PR #%d: %w", pr, err)
+	}
+	logins, err := approvers(out)
+	if err != nil {
+		return gate, err
+	}
+
+	gate.Passed = len(logins) >= required
+	gate.Detail = fmt.Sprintf("%d of %d required on PR #%d", len(logins), required, pr)
+	if len(logins) > 0 {
+		gate.Detail += " (" + strings.Join(logins, ", ") + ")"
+	}
+	return gate, nil
+}
+
+// changelog checks that the changelog has an entry for the version.
+func (m *Releasegate) changelog(ctx context.Context, file, version string) (GateResult, error) {
+	gate := GateResult{Name: "Changelog"}
+	if version == "" {
+		return gate, fmt.Errorf("the policy requires a changelog entry: version is required")
+	}
+
+	ok, err := m.Source.Exists(ctx, file)
+	if err != nil {
+		return gate, fmt.Errorf("failed to inspect source directory: %w", err)
+	}
+	if !ok {
+		gate.Detail = fmt.Sprintf("%s not found", file)
+		return gate, nil
+	}
+
+	contents, err := m.Source.File(file).Contents(ctx)
+	if err != nil {
+		return gate, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	gate.Passed = hasVersionHeading(contents, version)
+	if gate.Passed {
+		gate.Detail = fmt.Sprintf("%s has a %s entry", file, version)
+	} else {
+		gate.Detail = fmt.Sprintf("%s has no heading for %s", file, version)
+	}
+	return gate, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	// Embed the time zone database; the module runtime may not have one.
+	_ "time/tzdata"
+)
+
+// policy is the release policy file.
+type policy struct {
+	// IANA time zone freeze windows are written in (default UTC)
+	Timezone string `json:"timezone"`
+
+	Freezes []freeze `json:"freezes"`
+
+	Approvals struct {
+		// Approving reviews required on the pull request that introduced
+		// the released commit
+		Required int `json:"required"`
+	} `json:"approvals"`
+
+	Changelog struct {
+		// Changelog that must have a heading for the released version
+		File string `json:"file"`
+	} `json:"changelog"`
+}
+
+// freeze is a window in which releases are blocked: either a one-off range
+// from start to end, or a weekly window on the given days, optionally
+// limited to from-to times of day.
+type freeze struct {
+	Name  string   `json:"name"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+	Days  []string `json:"days"`
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+}
+
+// dateLayouts are the accepted formats of one-off freeze bounds, in the
+// policy's time zone unless they carry an offset.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parsePolicy decodes the policy file, converted to JSON.
+func parsePolicy(data string) (*policy, *time.Location, error) {
+	var p policy
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse release policy: %w", err)
+	}
+
+	loc := time.UTC
+	if p.Timezone != "" {
+		l, err := time.LoadLocation(p.Timezone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid timezone %q: %w", p.Timezone, err)
+		}
+		loc = l
+	}
+	return &p, loc, nil
+}
+
+// activeFreeze returns the freeze window now falls in, and when it ends.
+func activeFreeze(freezes []freeze, now time.Time, loc *time.Location) (*freeze, time.Time, error) {
+	now = now.In(loc)
+	for i, f := range freezes {
+		if f.Name == "" {
+			f.Name = fmt.Sprintf("freeze #%d", i+1)
+		}
+
+		if len(f.Days) == 0 {
+			start, err := parseDate(f.Start, loc)
+			if err != nil {
+				return nil, time.Time{}, fmt.Errorf("%s: invalid start: %w", f.Name, err)
+			}
+			end, err := parseDate(f.End, loc)
+			if err != nil {
+				return nil, time.Time{}, fmt.Errorf("%s: invalid end: %w", f.Name, err)
+			}
+			if !now.Before(start) && now.Before(end) {
+				return &f, end, nil
+			}
+			continue
+		}
+
+		from, err := parseClock(f.From, 0)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("%s: invalid from: %w", f.Name, err)
+		}
+		to, err := parseClock(f.To, 24*time.Hour)
+		if err != nil {
+			return nil, time.Time{}, fmt.Errorf("%s: invalid to: %w", f.Name, err)
+		}
+		// A window ending before it starts runs past midnight.
+		length := to - from
+		if length <= 0 {
+			length += 24 * time.Hour
+		}
+
+		for _, d := range f.Days {
+			day, err := parseWeekday(d)
+			if err != nil {
+				return nil, time.Time{}, fmt.Errorf("%s: %w", f.Name, err)
+			}
+			// Windows started today or, past midnight, yesterday.
+			for _, offset := range []int{0, -1} {
+				date := now.AddDate(0, 0, offset)
+				if date.Weekday() != day {
+					continue
+				}
+				start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc).Add(from)
+				end := start.Add(length)
+				if !now.Before(start) && now.Before(end) {
+					return &f, end, nil
+				}
+			}
+		}
+	}
+	return nil, time.Time{}, nil
+}
+
+func parseDate(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date like 2006-01-02 or 2006-01-02T15:04", s)
+}
+
+// parseClock parses an "HH:MM" time of day, or returns dflt when empty.
+func parseClock(s string, dflt time.Duration) (time.Duration, error) {
+	if s == "" {
+		return dflt, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a time like 17:00", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(s)
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day %q", s)
+}
+
+// review is a pull request review.
+type review struct {
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	State string `json:"state"`
+}
+
+// approvers returns the reviewers whose latest review approves, the way
+// GitHub counts approvals: comments do not change a reviewer's verdict,
+// and dismissed or change-requesting reviews revoke it. Reviews are a
+// stream of JSON objects in chronological order.
+func approvers(data string) ([]string, error) {
+	verdicts := map[string]string{}
+	dec := json.NewDecoder(strings.NewReader(data))
+	for {
+		var r review
+		if err := dec.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse reviews: %w", err)
+		}
+		if r.State != "COMMENTED" && r.State != "PENDING" {
+			verdicts[r.User.Login] = r.State
+		}
+	}
+
+	var logins []string
+	for login, state := range verdicts {
+		if state == "APPROVED" {
+			logins = append(logins, login)
+		}
+	}
+	sort.Strings(logins)
+	return logins, nil
+}
+
+// hasVersionHeading reports whether a markdown changelog has a heading
+// naming version, with or without a "v" prefix (e.g. "## [1.2.3] - ..." or
+// "## v1.2.3").
+func hasVersionHeading(changelog, version string) bool {
+	v := regexp.QuoteMeta(strings.TrimPrefix(version, "v"))
+	heading := regexp.MustCompile(`(?m)^#+\s+(?:.*[^\w.])?v?` + v + `(?:$|[^\w.-])`)
+	return heading.MatchString(changelog)
+}
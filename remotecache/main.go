@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"dagger/remotecache/internal/dagger"
+)
+
+const (
+	awsImage    string = "amazon/aws-cli:latest"
+	alpineImage string = "alpine:3.21"
+
+	// archiveExt is the extension of cache archives in the bucket.
+	archiveExt string = ".tar.zst"
+)
+
+// validKey restricts cache keys to characters that are safe in bucket
+// object keys.
+var validKey = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+
+// lookupScript prints the object key of the cache to restore: the exact
+// key when it exists, or else the newest archive under the first restore
+// key that matches any. It prints nothing on a miss.
+const lookupScript string = `set -eu
+exact="$1"
+shift
+if out=$(aws s3api head-object --bucket "$BUCKET_NAME" --key "$exact" --endpoint-url "$BUCKET_ENDPOINT" 2>&1); then
+	echo "$exact"
+	exit 0
+elif ! echo "$out" | grep -q -e "Not Found" -e "404"; then
+	echo "$out" >&2
+	exit 1
+fi
+for prefix in "$@"; do
+	found=$(aws s3api list-objects-v2 --bucket "$BUCKET_NAME" --prefix "$prefix" --endpoint-url "$BUCKET_ENDPOINT" --query "$NEWEST_ARCHIVE" --output text)
+	if [ -n "$found" ] && [ "$found" != "None" ]; then
+		echo "$found"
+		exit 0
+	fi
+done
+`
+
+// newestArchive is the JMESPath query selecting the newest archive of a
+// list-objects-v2 response.
+const newestArchive string = "sort_by((Contents || `[]`)[?ends_with(Key, '" + archiveExt + "')], &LastModified)[-1].Key"
+
+// Remotecache saves and restores keyed build caches to an S3-compatible
+// bucket, for runners without persistent cache volumes.
+type Remotecache struct {
+	// Bucket endpoint URL
+	//
+	// +private
+	Endpoint *dagger.Secret
+
+	// Bucket name
+	//
+	// +private
+	Bucket *dagger.Secret
+
+	// Bucket access key ID
+	//
+	// +private
+	AccessKeyID *dagger.Secret
+
+	// Bucket secret access key
+	//
+	// +private
+	SecretAccessKey *dagger.Secret
+
+	// Bucket key prefix the caches are stored under
+	//
+	// +private
+	Prefix string
+}
+
+// New creates a new Remotecache instance configured with bucket
+// credentials.
+func New(
+	// Bucket endpoint URL
+	endpoint *dagger.Secret,
+
+	// Bucket name
+	bucket *dagger.Secret,
+
+	// Bucket access key ID
+	accessKeyID *dagger.Secret,
+
+	// Bucket secret access key
+	secretAccessKey *dagger.Secret,
+
+	// Bucket key prefix the caches are stored under
+	// +optional
+	// +default="cache"
+	prefix string,
+) *Remotecache {
+	return &Remotecache{
+		Endpoint:        endpoint,
+		Bucket:          bucket,
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+		Prefix:          strings.Trim(prefix, "/"),
+	}
+}
+
+// Key returns a cache key made of name and a hash of the files' contents,
+// such as go.sum or a lockfile, e.g. "go-build-linux-3f2a9c1e0b7d4a56".
+// The key changes whenever any of the files does.
+func (m *Remotecache) Key(
+	ctx context.Context,
+
+	// Name of the cache (e.g., "go-build-linux")
+	name string,
+
+	// Files the cache depends on
+	files []*dagger.File,
+) (string, error) {
+	h := sha256.New()
+	for i, f := range files {
+		contents, err := f.Contents(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to read file %d: %w", i+1, err)
+		}
+		fmt.Fprintf(h, "%d\n%s", len(contents), contents)
+	}
+	return name + "-" + hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// Lookup returns the key of the cache Restore would restore, or "" on a
+// miss.
+func (m *Remotecache) Lookup(
+	ctx context.Context,
+
+	// Cache key
+	key string,
+
+	// Key prefixes to fall back to, in order, when the key misses. The
+	// newest cache under the first matching prefix is used.
+	// +optional
+	restoreKeys []string,
+) (string, error) {
+	object, err := m.lookup(ctx, key, restoreKeys)
+	if err != nil || object == "" {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(object, m.objectPrefix()), archiveExt), nil
+}
+
+// Restore returns the contents of the cache saved under key, or of the
+// newest cache under the first matching restore key. It returns an empty
+// directory on a miss, so restoring never fails a build.
+func (m *Remotecache) Restore(
+	ctx context.Context,
+
+	// Cache key
+	key string,
+
+	// Key prefixes to fall back to, in order, when the key misses (e.g.,
+	// "go-build-linux-"). The newest cache under the first matching prefix
+	// is used.
+	// +optional
+	restoreKeys []string,
+) (*dagger.Directory, error) {
+	object, err := m.lookup(ctx, key, restoreKeys)
+	if err != nil {
+		return nil, err
+	}
+	if object == "" {
+		return dag.Directory(), nil
+	}
+
+	archive := m.awsContainer().
+		WithExec([]string{"sh", "-c",
+			`aws s3 cp "s3://$BUCKET_NAME/$1" /cache` + archiveExt + ` --endpoint-url "$BUCKET_ENDPOINT"`,
+			"sh", object,
+		}).
+		File("/cache" + archiveExt)
+
+	dir, err := dag.Container().
+		From(alpineImage).
+		WithExec([]string{"apk", "add", "--no-cache", "tar", "zstd"}).
+		WithFile("/cache"+archiveExt, archive).
+		WithExec([]string{"sh", "-c", "mkdir -p /out && zstd -dc /cache" + archiveExt + " | tar -C /out -xf -"}).
+		Directory("/out").
+		Sync(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return nil, fmt.Errorf("failed to restore %s\n\n%s%s", object, e.Stdout, e.Stderr)
+	} else if err != nil {
+		return nil, fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return dir, nil
+}
+
+// Save archives a directory and saves it under key. Like other build
+// caches, a saved key is never replaced unless overwrite is set: change
+// the key when the contents should change.
+func (m *Remotecache) Save(
+	ctx context.Context,
+
+	// Directory to cache (e.g., a container's /root/.cache/go-build)
+	dir *dagger.Directory,
+
+	// Cache key
+	key string,
+
+	// Replace the cache when the key already exists
+	// +optional
+	overwrite bool,
+) (string, error) {
+	if !overwrite {
+		existing, err := m.lookup(ctx, key, nil)
+		if err != nil {
+			return "", err
+		}
+		if existing != "" {
+			return fmt.Sprintf("✅ cache %s already saved", key), nil
+		}
+	}
+
+	archive := dag.Container().
+		From(alpineImage).
+		WithExec([]string{"apk", "add", "--no-cache", "tar", "zstd"}).
+		WithMountedDirectory("/cache", dir).
+		WithExec([]string{"sh", "-c", "tar -C /cache -cf - . | zstd -T0 -q -o /cache" + archiveExt}).
+		File("/cache" + archiveExt)
+
+	object := m.objectKey(key)
+	_, err := m.awsContainer().
+		WithFile("/cache"+archiveExt, archive).
+		WithExec([]string{"sh", "-c",
+			`aws s3 cp /cache` + archiveExt + ` "s3://$BUCKET_NAME/$1" --endpoint-url "$BUCKET_ENDPOINT"`,
+			"sh", object,
+		}).
+		Sync(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("failed to save %s\n\n%s%s", key, e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return fmt.Sprintf("✅ saved cache %s", key), nil
+}
+
+// lookup returns the object key of the cache to restore, or "" on a miss.
+func (m *Remotecache) lookup(ctx context.Context, key string, restoreKeys []string) (string, error) {
+	if !validKey.MatchString(key) {
+		return "", fmt.Errorf("invalid cache key %q: must match %s", key, validKey)
+	}
+	args := []string{"sh", "-c", lookupScript, "sh", m.objectKey(key)}
+	for _, rk := range restoreKeys {
+		if !validKey.MatchString(rk) {
+			return "", fmt.Errorf("invalid restore key %q: must match %s", rk, validKey)
+		}
+		args = append(args, m.objectPrefix()+rk)
+	}
+
+	out, err := m.awsContainer().
+		WithEnvVariable("NEWEST_ARCHIVE", newestArchive).
+		WithExec(args).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("failed to look up cache %s\n\n%s%s", key, e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return strings.TrimSpace(out), nil
+}
+
+// awsContainer returns an AWS CLI container with the bucket credentials,
+// which always runs: the bucket changes between calls.
+func (m *Remotecache) awsContainer() *dagger.Container {
+	return dag.Container().
+		From(awsImage).
+		WithSecretVariable("AWS_ACCESS_KEY_ID", m.AccessKeyID).
+		WithSecretVariable("AWS_SECRET_ACCESS_KEY", m.SecretAccessKey).
+		WithEnvVariable("AWS_DEFAULT_REGION", "auto").
+		WithSecretVariable("BUCKET_ENDPOINT", m.Endpoint).
+		WithSecretVariable("BUCKET_NAME", m.Bucket).
+		WithEnvVariable("REMOTECACHE_SESSION", fmt.Sprintf("%d", time.Now().UnixNano()))
+}
+
+// objectPrefix returns the prefix of every cache's object key.
+func (m *Remotecache) objectPrefix() string {
+	if m.Prefix == "" {
+		return ""
+	}
+	return m.Prefix + "/"
+}
+
+// objectKey returns the object key of the cache saved under key.
+func (m *Remotecache) objectKey(key string) string {
+	return path.Clean(m.objectPrefix()+key) + archiveExt
+}
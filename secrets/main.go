@@ -0,0 +1,205 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"dagger/secrets/internal/dagger"
+)
+
+const (
+	vaultImage string = "hashicorp/vault:1.18"
+	sopsImage  string = "ghcr.io/getsops/sops:v3.9.4-alpine"
+)
+
+// envNamePattern matches valid environment variable names.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Secrets reads secrets from HashiCorp Vault and SOPS-encrypted files and
+// returns them as Dagger secrets.
+type Secrets struct {
+	// Vault server address
+	//
+	// +private
+	VaultAddr string
+
+	// Vault Enterprise namespace
+	//
+	// +private
+	VaultNamespace string
+
+	// Vault token
+	//
+	// +private
+	VaultToken *dagger.Secret
+
+	// AppRole role ID
+	//
+	// +private
+	VaultRoleID *dagger.Secret
+
+	// AppRole secret ID
+	//
+	// +private
+	VaultSecretID *dagger.Secret
+
+	// Mount path of the AppRole auth method
+	//
+	// +private
+	VaultAppRoleMount string
+
+	// age private keys, in the format of an age key file
+	//
+	// +private
+	AgeKey *dagger.Secret
+
+	// AWS access key ID for KMS
+	//
+	// +private
+	AwsAccessKeyID *dagger.Secret
+
+	// AWS secret access key for KMS
+	//
+	// +private
+	AwsSecretAccessKey *dagger.Secret
+
+	// AWS session token for KMS
+	//
+	// +private
+	AwsSessionToken *dagger.Secret
+
+	// AWS region for KMS
+	//
+	// +private
+	AwsRegion string
+
+	// GCP service account key for Cloud KMS
+	//
+	// +private
+	GcpCredentials *dagger.Secret
+}
+
+// New creates a new Secrets module instance. Configure a source with
+// WithVault, WithVaultAppRole, or the SOPS key functions.
+func New() *Secrets {
+	return &Secrets{}
+}
+
+// WithVault authenticates to Vault with a token.
+func (m *Secrets) WithVault(
+	// Vault server address (e.g., "https://vault.example.com:8200")
+	addr string,
+
+	// Vault token
+	token *dagger.Secret,
+
+	// Vault Enterprise namespace
+	// +optional
+	namespace string,
+) *Secrets {
+	m.VaultAddr = addr
+	m.VaultToken = token
+	m.VaultNamespace = namespace
+	return m
+}
+
+// WithVaultAppRole authenticates to Vault with AppRole. Every read logs in
+// first, so the token never leaves the container.
+func (m *Secrets) WithVaultAppRole(
+	// Vault server address (e.g., "https://vault.example.com:8200")
+	addr string,
+
+	// AppRole role ID
+	roleID *dagger.Secret,
+
+	// AppRole secret ID
+	secretID *dagger.Secret,
+
+	// Mount path of the AppRole auth method
+	// +optional
+	// +default="approle"
+	mount string,
+
+	// Vault Enterprise namespace
+	// +optional
+	namespace string,
+) *Secrets {
+	m.VaultAddr = addr
+	m.VaultRoleID = roleID
+	m.VaultSecretID = secretID
+	m.VaultAppRoleMount = mount
+	m.VaultNamespace = namespace
+	return m
+}
+
+// WithAgeKey decrypts SOPS files with age keys.
+func (m *Secrets) WithAgeKey(
+	// age private keys, in the format of an age key file
+	// ("AGE-SECRET-KEY-..." lines)
+	key *dagger.Secret,
+) *Secrets {
+	m.AgeKey = key
+	return m
+}
+
+// WithAwsKms decrypts SOPS files with AWS KMS keys.
+func (m *Secrets) WithAwsKms(
+	// AWS access key ID
+	accessKeyID *dagger.Secret,
+
+	// AWS secret access key
+	secretAccessKey *dagger.Secret,
+
+	// AWS region of the keys. Defaults to the region in the key ARNs.
+	// +optional
+	region string,
+
+	// AWS session token, for temporary credentials
+	// +optional
+	sessionToken *dagger.Secret,
+) *Secrets {
+	m.AwsAccessKeyID = accessKeyID
+	m.AwsSecretAccessKey = secretAccessKey
+	m.AwsRegion = region
+	m.AwsSessionToken = sessionToken
+	return m
+}
+
+// WithGcpKms decrypts SOPS files with Google Cloud KMS keys.
+func (m *Secrets) WithGcpKms(
+	// Service account key JSON
+	credentials *dagger.Secret,
+) *Secrets {
+	m.GcpCredentials = credentials
+	return m
+}
+
+// withEnv sets each value as a secret variable of ctr, in sorted order, as
+// "<prefix><name>".
+func withEnv(ctr *dagger.Container, values map[string]string, prefix, source string) (*dagger.Container, error) {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if !envNamePattern.MatchString(prefix + name) {
+			return nil, fmt.Errorf("invalid variable name %q from %s: must match %s", prefix+name, source, envNamePattern)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ctr = ctr.WithSecretVariable(prefix+name, dag.SetSecret(source+"#"+name, values[name]))
+	}
+	return ctr, nil
+}
+
+// execError returns the error output of a failed command, or the error
+// itself. Standard output is left out, as it may hold secret values.
+func execError(msg string, err error) error {
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return fmt.Errorf("%s\n\n%s", msg, e.Stderr)
+	}
+	return fmt.Errorf("unexpected error: %w", err)
+}
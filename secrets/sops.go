@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"dagger/secrets/internal/dagger"
+)
+
+// Sops decrypts a SOPS-encrypted file and returns it, or one value of it,
+// as a secret. The file format is detected from its extension, as sops
+// does.
+func (m *Secrets) Sops(
+	ctx context.Context,
+
+	// SOPS-encrypted YAML, JSON, dotenv, INI, or binary file
+	file *dagger.File,
+
+	// Value to extract, as a dotted path (e.g., "db.password") or in sops
+	// --extract syntax (e.g., '["db"]["password"]'). Returns the whole
+	// file when empty.
+	// +optional
+	key string,
+) (*dagger.Secret, error) {
+	args := []string{"decrypt"}
+	if key != "" {
+		args = append(args, "--extract", extractPath(key))
+	}
+
+	out, err := m.sops(ctx, file, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := m.sopsName(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		name += "#" + key
+	}
+	return dag.SetSecret(name, out), nil
+}
+
+// WithSopsEnv decrypts a SOPS-encrypted file into a container as secret
+// environment variables, one per top-level key. Values must be strings,
+// numbers, or booleans.
+func (m *Secrets) WithSopsEnv(
+	ctx context.Context,
+
+	// Container to add the variables to
+	ctr *dagger.Container,
+
+	// SOPS-encrypted YAML, JSON, or dotenv file
+	file *dagger.File,
+
+	// Prefix for the variable names (e.g., "DEPLOY_")
+	// +optional
+	prefix string,
+) (*dagger.Container, error) {
+	out, err := m.sops(ctx, file, "decrypt", "--output-type", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(out), &fields); err != nil {
+		return nil, fmt.Errorf("decrypted file is not a map of values: %w", err)
+	}
+
+	values := map[string]string{}
+	for name, raw := range fields {
+		if t := strings.TrimSpace(string(raw)); strings.HasPrefix(t, "{") || strings.HasPrefix(t, "[") {
+			return nil, fmt.Errorf("value of %q is not a string, number, or boolean: read it with sops and --key instead", name)
+		}
+		values[name] = scalar(raw)
+	}
+
+	name, err := m.sopsName(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	return withEnv(ctr, values, prefix, name)
+}
+
+// sops runs a sops command on file with the configured keys and returns
+// its output.
+func (m *Secrets) sops(ctx context.Context, file *dagger.File, args ...string) (string, error) {
+	if m.AgeKey == nil && m.AwsAccessKeyID == nil && m.GcpCredentials == nil {
+		return "", errors.New("no SOPS key configured: use with-age-key, with-aws-kms, or with-gcp-kms")
+	}
+
+	// sops detects the format from the file extension.
+	name, err := file.Name(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file name: %w", err)
+	}
+	p := path.Join("/work", name)
+
+	ctr := dag.Container().
+		From(sopsImage).
+		WithFile(p, file)
+	if m.AgeKey != nil {
+		ctr = ctr.WithSecretVariable("SOPS_AGE_KEY", m.AgeKey)
+	}
+	if m.AwsAccessKeyID != nil {
+		ctr = ctr.
+			WithSecretVariable("AWS_ACCESS_KEY_ID", m.AwsAccessKeyID).
+			WithSecretVariable("AWS_SECRET_ACCESS_KEY", m.AwsSecretAccessKey)
+		if m.AwsSessionToken != nil {
+			ctr = ctr.WithSecretVariable("AWS_SESSION_TOKEN", m.AwsSessionToken)
+		}
+		if m.AwsRegion != "" {
+			ctr = ctr.WithEnvVariable("AWS_REGION", m.AwsRegion)
+		}
+	}
+	if m.GcpCredentials != nil {
+		ctr = ctr.
+			WithMountedSecret("/run/secrets/gcp-credentials.json", m.GcpCredentials).
+			WithEnvVariable("GOOGLE_APPLICATION_CREDENTIALS", "/run/secrets/gcp-credentials.json")
+	}
+
+	out, err := ctr.
+		WithExec(append(append([]string{"sops"}, args...), p)).
+		Stdout(ctx)
+	if err != nil {
+		return "", execError("failed to decrypt "+name, err)
+	}
+	return out, nil
+}
+
+// sopsName returns the name the secrets of a file are set under, which is
+// unique to its contents.
+func (m *Secrets) sopsName(ctx context.Context, file *dagger.File) (string, error) {
+	digest, err := file.Digest(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to digest file: %w", err)
+	}
+	return "sops:" + digest, nil
+}
+
+// extractPath converts a dotted path (e.g., "db.hosts.0") into sops
+// --extract syntax (e.g., '["db"]["hosts"][0]'). Paths already in that
+// syntax are returned as is.
+func extractPath(key string) string {
+	if strings.HasPrefix(key, "[") {
+		return key
+	}
+	var b strings.Builder
+	for _, part := range strings.Split(key, ".") {
+		if _, err := strconv.Atoi(part); err == nil {
+			b.WriteString("[" + part + "]")
+			continue
+		}
+		b.WriteString("[" + strconv.Quote(part) + "]")
+	}
+	return b.String()
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"dagger/secrets/internal/dagger"
+)
+
+// vaultScript logs in with AppRole when a role ID is set, then runs its
+// arguments as a vault command, so the token never leaves the container.
+const vaultScript string = `set -eu
+if [ -n "${VAULT_ROLE_ID:-}" ]; then
+	VAULT_TOKEN=$(vault write -field=token "auth/$VAULT_APPROLE_MOUNT/login" role_id="$VAULT_ROLE_ID" secret_id="$VAULT_SECRET_ID")
+	export VAULT_TOKEN
+fi
+exec vault "$@"
+`
+
+// Vault reads one field of a KV secret (version 1 or 2).
+func (m *Secrets) Vault(
+	ctx context.Context,
+
+	// Path of the secret in the KV engine (e.g., "ci/deploy")
+	path string,
+
+	// Field of the secret to read (e.g., "password")
+	field string,
+
+	// Mount path of the KV engine
+	// +optional
+	// +default="secret"
+	mount string,
+) (*dagger.Secret, error) {
+	value, err := m.vault(ctx, "kv", "get", "-mount="+mount, "-field="+field, path)
+	if err != nil {
+		return nil, err
+	}
+	return dag.SetSecret(fmt.Sprintf("vault:%s/%s#%s", mount, path, field), value), nil
+}
+
+// WithVaultEnv reads every field of a KV secret into a container as secret
+// environment variables named after the fields.
+func (m *Secrets) WithVaultEnv(
+	ctx context.Context,
+
+	// Container to add the variables to
+	ctr *dagger.Container,
+
+	// Path of the secret in the KV engine (e.g., "ci/deploy")
+	path string,
+
+	// Mount path of the KV engine
+	// +optional
+	// +default="secret"
+	mount string,
+
+	// Prefix for the variable names (e.g., "DEPLOY_")
+	// +optional
+	prefix string,
+) (*dagger.Container, error) {
+	out, err := m.vault(ctx, "kv", "get", "-mount="+mount, "-format=json", path)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := kvData(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s/%s: %w", mount, path, err)
+	}
+	return withEnv(ctr, values, prefix, fmt.Sprintf("vault:%s/%s", mount, path))
+}
+
+// vault runs a vault command and returns its output.
+func (m *Secrets) vault(ctx context.Context, args ...string) (string, error) {
+	if m.VaultAddr == "" {
+		return "", errors.New("no Vault configured: use with-vault or with-vault-app-role")
+	}
+
+	ctr := dag.Container().
+		From(vaultImage).
+		WithEnvVariable("VAULT_ADDR", m.VaultAddr)
+	if m.VaultNamespace != "" {
+		ctr = ctr.WithEnvVariable("VAULT_NAMESPACE", m.VaultNamespace)
+	}
+	if m.VaultRoleID != nil {
+		ctr = ctr.
+			WithSecretVariable("VAULT_ROLE_ID", m.VaultRoleID).
+			WithSecretVariable("VAULT_SECRET_ID", m.VaultSecretID).
+			WithEnvVariable("VAULT_APPROLE_MOUNT", m.VaultAppRoleMount)
+	} else {
+		ctr = ctr.WithSecretVariable("VAULT_TOKEN", m.VaultToken)
+	}
+
+	out, err := ctr.
+		// Secrets are rotated without their path changing; always read
+		// them.
+		WithEnvVariable("SECRETS_SESSION", fmt.Sprintf("%d", time.Now().UnixNano())).
+		WithExec(append([]string{"sh", "-c", vaultScript, "vault"}, args...)).
+		Stdout(ctx)
+	if err != nil {
+		return "", execError("failed to read from Vault", err)
+	}
+	return out, nil
+}
+
+// kvData returns the fields of "vault kv get -format=json" output. KV
+// version 2 nests them under data.data, next to data.metadata.
+func kvData(out string) (map[string]string, error) {
+	var resp struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(out), &resp); err != nil {
+		return nil, fmt.Errorf("invalid vault output: %w", err)
+	}
+
+	fields := resp.Data
+	if nested, ok := resp.Data["data"]; ok {
+		if _, v2 := resp.Data["metadata"]; v2 {
+			fields = nil
+			if err := json.Unmarshal(nested, &fields); err != nil {
+				return nil, fmt.Errorf("invalid vault output: %w", err)
+			}
+		}
+	}
+
+	values := map[string]string{}
+	for name, raw := range fields {
+		values[name] = scalar(raw)
+	}
+	return values, nil
+}
+
+// scalar returns a JSON string's value, or any other JSON value as is.
+func scalar(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
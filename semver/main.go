@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"dagger/semver/internal/dagger"
+)
+
+const (
+	gitImage string = "alpine/git:v2.47.1"
+)
+
+// conventionalPattern matches a conventional commit subject, optionally
+// prefixed with an emoji or gitmoji shortcode (e.g., "✨ feat(api)!: ...").
+var conventionalPattern = regexp.MustCompile(`^(?:(?::\w+:|[^\w\s]+)\s*)?(\w+)(?:\([^)]*\))?(!)?:`)
+
+// channelPattern matches valid prerelease channel names.
+var channelPattern = regexp.MustCompile(`^[0-9A-Za-z-]+$`)
+
+type Semver struct {
+	// Source is the git repository to read tags and history from. It must
+	// include .git.
+	//
+	// +private
+	Source *dagger.Directory
+}
+
+// New creates a new Semver module instance.
+func New(
+	// A directory containing a git repository (must include .git).
+	// +defaultPath="/"
+	source *dagger.Directory,
+) *Semver {
+	return &Semver{
+		Source: source,
+	}
+}
+
+// Current returns the latest release tag reachable from HEAD, ignoring
+// prereleases. Fails when there is none.
+func (m *Semver) Current(
+	ctx context.Context,
+
+	// Tag prefix
+	// +optional
+	// +default="v"
+	prefix string,
+) (string, error) {
+	tags, err := m.tags(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	tag, _, ok := latestRelease(tags, prefix)
+	if !ok {
+		return "", fmt.Errorf("no release tags matching %q reachable from HEAD", prefix+"X.Y.Z")
+	}
+	return tag, nil
+}
+
+// Next computes the next version from the commits since the latest release
+// tag: breaking changes ("type!:", "breaking:", or a BREAKING CHANGE footer)
+// bump major, "feat" commits bump minor, and anything else bumps patch.
+// Commit subjects may carry an emoji prefix as produced for ghrelease.
+//
+// With a channel the result is the next prerelease of that version on the
+// channel (e.g., "v1.3.0-rc.2" after "v1.3.0-rc.1"). When there are no
+// commits since the latest release, that release is returned unchanged.
+func (m *Semver) Next(
+	ctx context.Context,
+
+	// Prerelease channel (e.g., "rc", "beta"). Empty for a release.
+	// +optional
+	channel string,
+
+	// Force a bump level ("major", "minor", or "patch") instead of deriving
+	// it from commits
+	// +optional
+	bump string,
+
+	// Use this version verbatim (e.g., "v2.0.0"), after validating it
+	// +optional
+	override string,
+
+	// Tag prefix
+	// +optional
+	// +default="v"
+	prefix string,
+
+	// Version to use when there are no release tags yet
+	// +optional
+	// +default="0.1.0"
+	initial string,
+) (string, error) {
+	if override != "" {
+		if _, ok := parseVersion(override, prefix); !ok {
+			return "", fmt.Errorf("invalid override %q: must be %sMAJOR.MINOR.PATCH[-PRERELEASE]", override, prefix)
+		}
+		return override, nil
+	}
+	if bump != "" && levels[bump] == 0 {
+		return "", fmt.Errorf("invalid bump %q: must be \"major\", \"minor\", or \"patch\"", bump)
+	}
+	if channel != "" && !channelPattern.MatchString(channel) {
+		return "", fmt.Errorf("invalid channel %q: must be alphanumeric", channel)
+	}
+
+	tags, err := m.tags(ctx, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	var next version
+	tag, current, ok := latestRelease(tags, prefix)
+	if !ok {
+		next, ok = parseVersion(initial, "")
+		if !ok {
+			return "", fmt.Errorf("invalid initial version %q: must be MAJOR.MINOR.PATCH", initial)
+		}
+	} else {
+		level := bump
+		if level == "" {
+			level, err = m.bumpSince(ctx, tag)
+			if err != nil {
+				return "", err
+			}
+			if level == "" {
+				return tag, nil
+			}
+		}
+		next = current.bump(level)
+	}
+
+	if channel != "" {
+		next.prerelease = nextPrerelease(tags, prefix, next, channel)
+	}
+
+	return next.format(prefix), nil
+}
+
+// bumpSince returns the bump level implied by the commits since tag, or ""
+// when there are none.
+func (m *Semver) bumpSince(ctx context.Context, tag string) (string, error) {
+	out, err := m.container().
+		WithExec([]string{"git", "log", "--no-merges", "--format=%s%n%b%x1e", tag + "..HEAD", "--"}).
+		Stdout(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commits since %s: %w", tag, err)
+	}
+
+	level := ""
+	for _, commit := range strings.Split(out, "\x1e") {
+		commit = strings.TrimLeft(commit, "\n")
+		if commit == "" {
+			continue
+		}
+		if l := commitLevel(commit); levels[l] > levels[level] {
+			level = l
+		}
+	}
+	return level, nil
+}
+
+// commitLevel classifies a commit message (subject, then body).
+func commitLevel(message string) string {
+	subject, body, _ := strings.Cut(message, "\n")
+
+	if strings.Contains(body, "BREAKING CHANGE:") || strings.Contains(body, "BREAKING-CHANGE:") {
+		return "major"
+	}
+
+	m := conventionalPattern.FindStringSubmatch(subject)
+	switch {
+	case m == nil:
+		return "patch"
+	case m[2] == "!" || strings.EqualFold(m[1], "breaking"):
+		return "major"
+	case strings.EqualFold(m[1], "feat"):
+		return "minor"
+	default:
+		return "patch"
+	}
+}
+
+// tags lists tags with the prefix that are reachable from HEAD.
+func (m *Semver) tags(ctx context.Context, prefix string) ([]string, error) {
+	out, err := m.container().
+		WithExec([]string{"git", "tag", "--merged", "HEAD", "--list", prefix + "*"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return strings.Fields(out), nil
+}
+
+// container returns a git container with the source mounted.
+func (m *Semver) container() *dagger.Container {
+	return dag.Container().
+		From(gitImage).
+		WithDirectory("/src", m.Source).
+		WithWorkdir("/src").
+		// The mounted source may be owned by another user.
+		WithExec([]string{"git", "config", "--global", "--add", "safe.directory", "/src"})
+}
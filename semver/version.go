@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// versionPattern matches "<major>.<minor>.<patch>[-<prerelease>]" after the
+// tag prefix has been removed. Build metadata is not used in tags.
+var versionPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z.-]+))?$`)
+
+// levels orders bump levels from smallest to largest.
+var levels = map[string]int{
+	"patch": 1,
+	"minor": 2,
+	"major": 3,
+}
+
+// version is a parsed semantic version tag.
+type version struct {
+	major      int
+	minor      int
+	patch      int
+	prerelease string
+}
+
+// parseVersion parses a tag with the given prefix.
+func parseVersion(tag, prefix string) (version, bool) {
+	rest, ok := strings.CutPrefix(tag, prefix)
+	if !ok {
+		return version{}, false
+	}
+	m := versionPattern.FindStringSubmatch(rest)
+	if m == nil {
+		return version{}, false
+	}
+
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	patch, _ := strconv.Atoi(m[3])
+	return version{major: major, minor: minor, patch: patch, prerelease: m[4]}, true
+}
+
+// format renders the version with a tag prefix.
+func (v version) format(prefix string) string {
+	s := fmt.Sprintf("%s%d.%d.%d", prefix, v.major, v.minor, v.patch)
+	if v.prerelease != "" {
+		s += "-" + v.prerelease
+	}
+	return s
+}
+
+// less orders release versions by major, minor, and patch only.
+func (v version) less(o version) bool {
+	if v.major != o.major {
+		return v.major < o.major
+	}
+	if v.minor != o.minor {
+		return v.minor < o.minor
+	}
+	return v.patch < o.patch
+}
+
+// bump returns the next release version for a level.
+func (v version) bump(level string) version {
+	switch level {
+	case "major":
+		return version{major: v.major + 1}
+	case "minor":
+		return version{major: v.major, minor: v.minor + 1}
+	default:
+		return version{major: v.major, minor: v.minor, patch: v.patch + 1}
+	}
+}
+
+// latestRelease returns the tag and version of the highest non-prerelease
+// version among tags.
+func latestRelease(tags []string, prefix string) (string, version, bool) {
+	var (
+		bestTag string
+		best    version
+		found   bool
+	)
+	for _, tag := range tags {
+		v, ok := parseVersion(tag, prefix)
+		if !ok || v.prerelease != "" {
+			continue
+		}
+		if !found || best.less(v) {
+			bestTag, best, found = tag, v, true
+		}
+	}
+	return bestTag, best, found
+}
+
+// nextPrerelease returns "<channel>.<n>" for the next prerelease of base on
+// a channel, one past the highest existing "<channel>.<n>" tag for base.
+func nextPrerelease(tags []string, prefix string, base version, channel string) string {
+	numbers := []int{0}
+	for _, tag := range tags {
+		v, ok := parseVersion(tag, prefix)
+		if !ok || v.less(base) || base.less(v) {
+			continue
+		}
+		if n, ok := strings.CutPrefix(v.prerelease, channel+"."); ok {
+			if i, err := strconv.Atoi(n); err == nil {
+				numbers = append(numbers, i)
+			}
+		}
+	}
+	sort.Ints(numbers)
+	return fmt.Sprintf("%s.%d", channel, numbers[len(numbers)-1]+1)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dagger/shell/internal/dagger"
+)
+
+const (
+	alpineImage string = "alpine:3.21"
+)
+
+// severities lists the shellcheck severity levels, most severe first.
+var severities = []string{"error", "warning", "info", "style"}
+
+type Shell struct {
+	// Source is the directory containing the shell scripts.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Include is the list of globs selecting the scripts to check.
+	//
+	// +private
+	Include []string
+
+	// Exclude is the list of globs removed from the selection.
+	//
+	// +private
+	Exclude []string
+}
+
+// New creates a new Shell module instance.
+func New(
+	// The directory containing the shell scripts.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Globs selecting the scripts to check (defaults to "**/*.sh" and
+	// "**/*.bash")
+	// +optional
+	include []string,
+
+	// Globs removed from the selection (e.g., "vendor/**")
+	// +optional
+	exclude []string,
+) *Shell {
+	if len(include) == 0 {
+		include = []string{"**/*.sh", "**/*.bash"}
+	}
+	return &Shell{
+		Source:  source,
+		Include: include,
+		Exclude: exclude,
+	}
+}
+
+// Lint runs shellcheck on the selected scripts and fails on any finding at
+// or above the given severity. A .shellcheckrc in the source is honored.
+func (m *Shell) Lint(
+	ctx context.Context,
+
+	// Minimum severity to report: "error", "warning", "info", or "style"
+	// +optional
+	// +default="style"
+	severity string,
+) (string, error) {
+	if !isSeverity(severity) {
+		return "", fmt.Errorf("invalid severity %q: must be one of %s", severity, strings.Join(severities, ", "))
+	}
+
+	scripts, err := m.scripts(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(scripts) == 0 {
+		return "✅ no shell scripts found", nil
+	}
+
+	args := append([]string{"shellcheck", "--external-sources", "--severity", severity}, scripts...)
+	_, err = m.shellContainer().
+		WithExec(args).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("shellcheck found problems\n\n%s", e.Stdout)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return fmt.Sprintf("✅ %d scripts passed shellcheck", len(scripts)), nil
+}
+
+// CheckFormat runs shfmt on the selected scripts and fails with a diff when
+// any script is not formatted. Formatting options are read from an
+// .editorconfig in the source.
+func (m *Shell) CheckFormat(ctx context.Context) (string, error) {
+	scripts, err := m.scripts(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(scripts) == 0 {
+		return "✅ no shell scripts found", nil
+	}
+
+	args := append([]string{"shfmt", "--diff"}, scripts...)
+	_, err = m.shellContainer().
+		WithExec(args).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("scripts are not formatted: run shfmt and commit the changes\n\n%s", e.Stdout)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return fmt.Sprintf("✅ %d scripts are formatted", len(scripts)), nil
+}
+
+// Format runs shfmt on the selected scripts and returns the source directory
+// with the scripts rewritten.
+func (m *Shell) Format(ctx context.Context) (*dagger.Directory, error) {
+	scripts, err := m.scripts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(scripts) == 0 {
+		return m.Source, nil
+	}
+
+	args := append([]string{"shfmt", "--write"}, scripts...)
+	return m.shellContainer().
+		WithExec(args).
+		Directory("/src"), nil
+}
+
+// Check runs shellcheck at warning severity and the shfmt format check,
+// making this suitable for CI checks.
+//
+// +check
+func (m *Shell) Check(ctx context.Context) (string, error) {
+	lint, err := m.Lint(ctx, "warning")
+	if err != nil {
+		return "", err
+	}
+
+	format, err := m.CheckFormat(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return lint + "\n" + format, nil
+}
+
+// scripts returns the sorted relative paths of the selected scripts.
+func (m *Shell) scripts(ctx context.Context) ([]string, error) {
+	entries, err := m.Source.
+		Filter(dagger.DirectoryFilterOpts{Include: m.Include, Exclude: m.Exclude}).
+		Glob(ctx, "**/*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shell scripts: %w", err)
+	}
+
+	var scripts []string
+	// Glob returns directory entries with a trailing slash — skip them.
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+		scripts = append(scripts, entry)
+	}
+	return scripts, nil
+}
+
+// shellContainer returns a container with shellcheck and shfmt installed and
+// the source mounted.
+func (m *Shell) shellContainer() *dagger.Container {
+	return dag.Container().
+		From(alpineImage).
+		WithExec([]string{"apk", "add", "--no-cache", "shellcheck", "shfmt"}).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+}
+
+func isSeverity(severity string) bool {
+	for _, s := range severities {
+		if severity == s {
+			return true
+		}
+	}
+	return false
+}
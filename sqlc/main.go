@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dagger/sqlc/internal/dagger"
+)
+
+const (
+	goImage     string = "golang:1.26-bookworm"
+	sqlcVersion string = "v1.28.0"
+
+	databaseURL string = "postgres://app:app@db:5432/app?sslmode=disable"
+)
+
+// runScript loads the schema files given as arguments into the database,
+// then runs sqlc. Both happen in one exec so the service cannot restart in
+// between and lose the schema.
+const runScript string = `set -eu
+for p in "$@"; do
+	find "/src/$p" -type f -name '*.sql' | sort | while read -r f; do
+		psql -q -v ON_ERROR_STOP=1 -f "$f" "$DATABASE_URL"
+	done
+done
+cd "$SQLC_DIR"
+exec sqlc "$SQLC_COMMAND"
+`
+
+type Sqlc struct {
+	// Source is the repository containing the sqlc project.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Path is the directory of sqlc.yaml, relative to Source.
+	//
+	// +private
+	Path string
+
+	// Schema lists the schema files and directories loaded into the
+	// database, relative to Source.
+	//
+	// +private
+	Schema []string
+
+	// PostgresImage is the PostgreSQL image the queries run against.
+	//
+	// +private
+	PostgresImage string
+}
+
+// New creates a new Sqlc module instance.
+func New(
+	// The repository containing the sqlc project.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Directory of sqlc.yaml, relative to source
+	// +optional
+	// +default="."
+	path string,
+
+	// Schema files and directories to load into the database before
+	// running sqlc, relative to source. Directories load every .sql file
+	// in lexical order.
+	// +optional
+	schema []string,
+
+	// PostgreSQL image, matching the production major version
+	// +optional
+	// +default="postgres:17-alpine"
+	postgresImage string,
+) *Sqlc {
+	return &Sqlc{
+		Source:        source,
+		Path:          path,
+		Schema:        schema,
+		PostgresImage: postgresImage,
+	}
+}
+
+// Generate runs "sqlc generate" and returns the source directory with the
+// generated code written.
+func (m *Sqlc) Generate() *dagger.Directory {
+	return m.run("generate").Directory("/src")
+}
+
+// Vet runs "sqlc vet" and fails on any rule violation or query that does
+// not prepare against the database.
+func (m *Sqlc) Vet(ctx context.Context) (string, error) {
+	out, err := m.run("vet").CombinedOutput(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("sqlc vet failed\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return fmt.Sprintf("✅ sqlc vet passed\n%s", out), nil
+}
+
+// Diff runs "sqlc diff" and fails with the diff when the committed
+// generated code differs from what sqlc generates.
+func (m *Sqlc) Diff(ctx context.Context) (string, error) {
+	_, err := m.run("diff").Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("generated code is out of date: run 'sqlc generate' and commit the changes\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return "✅ generated code is up to date", nil
+}
+
+// Check runs Vet and Diff, making this suitable for CI checks.
+//
+// +check
+func (m *Sqlc) Check(ctx context.Context) (string, error) {
+	vetOut, err := m.Vet(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	diffOut, err := m.Diff(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return vetOut + "\n" + diffOut, nil
+}
+
+// run returns a container that has loaded the schema into a fresh
+// PostgreSQL service at "db" and run the sqlc command. The database URL is
+// exported as DATABASE_URL for sqlc.yaml's "database.uri".
+func (m *Sqlc) run(command string) *dagger.Container {
+	db := dag.Container().
+		From(m.PostgresImage).
+		WithEnvVariable("POSTGRES_DB", "app").
+		WithEnvVariable("POSTGRES_USER", "app").
+		WithEnvVariable("POSTGRES_PASSWORD", "app").
+		WithEnvVariable("SQLC_COMMAND", command).
+		WithExposedPort(5432).
+		AsService(dagger.ContainerAsServiceOpts{UseEntrypoint: true})
+
+	args := []string{"sh", "-c", runScript, "sh"}
+	for _, s := range m.Schema {
+		args = append(args, strings.TrimPrefix(s, "/"))
+	}
+
+	return m.sqlcContainer().
+		WithServiceBinding("db", db).
+		WithEnvVariable("DATABASE_URL", databaseURL).
+		WithEnvVariable("SQLC_DIR", "/src/"+strings.TrimPrefix(m.Path, "/")).
+		WithEnvVariable("SQLC_COMMAND", command).
+		WithDirectory("/src", m.Source).
+		WithExec(args)
+}
+
+// sqlcContainer returns a Go container with sqlc and psql installed and the
+// Go module and build caches shared with the other Go modules in this
+// daggerverse. sqlc needs cgo for its PostgreSQL parser.
+func (m *Sqlc) sqlcContainer() *dagger.Container {
+	return dag.Container().
+		From(goImage).
+		WithMountedCache("/var/cache/apt", dag.CacheVolume("sqlc-apt")).
+		WithExec([]string{"apt-get", "update"}).
+		WithExec([]string{"apt-get", "install", "-y", "--no-install-recommends", "postgresql-client"}).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithExec([]string{"go", "install", "github.com/sqlc-dev/sqlc/cmd/sqlc@" + sqlcVersion})
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/staticcheck/internal/dagger"
+)
+
+const (
+	goImage     string = "golang:1.26-bookworm"
+	staticcheck string = "honnef.co/go/tools/cmd/staticcheck@2025.1.1"
+)
+
+type Staticcheck struct {
+	// Source is the Go source directory to check.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Tags is the list of build tags to check with.
+	//
+	// +private
+	Tags []string
+
+	// EnvVars is an optional list of environment variables to set in the
+	// check container. Each entry must be in "KEY=VALUE" format.
+	//
+	// +private
+	EnvVars []string
+}
+
+// New creates a new Staticcheck module instance.
+func New(
+	// The Go source directory to check.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Build tags to check with (e.g., "integration")
+	// +optional
+	tags []string,
+
+	// Optional environment variables to set in the check container.
+	// Each entry must be in "KEY=VALUE" format (e.g. "GOEXPERIMENT=rangefunc").
+	// +optional
+	envVars []string,
+) *Staticcheck {
+	return &Staticcheck{
+		Source:  source,
+		Tags:    tags,
+		EnvVars: envVars,
+	}
+}
+
+// Lint runs staticcheck on the source directory with the given checks and
+// fails when any problem is found. Without checks, the "checks" setting of
+// the repository's staticcheck.conf (or staticcheck's defaults) applies.
+func (m *Staticcheck) Lint(
+	ctx context.Context,
+
+	// Checks to run, using staticcheck's -checks syntax
+	// (e.g., "all", "-ST1000", "SA*")
+	// +optional
+	checks []string,
+) (string, error) {
+	ctr, err := m.staticcheckContainer()
+	if err != nil {
+		return "", fmt.Errorf("could not create staticcheck container: %w", err)
+	}
+
+	return ctr.
+		WithExec(m.buildArgs(checks, "-f", "stylish")).
+		Stdout(ctx)
+}
+
+// Check runs staticcheck with the repository's configured checks and fails
+// when any problem is found, making this suitable for CI checks.
+//
+// +check
+func (m *Staticcheck) Check(ctx context.Context) (string, error) {
+	return m.Lint(ctx, nil)
+}
+
+// Report runs staticcheck and returns its output in the given format
+// ("json", "sarif", "text", or "stylish") without failing on problems.
+func (m *Staticcheck) Report(
+	// Output format: "json", "sarif", "text", or "stylish"
+	// +optional
+	// +default="json"
+	format string,
+
+	// Checks to run, using staticcheck's -checks syntax
+	// +optional
+	checks []string,
+) (*dagger.File, error) {
+	name := "staticcheck." + format
+	switch format {
+	case "json", "sarif":
+	case "text", "stylish":
+		name = "staticcheck.txt"
+	default:
+		return nil, fmt.Errorf("invalid format %q: must be \"json\", \"sarif\", \"text\", or \"stylish\"", format)
+	}
+
+	ctr, err := m.staticcheckContainer()
+	if err != nil {
+		return nil, fmt.Errorf("could not create staticcheck container: %w", err)
+	}
+
+	return ctr.
+		WithExec(
+			m.buildArgs(checks, "-f", format),
+			dagger.ContainerWithExecOpts{
+				RedirectStdout: "/" + name,
+				// staticcheck exits non-zero when problems are found.
+				Expect: dagger.ReturnTypeAny,
+			},
+		).
+		File("/" + name), nil
+}
+
+// buildArgs constructs the staticcheck command arguments. Any extra flags
+// passed in are appended before the package pattern.
+func (m *Staticcheck) buildArgs(checks []string, extra ...string) []string {
+	args := []string{"staticcheck"}
+	if len(checks) > 0 {
+		args = append(args, "-checks", strings.Join(checks, ","))
+	}
+	if len(m.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(m.Tags, ","))
+	}
+	args = append(args, extra...)
+	args = append(args, "./...")
+	return args
+}
+
+// staticcheckContainer returns a container with staticcheck installed, the
+// source mounted, and the Go module and build caches shared with the other
+// Go modules in this daggerverse.
+func (m *Staticcheck) staticcheckContainer() (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithMountedCache("/root/.cache/staticcheck", dag.CacheVolume("staticcheck")).
+		WithExec([]string{"go", "install", staticcheck}).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+
+	// Apply caller-provided environment variables.
+	for _, env := range m.EnvVars {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(parts[0], parts[1])
+	}
+
+	return ctr, nil
+}
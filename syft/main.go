@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/syft/internal/dagger"
+)
+
+const (
+	syftImage string = "anchore/syft:v1.18.1"
+)
+
+// formatExtensions maps syft output formats to SBOM file extensions. The
+// extensions are ones the utils flatten helpers keep at the end of flattened
+// names, so "myapp.spdx.json" becomes "myapp-linux-amd64.spdx.json".
+var formatExtensions = map[string]string{
+	"spdx-json":      ".spdx.json",
+	"cyclonedx-json": ".cdx.json",
+}
+
+type Syft struct {
+	// RegistryUsername is the username for pulling image references.
+	//
+	// +private
+	RegistryUsername string
+
+	// RegistryToken is the token for pulling image references.
+	//
+	// +private
+	RegistryToken *dagger.Secret
+}
+
+// New creates a new Syft module instance.
+func New() *Syft {
+	return &Syft{}
+}
+
+// WithRegistryAuth sets credentials for pulling private image references.
+func (m *Syft) WithRegistryAuth(
+	// Registry username
+	username string,
+
+	// Registry token or password
+	token *dagger.Secret,
+) *Syft {
+	m.RegistryUsername = username
+	m.RegistryToken = token
+	return m
+}
+
+// Directory generates an SBOM for a directory, such as a source tree, and
+// returns it as "<name><ext>" (e.g., "sbom.spdx.json").
+func (m *Syft) Directory(
+	// Directory to catalog
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// SBOM format: "spdx-json" or "cyclonedx-json"
+	// +optional
+	// +default="spdx-json"
+	format string,
+
+	// SBOM file name without extension
+	// +optional
+	// +default="sbom"
+	name string,
+) (*dagger.File, error) {
+	ctr := m.container().WithDirectory("/src", source)
+	return m.generate(ctr, "dir:/src", format, name)
+}
+
+// Image generates an SBOM for a container image, given either as a registry
+// reference or as a container, and returns it as "<name><ext>".
+func (m *Syft) Image(
+	// Image reference (e.g., "ghcr.io/acme/myapp:v1.2.3")
+	// +optional
+	imageRef string,
+
+	// Container to catalog instead of an image reference
+	// +optional
+	ctr *dagger.Container,
+
+	// SBOM format: "spdx-json" or "cyclonedx-json"
+	// +optional
+	// +default="spdx-json"
+	format string,
+
+	// SBOM file name without extension
+	// +optional
+	// +default="sbom"
+	name string,
+) (*dagger.File, error) {
+	scanner := m.container()
+
+	var source string
+	switch {
+	case ctr != nil:
+		scanner = scanner.WithFile("/image.tar", ctr.AsTarball())
+		source = "oci-archive:/image.tar"
+	case imageRef != "":
+		source = "registry:" + imageRef
+	default:
+		return nil, fmt.Errorf("either image-ref or ctr is required")
+	}
+
+	return m.generate(scanner, source, format, name)
+}
+
+// Artifacts generates an SBOM next to every file in a build directory, such
+// as the <os>/<arch>/<binary> output of the gobuild module, named
+// "<binary><ext>" for each format. Checksum, signature, and existing SBOM
+// files are skipped. The result contains only the SBOMs; merge it with the
+// build directory before attaching assets with ghrelease or uploading with
+// bucketupload.
+func (m *Syft) Artifacts(
+	ctx context.Context,
+
+	// Build directory of artifacts
+	build *dagger.Directory,
+
+	// SBOM formats to generate for each artifact. Defaults to "spdx-json".
+	// +optional
+	formats []string,
+) (*dagger.Directory, error) {
+	if len(formats) == 0 {
+		formats = []string{"spdx-json"}
+	}
+
+	files, err := build.Glob(ctx, "**")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list artifacts: %w", err)
+	}
+
+	ctr := m.container().WithDirectory("/build", build)
+	out := dag.Directory()
+	for _, f := range files {
+		// Glob returns directory entries with a trailing slash — skip them.
+		if strings.HasSuffix(f, "/") || isMetadataFile(f) {
+			continue
+		}
+
+		for _, format := range formats {
+			sbom, err := m.generate(ctr, "file:/build/"+f, format, path.Base(f))
+			if err != nil {
+				return nil, err
+			}
+			out = out.WithFile(f+formatExtensions[format], sbom)
+		}
+	}
+
+	return out, nil
+}
+
+// generate runs syft against a source and returns the SBOM file.
+func (m *Syft) generate(ctr *dagger.Container, source, format, name string) (*dagger.File, error) {
+	ext, ok := formatExtensions[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported format %q: must be \"spdx-json\" or \"cyclonedx-json\"", format)
+	}
+
+	out := "/out/" + name + ext
+	return ctr.
+		WithExec(
+			[]string{"scan", source, "--output", format + "=" + out},
+			dagger.ContainerWithExecOpts{UseEntrypoint: true},
+		).
+		File(out), nil
+}
+
+// container returns the syft container with registry credentials.
+func (m *Syft) container() *dagger.Container {
+	ctr := dag.Container().From(syftImage)
+	if m.RegistryToken != nil {
+		ctr = ctr.
+			WithEnvVariable("SYFT_REGISTRY_AUTH_USERNAME", m.RegistryUsername).
+			WithSecretVariable("SYFT_REGISTRY_AUTH_PASSWORD", m.RegistryToken)
+	}
+	return ctr
+}
+
+// isMetadataFile reports whether a file is a checksum, signature, or SBOM
+// rather than an artifact.
+func isMetadataFile(name string) bool {
+	for _, ext := range []string{
+		".sha256", ".sha512", ".sha1", ".md5",
+		".sig", ".asc", ".pem", ".cert",
+		".sbom.json", ".spdx.json", ".cdx.json", ".intoto.jsonl",
+	} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return strings.HasSuffix(name, "SUMS")
+}
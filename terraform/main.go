@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"dagger/terraform/internal/dagger"
+)
+
+const (
+	terraformImage string = "hashicorp/terraform:1.10.5"
+	pluginCacheDir string = "/root/.terraform.d/plugin-cache"
+	planFile       string = "tfplan"
+)
+
+type Terraform struct {
+	// Source is the repository containing the Terraform configuration.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Path is the configuration directory, relative to Source.
+	//
+	// +private
+	Path string
+
+	// EnvVars are extra environment variables in KEY=VALUE format.
+	//
+	// +private
+	EnvVars []string
+
+	// BackendConfig are -backend-config KEY=VALUE pairs passed to init.
+	//
+	// +private
+	BackendConfig []string
+
+	// SecretEnvNames are the environment variables added with WithSecretEnv.
+	//
+	// +private
+	SecretEnvNames []string
+
+	// SecretEnvValues are the secrets for SecretEnvNames, by index.
+	//
+	// +private
+	SecretEnvValues []*dagger.Secret
+}
+
+// PlanResult is a saved Terraform plan and its human-readable summary.
+type PlanResult struct {
+	// Plan is the binary plan file, to be passed to apply.
+	Plan *dagger.File
+
+	// Summary is the "terraform show" rendering of the plan.
+	Summary string
+
+	// HasChanges is true when the plan would change infrastructure.
+	HasChanges bool
+}
+
+// New creates a new Terraform module instance.
+func New(
+	// The repository containing the Terraform configuration.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Configuration directory, relative to source (e.g., "infra/prod")
+	// +optional
+	// +default="."
+	path string,
+
+	// Environment variables in KEY=VALUE format (e.g., "TF_VAR_region=eu-west-1")
+	// +optional
+	envVars []string,
+
+	// Backend configuration in KEY=VALUE format, passed to
+	// "terraform init -backend-config" (e.g., "bucket=acme-tfstate")
+	// +optional
+	backendConfig []string,
+) *Terraform {
+	return &Terraform{
+		Source:        source,
+		Path:          path,
+		EnvVars:       envVars,
+		BackendConfig: backendConfig,
+	}
+}
+
+// WithSecretEnv adds a secret environment variable, such as backend or
+// provider credentials (e.g., "AWS_SECRET_ACCESS_KEY") or a sensitive
+// variable ("TF_VAR_db_password"). Chain once per variable.
+func (m *Terraform) WithSecretEnv(
+	// Environment variable name
+	name string,
+
+	// Secret value
+	value *dagger.Secret,
+) *Terraform {
+	m.SecretEnvNames = append(m.SecretEnvNames, name)
+	m.SecretEnvValues = append(m.SecretEnvValues, value)
+	return m
+}
+
+// Fmt checks that every Terraform file under the configuration directory
+// is formatted, failing with the formatting diff when one is not.
+func (m *Terraform) Fmt(ctx context.Context) (string, error) {
+	ctr, err := m.container()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = ctr.
+		WithExec([]string{"terraform", "fmt", "-check", "-recursive", "-diff", "-no-color"}).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("terraform files are not formatted\n\n%s", e.Stdout)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return "✅ Terraform files are formatted", nil
+}
+
+// Validate initializes the configuration without a backend and runs
+// "terraform validate". No credentials are needed.
+func (m *Terraform) Validate(ctx context.Context) (string, error) {
+	ctr, err := m.container()
+	if err != nil {
+		return "", err
+	}
+
+	out, err := ctr.
+		WithExec([]string{"terraform", "init", "-backend=false", "-input=false", "-no-color"}).
+		WithExec([]string{"terraform", "validate", "-no-color"}).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("terraform validate failed\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return out, nil
+}
+
+// Check runs Fmt and Validate, making this suitable for CI checks.
+//
+// +check
+func (m *Terraform) Check(ctx context.Context) (string, error) {
+	fmtOut, err := m.Fmt(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	validateOut, err := m.Validate(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return fmtOut + "\n" + validateOut, nil
+}
+
+// Plan initializes the backend and creates a saved plan, returning the plan
+// file with a human-readable summary. Pass the plan file to Apply to apply
+// exactly what was reviewed.
+func (m *Terraform) Plan(
+	ctx context.Context,
+
+	// Variables in KEY=VALUE format (terraform -var)
+	// +optional
+	vars []string,
+
+	// Variable files (.tfvars), applied in order
+	// +optional
+	varFiles []*dagger.File,
+
+	// Plan to destroy all managed infrastructure
+	// +optional
+	destroy bool,
+) (*PlanResult, error) {
+	ctr, err := m.initContainer()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"terraform", "plan", "-input=false", "-no-color", "-detailed-exitcode", "-out=" + planFile}
+	if destroy {
+		args = append(args, "-destroy")
+	}
+	for _, v := range vars {
+		if k, _, ok := strings.Cut(v, "="); !ok || k == "" {
+			return nil, fmt.Errorf("invalid var %q: must be in KEY=VALUE format", v)
+		}
+		args = append(args, "-var", v)
+	}
+	for i, f := range varFiles {
+		path := fmt.Sprintf("/tfvars/%d.tfvars", i)
+		ctr = ctr.WithFile(path, f)
+		args = append(args, "-var-file="+path)
+	}
+
+	// -detailed-exitcode exits 2 when the plan has changes, so accept any
+	// exit code and interpret it below. Remote state drifts between runs, so
+	// never reuse a cached plan.
+	planned := ctr.
+		WithEnvVariable("TERRAFORM_SESSION", fmt.Sprintf("%d", time.Now().UnixNano())).
+		WithExec(args, dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny})
+
+	code, err := planned.ExitCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected error: %w", err)
+	}
+	if code != 0 && code != 2 {
+		stderr, _ := planned.Stderr(ctx)
+		return nil, fmt.Errorf("terraform plan failed\n\n%s", stderr)
+	}
+
+	summary, err := planned.
+		WithExec([]string{"terraform", "show", "-no-color", planFile}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render plan: %w", err)
+	}
+
+	return &PlanResult{
+		Plan:       planned.File(planFile),
+		Summary:    summary,
+		HasChanges: code == 2,
+	}, nil
+}
+
+// Apply applies a saved plan from Plan. Applying changes real
+// infrastructure, so it refuses to run unless approve is set.
+func (m *Terraform) Apply(
+	ctx context.Context,
+
+	// Saved plan file from Plan
+	plan *dagger.File,
+
+	// Explicitly approve applying the plan
+	// +optional
+	approve bool,
+) (string, error) {
+	if !approve {
+		return "", fmt.Errorf("refusing to apply without --approve")
+	}
+
+	ctr, err := m.initContainer()
+	if err != nil {
+		return "", err
+	}
+
+	out, err := ctr.
+		WithFile(planFile, plan).
+		// Applying changes real infrastructure; always run it.
+		WithEnvVariable("TERRAFORM_SESSION", fmt.Sprintf("%d", time.Now().UnixNano())).
+		WithExec([]string{"terraform", "apply", "-input=false", "-no-color", planFile}).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("terraform apply failed\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return out, nil
+}
+
+// initContainer returns the Terraform container after "terraform init"
+// with the configured backend.
+func (m *Terraform) initContainer() (*dagger.Container, error) {
+	ctr, err := m.container()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"terraform", "init", "-input=false", "-no-color"}
+	for _, kv := range m.BackendConfig {
+		if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+			return nil, fmt.Errorf("invalid backend config %q: must be in KEY=VALUE format", kv)
+		}
+		args = append(args, "-backend-config="+kv)
+	}
+
+	return ctr.WithExec(args), nil
+}
+
+// container returns a Terraform container with the source mounted, the
+// provider plugin cache, and the environment and secrets applied.
+func (m *Terraform) container() (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(terraformImage).
+		WithMountedCache(pluginCacheDir, dag.CacheVolume("terraform-plugins")).
+		WithEnvVariable("TF_PLUGIN_CACHE_DIR", pluginCacheDir).
+		WithEnvVariable("TF_IN_AUTOMATION", "1").
+		WithDirectory("/src", m.Source).
+		WithWorkdir("/src/" + m.Path)
+
+	for _, env := range m.EnvVars {
+		k, v, ok := strings.Cut(env, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(k, v)
+	}
+	for i, name := range m.SecretEnvNames {
+		ctr = ctr.WithSecretVariable(name, m.SecretEnvValues[i])
+	}
+
+	return ctr, nil
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/testreport/internal/dagger"
+)
+
+// Report formats.
+const (
+	formatJUnit  string = "junit"
+	formatGoTest string = "gotest"
+)
+
+// Testreport merges test reports from many CI jobs into one summary.
+type Testreport struct {
+	// Title of the report
+	//
+	// +private
+	Title string
+
+	// Number of slowest tests to list
+	//
+	// +private
+	Slowest int
+
+	// Reports to merge
+	//
+	// +private
+	Inputs []Input
+}
+
+// Input is a test report and the job it came from.
+type Input struct {
+	// Name of the CI job the report came from
+	Job string
+
+	// Report format: "junit" or "gotest"
+	Format string
+
+	// Report file
+	Report *dagger.File
+}
+
+// New creates a new Testreport module instance. Add reports with
+// WithJunit, WithGoTest, or WithReports.
+func New(
+	// Title of the report
+	// +optional
+	// +default="Test report"
+	title string,
+
+	// Number of slowest tests to list
+	// +optional
+	// +default=10
+	slowest int,
+) *Testreport {
+	return &Testreport{
+		Title:   title,
+		Slowest: slowest,
+	}
+}
+
+// WithJunit adds a JUnit XML report.
+func (m *Testreport) WithJunit(
+	ctx context.Context,
+
+	// JUnit XML report
+	report *dagger.File,
+
+	// Name of the CI job the report came from. Defaults to the file name
+	// without its extension.
+	// +optional
+	job string,
+) (*Testreport, error) {
+	return m.withInput(ctx, formatJUnit, report, job)
+}
+
+// WithGoTest adds "go test -json" output, such as gotestsum's --jsonfile.
+func (m *Testreport) WithGoTest(
+	ctx context.Context,
+
+	// "go test -json" output
+	report *dagger.File,
+
+	// Name of the CI job the report came from. Defaults to the file name
+	// without its extension.
+	// +optional
+	job string,
+) (*Testreport, error) {
+	return m.withInput(ctx, formatGoTest, report, job)
+}
+
+// WithReports adds every JUnit XML (*.xml) and "go test -json" (*.json)
+// report in a directory, such as the artifacts of several jobs downloaded
+// into one directory each, or the gotest module's report directory.
+//
+// Each report's job is its top-level directory, or its file name for
+// reports at the top. A directory's JUnit reports are skipped when it also
+// holds "go test -json" output, as gotest writes both for the same run.
+func (m *Testreport) WithReports(
+	ctx context.Context,
+
+	// Directory of reports
+	reports *dagger.Directory,
+
+	// Name of the CI job every report came from, instead of their
+	// directories
+	// +optional
+	job string,
+) (*Testreport, error) {
+	jsonFiles, err := reports.Glob(ctx, "**/*.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+	xmlFiles, err := reports.Glob(ctx, "**/*.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reports: %w", err)
+	}
+
+	jobOf := func(p string) string {
+		if job != "" {
+			return job
+		}
+		if first, _, nested := strings.Cut(p, "/"); nested {
+			return first
+		}
+		return strings.TrimSuffix(p, path.Ext(p))
+	}
+
+	// Other JSON files, such as package.json, hold no test events.
+	goTestDirs := map[string]bool{}
+	for _, p := range jsonFiles {
+		contents, err := reports.File(p).Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		if len(parseGoTest("", contents)) == 0 {
+			continue
+		}
+		goTestDirs[path.Dir(p)] = true
+		m.Inputs = append(m.Inputs, Input{Job: jobOf(p), Format: formatGoTest, Report: reports.File(p)})
+	}
+
+	for _, p := range xmlFiles {
+		if goTestDirs[path.Dir(p)] {
+			continue
+		}
+		m.Inputs = append(m.Inputs, Input{Job: jobOf(p), Format: formatJUnit, Report: reports.File(p)})
+	}
+	return m, nil
+}
+
+// Markdown returns the report as markdown, for a PR comment or a job
+// summary. Failed tests come with the end of their output.
+func (m *Testreport) Markdown(ctx context.Context) (string, error) {
+	s, err := m.summary(ctx)
+	if err != nil {
+		return "", err
+	}
+	return markdown(m.Title, s), nil
+}
+
+// HTML returns the report as a standalone HTML page listing every test.
+func (m *Testreport) HTML(ctx context.Context) (*dagger.File, error) {
+	s, err := m.summary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page, err := html(m.Title, s)
+	if err != nil {
+		return nil, err
+	}
+	return dag.Directory().
+		WithNewFile("index.html", page).
+		File("index.html"), nil
+}
+
+// Report returns a directory with the HTML report as index.html, the
+// markdown report as report.md, and every test's statistics as
+// summary.json, ready to host in a bucket.
+func (m *Testreport) Report(ctx context.Context) (*dagger.Directory, error) {
+	s, err := m.summary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page, err := html(m.Title, s)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode summary: %w", err)
+	}
+
+	return dag.Directory().
+		WithNewFile("index.html", page).
+		WithNewFile("report.md", markdown(m.Title, s)).
+		WithNewFile("summary.json", string(data)+"\n"), nil
+}
+
+// Check fails with the markdown report when any test failed in every run,
+// or when no test results were found. Flaky tests only fail the check with
+// failOnFlaky.
+func (m *Testreport) Check(
+	ctx context.Context,
+
+	// Fail on flaky tests too
+	// +optional
+	failOnFlaky bool,
+) (string, error) {
+	s, err := m.summary(ctx)
+	if err != nil {
+		return "", err
+	}
+	report := markdown(m.Title, s)
+
+	switch {
+	case s.Tests == 0:
+		return "", errors.New("no test results were found in the reports")
+	case s.Failed > 0:
+		return "", fmt.Errorf("%d test(s) failed\n\n%s", s.Failed, report)
+	case failOnFlaky && s.Flaky > 0:
+		return "", fmt.Errorf("%d test(s) are flaky\n\n%s", s.Flaky, report)
+	}
+	return report, nil
+}
+
+// withInput adds a report, naming its job after the file when job is
+// empty.
+func (m *Testreport) withInput(ctx context.Context, format string, report *dagger.File, job string) (*Testreport, error) {
+	if job == "" {
+		name, err := report.Name(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report name: %w", err)
+		}
+		job = strings.TrimSuffix(name, path.Ext(name))
+	}
+	m.Inputs = append(m.Inputs, Input{Job: job, Format: format, Report: report})
+	return m, nil
+}
+
+// summary reads and merges every report.
+func (m *Testreport) summary(ctx context.Context) (*summary, error) {
+	if len(m.Inputs) == 0 {
+		return nil, errors.New("no reports added: use with-junit, with-go-test, or with-reports")
+	}
+
+	var results []result
+	for i, in := range m.Inputs {
+		contents, err := in.Report.Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read report %d of %s: %w", i+1, in.Job, err)
+		}
+		switch in.Format {
+		case formatGoTest:
+			results = append(results, parseGoTest(in.Job, contents)...)
+		default:
+			r, err := parseJUnit(in.Job, contents)
+			if err != nil {
+				return nil, fmt.Errorf("report %d of %s: %w", i+1, in.Job, err)
+			}
+			results = append(results, r...)
+		}
+	}
+	return summarize(results, m.Slowest), nil
+}
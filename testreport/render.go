@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+)
+
+const (
+	// detailLines is how many lines of a failed test's output are shown.
+	detailLines int = 40
+
+	// maxDetails is how many failed tests' output the markdown report
+	// includes, to keep it within PR comment limits.
+	maxDetails int = 20
+)
+
+// headline summarizes the counts in one line.
+func headline(s *summary) string {
+	icon := "✅"
+	switch {
+	case s.Failed > 0:
+		icon = "❌"
+	case s.Flaky > 0:
+		icon = "⚠️"
+	}
+	return fmt.Sprintf("%s %d failed, %d flaky, %d passed, %d skipped: %d tests across %d jobs, %s of test time",
+		icon, s.Failed, s.Flaky, s.Passed, s.Skipped, s.Tests, len(s.Jobs), formatDuration(s.Duration))
+}
+
+// markdown renders the report for a PR comment or job summary.
+func markdown(title string, s *summary) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n\n", title)
+	if s.Tests == 0 {
+		b.WriteString("No test results were found in the reports.\n")
+		return b.String()
+	}
+	b.WriteString(headline(s) + "\n")
+
+	if failed := s.withStatus(statusFailed); len(failed) > 0 {
+		b.WriteString("\n### Failed tests\n\n")
+		b.WriteString("| Test | Jobs | Failures |\n")
+		b.WriteString("|------|------|----------|\n")
+		for _, t := range failed {
+			fmt.Fprintf(&b, "| `%s` | %s | %d / %d |\n", t.Name, jobList(t.Jobs), t.Failed, t.Runs-t.Skipped)
+		}
+		for i, t := range failed {
+			if i == maxDetails {
+				fmt.Fprintf(&b, "\n…and the output of %d more failed tests in the full report.\n", len(failed)-maxDetails)
+				break
+			}
+			if t.Output == "" {
+				continue
+			}
+			fence := "```"
+			if strings.Contains(t.Output, fence) {
+				fence = "~~~~"
+			}
+			fmt.Fprintf(&b, "\n<details><summary><code>%s</code></summary>\n\n%s\n%s\n%s\n\n</details>\n",
+				template.HTMLEscapeString(t.Name), fence, tail(t.Output, detailLines), fence)
+		}
+	}
+
+	if flaky := s.withStatus(statusFlaky); len(flaky) > 0 {
+		b.WriteString("\n### Flaky tests\n\n")
+		b.WriteString("| Test | Jobs | Failures | Flake rate |\n")
+		b.WriteString("|------|------|----------|------------|\n")
+		for _, t := range flaky {
+			ran := t.Runs - t.Skipped
+			fmt.Fprintf(&b, "| `%s` | %s | %d / %d | %.0f%% |\n", t.Name, jobList(t.Jobs), t.Failed, ran, float64(t.Failed)/float64(ran)*100)
+		}
+	}
+
+	if len(s.Slowest) > 0 {
+		b.WriteString("\n### Slowest tests\n\n")
+		b.WriteString("| Test | Duration | Jobs |\n")
+		b.WriteString("|------|----------|------|\n")
+		for _, t := range s.Slowest {
+			fmt.Fprintf(&b, "| `%s` | %s | %s |\n", t.Name, formatDuration(t.Duration), jobList(t.Jobs))
+		}
+	}
+
+	return b.String()
+}
+
+// page is the standalone HTML report, for hosting in a bucket or as a CI
+// artifact.
+var page = template.Must(template.New("report").Funcs(template.FuncMap{
+	"duration": formatDuration,
+	"jobs":     func(jobs []string) string { return strings.Join(jobs, ", ") },
+	"tail":     func(s string) string { return tail(s, detailLines) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem auto; max-width: 72rem; padding: 0 1rem; color: #1f2328; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2rem; }
+th, td { border: 1px solid #d1d9e0; padding: 0.4rem 0.6rem; text-align: left; vertical-align: top; }
+th { background: #f6f8fa; }
+code, pre { font-family: ui-monospace, SFMono-Regular, Menlo, monospace; font-size: 0.85rem; }
+pre { background: #f6f8fa; padding: 0.75rem; overflow-x: auto; white-space: pre-wrap; }
+.failed { color: #d1242f; } .flaky { color: #9a6700; } .passed { color: #1a7f37; } .skipped { color: #59636e; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Headline}}</p>
+{{with .Summary}}{{if .Jobs}}<p>Jobs: {{jobs .Jobs}}</p>{{end}}{{end}}
+{{with .Failed}}
+<h2>Failed tests</h2>
+{{range .}}<details open><summary><code class="failed">{{.Name}}</code> ({{jobs .Jobs}}: {{.Failed}} of {{.Runs}} runs)</summary>
+{{if .Output}}<pre>{{tail .Output}}</pre>{{end}}
+</details>
+{{end}}{{end}}
+{{with .Flaky}}
+<h2>Flaky tests</h2>
+{{range .}}<details><summary><code class="flaky">{{.Name}}</code> ({{jobs .Jobs}}: {{.Failed}} of {{.Runs}} runs failed)</summary>
+{{if .Output}}<pre>{{tail .Output}}</pre>{{end}}
+</details>
+{{end}}{{end}}
+{{with .Summary.Slowest}}
+<h2>Slowest tests</h2>
+<table>
+<tr><th>Test</th><th>Duration</th><th>Jobs</th></tr>
+{{range .}}<tr><td><code>{{.Name}}</code></td><td>{{duration .Duration}}</td><td>{{jobs .Jobs}}</td></tr>
+{{end}}</table>
+{{end}}
+<h2>All tests</h2>
+<table>
+<tr><th>Test</th><th>Status</th><th>Runs</th><th>Duration</th><th>Jobs</th></tr>
+{{range .Summary.Results}}<tr><td><code>{{.Name}}</code></td><td class="{{.Status}}">{{.Status}}</td><td>{{.Runs}}</td><td>{{duration .Duration}}</td><td>{{jobs .Jobs}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// html renders the standalone HTML report.
+func html(title string, s *summary) (string, error) {
+	var b strings.Builder
+	err := page.Execute(&b, map[string]any{
+		"Title":    title,
+		"Headline": headline(s),
+		"Summary":  s,
+		"Failed":   s.withStatus(statusFailed),
+		"Flaky":    s.withStatus(statusFlaky),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render HTML report: %w", err)
+	}
+	return b.String(), nil
+}
+
+func jobList(jobs []string) string {
+	return "`" + strings.Join(jobs, "`, `") + "`"
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// tail returns the last n lines of s.
+func tail(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
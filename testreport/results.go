@@ -0,0 +1,307 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Test statuses. A test is flaky when it both passed and failed within
+// each job it failed in, e.g. across reruns; a test that fails every run
+// of one job fails, even when it passes in others.
+const (
+	statusPassed  string = "passed"
+	statusFailed  string = "failed"
+	statusFlaky   string = "flaky"
+	statusSkipped string = "skipped"
+)
+
+// result is one run of one test.
+type result struct {
+	name     string
+	job      string
+	status   string
+	duration time.Duration
+	// output is the failure message and output of a failed run.
+	output string
+}
+
+// goTestEvent is an event of "go test -json".
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// parseGoTest reads every test run from "go test -json" output, named
+// "<package>.<test>". A package that fails without a failing test, such as
+// one that does not compile, is reported as a failed run named after the
+// package. Lines that are not JSON events are skipped.
+func parseGoTest(job, out string) []result {
+	var results []result
+	output := map[string]*strings.Builder{}
+	failedTests := map[string]bool{}
+
+	sc := bufio.NewScanner(strings.NewReader(out))
+	sc.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var e goTestEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			continue
+		}
+
+		name := e.Package
+		if e.Test != "" {
+			name = e.Package + "." + e.Test
+		}
+		switch e.Action {
+		case "run":
+			output[name] = &strings.Builder{}
+		case "output":
+			if output[name] == nil {
+				output[name] = &strings.Builder{}
+			}
+			output[name].WriteString(e.Output)
+		case "pass", "fail", "skip":
+			if e.Test == "" && (e.Action != "fail" || failedTests[e.Package]) {
+				continue
+			}
+			r := result{
+				name:     name,
+				job:      job,
+				status:   map[string]string{"pass": statusPassed, "fail": statusFailed, "skip": statusSkipped}[e.Action],
+				duration: seconds(e.Elapsed),
+			}
+			if e.Action == "fail" {
+				if output[name] != nil {
+					r.output = output[name].String()
+				}
+				failedTests[e.Package] = true
+			}
+			results = append(results, r)
+		}
+	}
+	return results
+}
+
+// junitSuite is a JUnit XML test suite; suites may nest.
+type junitSuite struct {
+	Suites []junitSuite `xml:"testsuite"`
+	Cases  []struct {
+		Classname string        `xml:"classname,attr"`
+		Name      string        `xml:"name,attr"`
+		Time      string        `xml:"time,attr"`
+		Failure   *junitFailure `xml:"failure"`
+		Error     *junitFailure `xml:"error"`
+		Skipped   *struct{}     `xml:"skipped"`
+		SystemOut string        `xml:"system-out"`
+	} `xml:"testcase"`
+}
+
+// junitFailure is the <failure> or <error> of a JUnit test case.
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// parseJUnit reads every test case of a JUnit XML report, whose root is
+// either <testsuites> or a single <testsuite>. Test cases are named
+// "<classname>.<name>".
+func parseJUnit(job, report string) ([]result, error) {
+	var root junitSuite
+	if err := xml.Unmarshal([]byte(report), &root); err != nil {
+		return nil, fmt.Errorf("failed to parse JUnit report: %w", err)
+	}
+
+	var results []result
+	var walk func(s junitSuite)
+	walk = func(s junitSuite) {
+		for _, c := range s.Cases {
+			name := c.Name
+			if c.Classname != "" {
+				name = c.Classname + "." + c.Name
+			}
+			r := result{name: name, job: job, status: statusPassed}
+			if secs, err := strconv.ParseFloat(strings.ReplaceAll(c.Time, ",", ""), 64); err == nil {
+				r.duration = seconds(secs)
+			}
+
+			failure := c.Failure
+			if failure == nil {
+				failure = c.Error
+			}
+			switch {
+			case failure != nil:
+				r.status = statusFailed
+				r.output = strings.TrimSpace(strings.Join([]string{failure.Message, failure.Text, c.SystemOut}, "\n"))
+			case c.Skipped != nil:
+				r.status = statusSkipped
+			}
+			results = append(results, r)
+		}
+		for _, child := range s.Suites {
+			walk(child)
+		}
+	}
+	walk(root)
+	return results, nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// testStats is the outcome of one test across every job and run.
+type testStats struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Jobs    []string `json:"jobs"`
+	Runs    int      `json:"runs"`
+	Passed  int      `json:"passed"`
+	Failed  int      `json:"failed"`
+	Skipped int      `json:"skipped"`
+	// Duration is the test's longest run.
+	Duration time.Duration `json:"-"`
+	Seconds  float64       `json:"seconds"`
+	// Output is the output of the test's last failed run.
+	Output string `json:"output,omitempty"`
+
+	// passedIn and failedIn count the test's passed and failed runs per
+	// job.
+	passedIn map[string]int
+	failedIn map[string]int
+}
+
+// summary is the merged outcome of every report.
+type summary struct {
+	Jobs    []string `json:"jobs"`
+	Tests   int      `json:"tests"`
+	Passed  int      `json:"passed"`
+	Failed  int      `json:"failed"`
+	Flaky   int      `json:"flaky"`
+	Skipped int      `json:"skipped"`
+	// Duration is the sum of every run's duration.
+	Duration time.Duration `json:"-"`
+	Seconds  float64       `json:"seconds"`
+	// Results are every test, failed first, then flaky, then by name.
+	Results []*testStats `json:"results"`
+	// Slowest are the slowest tests that ran, slowest first.
+	Slowest []*testStats `json:"-"`
+}
+
+// statusOrder sorts the tests that need attention first.
+var statusOrder = map[string]int{statusFailed: 0, statusFlaky: 1, statusPassed: 2, statusSkipped: 3}
+
+// summarize merges test runs into per-test statistics and keeps the n
+// slowest tests.
+func summarize(results []result, n int) *summary {
+	s := &summary{}
+	byName := map[string]*testStats{}
+	jobs := map[string]bool{}
+	for _, r := range results {
+		jobs[r.job] = true
+		t, ok := byName[r.name]
+		if !ok {
+			t = &testStats{Name: r.name, passedIn: map[string]int{}, failedIn: map[string]int{}}
+			byName[r.name] = t
+		}
+		if !slices.Contains(t.Jobs, r.job) {
+			t.Jobs = append(t.Jobs, r.job)
+		}
+		t.Runs++
+		switch r.status {
+		case statusPassed:
+			t.Passed++
+			t.passedIn[r.job]++
+		case statusFailed:
+			t.Failed++
+			t.failedIn[r.job]++
+			t.Output = r.output
+		case statusSkipped:
+			t.Skipped++
+		}
+		if r.duration > t.Duration {
+			t.Duration = r.duration
+		}
+		s.Duration += r.duration
+	}
+
+	for job := range jobs {
+		s.Jobs = append(s.Jobs, job)
+	}
+	sort.Strings(s.Jobs)
+
+	s.Seconds = s.Duration.Seconds()
+
+	for _, t := range byName {
+		sort.Strings(t.Jobs)
+		t.Seconds = t.Duration.Seconds()
+		switch {
+		case t.Failed > 0 && t.flaky():
+			t.Status = statusFlaky
+			s.Flaky++
+		case t.Failed > 0:
+			t.Status = statusFailed
+			s.Failed++
+		case t.Passed > 0:
+			t.Status = statusPassed
+			s.Passed++
+		default:
+			t.Status = statusSkipped
+			s.Skipped++
+		}
+		s.Results = append(s.Results, t)
+	}
+	s.Tests = len(s.Results)
+	sort.Slice(s.Results, func(i, j int) bool {
+		a, b := s.Results[i], s.Results[j]
+		if statusOrder[a.Status] != statusOrder[b.Status] {
+			return statusOrder[a.Status] < statusOrder[b.Status]
+		}
+		return a.Name < b.Name
+	})
+
+	for _, t := range s.Results {
+		if t.Status != statusSkipped {
+			s.Slowest = append(s.Slowest, t)
+		}
+	}
+	sort.SliceStable(s.Slowest, func(i, j int) bool { return s.Slowest[i].Duration > s.Slowest[j].Duration })
+	if len(s.Slowest) > n {
+		s.Slowest = s.Slowest[:n]
+	}
+	return s
+}
+
+// flaky reports whether the test also passed in every job it failed in.
+func (t *testStats) flaky() bool {
+	for job := range t.failedIn {
+		if t.passedIn[job] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// withStatus returns the tests with the given status.
+func (s *summary) withStatus(status string) []*testStats {
+	var tests []*testStats
+	for _, t := range s.Results {
+		if t.Status == status {
+			tests = append(tests, t)
+		}
+	}
+	return tests
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/tinygo/internal/dagger"
+)
+
+const (
+	tinygoImage string = "tinygo/tinygo:0.37.0"
+)
+
+// wasmTargets are the TinyGo targets that produce WebAssembly modules.
+var wasmTargets = map[string]bool{
+	"wasm":         true,
+	"wasi":         true,
+	"wasip1":       true,
+	"wasip2":       true,
+	"wasm-unknown": true,
+}
+
+type Tinygo struct {
+	// Source is the Go source directory to build.
+	//
+	// +private
+	Source *dagger.Directory
+}
+
+// BuildResult is the output of a TinyGo build.
+type BuildResult struct {
+	// Binaries in the <target>/<name>.<format> layout
+	Binaries *dagger.Directory
+
+	// Size reports in the <target>.txt layout, as printed by
+	// "tinygo build -size full"
+	Reports *dagger.Directory
+
+	// Size reports of every target combined
+	Sizes string
+}
+
+// New creates a new Tinygo module instance.
+func New(
+	// The Go source directory to build.
+	// +defaultPath="/"
+	source *dagger.Directory,
+) *Tinygo {
+	return &Tinygo{
+		Source: source,
+	}
+}
+
+// Build compiles a Go main package with TinyGo for every target and returns
+// the binaries with a size report per target.
+//
+// WebAssembly targets ("wasm", "wasip1", "wasip2", "wasm-unknown") produce
+// ".wasm" modules; microcontroller targets (e.g., "pico", "arduino-nano33")
+// produce firmware in the given format.
+func (m *Tinygo) Build(
+	ctx context.Context,
+
+	// TinyGo targets (e.g., "wasip1", "pico")
+	targets []string,
+
+	// Name of the output binary. Defaults to the last element of the
+	// package's import path.
+	// +optional
+	name string,
+
+	// Package to build, relative to the source directory
+	// +optional
+	// +default="."
+	pkg string,
+
+	// Optimization level: "0", "1", "2", "s", or "z"
+	// +optional
+	// +default="z"
+	opt string,
+
+	// Scheduler: "none", "tasks", "asyncify", or empty for the target
+	// default
+	// +optional
+	scheduler string,
+
+	// Garbage collector: "none", "leaking", "conservative", "precise", or
+	// empty for the target default
+	// +optional
+	gc string,
+
+	// Firmware format for microcontroller targets: "elf", "hex", "bin", or
+	// "uf2"
+	// +optional
+	// +default="elf"
+	format string,
+
+	// Build tags
+	// +optional
+	tags []string,
+
+	// Extra arguments passed to tinygo build (e.g., "-panic=trap")
+	// +optional
+	extraArgs []string,
+) (*BuildResult, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("at least one target is required")
+	}
+	switch format {
+	case "elf", "hex", "bin", "uf2":
+	default:
+		return nil, fmt.Errorf("invalid format %q: must be \"elf\", \"hex\", \"bin\", or \"uf2\"", format)
+	}
+
+	ctr := m.tinygoContainer()
+
+	if name == "" {
+		importPath, err := ctr.
+			WithExec([]string{"go", "list", "-f", "{{.ImportPath}}", pkg}).
+			Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve package %s: %w", pkg, err)
+		}
+		name = path.Base(strings.TrimSpace(importPath))
+	}
+
+	args := []string{"tinygo", "build", "-opt", opt, "-no-debug"}
+	if scheduler != "" {
+		args = append(args, "-scheduler", scheduler)
+	}
+	if gc != "" {
+		args = append(args, "-gc", gc)
+	}
+	if len(tags) > 0 {
+		args = append(args, "-tags", strings.Join(tags, " "))
+	}
+	args = append(args, extraArgs...)
+
+	binaries := dag.Directory()
+	reports := dag.Directory()
+	var sizes strings.Builder
+	for _, target := range targets {
+		ext := format
+		if wasmTargets[target] {
+			ext = "wasm"
+		}
+		binary := path.Join(target, name+"."+ext)
+		dest := path.Join("/out", binary)
+
+		built := ctr.
+			WithExec(append(append([]string{}, args...), "-target", target, "-size", "full", "-o", dest, pkg))
+
+		report, err := built.Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s for %s: %w", name, target, err)
+		}
+		fmt.Fprintf(&sizes, "%s:\n%s\n\n", target, strings.TrimSpace(report))
+
+		binaries = binaries.WithFile(binary, built.File(dest))
+		reports = reports.WithNewFile(target+".txt", report)
+	}
+
+	return &BuildResult{
+		Binaries: binaries,
+		Reports:  reports,
+		Sizes:    sizes.String(),
+	}, nil
+}
+
+// Targets lists the targets supported by the TinyGo toolchain.
+func (m *Tinygo) Targets(ctx context.Context) ([]string, error) {
+	out, err := m.tinygoContainer().
+		WithExec([]string{"tinygo", "targets"}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list targets: %w", err)
+	}
+	return strings.Fields(out), nil
+}
+
+// tinygoContainer returns a TinyGo container with the source mounted and
+// the Go module cache shared with the other Go modules in this daggerverse.
+func (m *Tinygo) tinygoContainer() *dagger.Container {
+	return dag.Container().
+		From(tinygoImage).
+		WithUser("root").
+		WithEnvVariable("GOMODCACHE", "/go/pkg/mod").
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/tinygo", dag.CacheVolume("tinygo")).
+		WithEnvVariable("XDG_CACHE_HOME", "/root/.cache").
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+}
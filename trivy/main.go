@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/trivy/internal/dagger"
+)
+
+const (
+	trivyImage string = "aquasec/trivy:0.58.1"
+)
+
+// severities lists trivy severities from lowest to highest.
+var severities = []string{"UNKNOWN", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+type Trivy struct {
+	// IgnoreFile is an optional .trivyignore file.
+	//
+	// +private
+	IgnoreFile *dagger.File
+
+	// Offline skips vulnerability database updates.
+	//
+	// +private
+	Offline bool
+
+	// RegistryUsername is the username for pulling image references.
+	//
+	// +private
+	RegistryUsername string
+
+	// RegistryToken is the token for pulling image references.
+	//
+	// +private
+	RegistryToken *dagger.Secret
+}
+
+// ScanResult is the outcome of a trivy scan.
+type ScanResult struct {
+	// Whether no findings at or above the fail-on severity were found
+	Passed bool
+
+	// Scan report in the requested format
+	Report *dagger.File
+
+	// Human-readable table of the findings that failed the scan
+	Failures string
+}
+
+// New creates a new Trivy module instance.
+func New(
+	// Optional .trivyignore file listing vulnerability IDs to ignore.
+	// +optional
+	ignoreFile *dagger.File,
+
+	// Skip vulnerability database updates and use the cached database only.
+	// The database is cached in a volume either way, so only the first scan
+	// (and daily refreshes) download it.
+	// +optional
+	offline bool,
+) *Trivy {
+	return &Trivy{
+		IgnoreFile: ignoreFile,
+		Offline:    offline,
+	}
+}
+
+// WithRegistryAuth sets credentials for pulling private image references.
+func (m *Trivy) WithRegistryAuth(
+	// Registry username
+	username string,
+
+	// Registry token or password
+	token *dagger.Secret,
+) *Trivy {
+	m.RegistryUsername = username
+	m.RegistryToken = token
+	return m
+}
+
+// ScanImage scans a container image, given either as a registry reference or
+// as a container, for vulnerabilities, secrets, and misconfigurations.
+func (m *Trivy) ScanImage(
+	ctx context.Context,
+
+	// Image reference (e.g., "ghcr.io/acme/myapp:v1.2.3")
+	// +optional
+	imageRef string,
+
+	// Container to scan instead of an image reference
+	// +optional
+	ctr *dagger.Container,
+
+	// Report format: "table", "json", or "sarif"
+	// +optional
+	// +default="table"
+	format string,
+
+	// Lowest severity included in the report
+	// +optional
+	// +default="UNKNOWN"
+	severity string,
+
+	// Lowest severity that fails the scan
+	// +optional
+	// +default="HIGH"
+	failOn string,
+
+	// Only report vulnerabilities with a fix available
+	// +optional
+	ignoreUnfixed bool,
+) (*ScanResult, error) {
+	scanner := m.container()
+
+	var target []string
+	switch {
+	case ctr != nil:
+		scanner = scanner.WithFile("/image.tar", ctr.AsTarball())
+		target = []string{"image", "--input", "/image.tar"}
+	case imageRef != "":
+		target = []string{"image", imageRef}
+	default:
+		return nil, fmt.Errorf("either image-ref or ctr is required")
+	}
+
+	return m.scan(ctx, scanner, target, format, severity, failOn, ignoreUnfixed)
+}
+
+// ScanFs scans a directory (source tree or build output) for vulnerable
+// dependencies, secrets, and misconfigurations.
+func (m *Trivy) ScanFs(
+	ctx context.Context,
+
+	// Directory to scan
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Report format: "table", "json", or "sarif"
+	// +optional
+	// +default="table"
+	format string,
+
+	// Lowest severity included in the report
+	// +optional
+	// +default="UNKNOWN"
+	severity string,
+
+	// Lowest severity that fails the scan
+	// +optional
+	// +default="HIGH"
+	failOn string,
+
+	// Only report vulnerabilities with a fix available
+	// +optional
+	ignoreUnfixed bool,
+) (*ScanResult, error) {
+	scanner := m.container().WithDirectory("/src", source)
+	return m.scan(ctx, scanner, []string{"fs", "/src"}, format, severity, failOn, ignoreUnfixed)
+}
+
+// ScanSbom scans an SPDX or CycloneDX SBOM (e.g., from the syft module) for
+// vulnerable packages.
+func (m *Trivy) ScanSbom(
+	ctx context.Context,
+
+	// SBOM file
+	sbom *dagger.File,
+
+	// Report format: "table", "json", or "sarif"
+	// +optional
+	// +default="table"
+	format string,
+
+	// Lowest severity included in the report
+	// +optional
+	// +default="UNKNOWN"
+	severity string,
+
+	// Lowest severity that fails the scan
+	// +optional
+	// +default="HIGH"
+	failOn string,
+
+	// Only report vulnerabilities with a fix available
+	// +optional
+	ignoreUnfixed bool,
+) (*ScanResult, error) {
+	scanner := m.container().WithFile("/sbom", sbom)
+	return m.scan(ctx, scanner, []string{"sbom", "/sbom"}, format, severity, failOn, ignoreUnfixed)
+}
+
+// Check fails if the scan found anything at or above the fail-on severity,
+// listing the failing findings.
+func (r *ScanResult) Check() (string, error) {
+	if !r.Passed {
+		return "", fmt.Errorf("trivy found issues at or above the fail-on severity\n\n%s", r.Failures)
+	}
+	return "✅ no issues at or above the fail-on severity", nil
+}
+
+// scan renders the report, then rescans with only severities at or above
+// failOn to decide whether the scan passed. The second pass reuses the
+// cached database and scan cache.
+func (m *Trivy) scan(
+	ctx context.Context,
+	scanner *dagger.Container,
+	target []string,
+	format, severity, failOn string,
+	ignoreUnfixed bool,
+) (*ScanResult, error) {
+	switch format {
+	case "table", "json", "sarif":
+	default:
+		return nil, fmt.Errorf("invalid format %q: must be \"table\", \"json\", or \"sarif\"", format)
+	}
+
+	reported, err := severitiesFrom(severity)
+	if err != nil {
+		return nil, err
+	}
+	failing, err := severitiesFrom(failOn)
+	if err != nil {
+		return nil, err
+	}
+
+	common := []string{}
+	if ignoreUnfixed {
+		common = append(common, "--ignore-unfixed")
+	}
+	if m.IgnoreFile != nil {
+		common = append(common, "--ignorefile", "/.trivyignore")
+	}
+	if m.Offline {
+		common = append(common, "--skip-db-update", "--skip-java-db-update", "--offline-scan")
+	}
+
+	report := scanner.
+		WithExec(append(append(append([]string{}, target...), common...),
+			"--format", format,
+			"--severity", reported,
+			"--output", "/report",
+		), dagger.ContainerWithExecOpts{UseEntrypoint: true}).
+		File("/report")
+
+	gate := scanner.WithExec(append(append(append([]string{}, target...), common...),
+		"--format", "table",
+		"--severity", failing,
+		"--exit-code", "1",
+		// The report pass already refreshed the database.
+		"--skip-db-update",
+		"--skip-java-db-update",
+	), dagger.ContainerWithExecOpts{UseEntrypoint: true, Expect: dagger.ReturnTypeAny})
+
+	// Make sure the report pass (and its database download) runs first.
+	if _, err := report.Sync(ctx); err != nil {
+		return nil, fmt.Errorf("trivy scan failed: %w", err)
+	}
+
+	code, err := gate.ExitCode(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("trivy scan failed: %w", err)
+	}
+	if code != 0 && code != 1 {
+		stderr, _ := gate.Stderr(ctx)
+		return nil, fmt.Errorf("trivy scan failed with exit code %d: %s", code, stderr)
+	}
+
+	failures := ""
+	if code == 1 {
+		if failures, err = gate.Stdout(ctx); err != nil {
+			return nil, fmt.Errorf("failed to read trivy output: %w", err)
+		}
+	}
+
+	return &ScanResult{
+		Passed:   code == 0,
+		Report:   report,
+		Failures: failures,
+	}, nil
+}
+
+// container returns the trivy container with the database cache volume,
+// ignore file, and registry credentials.
+func (m *Trivy) container() *dagger.Container {
+	ctr := dag.Container().
+		From(trivyImage).
+		WithMountedCache("/root/.cache/trivy", dag.CacheVolume("trivy-cache"))
+
+	if m.IgnoreFile != nil {
+		ctr = ctr.WithFile("/.trivyignore", m.IgnoreFile)
+	}
+	if m.RegistryToken != nil {
+		ctr = ctr.
+			WithEnvVariable("TRIVY_USERNAME", m.RegistryUsername).
+			WithSecretVariable("TRIVY_PASSWORD", m.RegistryToken)
+	}
+
+	return ctr
+}
+
+// severitiesFrom returns the comma-separated severities at or above lowest.
+func severitiesFrom(lowest string) (string, error) {
+	for i, s := range severities {
+		if strings.EqualFold(s, lowest) {
+			return strings.Join(severities[i:], ","), nil
+		}
+	}
+	return "", fmt.Errorf("invalid severity %q: must be one of %s", lowest, strings.Join(severities, ", "))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+const extendWordsHeader string = "[default.extend-words]"
+
+// withDictionary adds the words of a project dictionary (one per line, "#"
+// comments allowed) to a typos config as accepted spellings, inserting them
+// into an existing [default.extend-words] table or appending one.
+func withDictionary(config, dictionary string) string {
+	var entries []string
+	for _, line := range strings.Split(dictionary, "\n") {
+		word := strings.TrimSpace(line)
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		// Mapping a word to itself marks it as correct.
+		entries = append(entries, strconv.Quote(word)+" = "+strconv.Quote(word))
+	}
+	if len(entries) == 0 {
+		return config
+	}
+	words := strings.Join(entries, "\n")
+
+	lines := strings.Split(config, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == extendWordsHeader {
+			return strings.Join(append(lines[:i+1], append([]string{words}, lines[i+1:]...)...), "\n")
+		}
+	}
+
+	if config != "" && !strings.HasSuffix(config, "\n") {
+		config += "\n"
+	}
+	return config + "\n" + extendWordsHeader + "\n" + words + "\n"
+}
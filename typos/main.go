@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"dagger/typos/internal/dagger"
+)
+
+const (
+	pythonImage  string = "python:3.13-slim-bookworm"
+	typosVersion string = "1.31.1"
+)
+
+// configPaths are the config files typos reads from the source root.
+var configPaths = []string{"typos.toml", "_typos.toml", ".typos.toml"}
+
+type Typos struct {
+	// Source is the directory to check.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Config is an optional typos configuration file.
+	//
+	// +private
+	Config *dagger.File
+
+	// Dictionary is an optional project word list.
+	//
+	// +private
+	Dictionary *dagger.File
+
+	// Exclude are glob patterns of files to skip.
+	//
+	// +private
+	Exclude []string
+}
+
+// New creates a new Typos module instance.
+func New(
+	// The directory to check.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Optional typos configuration file. When not given, a typos.toml,
+	// _typos.toml, or .typos.toml at the source root is used if present.
+	// +optional
+	config *dagger.File,
+
+	// Optional project dictionary: one accepted word per line (product
+	// names, jargon), "#" comments allowed
+	// +optional
+	dictionary *dagger.File,
+
+	// Glob patterns of files to skip (e.g., "*.svg", "testdata/**")
+	// +optional
+	exclude []string,
+) *Typos {
+	return &Typos{
+		Source:     source,
+		Config:     config,
+		Dictionary: dictionary,
+		Exclude:    exclude,
+	}
+}
+
+// Check scans the source and docs for misspellings and fails listing each
+// one with its suggested correction.
+//
+// +check
+func (m *Typos) Check(ctx context.Context) (string, error) {
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = ctr.
+		WithExec(m.args("--format", "brief")).
+		Stdout(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("typos found\n\n%s", e.Stdout)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return "✅ no typos found", nil
+}
+
+// Diff returns the corrections Fix would make as a unified diff, without
+// failing.
+func (m *Typos) Diff(ctx context.Context) (string, error) {
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return ctr.
+		WithExec(m.args("--diff"), dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny}).
+		Stdout(ctx)
+}
+
+// Fix corrects every misspelling with a single unambiguous suggestion and
+// returns the corrected source directory. Ambiguous typos are left for
+// Check to report.
+func (m *Typos) Fix(ctx context.Context) (*dagger.Directory, error) {
+	ctr, err := m.container(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// typos exits non-zero when it found typos, even after fixing them.
+	return ctr.
+		WithExec(m.args("--write-changes"), dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny}).
+		Directory("/src"), nil
+}
+
+// args builds the typos command line. Any extra flags passed in are
+// appended.
+func (m *Typos) args(extra ...string) []string {
+	args := []string{"typos"}
+	if m.Config != nil || m.Dictionary != nil {
+		args = append(args, "--config", "/typos.toml")
+	}
+	for _, pattern := range m.Exclude {
+		args = append(args, "--exclude", pattern)
+	}
+	return append(args, extra...)
+}
+
+// container returns a container with typos, the source mounted, and the
+// config merged with the project dictionary.
+func (m *Typos) container(ctx context.Context) (*dagger.Container, error) {
+	ctr := dag.Container().
+		From(pythonImage).
+		WithMountedCache("/root/.cache/pip", dag.CacheVolume("pip")).
+		WithExec([]string{"pip", "install", "typos==" + typosVersion}).
+		WithDirectory("/src", m.Source).
+		WithWorkdir("/src")
+
+	if m.Dictionary == nil {
+		if m.Config != nil {
+			ctr = ctr.WithFile("/typos.toml", m.Config)
+		}
+		return ctr, nil
+	}
+
+	config, err := m.config(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dictionary, err := m.Dictionary.Contents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dictionary: %w", err)
+	}
+
+	return ctr.WithNewFile("/typos.toml", withDictionary(config, dictionary)), nil
+}
+
+// config returns the contents of the configured or discovered typos config,
+// or an empty config when there is none.
+func (m *Typos) config(ctx context.Context) (string, error) {
+	file := m.Config
+	if file == nil {
+		for _, p := range configPaths {
+			ok, err := m.Source.Exists(ctx, p)
+			if err != nil {
+				return "", fmt.Errorf("failed to inspect source directory: %w", err)
+			}
+			if ok {
+				file = m.Source.File(p)
+				break
+			}
+		}
+	}
+	if file == nil {
+		return "", nil
+	}
+
+	contents, err := file.Contents(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read typos config: %w", err)
+	}
+	return contents, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"dagger/utils/internal/dagger"
+)
+
+// Archive takes a build artifact directory organized as <os>/<arch>/... and
+// packages each platform into <name>-<os>-<arch>.tar.gz (or .zip for windows).
+// Files are placed at the archive root, exec bits are preserved, and any
+// extra files (e.g. LICENSE, README.md) are added alongside them. An extra
+// file whose name collides with an artifact, or a build directory without
+// any <os>/<arch>/ platforms, fails the pipeline.
+func (m *Utils) Archive(
+	// Directory containing build artifacts organized as <os>/<arch>/...
+	build *dagger.Directory,
+
+	// Archive name prefix (e.g., "myapp" produces "myapp-linux-amd64.tar.gz")
+	name string,
+
+	// Extra files to include at the root of every archive (e.g. LICENSE, README.md)
+	// +optional
+	extraFiles []*dagger.File,
+) (*dagger.Directory, error) {
+	if name == "" {
+		return nil, fmt.Errorf("archive name must not be empty")
+	}
+
+	ctr := dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "tar", "zip"}).
+		WithDirectory("/build", build).
+		WithDirectory("/extra", dag.Directory()).
+		WithDirectory("/out", dag.Directory())
+
+	if len(extraFiles) > 0 {
+		ctr = ctr.WithFiles("/extra", extraFiles)
+	}
+
+	return ctr.
+		WithEnvVariable("NAME", name).
+		WithWorkdir("/build").
+		WithExec([]string{"sh", "-c", `
+			set -e
+			count=0
+			for dir in */*/; do
+				[ -d "$dir" ] || continue
+				os=$(echo "$dir" | cut -d / -f 1)
+				arch=$(echo "$dir" | cut -d / -f 2)
+				base="${NAME}-${os}-${arch}"
+
+				stage=$(mktemp -d)
+				cp -a "$dir". "$stage"/
+				for extra in /extra/* /extra/.[!.]*; do
+					[ -e "$extra" ] || continue
+					if [ -e "$stage/$(basename "$extra")" ]; then
+						echo "extra file $(basename "$extra") collides with an artifact in ${dir}" >&2
+						exit 1
+					fi
+				done
+				cp -a /extra/. "$stage"/
+
+				if [ "$os" = "windows" ]; then
+					(cd "$stage" && zip -q -r -X "/out/${base}.zip" .)
+				else
+					tar -C "$stage" --sort=name --owner=0 --group=0 --numeric-owner -czf "/out/${base}.tar.gz" .
+				fi
+				rm -rf "$stage"
+				count=$((count + 1))
+			done
+
+			if [ "$count" -eq 0 ]; then
+				echo "no <os>/<arch>/ platform directories found in build directory" >&2
+				exit 1
+			fi
+		`}).
+		Directory("/out"), nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+// SizeBudget caps the size of artifacts matching a glob.
+type SizeBudget struct {
+	// Glob matched against relative paths in the directory (e.g., "**/myapp*")
+	Pattern string
+
+	// Maximum allowed size in bytes for each matching file
+	MaxBytes int
+}
+
+// NewSizeBudget returns a SizeBudget limiting every file matching pattern to
+// maxBytes:
+//
+//	budget := utils.NewSizeBudget("linux/*/myapp", 25*1024*1024)
+func (m *Utils) NewSizeBudget(
+	// Glob matched against relative paths in the directory
+	pattern string,
+
+	// Maximum allowed size in bytes for each matching file
+	maxBytes int,
+) *SizeBudget {
+	return &SizeBudget{Pattern: pattern, MaxBytes: maxBytes}
+}
+
+// EnforceSizeBudget checks every file matching each budget's glob against
+// its size limit and returns a report of sizes vs budgets. If any file
+// exceeds its budget the pipeline fails with the same report, stopping
+// binary bloat from sneaking into releases. A budget whose glob matches no
+// files also fails, since that usually means the artifact was renamed or
+// dropped.
+func (m *Utils) EnforceSizeBudget(
+	ctx context.Context,
+
+	// Directory containing the artifacts to check
+	dir *dagger.Directory,
+
+	// Size budgets to enforce
+	budgets []SizeBudget,
+) (string, error) {
+	if len(budgets) == 0 {
+		return "", fmt.Errorf("no size budgets given")
+	}
+
+	var report []string
+	var failures int
+
+	for _, budget := range budgets {
+		if budget.MaxBytes <= 0 {
+			return "", fmt.Errorf("budget for %q must have a positive MaxBytes", budget.Pattern)
+		}
+
+		entries, err := dir.Glob(ctx, budget.Pattern)
+		if err != nil {
+			return "", fmt.Errorf("failed to list files matching %q: %w", budget.Pattern, err)
+		}
+
+		files := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			// Glob returns directory entries with a trailing slash — skip them.
+			if !strings.HasSuffix(entry, "/") {
+				files = append(files, entry)
+			}
+		}
+		sort.Strings(files)
+
+		if len(files) == 0 {
+			failures++
+			report = append(report, fmt.Sprintf("❌ %s: no matching files (budget %s)", budget.Pattern, formatBytes(budget.MaxBytes)))
+			continue
+		}
+
+		for _, f := range files {
+			size, err := dir.File(f).Size(ctx)
+			if err != nil {
+				return "", fmt.Errorf("failed to get size of %s: %w", f, err)
+			}
+
+			status := "✅"
+			if size > budget.MaxBytes {
+				status = "❌"
+				failures++
+			}
+			report = append(report, fmt.Sprintf(
+				"%s %s: %s / %s (%.1f%%)",
+				status, f, formatBytes(size), formatBytes(budget.MaxBytes),
+				float64(size)/float64(budget.MaxBytes)*100,
+			))
+		}
+	}
+
+	out := strings.Join(report, "\n")
+	if failures > 0 {
+		return "", fmt.Errorf("%d size budget violation(s):\n\n%s", failures, out)
+	}
+
+	return out, nil
+}
+
+// formatBytes renders a byte count using binary units (e.g. "12.3 MiB").
+func formatBytes(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := int64(n) / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+// ArtifactDelta describes how a single artifact changed between releases.
+type ArtifactDelta struct {
+	// Relative path of the artifact (e.g., "linux/amd64/myapp")
+	Path string `json:"path"`
+
+	// One of "added", "removed", "changed", or "unchanged"
+	Status string `json:"status"`
+
+	// Size in bytes in the previous release, 0 if added
+	PreviousSize int `json:"previousSize"`
+
+	// Size in bytes in the current release, 0 if removed
+	CurrentSize int `json:"currentSize"`
+
+	// CurrentSize minus PreviousSize
+	Delta int `json:"delta"`
+}
+
+// ReleaseComparison is the result of CompareReleases.
+type ReleaseComparison struct {
+	// Every artifact present in either release, sorted by path
+	Artifacts []ArtifactDelta `json:"artifacts"`
+}
+
+// CompareReleases compares the artifacts of the current release against a
+// previous one, reporting per-artifact size deltas and which artifacts were
+// added or removed. Render the result with Markdown for release notes or
+// JSON for tooling, and chain Check to fail when an artifact was dropped.
+func (m *Utils) CompareReleases(
+	ctx context.Context,
+
+	// Artifacts of the release being built
+	current *dagger.Directory,
+
+	// Artifacts of the previous release
+	previous *dagger.Directory,
+) (*ReleaseComparison, error) {
+	before, err := artifactEntries(ctx, previous)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe previous release: %w", err)
+	}
+
+	after, err := artifactEntries(ctx, current)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe current release: %w", err)
+	}
+
+	return compareEntries(before, after), nil
+}
+
+// Markdown renders the comparison as a markdown table.
+func (c *ReleaseComparison) Markdown() string {
+	var b strings.Builder
+	b.WriteString("| Artifact | Status | Previous | Current | Delta |\n")
+	b.WriteString("| --- | --- | ---: | ---: | ---: |\n")
+	for _, a := range c.Artifacts {
+		previous, current := "-", "-"
+		if a.Status != "added" {
+			previous = formatBytes(a.PreviousSize)
+		}
+		if a.Status != "removed" {
+			current = formatBytes(a.CurrentSize)
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | %s |\n", a.Path, a.Status, previous, current, formatDelta(a))
+	}
+	return b.String()
+}
+
+// JSON renders the comparison as indented JSON.
+func (c *ReleaseComparison) JSON() (string, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode comparison: %w", err)
+	}
+	return string(data) + "\n", nil
+}
+
+// Check fails if any artifact of the previous release is missing from the
+// current one.
+func (c *ReleaseComparison) Check() (string, error) {
+	var removed []string
+	for _, a := range c.Artifacts {
+		if a.Status == "removed" {
+			removed = append(removed, a.Path)
+		}
+	}
+	if len(removed) > 0 {
+		return "", fmt.Errorf("%d artifact(s) missing from the current release:\n  %s", len(removed), strings.Join(removed, "\n  "))
+	}
+	return "✅ no artifacts were dropped", nil
+}
+
+// compareEntries pairs up manifest entries by path, sorted by path.
+func compareEntries(before, after []ArtifactEntry) *ReleaseComparison {
+	previous := map[string]ArtifactEntry{}
+	for _, e := range before {
+		previous[e.Path] = e
+	}
+
+	c := &ReleaseComparison{Artifacts: []ArtifactDelta{}}
+	for _, e := range after {
+		d := ArtifactDelta{Path: e.Path, Status: "added", CurrentSize: e.Size, Delta: e.Size}
+		if p, ok := previous[e.Path]; ok {
+			d.Status = "unchanged"
+			if p.SHA256 != e.SHA256 {
+				d.Status = "changed"
+			}
+			d.PreviousSize = p.Size
+			d.Delta = e.Size - p.Size
+			delete(previous, e.Path)
+		}
+		c.Artifacts = append(c.Artifacts, d)
+	}
+	for _, p := range previous {
+		c.Artifacts = append(c.Artifacts, ArtifactDelta{Path: p.Path, Status: "removed", PreviousSize: p.Size, Delta: -p.Size})
+	}
+
+	sort.Slice(c.Artifacts, func(i, j int) bool {
+		return c.Artifacts[i].Path < c.Artifacts[j].Path
+	})
+
+	return c
+}
+
+// formatDelta renders a size delta with an explicit sign and, when both
+// sizes are known, the relative change.
+func formatDelta(a ArtifactDelta) string {
+	if a.Delta == 0 {
+		return "0 B"
+	}
+	sign, n := "+", a.Delta
+	if n < 0 {
+		sign, n = "-", -n
+	}
+	if a.PreviousSize == 0 || a.CurrentSize == 0 {
+		return sign + formatBytes(n)
+	}
+	return fmt.Sprintf("%s%s (%+.1f%%)", sign, formatBytes(n), float64(a.Delta)*100/float64(a.PreviousSize))
+}
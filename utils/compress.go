@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+// compression describes a single-file compression algorithm.
+type compression struct {
+	ext      string
+	minLevel int
+	maxLevel int
+	command  func(level int) string
+}
+
+// compressions maps algorithm names to their settings.
+var compressions = map[string]compression{
+	"gzip": {
+		ext:      ".gz",
+		minLevel: 1,
+		maxLevel: 9,
+		command: func(level int) string {
+			return fmt.Sprintf("gzip -n -%d -c", level)
+		},
+	},
+	"zstd": {
+		ext:      ".zst",
+		minLevel: 1,
+		maxLevel: 19,
+		command: func(level int) string {
+			return fmt.Sprintf("zstd -q -%d -c", level)
+		},
+	},
+}
+
+// Compress compresses a single file with gzip or zstd and returns the
+// compressed file, named after the original with a ".gz" or ".zst"
+// extension. Useful for large standalone artifacts like SQL dumps or wasm
+// bundles.
+func (m *Utils) Compress(
+	ctx context.Context,
+
+	// File to compress
+	file *dagger.File,
+
+	// Compression algorithm: "gzip" or "zstd"
+	// +default="gzip"
+	algorithm string,
+
+	// Compression level: 1-9 for gzip, 1-19 for zstd.
+	// Defaults to 6 for gzip and 3 for zstd.
+	// +optional
+	level int,
+) (*dagger.File, error) {
+	c, ok := compressions[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported compression algorithm %q: must be \"gzip\" or \"zstd\"", algorithm)
+	}
+
+	if level == 0 {
+		level = 6
+		if algorithm == "zstd" {
+			level = 3
+		}
+	}
+	if level < c.minLevel || level > c.maxLevel {
+		return nil, fmt.Errorf("invalid %s level %d: must be between %d and %d", algorithm, level, c.minLevel, c.maxLevel)
+	}
+
+	name, err := file.Name(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get file name: %w", err)
+	}
+	out := "/out/" + name + c.ext
+
+	return dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "gzip", "zstd"}).
+		WithFile("/in", file).
+		WithExec([]string{"mkdir", "-p", "/out"}).
+		WithEnvVariable("OUT", out).
+		WithExec([]string{"sh", "-c", c.command(level) + ` /in > "$OUT"`}).
+		File(out), nil
+}
+
+// Decompress decompresses a single gzip or zstd file, detecting the format
+// from its magic bytes. The result is named after the original with its
+// ".gz"/".zst" extension removed.
+func (m *Utils) Decompress(
+	ctx context.Context,
+
+	// Compressed file to decompress
+	file *dagger.File,
+) (*dagger.File, error) {
+	name, err := file.Name(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get file name: %w", err)
+	}
+
+	for _, c := range compressions {
+		if trimmed := strings.TrimSuffix(name, c.ext); trimmed != name && trimmed != "" {
+			name = trimmed
+			break
+		}
+	}
+	out := "/out/" + name
+
+	return dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "gzip", "zstd"}).
+		WithFile("/in", file).
+		WithExec([]string{"mkdir", "-p", "/out"}).
+		WithEnvVariable("OUT", out).
+		WithExec([]string{"sh", "-c", `
+			set -e
+			magic=$(head -c 4 /in | od -An -tx1 | tr -d ' \n')
+			case "$magic" in
+				1f8b*)
+					gzip -d -c /in > "$OUT"
+					;;
+				28b52ffd)
+					zstd -q -d -c /in > "$OUT"
+					;;
+				*)
+					echo "unrecognized compression format (magic bytes: ${magic})" >&2
+					exit 1
+					;;
+			esac
+		`}).
+		File(out), nil
+}
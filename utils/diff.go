@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+// hashTreeScript prints "<sha256>  <path>" for every file under the current
+// directory, sorted by path.
+const hashTreeScript = `
+	find . -type f | sed 's|^\./||' | LC_ALL=C sort | while IFS= read -r file; do
+		printf '%s  %s\n' "$(sha256sum "$file" | cut -d ' ' -f 1)" "$file"
+	done
+`
+
+// FileChange describes a file that differs between two directories.
+type FileChange struct {
+	// Relative path of the file
+	Path string
+
+	// Hex-encoded SHA256 of the file in the first directory, empty if added
+	Before string
+
+	// Hex-encoded SHA256 of the file in the second directory, empty if removed
+	After string
+}
+
+// DirDiff is the result of DiffDirs.
+type DirDiff struct {
+	// Files only present in the second directory
+	Added []FileChange
+
+	// Files only present in the first directory
+	Removed []FileChange
+
+	// Files present in both directories with different contents
+	Changed []FileChange
+}
+
+// DiffDirs compares two directories by content and reports which files were
+// added, removed, or changed (with their SHA256 hashes). Chain Check to fail
+// the pipeline when anything differs, e.g. to verify that regenerating code
+// or running a linter with --fix produced no changes.
+func (m *Utils) DiffDirs(
+	ctx context.Context,
+
+	// The original ("before") directory
+	a *dagger.Directory,
+
+	// The new ("after") directory
+	b *dagger.Directory,
+) (*DirDiff, error) {
+	before, err := hashTree(ctx, a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash first directory: %w", err)
+	}
+
+	after, err := hashTree(ctx, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash second directory: %w", err)
+	}
+
+	return diffHashes(before, after), nil
+}
+
+// Empty reports whether the two directories have identical contents.
+func (d *DirDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Summary returns a human-readable list of every difference.
+func (d *DirDiff) Summary() string {
+	if d.Empty() {
+		return "no differences"
+	}
+
+	var b strings.Builder
+	for _, c := range d.Added {
+		fmt.Fprintf(&b, "+ %s (%s)\n", c.Path, c.After)
+	}
+	for _, c := range d.Removed {
+		fmt.Fprintf(&b, "- %s (%s)\n", c.Path, c.Before)
+	}
+	for _, c := range d.Changed {
+		fmt.Fprintf(&b, "~ %s (%s -> %s)\n", c.Path, c.Before, c.After)
+	}
+	return b.String()
+}
+
+// Check fails if the two directories differ, listing every difference.
+func (d *DirDiff) Check() (string, error) {
+	if !d.Empty() {
+		return "", fmt.Errorf(
+			"directories differ: %d added, %d removed, %d changed\n\n%s",
+			len(d.Added), len(d.Removed), len(d.Changed), d.Summary(),
+		)
+	}
+	return "✅ directories are identical", nil
+}
+
+// hashTree returns a map of relative paths to hex SHA256 hashes for every
+// file in dir.
+func hashTree(ctx context.Context, dir *dagger.Directory) (map[string]string, error) {
+	out, err := dag.Container().
+		From("alpine:latest").
+		WithDirectory("/tree", dir).
+		WithWorkdir("/tree").
+		WithExec([]string{"sh", "-c", hashTreeScript}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := map[string]string{}
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "  ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("unexpected hash output line %q", line)
+		}
+		hashes[parts[1]] = parts[0]
+	}
+
+	return hashes, nil
+}
+
+// diffHashes compares two path→hash maps, returning sorted changes.
+func diffHashes(before, after map[string]string) *DirDiff {
+	d := &DirDiff{}
+
+	for p, h := range before {
+		a, ok := after[p]
+		switch {
+		case !ok:
+			d.Removed = append(d.Removed, FileChange{Path: p, Before: h})
+		case a != h:
+			d.Changed = append(d.Changed, FileChange{Path: p, Before: h, After: a})
+		}
+	}
+	for p, h := range after {
+		if _, ok := before[p]; !ok {
+			d.Added = append(d.Added, FileChange{Path: p, After: h})
+		}
+	}
+
+	for _, changes := range [][]FileChange{d.Added, d.Removed, d.Changed} {
+		sort.Slice(changes, func(i, j int) bool {
+			return changes[i].Path < changes[j].Path
+		})
+	}
+
+	return d
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+// envNamePattern matches valid environment variable names.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// dotenvEscaper escapes characters that are special inside a double-quoted
+// .env value.
+var dotenvEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`"`, `\"`,
+	`$`, `\$`,
+	"`", "\\`",
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+// EnvFile renders secrets into .env file contents, one KEY="VALUE" line per
+// secret with values double-quoted and escaped (backslashes, quotes, "$",
+// backticks, and newlines), in the order given. names[i] is the variable
+// name for secrets[i].
+//
+// The result is returned as a Secret rather than a File so the plaintext is
+// never stored in the cache or logs; mount it with WithMountedSecret.
+func (m *Utils) EnvFile(
+	ctx context.Context,
+
+	// Variable names, one per secret (e.g., "DATABASE_URL")
+	names []string,
+
+	// Secrets holding the values, in the same order as names
+	secrets []*dagger.Secret,
+) (*dagger.Secret, error) {
+	if err := validateSecretEnv(names, secrets); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	for i, name := range names {
+		value, err := secrets[i].Plaintext(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read secret for %s: %w", name, err)
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"\n", name, dotenvEscaper.Replace(value))
+	}
+
+	return dag.SetSecret("dotenv-"+strings.Join(names, "-"), b.String()), nil
+}
+
+// WithSecretEnv exports secrets into a container as secret environment
+// variables. names[i] is the variable name for secrets[i].
+func (m *Utils) WithSecretEnv(
+	// Container to add the variables to
+	ctr *dagger.Container,
+
+	// Variable names, one per secret (e.g., "DATABASE_URL")
+	names []string,
+
+	// Secrets holding the values, in the same order as names
+	secrets []*dagger.Secret,
+) (*dagger.Container, error) {
+	if err := validateSecretEnv(names, secrets); err != nil {
+		return nil, err
+	}
+
+	for i, name := range names {
+		ctr = ctr.WithSecretVariable(name, secrets[i])
+	}
+
+	return ctr, nil
+}
+
+// validateSecretEnv checks that names and secrets pair up and that every
+// name is a valid, unique environment variable name.
+func validateSecretEnv(names []string, secrets []*dagger.Secret) error {
+	if len(names) != len(secrets) {
+		return fmt.Errorf("got %d names but %d secrets: each secret needs exactly one name", len(names), len(secrets))
+	}
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !envNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid variable name %q: must match %s", name, envNamePattern)
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate variable name %q", name)
+		}
+		seen[name] = true
+	}
+
+	return nil
+}
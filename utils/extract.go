@@ -0,0 +1,34 @@
+package main
+
+import (
+	"dagger/utils/internal/dagger"
+)
+
+// Extract unpacks an archive file and returns its contents as a directory.
+// The format is detected from the file's magic bytes rather than its name:
+// zip archives are unpacked with unzip, and everything else is handed to
+// GNU tar, which transparently handles plain, gzip, xz, bzip2, and zstd
+// compressed tarballs.
+func (m *Utils) Extract(
+	// Archive to extract (tar, tar.gz, tar.xz, tar.bz2, tar.zst, or zip)
+	file *dagger.File,
+) *dagger.Directory {
+	return dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "tar", "gzip", "xz", "bzip2", "zstd", "unzip"}).
+		WithFile("/archive", file).
+		WithDirectory("/out", dag.Directory()).
+		WithExec([]string{"sh", "-c", `
+			set -e
+			magic=$(head -c 4 /archive | od -An -tx1 | tr -d ' \n')
+			case "$magic" in
+				504b0304|504b0506)
+					unzip -q /archive -d /out
+					;;
+				*)
+					tar -xf /archive -C /out
+					;;
+			esac
+		`}).
+		Directory("/out")
+}
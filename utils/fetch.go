@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	neturl "net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+// sha256Pattern matches a hex-encoded SHA256 digest.
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// FetchVerified downloads a file over HTTP(S) and returns it only if its
+// SHA256 digest matches the expected value; otherwise the pipeline fails.
+// This is the safe primitive for pulling third-party tools and assets.
+// The returned file is named after the last segment of the URL path.
+func (m *Utils) FetchVerified(
+	// URL to download
+	url string,
+
+	// Expected hex-encoded SHA256 digest of the file
+	sha256 string,
+) (*dagger.File, error) {
+	want := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(sha256), "sha256:"))
+	if !sha256Pattern.MatchString(want) {
+		return nil, fmt.Errorf("invalid sha256 %q: must be 64 hex characters", sha256)
+	}
+
+	name, err := fileNameFromURL(url)
+	if err != nil {
+		return nil, err
+	}
+	dest := path.Join("/download", name)
+
+	return dag.Container().
+		From("alpine:latest").
+		WithFile(dest, dag.HTTP(url)).
+		WithEnvVariable("WANT", want).
+		WithEnvVariable("DEST", dest).
+		WithExec([]string{"sh", "-c", `
+			set -e
+			GOT=$(sha256sum "$DEST" | cut -d ' ' -f 1)
+			if [ "$GOT" != "$WANT" ]; then
+				echo "checksum mismatch for ${DEST}: expected ${WANT}, got ${GOT}" >&2
+				exit 1
+			fi
+		`}).
+		File(dest), nil
+}
+
+// fileNameFromURL returns the last path segment of rawURL.
+func fileNameFromURL(rawURL string) (string, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid url %q: must be http or https", rawURL)
+	}
+
+	name := path.Base(u.Path)
+	if name == "/" || name == "." || name == "" {
+		return "", fmt.Errorf("invalid url %q: cannot determine a file name", rawURL)
+	}
+
+	return name, nil
+}
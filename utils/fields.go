@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+const yqImage = "mikefarah/yq:4"
+
+// SetJSONField sets a field in a JSON file inside a directory and returns
+// the updated directory, e.g. to bump "version" in package.json.
+//
+// fieldPath is a yq path expression such as ".version" or
+// ".dependencies[0].version". The value is written as a string unless
+// jsonValue is set, in which case it is parsed as a JSON literal
+// (e.g. "42", "true", or "{\"a\": 1}").
+func (m *Utils) SetJSONField(
+	// Directory containing the file
+	dir *dagger.Directory,
+
+	// Relative path of the JSON file (e.g., "package.json")
+	file string,
+
+	// Path expression of the field to set (e.g., ".version")
+	fieldPath string,
+
+	// Value to set
+	value string,
+
+	// Parse value as a JSON literal instead of writing a string
+	// +optional
+	jsonValue bool,
+) (*dagger.Directory, error) {
+	return setField(dir, file, fieldPath, value, jsonValue, "json")
+}
+
+// SetYAMLField sets a field in a YAML file inside a directory and returns
+// the updated directory, e.g. to bump "version" in a Helm Chart.yaml.
+// Comments and key order are preserved.
+//
+// fieldPath is a yq path expression such as ".version" or
+// ".dependencies[0].version". The value is written as a string unless
+// yamlValue is set, in which case it is parsed as a YAML literal
+// (e.g. "42", "true", or "[a, b]").
+func (m *Utils) SetYAMLField(
+	// Directory containing the file
+	dir *dagger.Directory,
+
+	// Relative path of the YAML file (e.g., "charts/myapp/Chart.yaml")
+	file string,
+
+	// Path expression of the field to set (e.g., ".version")
+	fieldPath string,
+
+	// Value to set
+	value string,
+
+	// Parse value as a YAML literal instead of writing a string
+	// +optional
+	yamlValue bool,
+) (*dagger.Directory, error) {
+	return setField(dir, file, fieldPath, value, yamlValue, "yaml")
+}
+
+// setField edits a single field of a JSON or YAML file in place with yq.
+// The value is passed through the environment so it is never interpreted
+// as part of the yq expression.
+func setField(dir *dagger.Directory, file, fieldPath, value string, literal bool, format string) (*dagger.Directory, error) {
+	if file == "" || strings.HasPrefix(file, "/") || strings.Contains(file, "..") {
+		return nil, fmt.Errorf("invalid file %q: must be a relative path inside the directory", file)
+	}
+	if !strings.HasPrefix(fieldPath, ".") {
+		return nil, fmt.Errorf("invalid field path %q: must start with \".\" (e.g. \".version\")", fieldPath)
+	}
+
+	// env() parses the variable as a literal, strenv() always yields a string.
+	read := "strenv(VALUE)"
+	if literal {
+		read = "env(VALUE)"
+	}
+
+	return dag.Container().
+		From(yqImage).
+		WithUser("root").
+		WithDirectory("/src", dir).
+		WithWorkdir("/src").
+		WithEnvVariable("VALUE", value).
+		WithExec([]string{
+			"yq", "-i",
+			"-p", format, "-o", format,
+			fmt.Sprintf("%s = %s", fieldPath, read),
+			file,
+		}).
+		Directory("/src"), nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+// defaultFlattenTemplate reproduces the historical <filename>-<os>-<arch> naming.
+const defaultFlattenTemplate = "{{.name}}{{.sep}}{{.os}}{{.sep}}{{.arch}}"
+
+// defaultFlattenPattern captures the <os>/<arch>/<filename> layout.
+const defaultFlattenPattern = `^(?P<os>[^/]+)/(?P<arch>[^/]+)/(?P<name>[^/]+)$`
+
+// defaultFlattenSuffixes are the extensions kept at the end of flattened
+// names, so "myapp.sha256" becomes "myapp-linux-amd64.sha256" rather than
+// "myapp.sha256-linux-amd64".
+var defaultFlattenSuffixes = []string{
+	".sha256", ".sha512", ".sha1", ".md5",
+	".sig", ".asc", ".pem", ".cert",
+	".sbom.json", ".spdx.json", ".cdx.json", ".intoto.jsonl",
+}
+
+// flattenOpts controls how FlattenNameOsArch names flattened files.
+type flattenOpts struct {
+	re          *regexp.Regexp
+	sep         string
+	tmpl        *texttemplate.Template
+	osAliases   map[string]string
+	archAliases map[string]string
+	suffixes    []string
+}
+
+// newFlattenOpts validates and compiles the flatten options.
+func newFlattenOpts(pattern, separator, nameTemplate string, osAliases, archAliases, suffixes []string) (*flattenOpts, error) {
+	if pattern == "" {
+		pattern = defaultFlattenPattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	if re.SubexpIndex("os") < 0 || re.SubexpIndex("arch") < 0 {
+		return nil, fmt.Errorf("invalid pattern %q: must capture named groups \"os\" and \"arch\"", pattern)
+	}
+
+	if nameTemplate == "" {
+		nameTemplate = defaultFlattenTemplate
+	}
+	tmpl, err := texttemplate.New("flatten").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid name template %q: %w", nameTemplate, err)
+	}
+
+	osMap, err := parseAliases(osAliases)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OS alias: %w", err)
+	}
+	archMap, err := parseAliases(archAliases)
+	if err != nil {
+		return nil, fmt.Errorf("invalid arch alias: %w", err)
+	}
+
+	if len(suffixes) == 0 {
+		suffixes = defaultFlattenSuffixes
+	}
+	// Match the longest suffix first so ".sbom.json" wins over ".json".
+	sorted := append([]string(nil), suffixes...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+
+	return &flattenOpts{
+		re:          re,
+		sep:         separator,
+		tmpl:        tmpl,
+		osAliases:   osMap,
+		archAliases: archMap,
+		suffixes:    sorted,
+	}, nil
+}
+
+// parseAliases parses "FROM=TO" entries into a lookup map.
+func parseAliases(aliases []string) (map[string]string, error) {
+	m := make(map[string]string, len(aliases))
+	for _, a := range aliases {
+		parts := strings.SplitN(a, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("%q must be in FROM=TO format", a)
+		}
+		m[parts[0]] = parts[1]
+	}
+	return m, nil
+}
+
+// match extracts the file name, OS, and arch from a relative path using the
+// capture pattern. The "name" group is optional and defaults to the path's
+// base name. The boolean result is false when entry does not match.
+func (o *flattenOpts) match(entry string) (filename, os, arch string, ok bool) {
+	m := o.re.FindStringSubmatch(entry)
+	if m == nil {
+		return "", "", "", false
+	}
+
+	filename = path.Base(entry)
+	if i := o.re.SubexpIndex("name"); i >= 0 && m[i] != "" {
+		filename = m[i]
+	}
+
+	return filename, m[o.re.SubexpIndex("os")], m[o.re.SubexpIndex("arch")], true
+}
+
+// name returns the flattened name for filename built for os/arch.
+func (o *flattenOpts) name(filename, os, arch string) (string, error) {
+	base, suffix := filename, ""
+	for _, s := range o.suffixes {
+		if strings.HasSuffix(filename, s) && len(filename) > len(s) {
+			base, suffix = strings.TrimSuffix(filename, s), s
+			break
+		}
+	}
+
+	if alias, ok := o.osAliases[os]; ok {
+		os = alias
+	}
+	if alias, ok := o.archAliases[arch]; ok {
+		arch = alias
+	}
+
+	var b strings.Builder
+	if err := o.tmpl.Execute(&b, map[string]string{
+		"name": base,
+		"os":   os,
+		"arch": arch,
+		"sep":  o.sep,
+	}); err != nil {
+		return "", fmt.Errorf("failed to render name for %q: %w", filename, err)
+	}
+
+	newName := strings.TrimSpace(b.String())
+	if newName == "" || strings.Contains(newName, "/") {
+		return "", fmt.Errorf("invalid flattened name %q for %q: must be a non-empty file name", newName, filename)
+	}
+
+	return newName + suffix, nil
+}
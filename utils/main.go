@@ -13,36 +13,109 @@ type Utils struct{}
 // FlattenNameOsArch takes a build artifact directory organized as <os>/<arch>/<filename>
 // and returns a flat directory with files renamed to <filename>-<os>-<arch>
 // (or <filename>-<os>-<arch>.sha256 for checksum files).
-// This is a standalone utility — for the chained workflow, use WithFlatten instead.
+// This is a standalone utility — for the chained workflow, use Pipeline().WithFlatten instead.
+//
+// Naming is configurable: separator replaces "-", nameTemplate is a Go
+// text/template over .name, .os, .arch, and .sep, and osAliases/archAliases
+// rename platforms (e.g. "darwin=macos", "amd64=x86_64"). Known extensions
+// such as .sha256, .sig, .asc, and .sbom.json always stay at the end of the
+// name (e.g. "myapp-linux-amd64.sig"); pass suffixes to replace that list.
+//
+// Trees deeper or shaped differently than <os>/<arch>/<filename> are
+// supported by passing a glob selecting the files and a pattern, a regular
+// expression with named capture groups "os", "arch", and optionally "name",
+// matched against each selected path. For example, glob "*/*/bin/*" with
+// pattern `^(?P<os>[^/]+)/(?P<arch>[^/]+)/bin/(?P<name>[^/]+)$` handles
+// <os>/<arch>/bin/<file>. Selected entries that do not match the pattern,
+// and directories selected by a glob without "**", fail the pipeline rather
+// than being skipped silently. Two files flattening to the same name also
+// fail the pipeline.
 func (m *Utils) FlattenNameOsArch(
 	ctx context.Context,
 
 	// Directory containing build artifacts organized as <os>/<arch>/<filename>
 	build *dagger.Directory,
+
+	// Glob selecting the artifacts to flatten
+	// +default="*/*/*"
+	glob string,
+
+	// Regular expression with named groups "os", "arch", and optionally
+	// "name", matched against each selected path.
+	// Defaults to `^(?P<os>[^/]+)/(?P<arch>[^/]+)/(?P<name>[^/]+)$`.
+	// +optional
+	pattern string,
+
+	// Separator placed between name, OS, and arch
+	// +default="-"
+	separator string,
+
+	// Go text/template for the flattened name, without the terminal suffix.
+	// Fields: .name, .os, .arch, .sep. Defaults to "{{.name}}{{.sep}}{{.os}}{{.sep}}{{.arch}}".
+	// +optional
+	nameTemplate string,
+
+	// OS aliases in FROM=TO format (e.g., "darwin=macos")
+	// +optional
+	osAliases []string,
+
+	// Architecture aliases in FROM=TO format (e.g., "amd64=x86_64")
+	// +optional
+	archAliases []string,
+
+	// Extensions kept at the end of flattened names. Replaces the default
+	// list (.sha256, .sha512, .sha1, .md5, .sig, .asc, .pem, .cert,
+	// .sbom.json, .spdx.json, .cdx.json, .intoto.jsonl).
+	// +optional
+	suffixes []string,
 ) (*dagger.Directory, error) {
-	entries, err := build.Glob(ctx, "*/*/*")
+	opts, err := newFlattenOpts(pattern, separator, nameTemplate, osAliases, archAliases, suffixes)
+	if err != nil {
+		return nil, err
+	}
+
+	return flatten(ctx, build, glob, opts)
+}
+
+// flatten renames every artifact selected by glob into a flat directory
+// according to opts.
+func flatten(ctx context.Context, build *dagger.Directory, glob string, opts *flattenOpts) (*dagger.Directory, error) {
+	entries, err := build.Glob(ctx, glob)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list build artifacts: %w", err)
 	}
 
 	dist := dag.Directory()
+	seen := map[string]string{}
 
 	for _, entry := range entries {
-		parts := strings.SplitN(entry, "/", 3)
-		if len(parts) != 3 {
-			continue
+		// Glob returns directory entries with a trailing slash. Recursive
+		// globs always list intermediate directories, so skip them there;
+		// anywhere else a directory means the tree is deeper than the glob.
+		if strings.HasSuffix(entry, "/") {
+			if strings.Contains(glob, "**") {
+				continue
+			}
+			return nil, fmt.Errorf(
+				"%q is a directory: pass glob and pattern to flatten trees deeper than <os>/<arch>/<filename>",
+				entry,
+			)
+		}
+
+		filename, os, arch, ok := opts.match(entry)
+		if !ok {
+			return nil, fmt.Errorf("%q does not match pattern %q", entry, opts.re.String())
+		}
+
+		newName, err := opts.name(filename, os, arch)
+		if err != nil {
+			return nil, err
 		}
-		os := parts[0]
-		arch := parts[1]
-		filename := parts[2]
-
-		var newName string
-		if strings.HasSuffix(filename, ".sha256") {
-			base := strings.TrimSuffix(filename, ".sha256")
-			newName = fmt.Sprintf("%s-%s-%s.sha256", base, os, arch)
-		} else {
-			newName = fmt.Sprintf("%s-%s-%s", filename, os, arch)
+
+		if prev, exists := seen[newName]; exists {
+			return nil, fmt.Errorf("both %q and %q would be flattened to %q", prev, entry, newName)
 		}
+		seen[newName] = entry
 
 		dist = dist.WithFile(newName, build.File(entry))
 	}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+// ArtifactEntry describes a single file in an artifact manifest.
+type ArtifactEntry struct {
+	// Relative path of the file inside the directory (e.g., "linux/amd64/myapp")
+	Path string `json:"path"`
+
+	// Size of the file in bytes
+	Size int `json:"size"`
+
+	// Octal permission bits (e.g., "0755")
+	Mode string `json:"mode"`
+
+	// Hex-encoded SHA256 checksum of the file contents
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest writes a manifest.json listing every file in the directory,
+// sorted by path, with its size, permission bits, and SHA256 checksum. It is
+// the canonical input for release notes, upload metadata, and downstream
+// verification.
+func (m *Utils) Manifest(
+	ctx context.Context,
+
+	// Directory of artifacts to describe
+	dir *dagger.Directory,
+) (*dagger.File, error) {
+	entries, err := artifactEntries(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	return dag.Directory().
+		WithNewFile("manifest.json", string(data)+"\n").
+		File("manifest.json"), nil
+}
+
+// artifactEntries returns manifest entries for every file in dir, sorted by path.
+func artifactEntries(ctx context.Context, dir *dagger.Directory) ([]ArtifactEntry, error) {
+	out, err := dag.Container().
+		From("alpine:latest").
+		WithDirectory("/artifacts", dir).
+		WithWorkdir("/artifacts").
+		WithExec([]string{"sh", "-c", `
+			find . -type f | sed 's|^\./||' | LC_ALL=C sort | while IFS= read -r file; do
+				printf '%s %s %s %s\n' "$(sha256sum "$file" | cut -d ' ' -f 1)" "$(stat -c %s "$file")" "$(stat -c %a "$file")" "$file"
+			done
+		`}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash files: %w", err)
+	}
+
+	entries := []ArtifactEntry{}
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+
+		parts := strings.SplitN(line, " ", 4)
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("unexpected hash output line %q", line)
+		}
+
+		size, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid size for %s: %w", parts[3], err)
+		}
+
+		mode, err := strconv.ParseUint(parts[2], 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mode for %s: %w", parts[3], err)
+		}
+
+		entries = append(entries, ArtifactEntry{
+			Path:   parts[3],
+			Size:   size,
+			Mode:   fmt.Sprintf("%04o", mode),
+			SHA256: parts[0],
+		})
+	}
+
+	return entries, nil
+}
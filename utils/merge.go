@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+const (
+	conflictError     = "error"
+	conflictFirstWins = "first-wins"
+	conflictLastWins  = "last-wins"
+)
+
+// MergeResult is the outcome of MergeDirs.
+type MergeResult struct {
+	// The merged directory
+	Directory *dagger.Directory
+
+	// Relative paths that were present with different contents in more than
+	// one input directory, annotated with the indexes of the directories
+	// involved (e.g., "bin/myapp (dirs 0, 2)")
+	Conflicts []string
+}
+
+// mergeSource tracks which input directory currently provides a file.
+type mergeSource struct {
+	index   int
+	digests map[int]string
+	seen    []int
+}
+
+// MergeDirs merges several directories into one, e.g. to assemble a release
+// tree from the outputs of multiple build jobs.
+//
+// Files present in more than one directory with identical contents are not
+// conflicts. When contents differ, onConflict decides what happens:
+//
+//   - "error" (default): fail and list every conflicting path
+//   - "first-wins": keep the file from the earliest directory
+//   - "last-wins": keep the file from the latest directory
+//
+// With the winning policies, conflicts are still reported on the result.
+func (m *Utils) MergeDirs(
+	ctx context.Context,
+
+	// Directories to merge, in priority order
+	dirs []*dagger.Directory,
+
+	// Conflict policy: "error", "first-wins", or "last-wins"
+	// +default="error"
+	onConflict string,
+) (*MergeResult, error) {
+	switch onConflict {
+	case conflictError, conflictFirstWins, conflictLastWins:
+	default:
+		return nil, fmt.Errorf(
+			"invalid conflict policy %q: must be one of %q, %q, %q",
+			onConflict, conflictError, conflictFirstWins, conflictLastWins,
+		)
+	}
+
+	sources := map[string]*mergeSource{}
+	conflicted := map[string]bool{}
+
+	for i, dir := range dirs {
+		entries, err := dir.Glob(ctx, "**/*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list directory %d: %w", i, err)
+		}
+
+		// Glob returns directory entries with a trailing slash — skip them.
+		for _, entry := range entries {
+			if strings.HasSuffix(entry, "/") {
+				continue
+			}
+
+			src, ok := sources[entry]
+			if !ok {
+				sources[entry] = &mergeSource{index: i, digests: map[int]string{}, seen: []int{i}}
+				continue
+			}
+			src.seen = append(src.seen, i)
+
+			same, err := sameContents(ctx, dirs, src, entry, i)
+			if err != nil {
+				return nil, err
+			}
+			if same {
+				continue
+			}
+
+			conflicted[entry] = true
+			if onConflict == conflictLastWins {
+				src.index = i
+			}
+		}
+	}
+
+	paths := make([]string, 0, len(sources))
+	for p := range sources {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	result := &MergeResult{Directory: dag.Directory()}
+	for _, p := range paths {
+		src := sources[p]
+		if conflicted[p] {
+			idx := make([]string, len(src.seen))
+			for i, s := range src.seen {
+				idx[i] = fmt.Sprintf("%d", s)
+			}
+			result.Conflicts = append(result.Conflicts, fmt.Sprintf("%s (dirs %s)", p, strings.Join(idx, ", ")))
+		}
+		result.Directory = result.Directory.WithFile(p, dirs[src.index].File(p))
+	}
+
+	if onConflict == conflictError && len(result.Conflicts) > 0 {
+		return nil, fmt.Errorf(
+			"%d conflicting file(s) found while merging:\n  - %s",
+			len(result.Conflicts),
+			strings.Join(result.Conflicts, "\n  - "),
+		)
+	}
+
+	return result, nil
+}
+
+// sameContents reports whether the file at p in directory i has the same
+// contents as the file currently selected for p. Digests are computed
+// lazily and cached on the source, since most paths never collide.
+func sameContents(ctx context.Context, dirs []*dagger.Directory, src *mergeSource, p string, i int) (bool, error) {
+	digest := func(idx int) (string, error) {
+		if d, ok := src.digests[idx]; ok {
+			return d, nil
+		}
+		d, err := dirs[idx].File(p).Digest(ctx, dagger.FileDigestOpts{ExcludeMetadata: true})
+		if err != nil {
+			return "", fmt.Errorf("failed to digest %s in directory %d: %w", p, idx, err)
+		}
+		src.digests[idx] = d
+		return d, nil
+	}
+
+	current, err := digest(src.index)
+	if err != nil {
+		return false, err
+	}
+	candidate, err := digest(i)
+	if err != nil {
+		return false, err
+	}
+
+	return current == candidate, nil
+}
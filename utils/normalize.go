@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+// NormalizeText strips UTF-8 byte order marks and converts line endings to
+// LF or CRLF for every file matching globs, so cross-platform script
+// artifacts hash and package deterministically. Permission bits are kept.
+func (m *Utils) NormalizeText(
+	ctx context.Context,
+
+	// Directory containing the text files
+	dir *dagger.Directory,
+
+	// Globs selecting the text files to normalize (e.g., "**/*.sh", "**/*.ps1")
+	globs []string,
+
+	// Line ending to convert to: "lf" or "crlf"
+	// +default="lf"
+	eol string,
+) (*dagger.Directory, error) {
+	eol = strings.ToLower(eol)
+	if eol != "lf" && eol != "crlf" {
+		return nil, fmt.Errorf("invalid eol %q: must be \"lf\" or \"crlf\"", eol)
+	}
+
+	fileSet := map[string]bool{}
+	for _, glob := range globs {
+		entries, err := dir.Glob(ctx, glob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files matching %q: %w", glob, err)
+		}
+		for _, entry := range entries {
+			// Glob returns directory entries with a trailing slash — skip them.
+			if !strings.HasSuffix(entry, "/") {
+				fileSet[entry] = true
+			}
+		}
+	}
+
+	if len(fileSet) == 0 {
+		return dir, nil
+	}
+
+	files := make([]string, 0, len(fileSet))
+	for f := range fileSet {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+
+	return dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "sed"}).
+		WithDirectory("/src", dir).
+		WithWorkdir("/src").
+		WithEnvVariable("FILES", strings.Join(files, "\n")).
+		WithEnvVariable("EOL", eol).
+		WithExec([]string{"sh", "-c", `
+			set -e
+			echo "$FILES" | while IFS= read -r file; do
+				sed -i -e '1s/^\xEF\xBB\xBF//' -e 's/\r$//' "$file"
+				if [ "$EOL" = "crlf" ]; then
+					sed -i 's/$/\r/' "$file"
+				fi
+			done
+		`}).
+		Directory("/src"), nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+// NamedDirectory pairs a group name with the directory holding its files.
+type NamedDirectory struct {
+	// Group name (e.g., "binaries")
+	Name string
+
+	// Files belonging to the group, at their original relative paths
+	Directory *dagger.Directory
+}
+
+// Partition splits a directory into named subsets selected by globs, so a
+// single build output can be divided into e.g. "binaries", "debug symbols",
+// and "docs" for different upload destinations. Each group is given as
+// NAME=GLOB; repeat a name to add more globs to the same group. A file can
+// land in more than one group if several globs match it. Groups are returned
+// in the order their names first appear.
+func (m *Utils) Partition(
+	// Directory to split
+	dir *dagger.Directory,
+
+	// Groups in NAME=GLOB format (e.g., "binaries=*/*/myapp", "docs=**/*.md")
+	groups []string,
+) ([]*NamedDirectory, error) {
+	var names []string
+	globs := map[string][]string{}
+
+	for _, g := range groups {
+		parts := strings.SplitN(g, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid group %q: must be in NAME=GLOB format", g)
+		}
+		if _, ok := globs[parts[0]]; !ok {
+			names = append(names, parts[0])
+		}
+		globs[parts[0]] = append(globs[parts[0]], parts[1])
+	}
+
+	result := make([]*NamedDirectory, 0, len(names))
+	for _, name := range names {
+		result = append(result, &NamedDirectory{
+			Name: name,
+			Directory: dag.Directory().WithDirectory(".", dir, dagger.DirectoryWithDirectoryOpts{
+				Include: globs[name],
+			}),
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+// FixPermissions normalizes permission bits in a directory: files matching
+// any of execGlobs become 0755, every other file becomes 0644, and
+// directories become 0755. Artifacts passed through some build containers
+// lose their exec bits and ship broken; this restores a known-good state.
+func (m *Utils) FixPermissions(
+	ctx context.Context,
+
+	// Directory to normalize
+	dir *dagger.Directory,
+
+	// Globs selecting executables (e.g., "*/*/myapp", "**/*.sh")
+	// +optional
+	execGlobs []string,
+) (*dagger.Directory, error) {
+	execSet := map[string]bool{}
+	for _, glob := range execGlobs {
+		entries, err := dir.Glob(ctx, glob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files matching %q: %w", glob, err)
+		}
+		for _, entry := range entries {
+			// Glob returns directory entries with a trailing slash — skip them.
+			if !strings.HasSuffix(entry, "/") {
+				execSet[entry] = true
+			}
+		}
+	}
+
+	execs := make([]string, 0, len(execSet))
+	for e := range execSet {
+		execs = append(execs, e)
+	}
+	sort.Strings(execs)
+
+	return dag.Container().
+		From("alpine:latest").
+		WithDirectory("/src", dir).
+		WithWorkdir("/src").
+		WithEnvVariable("EXECUTABLES", strings.Join(execs, "\n")).
+		WithExec([]string{"sh", "-c", `
+			set -e
+			find . -type d -exec chmod 0755 {} +
+			find . -type f -exec chmod 0644 {} +
+			[ -n "$EXECUTABLES" ] || exit 0
+			echo "$EXECUTABLES" | while IFS= read -r file; do
+				chmod 0755 "$file"
+			done
+		`}).
+		Directory("/src"), nil
+}
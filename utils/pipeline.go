@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"dagger/utils/internal/dagger"
+)
+
+const (
+	stepFlatten   = "flatten"
+	stepChecksums = "checksums"
+)
+
+// Pipeline chains artifact preparation steps so multi-step preparation reads
+// as one fluent chain:
+//
+//	dag.Utils().Pipeline().
+//	    WithDirectory(build).
+//	    WithFlatten().
+//	    WithChecksums().
+//	    Directory(ctx)
+//
+// Steps run in the order they were chained when Directory is called.
+type Pipeline struct {
+	// Directory the steps are applied to
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Steps to apply, in order
+	//
+	// +private
+	Steps []string
+}
+
+// Pipeline returns an empty artifact preparation pipeline.
+// Chain WithDirectory first, then any steps, then Directory.
+func (m *Utils) Pipeline() *Pipeline {
+	return &Pipeline{}
+}
+
+// WithDirectory sets the directory the pipeline steps are applied to.
+func (p *Pipeline) WithDirectory(
+	// Directory of build artifacts
+	dir *dagger.Directory,
+) *Pipeline {
+	p.Source = dir
+	return p
+}
+
+// WithFlatten adds a step collapsing an <os>/<arch>/<filename> layout into a
+// flat directory of <filename>-<os>-<arch> files, with the same default
+// naming as FlattenNameOsArch.
+func (p *Pipeline) WithFlatten() *Pipeline {
+	p.Steps = append(p.Steps, stepFlatten)
+	return p
+}
+
+// WithChecksums adds a step generating a sibling <filename>.sha256 file for
+// every file in the directory.
+func (p *Pipeline) WithChecksums() *Pipeline {
+	p.Steps = append(p.Steps, stepChecksums)
+	return p
+}
+
+// Directory runs the chained steps in order and returns the result.
+// WithDirectory must be called before Directory.
+func (p *Pipeline) Directory(ctx context.Context) (*dagger.Directory, error) {
+	if p.Source == nil {
+		return nil, fmt.Errorf("no directory set: call WithDirectory before Directory")
+	}
+
+	dir := p.Source
+	for _, step := range p.Steps {
+		switch step {
+		case stepFlatten:
+			opts, err := newFlattenOpts("", "-", "", nil, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+			dir, err = flatten(ctx, dir, "*/*/*", opts)
+			if err != nil {
+				return nil, fmt.Errorf("flatten step failed: %w", err)
+			}
+		case stepChecksums:
+			dir = sha256Siblings(dir)
+		default:
+			return nil, fmt.Errorf("unknown pipeline step %q", step)
+		}
+	}
+
+	return dir, nil
+}
+
+// sha256Siblings returns dir with a <filename>.sha256 file next to every
+// file that is not already a checksum.
+func sha256Siblings(dir *dagger.Directory) *dagger.Directory {
+	return dag.Container().
+		From("alpine:latest").
+		WithDirectory("/artifacts", dir).
+		WithWorkdir("/artifacts").
+		WithExec([]string{"sh", "-c", `
+			find . -type f ! -name "*.sha256" | sed 's|^\./||' | while IFS= read -r file; do
+				printf '%s  %s\n' "$(sha256sum "$file" | cut -d ' ' -f 1)" "$file" > "${file}.sha256"
+			done
+		`}).
+		Directory("/artifacts")
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+
+	"dagger/utils/internal/dagger"
+)
+
+// RenameFiles renames every file in a directory whose relative path matches
+// pattern, a regular expression with named capture groups, using a Go
+// text/template to build the new relative path.
+//
+// The template can reference every named capture group as well as the
+// following fields derived from the original path (a capture group with the
+// same name takes precedence):
+//
+//   - .path: the full relative path (e.g. "linux/amd64/myapp.exe")
+//   - .dir: the parent directory (e.g. "linux/amd64")
+//   - .base: the file name (e.g. "myapp.exe")
+//   - .name: the file name without its extension (e.g. "myapp")
+//   - .ext: the file extension including the dot (e.g. ".exe")
+//
+// For example, pattern `^(?P<os>[^/]+)/(?P<arch>[^/]+)/[^/]+$` with template
+// `{{.name}}_{{.os}}_{{.arch}}{{.ext}}` turns "linux/amd64/myapp" into
+// "myapp_linux_amd64". Files that do not match are dropped unless
+// keepUnmatched is set.
+func (m *Utils) RenameFiles(
+	ctx context.Context,
+
+	// Directory containing the files to rename
+	dir *dagger.Directory,
+
+	// Regular expression matched against each file's relative path.
+	// Named capture groups become template fields.
+	pattern string,
+
+	// Go text/template producing the new relative path
+	// (e.g., "{{.name}}-{{.os}}-{{.arch}}{{.ext}}")
+	template string,
+
+	// Keep files that do not match pattern at their original path
+	// instead of dropping them.
+	// +optional
+	keepUnmatched bool,
+) (*dagger.Directory, error) {
+	r, err := newRenamer(pattern, template)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := dir.Glob(ctx, "**/*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	out := dag.Directory()
+	seen := map[string]string{}
+
+	// Glob returns directory entries with a trailing slash — skip them.
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+
+		newName, ok, err := r.rename(entry)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			if !keepUnmatched {
+				continue
+			}
+			newName = entry
+		}
+
+		if prev, exists := seen[newName]; exists {
+			return nil, fmt.Errorf("both %q and %q would be renamed to %q", prev, entry, newName)
+		}
+		seen[newName] = entry
+
+		out = out.WithFile(newName, dir.File(entry))
+	}
+
+	return out, nil
+}
+
+// renamer maps relative file paths to new names via a regular expression
+// with named capture groups and a Go text/template.
+type renamer struct {
+	re   *regexp.Regexp
+	tmpl *texttemplate.Template
+}
+
+// newRenamer compiles pattern and tmpl into a renamer.
+func newRenamer(pattern, tmpl string) (*renamer, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+
+	t, err := texttemplate.New("rename").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template %q: %w", tmpl, err)
+	}
+
+	return &renamer{re: re, tmpl: t}, nil
+}
+
+// rename returns the new relative path for p. The boolean result is false
+// when p does not match the renamer's pattern.
+func (r *renamer) rename(p string) (string, bool, error) {
+	match := r.re.FindStringSubmatch(p)
+	if match == nil {
+		return "", false, nil
+	}
+
+	base := path.Base(p)
+	ext := path.Ext(base)
+	data := map[string]string{
+		"path": p,
+		"dir":  path.Dir(p),
+		"base": base,
+		"name": strings.TrimSuffix(base, ext),
+		"ext":  ext,
+	}
+	for i, group := range r.re.SubexpNames() {
+		if group != "" {
+			data[group] = match[i]
+		}
+	}
+
+	var b strings.Builder
+	if err := r.tmpl.Execute(&b, data); err != nil {
+		return "", false, fmt.Errorf("failed to render new name for %q: %w", p, err)
+	}
+
+	newName := path.Clean(strings.TrimSpace(b.String()))
+	if newName == "." || path.IsAbs(newName) || newName == ".." || strings.HasPrefix(newName, "../") {
+		return "", false, fmt.Errorf("invalid new name %q for %q: must be a relative path inside the directory", newName, p)
+	}
+
+	return newName, true, nil
+}
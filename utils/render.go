@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	texttemplate "text/template"
+
+	"dagger/utils/internal/dagger"
+)
+
+// RenderTemplates renders every template file matching glob in a directory
+// with the given values using Go text/template syntax (e.g. "{{.VERSION}}"),
+// writing the result next to it without the ".tmpl" extension and removing
+// the template. Permission bits are carried over, so an "install.sh.tmpl"
+// with its exec bit set renders to an executable "install.sh". Referencing
+// a value that was not provided fails the pipeline.
+//
+// This is useful for producing install scripts, systemd units, and
+// Kubernetes manifests from templates at release time.
+func (m *Utils) RenderTemplates(
+	ctx context.Context,
+
+	// Directory containing templates
+	dir *dagger.Directory,
+
+	// Template values in KEY=VALUE format (e.g., "VERSION=v1.2.3")
+	// +optional
+	values []string,
+
+	// Glob selecting the templates to render
+	// +default="**/*.tmpl"
+	glob string,
+) (*dagger.Directory, error) {
+	data := make(map[string]string, len(values))
+	for _, v := range values {
+		parts := strings.SplitN(v, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid value %q: must be in KEY=VALUE format", v)
+		}
+		data[parts[0]] = parts[1]
+	}
+
+	entries, err := dir.Glob(ctx, glob)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	rendered := dir
+	var templates []string
+
+	for _, entry := range entries {
+		// Glob returns directory entries with a trailing slash — skip them.
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+
+		contents, err := dir.File(entry).Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read template %s: %w", entry, err)
+		}
+
+		tmpl, err := texttemplate.New(entry).Option("missingkey=error").Parse(contents)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template %s: %w", entry, err)
+		}
+
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("failed to render template %s: %w", entry, err)
+		}
+
+		target := strings.TrimSuffix(entry, ".tmpl")
+		if target == entry {
+			return nil, fmt.Errorf("template %s must have a .tmpl extension", entry)
+		}
+
+		rendered = rendered.WithNewFile(target, b.String())
+		templates = append(templates, entry)
+	}
+
+	if len(templates) == 0 {
+		return dir, nil
+	}
+
+	// Carry the templates' permission bits over to the rendered files and
+	// drop the templates themselves.
+	return dag.Container().
+		From("alpine:latest").
+		WithDirectory("/src", rendered).
+		WithWorkdir("/src").
+		WithEnvVariable("TEMPLATES", strings.Join(templates, "\n")).
+		WithExec([]string{"sh", "-c", `
+			set -e
+			echo "$TEMPLATES" | while IFS= read -r tmpl; do
+				chmod "$(stat -c %a "$tmpl")" "${tmpl%.tmpl}"
+				rm "$tmpl"
+			done
+		`}).
+		Directory("/src"), nil
+}
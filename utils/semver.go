@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// semverPattern is the official SemVer 2.0.0 regular expression with an
+// optional leading "v".
+var semverPattern = regexp.MustCompile(`^(v?)(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// rcPattern matches release candidate prerelease identifiers, e.g. "rc.3".
+var rcPattern = regexp.MustCompile(`^rc\.(\d+)$`)
+
+// semver is a parsed semantic version.
+type semver struct {
+	prefix     string
+	major      int
+	minor      int
+	patch      int
+	prerelease string
+	build      string
+}
+
+// parseSemver parses a SemVer 2.0.0 version with an optional "v" prefix.
+func parseSemver(v string) (*semver, error) {
+	match := semverPattern.FindStringSubmatch(strings.TrimSpace(v))
+	if match == nil {
+		return nil, fmt.Errorf("invalid semantic version %q", v)
+	}
+
+	nums := make([]int, 3)
+	for i := range nums {
+		n, err := strconv.Atoi(match[i+2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid semantic version %q: %w", v, err)
+		}
+		nums[i] = n
+	}
+
+	return &semver{
+		prefix:     match[1],
+		major:      nums[0],
+		minor:      nums[1],
+		patch:      nums[2],
+		prerelease: match[5],
+		build:      match[6],
+	}, nil
+}
+
+func (s *semver) String() string {
+	v := fmt.Sprintf("%s%d.%d.%d", s.prefix, s.major, s.minor, s.patch)
+	if s.prerelease != "" {
+		v += "-" + s.prerelease
+	}
+	if s.build != "" {
+		v += "+" + s.build
+	}
+	return v
+}
+
+// bump returns the next release version for the given level. A prerelease
+// is promoted to its release when the level would not move past it, e.g.
+// "v1.2.0-rc.1" bumped by "minor" becomes "v1.2.0".
+func (s *semver) bump(level string) (*semver, error) {
+	next := &semver{prefix: s.prefix, major: s.major, minor: s.minor, patch: s.patch}
+	pre := s.prerelease != ""
+
+	switch level {
+	case "major":
+		if !pre || s.minor != 0 || s.patch != 0 {
+			next.major++
+		}
+		next.minor, next.patch = 0, 0
+	case "minor":
+		if !pre || s.patch != 0 {
+			next.minor++
+		}
+		next.patch = 0
+	case "patch":
+		if !pre {
+			next.patch++
+		}
+	default:
+		return nil, fmt.Errorf("invalid bump level %q: must be one of \"major\", \"minor\", \"patch\"", level)
+	}
+
+	return next, nil
+}
+
+// SemverValidate checks that tag is a valid semantic version (an optional
+// leading "v" is allowed) and returns it normalized, without surrounding
+// whitespace.
+func (m *Utils) SemverValidate(
+	// Version or tag to validate (e.g., "v1.2.3-rc.1")
+	tag string,
+) (string, error) {
+	v, err := parseSemver(tag)
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
+
+// SemverBump returns the next version after current for the given level:
+// "major", "minor", or "patch". The "v" prefix is preserved and build
+// metadata is dropped. Bumping a prerelease promotes it to its release when
+// the level does not move past it (e.g. "v1.2.0-rc.1" + "minor" = "v1.2.0").
+func (m *Utils) SemverBump(
+	// Current version (e.g., "v1.2.3")
+	current string,
+
+	// Bump level: "major", "minor", or "patch"
+	level string,
+) (string, error) {
+	v, err := parseSemver(current)
+	if err != nil {
+		return "", err
+	}
+
+	next, err := v.bump(level)
+	if err != nil {
+		return "", err
+	}
+
+	return next.String(), nil
+}
+
+// SemverIsPrerelease reports whether version carries a prerelease
+// identifier (e.g. "v1.2.3-rc.1" or "v2.0.0-beta").
+func (m *Utils) SemverIsPrerelease(
+	// Version to inspect
+	version string,
+) (bool, error) {
+	v, err := parseSemver(version)
+	if err != nil {
+		return false, err
+	}
+	return v.prerelease != "", nil
+}
+
+// SemverNextRc returns the next release candidate after current. If current
+// is already a release candidate ("-rc.N") its number is incremented;
+// otherwise current is bumped by level and "-rc.1" is appended
+// (e.g. "v1.2.3" + "minor" = "v1.3.0-rc.1").
+func (m *Utils) SemverNextRc(
+	// Current version (e.g., "v1.2.3" or "v1.3.0-rc.1")
+	current string,
+
+	// Bump level used when current is not already a release candidate
+	// +default="patch"
+	level string,
+) (string, error) {
+	v, err := parseSemver(current)
+	if err != nil {
+		return "", err
+	}
+
+	if match := rcPattern.FindStringSubmatch(v.prerelease); match != nil {
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return "", fmt.Errorf("invalid release candidate %q: %w", current, err)
+		}
+		next := *v
+		next.prerelease = fmt.Sprintf("rc.%d", n+1)
+		next.build = ""
+		return next.String(), nil
+	}
+
+	if v.prerelease != "" {
+		return "", fmt.Errorf("%q is a non-rc prerelease: cannot determine the next release candidate", current)
+	}
+
+	next, err := v.bump(level)
+	if err != nil {
+		return "", err
+	}
+	next.prerelease = "rc.1"
+
+	return next.String(), nil
+}
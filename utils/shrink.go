@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"dagger/utils/internal/dagger"
+)
+
+//go:embed shrink.sh
+var shrinkScript string
+
+// ShrinkResult is the outcome of ShrinkBinaries.
+type ShrinkResult struct {
+	// Directory with the shrunk binaries
+	Directory *dagger.Directory
+
+	// Per-binary "<path>: <before> -> <after> bytes" report
+	Report string
+}
+
+// ShrinkBinaries strips debug symbols from every Linux (ELF) and Windows (PE)
+// executable in a directory and, when upx is set, packs them with
+// "upx --best". Cross-compiled binaries of any architecture are supported.
+// macOS (Mach-O) binaries are skipped, since stripping or packing them breaks
+// code signing. Non-executable files are left untouched.
+func (m *Utils) ShrinkBinaries(
+	ctx context.Context,
+
+	// Directory containing binaries to shrink
+	dir *dagger.Directory,
+
+	// Also pack binaries with "upx --best"
+	// +optional
+	upx bool,
+) (*ShrinkResult, error) {
+	ctr := dag.Container().
+		From("debian:bookworm-slim").
+		WithExec([]string{"sh", "-c", "apt-get update && apt-get install -y --no-install-recommends binutils-multiarch upx-ucl file && rm -rf /var/lib/apt/lists/*"}).
+		WithDirectory("/artifacts", dir).
+		WithEnvVariable("UPX", fmt.Sprintf("%t", upx)).
+		WithNewFile("/usr/local/bin/shrink.sh", shrinkScript, dagger.ContainerWithNewFileOpts{Permissions: 0o755}).
+		WithExec([]string{"/usr/local/bin/shrink.sh"})
+
+	report, err := ctr.Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to shrink binaries: %w", err)
+	}
+
+	return &ShrinkResult{
+		Directory: ctr.Directory("/artifacts"),
+		Report:    report,
+	}, nil
+}
@@ -0,0 +1,40 @@
+#!/usr/bin/env sh
+set -e
+
+# Strips, and optionally UPX-packs, every executable under /artifacts and
+# prints a "<path>: <before> -> <after> bytes" line per binary.
+#
+# Linux (ELF) and Windows (PE) binaries are stripped with multi-arch binutils
+# so cross-compiled artifacts work. macOS (Mach-O) binaries are left alone:
+# GNU strip cannot handle them and modifying them breaks code signatures.
+
+cd /artifacts
+
+find . -type f | sed 's|^\./||' | LC_ALL=C sort | while IFS= read -r FILE; do
+  KIND=$(file -b "$FILE")
+  case "$KIND" in
+    ELF*executable*|ELF*shared\ object*|PE32*) ;;
+    Mach-O*)
+      echo "${FILE}: skipped (Mach-O)"
+      continue
+      ;;
+    *)
+      continue
+      ;;
+  esac
+
+  BEFORE=$(stat -c %s "$FILE")
+  MODE=$(stat -c %a "$FILE")
+
+  strip --strip-unneeded "$FILE"
+
+  if [ "$UPX" = "true" ]; then
+    if ! upx -q --best "$FILE" > /dev/null 2>&1; then
+      echo "${FILE}: upx could not pack this binary, keeping it unpacked" >&2
+    fi
+  fi
+
+  chmod "$MODE" "$FILE"
+  AFTER=$(stat -c %s "$FILE")
+  echo "${FILE}: ${BEFORE} -> ${AFTER} bytes"
+done
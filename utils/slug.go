@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// slugDisallowed matches runs of characters not allowed in a slug.
+	slugDisallowed = regexp.MustCompile(`[^a-z0-9]+`)
+
+	// filenameDisallowed matches runs of characters not allowed in a
+	// filename-safe name.
+	filenameDisallowed = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+)
+
+// Slug turns an arbitrary string such as a branch name or version into a
+// lowercase slug that is safe as a bucket prefix, DNS label, or directory
+// name: runs of anything other than a-z and 0-9 (slashes, unicode,
+// punctuation) collapse into the separator, which is trimmed from both ends.
+// Slugs longer than maxLength are truncated and suffixed with a short hash of
+// the input so distinct long names stay distinct.
+//
+// For example, "feature/Add-ÜTF8_support" becomes "feature-add-tf8-support".
+func (m *Utils) Slug(
+	// String to slugify (e.g., "feature/my-branch")
+	value string,
+
+	// Maximum slug length
+	// +optional
+	// +default=63
+	maxLength int,
+
+	// Separator replacing disallowed characters
+	// +optional
+	// +default="-"
+	separator string,
+) (string, error) {
+	slug := slugDisallowed.ReplaceAllString(strings.ToLower(value), separator)
+	return limitSlug(value, strings.Trim(slug, separator), separator, maxLength)
+}
+
+// SafeFilename turns an arbitrary string into a name that is safe as a
+// single path component on every platform and as a release asset name:
+// case, dots, underscores, and dashes are kept, anything else (slashes,
+// spaces, unicode) collapses into "-", and leading dots and dashes are
+// trimmed so the result is never hidden or mistaken for a flag. Names longer
+// than maxLength are truncated and suffixed with a short hash of the input.
+//
+// For example, "release/v1.2.3 (final)" becomes "release-v1.2.3-final".
+func (m *Utils) SafeFilename(
+	// String to sanitize (e.g., "myapp v1.2.3/linux")
+	value string,
+
+	// Maximum filename length
+	// +optional
+	// +default=255
+	maxLength int,
+) (string, error) {
+	name := filenameDisallowed.ReplaceAllString(value, "-")
+	name = strings.TrimRight(strings.TrimLeft(name, ".-"), "-")
+	return limitSlug(value, name, "-", maxLength)
+}
+
+// limitSlug validates a sanitized name and shortens it to maxLength,
+// appending an 8 character hash of the original value when truncated.
+func limitSlug(original, slug, separator string, maxLength int) (string, error) {
+	if slug == "" {
+		return "", fmt.Errorf("%q contains no usable characters", original)
+	}
+	if len(slug) <= maxLength {
+		return slug, nil
+	}
+
+	sum := sha256.Sum256([]byte(original))
+	suffix := separator + hex.EncodeToString(sum[:])[:8]
+	if maxLength <= len(suffix) {
+		return "", fmt.Errorf("max length %d is too short: must be greater than %d", maxLength, len(suffix))
+	}
+
+	head := strings.TrimRight(slug[:maxLength-len(suffix)], separator+".")
+	return head + suffix, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"dagger/utils/internal/dagger"
+)
+
+// ReproducibleTar packs a directory into a bit-for-bit reproducible tar.gz:
+// entries are sorted by name, every mtime is fixed, ownership is reset to
+// 0:0 with no user or group names, and gzip omits its own name and
+// timestamp. Identical inputs therefore always produce identical archive
+// hashes, which only change when content (or permission bits) change.
+func (m *Utils) ReproducibleTar(
+	// Directory to archive; its contents land at the archive root
+	dir *dagger.Directory,
+
+	// Archive file name; ".tar.gz" is appended if missing (e.g., "myapp-src")
+	name string,
+
+	// Fixed mtime for every entry, as understood by GNU tar --mtime:
+	// a date (e.g., "2026-01-01T00:00:00Z") or "@<unix seconds>".
+	// Typically the commit timestamp (SOURCE_DATE_EPOCH).
+	// +default="@0"
+	mtime string,
+) (*dagger.File, error) {
+	if name == "" {
+		return nil, fmt.Errorf("archive name must not be empty")
+	}
+	if strings.Contains(name, "/") {
+		return nil, fmt.Errorf("invalid archive name %q: must not contain \"/\"", name)
+	}
+	if !strings.HasSuffix(name, ".tar.gz") {
+		name += ".tar.gz"
+	}
+
+	return dag.Container().
+		From("alpine:latest").
+		WithExec([]string{"apk", "add", "--no-cache", "tar", "gzip"}).
+		WithDirectory("/src", dir).
+		WithDirectory("/out", dag.Directory()).
+		WithEnvVariable("MTIME", mtime).
+		WithEnvVariable("OUT", "/out/"+name).
+		WithExec([]string{"sh", "-c", `
+			set -e
+			tar -C /src \
+				--sort=name \
+				--format=gnu \
+				--mtime="$MTIME" \
+				--owner=0 --group=0 --numeric-owner \
+				-cf - . | gzip -n -9 > "$OUT"
+		`}).
+		File("/out/" + name), nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"dagger/utils/internal/dagger"
+)
+
+const (
+	stampFormatText = "text"
+	stampFormatJSON = "json"
+	stampFormatAll  = "all"
+)
+
+// buildInfo is the structure written to buildinfo.json by VersionStamp.
+type buildInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+	Date    string `json:"date,omitempty"`
+}
+
+// VersionStamp returns a directory containing release metadata to embed in
+// artifact trees: a VERSION text file holding just the version, and/or a
+// buildinfo.json file holding the version, commit, and build date.
+func (m *Utils) VersionStamp(
+	// Release version (e.g., "v1.2.3")
+	version string,
+
+	// Git commit SHA the artifacts were built from
+	// +optional
+	commit string,
+
+	// Build date, preferably RFC 3339 (e.g., "2026-01-02T15:04:05Z")
+	// +optional
+	date string,
+
+	// Which files to emit: "text" (VERSION), "json" (buildinfo.json), or "all"
+	// +default="all"
+	format string,
+) (*dagger.Directory, error) {
+	if version == "" {
+		return nil, fmt.Errorf("version must not be empty")
+	}
+
+	dir := dag.Directory()
+
+	switch format {
+	case stampFormatText, stampFormatJSON, stampFormatAll:
+	default:
+		return nil, fmt.Errorf(
+			"invalid format %q: must be one of %q, %q, %q",
+			format, stampFormatText, stampFormatJSON, stampFormatAll,
+		)
+	}
+
+	if format == stampFormatText || format == stampFormatAll {
+		dir = dir.WithNewFile("VERSION", version+"\n")
+	}
+
+	if format == stampFormatJSON || format == stampFormatAll {
+		data, err := json.MarshalIndent(buildInfo{
+			Version: version,
+			Commit:  commit,
+			Date:    date,
+		}, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode build info: %w", err)
+		}
+		dir = dir.WithNewFile("buildinfo.json", string(data)+"\n")
+	}
+
+	return dir, nil
+}
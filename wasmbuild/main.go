@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/wasmbuild/internal/dagger"
+)
+
+const (
+	goImage string = "golang:1.26-bookworm"
+)
+
+// contentTypes maps the extensions of the files Build emits to the
+// Content-Type they must be served with. Browsers only stream-compile
+// WebAssembly served as application/wasm.
+var contentTypes = map[string]string{
+	".wasm": "application/wasm",
+	".js":   "text/javascript; charset=utf-8",
+	".html": "text/html; charset=utf-8",
+}
+
+type Wasmbuild struct {
+	// Source is the Go source directory to build.
+	//
+	// +private
+	Source *dagger.Directory
+}
+
+// New creates a new Wasmbuild module instance.
+func New(
+	// The Go source directory to build.
+	// +defaultPath="/"
+	source *dagger.Directory,
+) *Wasmbuild {
+	return &Wasmbuild{
+		Source: source,
+	}
+}
+
+// Build compiles a Go main package to WebAssembly and returns a directory
+// containing "<name>.wasm". For the "js" target, the Go toolchain's
+// wasm_exec.js loader is included alongside it; "wasip1" builds run on WASI
+// runtimes and need no loader.
+//
+// When optimize is set, the module is run through binaryen's wasm-opt to
+// reduce its size.
+func (m *Wasmbuild) Build(
+	ctx context.Context,
+
+	// Name of the output module, without the .wasm extension. Defaults to
+	// the last element of the package's import path.
+	// +optional
+	name string,
+
+	// Package to build, relative to the source directory
+	// +optional
+	// +default="."
+	pkg string,
+
+	// Target GOOS: "js" for browsers or "wasip1" for WASI runtimes
+	// +optional
+	// +default="js"
+	goos string,
+
+	// Version to stamp into the module (e.g., "v1.2.3")
+	// +optional
+	version string,
+
+	// Fully qualified variable that receives the version
+	// +optional
+	// +default="main.version"
+	versionVar string,
+
+	// Extra linker flags (e.g., "-X main.commit=abc123")
+	// +optional
+	ldflags []string,
+
+	// Build tags
+	// +optional
+	tags []string,
+
+	// Optimize the module with wasm-opt
+	// +optional
+	optimize bool,
+
+	// wasm-opt optimization level
+	// +optional
+	// +default="-Oz"
+	optLevel string,
+) (*dagger.Directory, error) {
+	if goos != "js" && goos != "wasip1" {
+		return nil, fmt.Errorf("invalid goos %q: must be \"js\" or \"wasip1\"", goos)
+	}
+
+	ctr := m.goContainer().
+		WithEnvVariable("CGO_ENABLED", "0").
+		WithEnvVariable("GOOS", goos).
+		WithEnvVariable("GOARCH", "wasm")
+
+	if name == "" {
+		importPath, err := ctr.
+			WithExec([]string{"go", "list", "-f", "{{.ImportPath}}", pkg}).
+			Stdout(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve package %s: %w", pkg, err)
+		}
+		name = path.Base(strings.TrimSpace(importPath))
+	}
+	wasm := "/out/" + name + ".wasm"
+
+	flags := []string{"-s", "-w"}
+	if version != "" {
+		flags = append(flags, fmt.Sprintf("-X %s=%s", versionVar, version))
+	}
+	args := []string{"go", "build", "-trimpath", "-ldflags", strings.Join(append(flags, ldflags...), " ")}
+	if len(tags) > 0 {
+		args = append(args, "-tags", strings.Join(tags, ","))
+	}
+
+	ctr = ctr.WithExec(append(args, "-o", wasm, pkg))
+
+	if goos == "js" {
+		// wasm_exec.js moved from misc/wasm to lib/wasm in Go 1.24.
+		ctr = ctr.WithExec([]string{"sh", "-c",
+			`root=$(go env GOROOT); cp "$root/lib/wasm/wasm_exec.js" /out/ 2>/dev/null || cp "$root/misc/wasm/wasm_exec.js" /out/`,
+		})
+	}
+
+	if optimize {
+		ctr = ctr.
+			WithExec([]string{"sh", "-c", "apt-get update && apt-get install -y --no-install-recommends binaryen"}).
+			WithExec([]string{
+				"wasm-opt", optLevel,
+				// Features the Go compiler emits.
+				"--enable-bulk-memory", "--enable-sign-ext", "--enable-nontrapping-float-to-int",
+				wasm, "-o", wasm,
+			})
+	}
+
+	return ctr.Directory("/out"), nil
+}
+
+// Publish uploads a Build output directory to an S3-compatible bucket via
+// the bucketupload module, with each file's Content-Type set so browsers
+// can stream-compile the module (application/wasm).
+func (m *Wasmbuild) Publish(
+	ctx context.Context,
+
+	// Build output directory
+	dist *dagger.Directory,
+
+	// Bucket endpoint URL
+	endpoint *dagger.Secret,
+
+	// Bucket name
+	bucket *dagger.Secret,
+
+	// Bucket access key ID
+	accessKeyID *dagger.Secret,
+
+	// Bucket secret access key
+	secretAccessKey *dagger.Secret,
+
+	// Bucket key prefix. Use "" to upload at the bucket root.
+	// +optional
+	prefix string,
+) error {
+	uploader := dag.Bucketuploader(endpoint, bucket, accessKeyID, secretAccessKey)
+
+	entries, err := dist.Glob(ctx, "**/*")
+	if err != nil {
+		return fmt.Errorf("failed to list build output: %w", err)
+	}
+
+	var metadata []*dagger.BucketuploaderFilePathMetadata
+	// Glob returns directory entries with a trailing slash — skip them.
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+		if contentType, ok := contentTypes[path.Ext(entry)]; ok {
+			metadata = append(metadata, uploader.NewFilePathMetadata(entry).WithContentType(contentType))
+		}
+	}
+
+	err = uploader.UploadTree(ctx, dist, dagger.BucketuploaderUploadTreeOpts{
+		Prefix:   prefix,
+		Metadata: metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish wasm build: %w", err)
+	}
+
+	return nil
+}
+
+// goContainer returns a container with the source mounted and the Go module
+// and build caches shared with the other Go modules in this daggerverse.
+func (m *Wasmbuild) goContainer() *dagger.Container {
+	return dag.Container().
+		From(goImage).
+		WithMountedCache("/go/pkg/mod", dag.CacheVolume("go-mod")).
+		WithMountedCache("/root/.cache/go-build", dag.CacheVolume("go-build")).
+		WithWorkdir("/src").
+		WithDirectory("/src", m.Source)
+}
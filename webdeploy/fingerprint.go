@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// rewrittenExtensions are the text files whose references to fingerprinted
+// assets are rewritten.
+var rewrittenExtensions = map[string]bool{
+	".html":        true,
+	".htm":         true,
+	".css":         true,
+	".js":          true,
+	".mjs":         true,
+	".cjs":         true,
+	".json":        true,
+	".webmanifest": true,
+	".svg":         true,
+	".xml":         true,
+}
+
+// reference matches the path-like runs of text that may refer to a file:
+// URLs, absolute and relative paths, and bare file names.
+var reference = regexp.MustCompile(`[\w@~%+./-]+`)
+
+// hashSegment matches a content hash as bundlers emit it.
+var hashSegment = regexp.MustCompile(`^[0-9A-Za-z_]{8,}$`)
+
+// isFingerprinted reports whether a file name already carries a content
+// hash as its last "-" or "." separated segment before the extension, as
+// Vite ("index-BxT3k9aQ.js") and webpack ("main.3f2a1b9c.js") emit them.
+// Hashes without a digit are not recognized; those files are fingerprinted
+// again, which is harmless.
+func isFingerprinted(name string) bool {
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	i := strings.LastIndexAny(base, ".-")
+	if i < 0 {
+		return false
+	}
+	seg := base[i+1:]
+	return hashSegment.MatchString(seg) && strings.ContainsAny(seg, "0123456789")
+}
+
+// fingerprintName inserts hash before the extension of p, keeping its
+// directory: "assets/logo.png" becomes "assets/logo.<hash>.png".
+func fingerprintName(p, hash string) string {
+	ext := path.Ext(p)
+	return strings.TrimSuffix(p, ext) + "." + hash + ext
+}
+
+// parseSums parses sha256sum output into a map of cleaned relative paths
+// to the first eight hex digits of each file's hash.
+func parseSums(out string) map[string]string {
+	sums := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		hash, file, ok := strings.Cut(scanner.Text(), "  ")
+		if !ok || len(hash) < 8 {
+			continue
+		}
+		sums[path.Clean(file)] = hash[:8]
+	}
+	return sums
+}
+
+// renamer rewrites references to renamed files. Renames map paths relative
+// to the site root to their fingerprinted names.
+type renamer struct {
+	renames map[string]string
+	// files holds every path in the site, renamed or not.
+	files map[string]bool
+	// byBase indexes the renamed paths by file name.
+	byBase map[string][]string
+}
+
+func newRenamer(files []string, renames map[string]string) *renamer {
+	r := &renamer{renames: renames, files: map[string]bool{}, byBase: map[string][]string{}}
+	for _, f := range files {
+		r.files[f] = true
+	}
+	for p := range renames {
+		base := path.Base(p)
+		r.byBase[base] = append(r.byBase[base], p)
+	}
+	return r
+}
+
+// rewrite replaces references in content, a file at path file, to renamed
+// files with their fingerprinted names. Only the last path segment of a
+// reference is replaced, so prefixes, hosts, and relative paths are kept.
+func (r *renamer) rewrite(file, content string) string {
+	return reference.ReplaceAllStringFunc(content, func(ref string) string {
+		target, ok := r.resolve(file, ref)
+		if !ok {
+			return ref
+		}
+		return strings.TrimSuffix(ref, path.Base(target)) + path.Base(r.renames[target])
+	})
+}
+
+// resolve returns the renamed file ref refers to. A reference that resolves
+// exactly, relative to file or to the site root, wins, and one that resolves
+// exactly to a file that was not renamed is left alone; otherwise the file
+// sharing the most trailing path segments with ref is used, as long as it
+// is the only one. That covers URLs with hosts or base paths in front.
+func (r *renamer) resolve(file, ref string) (string, bool) {
+	if strings.HasSuffix(ref, "/") {
+		return "", false
+	}
+	candidates := r.byBase[path.Base(ref)]
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	exact := path.Join(path.Dir(file), ref)
+	if strings.HasPrefix(ref, "/") {
+		exact = strings.TrimPrefix(path.Clean(ref), "/")
+	}
+	if _, ok := r.renames[exact]; ok {
+		return exact, true
+	}
+	if r.files[exact] {
+		return "", false
+	}
+
+	refSegs := segments(ref)
+	best, bestN, tie := "", 0, false
+	for _, c := range candidates {
+		n := commonSuffix(refSegs, segments(c))
+		switch {
+		case n > bestN:
+			best, bestN, tie = c, n, false
+		case n == bestN:
+			tie = true
+		}
+	}
+	if tie {
+		return "", false
+	}
+	return best, true
+}
+
+// segments splits p into its path segments, dropping empty, "." and ".."
+// segments.
+func segments(p string) []string {
+	var segs []string
+	for _, s := range strings.Split(p, "/") {
+		if s != "" && s != "." && s != ".." {
+			segs = append(segs, s)
+		}
+	}
+	return segs
+}
+
+// commonSuffix returns how many trailing segments a and b share.
+func commonSuffix(a, b []string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+	return n
+}
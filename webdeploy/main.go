@@ -0,0 +1,567 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"dagger/webdeploy/internal/dagger"
+)
+
+const (
+	nodeImage   string = "node:22-bookworm"
+	alpineImage string = "alpine:3.21"
+	awsImage    string = "amazon/aws-cli:latest"
+
+	cloudflare = "cloudflare"
+	cloudfront = "cloudfront"
+	fastly     = "fastly"
+
+	// immutableCacheControl is sent with fingerprinted assets, whose URLs
+	// change whenever their contents do.
+	immutableCacheControl = "public, max-age=31536000, immutable"
+
+	// pageCacheControl is sent with pages and every other file served at a
+	// stable URL, so browsers revalidate them on each load.
+	pageCacheControl = "public, max-age=0, must-revalidate"
+)
+
+// pagePatterns match the pages, which are uploaded after everything they
+// reference.
+var pagePatterns = []string{"**/*.html", "**/*.htm"}
+
+// extraContentTypes covers web asset extensions that the Go mime table may
+// not know about in a minimal container.
+var extraContentTypes = map[string]string{
+	".js":          "text/javascript; charset=utf-8",
+	".mjs":         "text/javascript; charset=utf-8",
+	".wasm":        "application/wasm",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".ico":         "image/x-icon",
+	".map":         "application/json",
+	".webmanifest": "application/manifest+json",
+	".txt":         "text/plain; charset=utf-8",
+}
+
+// Webdeploy builds a static web frontend, fingerprints its assets, uploads
+// it to an S3-compatible bucket, and purges the CDN in front of it.
+type Webdeploy struct {
+	// Source is the repository containing the frontend.
+	//
+	// +private
+	Source *dagger.Directory
+
+	// Path is the frontend package directory, relative to Source.
+	//
+	// +private
+	Path string
+
+	// Script is the package.json script that builds the site.
+	//
+	// +private
+	Script string
+
+	// Output is the build output directory, relative to Path.
+	//
+	// +private
+	Output string
+
+	// Assets is the directory of fingerprinted assets, relative to Output.
+	//
+	// +private
+	Assets string
+
+	// EnvVars are extra environment variables in KEY=VALUE format.
+	//
+	// +private
+	EnvVars []string
+
+	// +private
+	BucketEndpoint *dagger.Secret
+
+	// +private
+	BucketName *dagger.Secret
+
+	// +private
+	BucketAccessKeyID *dagger.Secret
+
+	// +private
+	BucketSecretAccessKey *dagger.Secret
+
+	// +private
+	BucketPrefix string
+
+	// CDN is the provider purged after a deploy: "cloudflare",
+	// "cloudfront", or "fastly".
+	//
+	// +private
+	CDN string
+
+	// CDNID is the Cloudflare zone, CloudFront distribution, or Fastly
+	// service ID.
+	//
+	// +private
+	CDNID string
+
+	// +private
+	CDNToken *dagger.Secret
+
+	// +private
+	CDNAccessKeyID *dagger.Secret
+
+	// +private
+	CDNSecretAccessKey *dagger.Secret
+}
+
+// New creates a new Webdeploy module instance.
+func New(
+	// The repository containing the frontend. The package manager is
+	// detected from the lockfile at its root.
+	// +defaultPath="/"
+	source *dagger.Directory,
+
+	// Frontend package directory, relative to source (e.g., "web")
+	// +optional
+	// +default="."
+	path string,
+
+	// package.json script that builds the site
+	// +optional
+	// +default="build"
+	script string,
+
+	// Build output directory, relative to path
+	// +optional
+	// +default="dist"
+	output string,
+
+	// Directory of bundled assets to fingerprint and serve as immutable,
+	// relative to output
+	// +optional
+	// +default="assets"
+	assets string,
+
+	// Environment variables in KEY=VALUE format for the build (e.g.,
+	// "VITE_API_URL=https://api.example.com")
+	// +optional
+	envVars []string,
+) *Webdeploy {
+	return &Webdeploy{
+		Source:  source,
+		Path:    path,
+		Script:  script,
+		Output:  output,
+		Assets:  assets,
+		EnvVars: envVars,
+	}
+}
+
+// WithBucket sets the S3-compatible bucket the site is uploaded to.
+func (m *Webdeploy) WithBucket(
+	// Bucket endpoint URL
+	endpoint *dagger.Secret,
+
+	// Bucket name
+	bucket *dagger.Secret,
+
+	// Bucket access key ID
+	accessKeyID *dagger.Secret,
+
+	// Bucket secret access key
+	secretAccessKey *dagger.Secret,
+
+	// Bucket key prefix. Use "" for the bucket root.
+	// +optional
+	prefix string,
+) *Webdeploy {
+	m.BucketEndpoint = endpoint
+	m.BucketName = bucket
+	m.BucketAccessKeyID = accessKeyID
+	m.BucketSecretAccessKey = secretAccessKey
+	m.BucketPrefix = prefix
+	return m
+}
+
+// WithCloudflare purges a Cloudflare zone's cache after each deploy.
+func (m *Webdeploy) WithCloudflare(
+	// Zone ID
+	zoneID string,
+
+	// API token with the Cache Purge permission
+	token *dagger.Secret,
+) *Webdeploy {
+	m.CDN = cloudflare
+	m.CDNID = zoneID
+	m.CDNToken = token
+	return m
+}
+
+// WithCloudfront invalidates a CloudFront distribution after each deploy.
+func (m *Webdeploy) WithCloudfront(
+	// Distribution ID
+	distributionID string,
+
+	// AWS access key ID with cloudfront:CreateInvalidation
+	accessKeyID *dagger.Secret,
+
+	// AWS secret access key
+	secretAccessKey *dagger.Secret,
+) *Webdeploy {
+	m.CDN = cloudfront
+	m.CDNID = distributionID
+	m.CDNAccessKeyID = accessKeyID
+	m.CDNSecretAccessKey = secretAccessKey
+	return m
+}
+
+// WithFastly purges a Fastly service after each deploy.
+func (m *Webdeploy) WithFastly(
+	// Service ID
+	serviceID string,
+
+	// API token with the purge_all scope
+	token *dagger.Secret,
+) *Webdeploy {
+	m.CDN = fastly
+	m.CDNID = serviceID
+	m.CDNToken = token
+	return m
+}
+
+// Build installs dependencies with the package manager whose lockfile is
+// present, runs the build script, and returns the build output directory.
+func (m *Webdeploy) Build(ctx context.Context) (*dagger.Directory, error) {
+	ctr, err := m.build(ctx)
+	if err != nil {
+		return nil, err
+	}
+	site, err := ctr.Directory(m.Output).Sync(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return nil, fmt.Errorf("build failed\n\n%s%s", e.Stdout, e.Stderr)
+	} else if err != nil {
+		return nil, fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return site, nil
+}
+
+// Fingerprint renames every file under the assets directory whose name
+// does not already carry a content hash to "<name>.<hash>.<ext>", and
+// rewrites references to the renamed files in the site's html, css, js,
+// json, svg, and xml files. Pages are never renamed.
+//
+// Hashes cover each file's contents before references are rewritten.
+func (m *Webdeploy) Fingerprint(
+	ctx context.Context,
+
+	// Built site. Defaults to the output of build.
+	// +optional
+	site *dagger.Directory,
+) (*dagger.Directory, error) {
+	if m.Assets == "" || path.Clean(m.Assets) == "." {
+		return nil, fmt.Errorf("invalid assets directory %q: must be a subdirectory of the build output", m.Assets)
+	}
+	if site == nil {
+		var err error
+		if site, err = m.Build(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	ok, err := site.Exists(ctx, m.Assets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect build output: %w", err)
+	}
+	if !ok {
+		return site, nil
+	}
+
+	out, err := dag.Container().
+		From(alpineImage).
+		WithMountedDirectory("/site", site).
+		WithWorkdir("/site").
+		WithExec([]string{"sh", "-c", `find "$1" -type f -exec sha256sum {} +`, "sh", path.Clean(m.Assets)}).
+		Stdout(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash assets: %w", err)
+	}
+
+	renames := map[string]string{}
+	for file, hash := range parseSums(out) {
+		if isFingerprinted(file) || isPage(file) {
+			continue
+		}
+		renames[file] = fingerprintName(file, hash)
+	}
+	if len(renames) == 0 {
+		return site, nil
+	}
+
+	files, err := listFiles(ctx, site)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newRenamer(files, renames)
+	fingerprinted := site
+	for _, file := range files {
+		name, renamed := renames[file]
+		if !renamed {
+			name = file
+		}
+
+		if !rewrittenExtensions[strings.ToLower(path.Ext(file))] {
+			if renamed {
+				fingerprinted = fingerprinted.WithoutFile(file).WithFile(name, site.File(file))
+			}
+			continue
+		}
+
+		content, err := site.File(file).Contents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		rewritten := r.rewrite(file, content)
+		if renamed || rewritten != content {
+			fingerprinted = fingerprinted.WithoutFile(file).WithNewFile(name, rewritten)
+		}
+	}
+
+	return fingerprinted, nil
+}
+
+// Deploy builds and fingerprints the site, uploads it to the bucket, and
+// purges the CDN when one is configured.
+//
+// Everything but the pages is uploaded first, so a page is never served
+// before the assets it references exist. Assets are uploaded with a one
+// year immutable Cache-Control; pages and every other file use
+// "max-age=0, must-revalidate". Files from earlier deploys are left in
+// place for clients still holding old pages.
+func (m *Webdeploy) Deploy(ctx context.Context) (string, error) {
+	if m.BucketEndpoint == nil {
+		return "", fmt.Errorf("no bucket configured: call with-bucket first")
+	}
+
+	site, err := m.Fingerprint(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	uploader := dag.Bucketuploader(m.BucketEndpoint, m.BucketName, m.BucketAccessKeyID, m.BucketSecretAccessKey)
+
+	assets := dag.Directory().WithDirectory("/", site, dagger.DirectoryWithDirectoryOpts{Exclude: pagePatterns})
+	pages := dag.Directory().WithDirectory("/", site, dagger.DirectoryWithDirectoryOpts{Include: pagePatterns})
+
+	uploaded := 0
+	for _, dir := range []*dagger.Directory{assets, pages} {
+		metadata, err := m.metadata(ctx, uploader, dir)
+		if err != nil {
+			return "", err
+		}
+		if len(metadata) == 0 {
+			continue
+		}
+
+		err = uploader.UploadTree(ctx, dir, dagger.BucketuploaderUploadTreeOpts{
+			Prefix:   m.BucketPrefix,
+			Metadata: metadata,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to upload site: %w", err)
+		}
+		uploaded += len(metadata)
+	}
+
+	summary := fmt.Sprintf("✅ Deployed %d files", uploaded)
+	if m.BucketPrefix != "" {
+		summary += " under " + m.BucketPrefix
+	}
+
+	if m.CDN != "" {
+		if _, err := m.Purge(ctx); err != nil {
+			return "", err
+		}
+		summary += ", purged " + m.CDN
+	}
+
+	return summary, nil
+}
+
+// Purge purges the configured CDN's whole cache. Fingerprinted assets are
+// unaffected in practice, since a new deploy only adds new asset URLs.
+func (m *Webdeploy) Purge(ctx context.Context) (string, error) {
+	var ctr *dagger.Container
+	switch m.CDN {
+	case cloudflare:
+		ctr = curlContainer(m.CDNID, m.CDNToken).
+			WithExec([]string{"sh", "-c",
+				`curl -sS --fail-with-body -X POST "https://api.cloudflare.com/client/v4/zones/$CDN_ID/purge_cache" ` +
+					`-H "Authorization: Bearer $CDN_TOKEN" -H 'Content-Type: application/json' --data '{"purge_everything":true}'`,
+			})
+	case fastly:
+		ctr = curlContainer(m.CDNID, m.CDNToken).
+			WithExec([]string{"sh", "-c",
+				`curl -sS --fail-with-body -X POST "https://api.fastly.com/service/$CDN_ID/purge_all" -H "Fastly-Key: $CDN_TOKEN"`,
+			})
+	case cloudfront:
+		ctr = dag.Container().
+			From(awsImage).
+			WithSecretVariable("AWS_ACCESS_KEY_ID", m.CDNAccessKeyID).
+			WithSecretVariable("AWS_SECRET_ACCESS_KEY", m.CDNSecretAccessKey).
+			WithEnvVariable("AWS_DEFAULT_REGION", "us-east-1").
+			WithEnvVariable("WEBDEPLOY_SESSION", fmt.Sprintf("%d", time.Now().UnixNano())).
+			WithExec([]string{
+				"aws", "cloudfront", "create-invalidation",
+				"--distribution-id", m.CDNID,
+				"--paths", "/*",
+			})
+	default:
+		return "", fmt.Errorf("no CDN configured: call with-cloudflare, with-cloudfront, or with-fastly first")
+	}
+
+	_, err := ctr.Sync(ctx)
+
+	var e *dagger.ExecError
+	if errors.As(err, &e) {
+		return "", fmt.Errorf("%s purge failed\n\n%s%s", m.CDN, e.Stdout, e.Stderr)
+	} else if err != nil {
+		return "", fmt.Errorf("unexpected error: %w", err)
+	}
+
+	return fmt.Sprintf("✅ Purged %s %s", m.CDN, m.CDNID), nil
+}
+
+// build returns the container after installing dependencies and running
+// the build script, with the package directory as workdir.
+func (m *Webdeploy) build(ctx context.Context) (*dagger.Container, error) {
+	pm, install, err := m.packageManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ctr := dag.Container().
+		From(nodeImage).
+		WithExec([]string{"corepack", "enable"}).
+		WithMountedCache("/root/.npm", dag.CacheVolume("npm")).
+		WithMountedCache("/root/.cache/yarn", dag.CacheVolume("yarn")).
+		WithMountedCache("/root/.local/share/pnpm/store", dag.CacheVolume("pnpm")).
+		WithDirectory("/src", m.Source, dagger.ContainerWithDirectoryOpts{
+			Exclude: []string{"**/node_modules"},
+		}).
+		WithWorkdir("/src").
+		WithExec(install).
+		WithWorkdir("/src/" + m.Path)
+
+	for _, env := range m.EnvVars {
+		k, v, ok := strings.Cut(env, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid env var %q: must be in KEY=VALUE format", env)
+		}
+		ctr = ctr.WithEnvVariable(k, v)
+	}
+
+	return ctr.WithExec([]string{pm, "run", m.Script}), nil
+}
+
+// packageManager returns the package manager whose lockfile is present at
+// the source root and its install command.
+func (m *Webdeploy) packageManager(ctx context.Context) (string, []string, error) {
+	lockfiles := []struct {
+		file    string
+		pm      string
+		install []string
+	}{
+		{"pnpm-lock.yaml", "pnpm", []string{"pnpm", "install", "--frozen-lockfile"}},
+		{"yarn.lock", "yarn", []string{"yarn", "install", "--frozen-lockfile"}},
+		{"package-lock.json", "npm", []string{"npm", "ci"}},
+	}
+	for _, l := range lockfiles {
+		ok, err := m.Source.Exists(ctx, l.file)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to inspect source directory: %w", err)
+		}
+		if ok {
+			return l.pm, l.install, nil
+		}
+	}
+	return "npm", []string{"npm", "install"}, nil
+}
+
+// metadata returns the upload metadata for every file in dir: an immutable
+// Cache-Control for files under the assets directory and the page
+// Cache-Control for everything else, plus a Content-Type.
+func (m *Webdeploy) metadata(
+	ctx context.Context,
+	uploader *dagger.Bucketuploader,
+	dir *dagger.Directory,
+) ([]*dagger.BucketuploaderFilePathMetadata, error) {
+	files, err := listFiles(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	assetsPrefix := path.Clean(m.Assets) + "/"
+	var metadata []*dagger.BucketuploaderFilePathMetadata
+	for _, file := range files {
+		cacheControl := pageCacheControl
+		if strings.HasPrefix(file, assetsPrefix) && !isPage(file) {
+			cacheControl = immutableCacheControl
+		}
+
+		meta := uploader.NewFilePathMetadata(file).WithCacheControl(cacheControl)
+		if contentType := contentTypeFor(strings.ToLower(path.Ext(file))); contentType != "" {
+			meta = meta.WithContentType(contentType)
+		}
+		metadata = append(metadata, meta)
+	}
+	return metadata, nil
+}
+
+// curlContainer returns a container with curl, the CDN ID, and the CDN
+// token, which is only ever exposed through a secret environment variable.
+func curlContainer(id string, token *dagger.Secret) *dagger.Container {
+	return dag.Container().
+		From(alpineImage).
+		WithExec([]string{"apk", "add", "--no-cache", "curl"}).
+		WithEnvVariable("CDN_ID", id).
+		WithSecretVariable("CDN_TOKEN", token).
+		WithEnvVariable("WEBDEPLOY_SESSION", fmt.Sprintf("%d", time.Now().UnixNano()))
+}
+
+// listFiles returns the paths of every file in dir.
+func listFiles(ctx context.Context, dir *dagger.Directory) ([]string, error) {
+	entries, err := dir.Glob(ctx, "**/*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list site files: %w", err)
+	}
+
+	var files []string
+	// Glob returns directory entries with a trailing slash — skip them.
+	for _, entry := range entries {
+		if strings.HasSuffix(entry, "/") {
+			continue
+		}
+		files = append(files, entry)
+	}
+	return files, nil
+}
+
+func isPage(file string) bool {
+	ext := strings.ToLower(path.Ext(file))
+	return ext == ".html" || ext == ".htm"
+}
+
+func contentTypeFor(ext string) string {
+	if t, ok := extraContentTypes[ext]; ok {
+		return t
+	}
+	return mime.TypeByExtension(ext)
+}
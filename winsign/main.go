@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"dagger/winsign/internal/dagger"
+)
+
+const (
+	debianImage  string = "debian:bookworm-slim"
+	javaImage    string = "eclipse-temurin:21-jre-jammy"
+	jsignVersion string = "7.1"
+)
+
+// Signing backends.
+const (
+	backendPfx   string = "pfx"
+	backendAzure string = "azure"
+	backendAws   string = "aws"
+)
+
+type Winsign struct {
+	// TimestampURL is the RFC 3161 timestamp server.
+	//
+	// +private
+	TimestampURL string
+
+	// Description is the program name shown by Windows.
+	//
+	// +private
+	Description string
+
+	// URL is the program URL embedded in the signature.
+	//
+	// +private
+	URL string
+
+	// Backend is the signing backend: "pfx", "azure", or "aws".
+	//
+	// +private
+	Backend string
+
+	// Certificate is the PFX (PKCS#12) certificate and key.
+	//
+	// +private
+	Certificate *dagger.Secret
+
+	// Password is the PFX password, or the Azure access token.
+	//
+	// +private
+	Password *dagger.Secret
+
+	// Keystore is the Azure Key Vault name or the AWS region.
+	//
+	// +private
+	Keystore string
+
+	// Alias is the Azure certificate name or the AWS KMS key ID.
+	//
+	// +private
+	Alias string
+
+	// CertificateChain is the public certificate chain for an AWS KMS key.
+	//
+	// +private
+	CertificateChain *dagger.File
+
+	// AccessKeyID is the AWS access key ID.
+	//
+	// +private
+	AccessKeyID *dagger.Secret
+
+	// SecretAccessKey is the AWS secret access key.
+	//
+	// +private
+	SecretAccessKey *dagger.Secret
+}
+
+// New creates a new Winsign module instance. Choose a signing backend with
+// WithPfx, WithAzureKeyVault, or WithAwsKms.
+func New(
+	// RFC 3161 timestamp server, so signatures outlive the certificate
+	// +optional
+	// +default="http://timestamp.digicert.com"
+	timestampURL string,
+
+	// Program name shown in the UAC prompt (e.g., "MyApp Installer")
+	// +optional
+	description string,
+
+	// Program URL embedded in the signature
+	// +optional
+	url string,
+) *Winsign {
+	return &Winsign{
+		TimestampURL: timestampURL,
+		Description:  description,
+		URL:          url,
+	}
+}
+
+// WithPfx signs with a code signing certificate and private key from a PFX
+// (PKCS#12) file, using osslsigncode.
+func (m *Winsign) WithPfx(
+	// PFX file with the certificate chain and private key
+	certificate *dagger.Secret,
+
+	// PFX password
+	// +optional
+	password *dagger.Secret,
+) *Winsign {
+	m.Backend = backendPfx
+	m.Certificate = certificate
+	m.Password = password
+	return m
+}
+
+// WithAzureKeyVault signs with a certificate whose key never leaves Azure
+// Key Vault, using jsign.
+func (m *Winsign) WithAzureKeyVault(
+	// Key Vault name (e.g., "acme-codesign")
+	vault string,
+
+	// Certificate name in the vault
+	certificateName string,
+
+	// Access token for https://vault.azure.net (e.g., from
+	// "az account get-access-token --resource https://vault.azure.net")
+	accessToken *dagger.Secret,
+) *Winsign {
+	m.Backend = backendAzure
+	m.Keystore = vault
+	m.Alias = certificateName
+	m.Password = accessToken
+	return m
+}
+
+// WithAwsKms signs with an AWS KMS key and its public certificate chain,
+// using jsign.
+func (m *Winsign) WithAwsKms(
+	// AWS region of the key (e.g., "eu-west-1")
+	region string,
+
+	// KMS key ID or ARN
+	keyID string,
+
+	// PEM certificate chain issued for the KMS key
+	certificateChain *dagger.File,
+
+	// AWS access key ID
+	accessKeyID *dagger.Secret,
+
+	// AWS secret access key
+	secretAccessKey *dagger.Secret,
+) *Winsign {
+	m.Backend = backendAws
+	m.Keystore = region
+	m.Alias = keyID
+	m.CertificateChain = certificateChain
+	m.AccessKeyID = accessKeyID
+	m.SecretAccessKey = secretAccessKey
+	return m
+}
+
+// Sign Authenticode-signs every .exe, .msi, and .dll file in a directory
+// with a SHA-256 signature and a timestamp, and returns the signed
+// directory.
+func (m *Winsign) Sign(
+	ctx context.Context,
+
+	// Directory of Windows artifacts
+	artifacts *dagger.Directory,
+
+	// Glob patterns of files to sign (default: "**/*.exe", "**/*.msi", "**/*.dll")
+	// +optional
+	include []string,
+) (*dagger.Directory, error) {
+	files, err := m.files(ctx, artifacts, include)
+	if err != nil {
+		return nil, err
+	}
+
+	var ctr *dagger.Container
+	switch m.Backend {
+	case backendPfx:
+		ctr = m.osslsigncode().
+			WithMountedSecret("/codesign/cert.pfx", m.Certificate).
+			WithDirectory("/artifacts", artifacts)
+		args := []string{"osslsigncode", "sign", "-pkcs12", "/codesign/cert.pfx", "-h", "sha256", "-ts", m.TimestampURL}
+		if m.Password != nil {
+			ctr = ctr.WithMountedSecret("/codesign/cert.pass", m.Password)
+			args = append(args, "-readpass", "/codesign/cert.pass")
+		}
+		if m.Description != "" {
+			args = append(args, "-n", m.Description)
+		}
+		if m.URL != "" {
+			args = append(args, "-i", m.URL)
+		}
+		for _, f := range files {
+			// osslsigncode writes to a new file; replace the original.
+			cmd := append(append([]string{}, args...), "-in", "/artifacts/"+f, "-out", "/tmp/signed")
+			ctr = ctr.
+				WithExec(cmd).
+				WithExec([]string{"mv", "/tmp/signed", "/artifacts/" + f})
+		}
+
+	case backendAzure, backendAws:
+		ctr = m.jsign().WithDirectory("/artifacts", artifacts)
+		args := []string{"java", "-jar", "/jsign.jar", "--alg", "SHA-256", "--tsaurl", m.TimestampURL, "--alias", m.Alias}
+		if m.Backend == backendAzure {
+			ctr = ctr.WithSecretVariable("AZURE_ACCESS_TOKEN", m.Password)
+			args = append(args, "--storetype", "AZUREKEYVAULT", "--keystore", m.Keystore, "--storepass", "env:AZURE_ACCESS_TOKEN")
+		} else {
+			// jsign reads AWS credentials from the standard environment
+			// variables when no storepass is given.
+			ctr = ctr.
+				WithSecretVariable("AWS_ACCESS_KEY_ID", m.AccessKeyID).
+				WithSecretVariable("AWS_SECRET_ACCESS_KEY", m.SecretAccessKey).
+				WithFile("/codesign/chain.pem", m.CertificateChain)
+			args = append(args, "--storetype", "AWS", "--keystore", m.Keystore, "--certfile", "/codesign/chain.pem")
+		}
+		if m.Description != "" {
+			args = append(args, "--name", m.Description)
+		}
+		if m.URL != "" {
+			args = append(args, "--url", m.URL)
+		}
+		for _, f := range files {
+			args = append(args, "/artifacts/"+f)
+		}
+		ctr = ctr.WithExec(args)
+
+	default:
+		return nil, fmt.Errorf("no signing backend: call with-pfx, with-azure-key-vault, or with-aws-kms first")
+	}
+
+	return ctr.Directory("/artifacts"), nil
+}
+
+// Verify checks the Authenticode signature of every .exe, .msi, and .dll
+// file in a directory and fails listing any that are unsigned or invalid.
+func (m *Winsign) Verify(
+	ctx context.Context,
+
+	// Directory of Windows artifacts
+	artifacts *dagger.Directory,
+
+	// Glob patterns of files to verify (default: "**/*.exe", "**/*.msi", "**/*.dll")
+	// +optional
+	include []string,
+) (string, error) {
+	files, err := m.files(ctx, artifacts, include)
+	if err != nil {
+		return "", err
+	}
+
+	ctr := m.osslsigncode().WithDirectory("/artifacts", artifacts)
+
+	var failures []string
+	for _, f := range files {
+		verified := ctr.WithExec(
+			[]string{"osslsigncode", "verify", "-in", "/artifacts/" + f},
+			dagger.ContainerWithExecOpts{Expect: dagger.ReturnTypeAny},
+		)
+		code, err := verified.ExitCode(ctx)
+		if err != nil {
+			return "", fmt.Errorf("unexpected error: %w", err)
+		}
+		if code != 0 {
+			out, _ := verified.CombinedOutput(ctx)
+			failures = append(failures, fmt.Sprintf("%s\n%s", f, out))
+		}
+	}
+
+	if len(failures) > 0 {
+		return "", fmt.Errorf("%d of %d files have no valid signature\n\n%s", len(failures), len(files), strings.Join(failures, "\n"))
+	}
+
+	return fmt.Sprintf("✅ %d files have valid signatures", len(files)), nil
+}
+
+// files returns the files in a directory matching the include patterns.
+func (m *Winsign) files(ctx context.Context, artifacts *dagger.Directory, include []string) ([]string, error) {
+	if len(include) == 0 {
+		include = []string{"**/*.exe", "**/*.msi", "**/*.dll"}
+	}
+
+	seen := map[string]bool{}
+	var files []string
+	for _, pattern := range include {
+		matches, err := artifacts.Glob(ctx, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list artifacts: %w", err)
+		}
+		// Glob returns directory entries with a trailing slash — skip them.
+		for _, match := range matches {
+			if strings.HasSuffix(match, "/") || seen[match] {
+				continue
+			}
+			seen[match] = true
+			files = append(files, path.Clean(match))
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files to sign matching %s", strings.Join(include, ", "))
+	}
+	return files, nil
+}
+
+// osslsigncode returns a Debian container with osslsigncode.
+func (m *Winsign) osslsigncode() *dagger.Container {
+	return dag.Container().
+		From(debianImage).
+		WithMountedCache("/var/cache/apt", dag.CacheVolume("winsign-apt")).
+		WithExec([]string{"apt-get", "update"}).
+		WithExec([]string{"apt-get", "install", "-y", "--no-install-recommends", "ca-certificates", "osslsigncode"})
+}
+
+// jsign returns a Java container with the jsign jar, which signs with keys
+// held in cloud key stores.
+func (m *Winsign) jsign() *dagger.Container {
+	url := fmt.Sprintf("https://github.com/ebourg/jsign/releases/download/%s/jsign-%s.jar", jsignVersion, jsignVersion)
+	return dag.Container().
+		From(javaImage).
+		WithFile("/jsign.jar", dag.HTTP(url))
+}